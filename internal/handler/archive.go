@@ -0,0 +1,186 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"path/filepath"
+
+	"github.com/PritOriginal/cryptolabs-back/internal/services/compression"
+	"github.com/PritOriginal/problem-map-server/pkg/handlers"
+	slogger "github.com/PritOriginal/problem-map-server/pkg/logger"
+	"github.com/PritOriginal/problem-map-server/pkg/responses"
+)
+
+type ArchiveService interface {
+	Create(files []compression.ArchiveFile) ([]byte, []compression.ArchiveEntry, error)
+	List(archive []byte) ([]compression.ArchiveEntry, error)
+	Extract(archive []byte, name string) (compression.ArchiveFile, error)
+	ExtractAll(archive []byte) ([]compression.ArchiveFile, error)
+}
+
+type ArchiveHandler struct {
+	handlers.BaseHandler
+	s ArchiveService
+}
+
+func NewArchiveHandler(log *slog.Logger, s ArchiveService) *ArchiveHandler {
+	return &ArchiveHandler{handlers.BaseHandler{Log: log}, s}
+}
+
+// Create упаковывает загруженные файлы (поле files) в архив. Алгоритм задаётся
+// полем algorithm: одно значение применяется ко всем файлам, а список
+// той же длины, что и files, задаёт алгоритм для каждого файла по порядку.
+// По умолчанию алгоритм выбирается автоматически.
+func (h *ArchiveHandler) Create() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := r.ParseMultipartForm(32 << 20) // 32 MB
+		if err != nil {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "error parse multipart form", Err: err}, responses.ErrBadRequest)
+			return
+		}
+
+		fileHeaders := r.MultipartForm.File["files"]
+		if len(fileHeaders) == 0 {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "files are required", Err: fmt.Errorf("no files")}, responses.ErrBadRequest)
+			return
+		}
+		algorithms := r.MultipartForm.Value["algorithm"]
+		if len(algorithms) > 1 && len(algorithms) != len(fileHeaders) {
+			h.RenderError(w, r,
+				handlers.HandlerError{Msg: "invalid algorithm", Err: fmt.Errorf("got %d algorithms for %d files", len(algorithms), len(fileHeaders))},
+				responses.ErrBadRequest,
+			)
+			return
+		}
+
+		files := make([]compression.ArchiveFile, len(fileHeaders))
+		for i, fileHeader := range fileHeaders {
+			data, err := readFile(fileHeader)
+			if err != nil {
+				h.RenderError(w, r, handlers.HandlerError{Msg: "failed read file", Err: err}, responses.ErrBadRequest)
+				return
+			}
+			files[i] = compression.ArchiveFile{Name: fileHeader.Filename, Data: data}
+			switch len(algorithms) {
+			case 0:
+			case 1:
+				files[i].Algorithm = algorithms[0]
+			default:
+				files[i].Algorithm = algorithms[i]
+			}
+		}
+
+		archive, entries, err := h.s.Create(files)
+		if err != nil {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "failed create archive", Err: err}, responses.ErrBadRequest)
+			return
+		}
+		h.Log.Debug("size archive", slog.Int("size", len(archive)))
+
+		mpw := multipart.NewWriter(w)
+		defer mpw.Close()
+		w.Header().Set("Content-Type", mpw.FormDataContentType())
+
+		entriesJson, err := json.Marshal(entries)
+		if err != nil {
+			h.RenderInternalError(w, r, handlers.HandlerError{Msg: "failed marshal entries", Err: err})
+			return
+		}
+		mpw.WriteField("entries", string(entriesJson))
+
+		fWriter, err := mpw.CreateFormFile("data", "archive.clar")
+		if err != nil {
+			h.RenderInternalError(w, r, handlers.HandlerError{Msg: "failed create form", Err: err})
+			return
+		}
+		fWriter.Write(archive)
+	}
+}
+
+// List возвращает оглавление архива.
+func (h *ArchiveHandler) List() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		archive, err := io.ReadAll(r.Body)
+		if err != nil {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "invalid data", Err: err}, responses.ErrBadRequest)
+			return
+		}
+
+		entries, err := h.s.List(archive)
+		if err != nil {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "invalid archive", Err: err}, responses.ErrBadRequest)
+			return
+		}
+
+		h.Render(w, r, responses.SucceededRenderer(entries))
+	}
+}
+
+// Extract возвращает файл архива, заданный параметром name, с исходным именем.
+func (h *ArchiveHandler) Extract() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		archive, err := io.ReadAll(r.Body)
+		if err != nil {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "invalid data", Err: err}, responses.ErrBadRequest)
+			return
+		}
+
+		file, err := h.s.Extract(archive, r.URL.Query().Get("name"))
+		if err != nil {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "failed extract file", Err: err}, responses.ErrBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", contentType(file.Name))
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": file.Name}))
+		if _, err := w.Write(file.Data); err != nil {
+			h.Log.Error("failed write file", slogger.Err(err))
+		}
+	}
+}
+
+// ExtractAll возвращает все файлы архива частями multipart с исходными именами.
+func (h *ArchiveHandler) ExtractAll() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		archive, err := io.ReadAll(r.Body)
+		if err != nil {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "invalid data", Err: err}, responses.ErrBadRequest)
+			return
+		}
+
+		files, err := h.s.ExtractAll(archive)
+		if err != nil {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "failed extract files", Err: err}, responses.ErrBadRequest)
+			return
+		}
+
+		mpw := multipart.NewWriter(w)
+		defer mpw.Close()
+		w.Header().Set("Content-Type", mpw.FormDataContentType())
+
+		for _, file := range files {
+			header := make(textproto.MIMEHeader)
+			header.Set("Content-Disposition", mime.FormatMediaType("form-data", map[string]string{"name": "files", "filename": file.Name}))
+			header.Set("Content-Type", contentType(file.Name))
+			fWriter, err := mpw.CreatePart(header)
+			if err != nil {
+				h.RenderInternalError(w, r, handlers.HandlerError{Msg: "failed create form", Err: err})
+				return
+			}
+			fWriter.Write(file.Data)
+		}
+	}
+}
+
+func contentType(name string) string {
+	if t := mime.TypeByExtension(filepath.Ext(name)); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+
+	"github.com/PritOriginal/cryptolabs-back/internal/services/audio"
+	"github.com/PritOriginal/problem-map-server/pkg/handlers"
+	slogger "github.com/PritOriginal/problem-map-server/pkg/logger"
+	"github.com/PritOriginal/problem-map-server/pkg/responses"
+)
+
+type AudioCompressionService interface {
+	Compress(wav []byte, opts audio.AudioOptions) (audio.AudioCompressionDetails, error)
+	Decompress(data []byte) ([]byte, error)
+}
+
+type AudioHandler struct {
+	handlers.BaseHandler
+	s AudioCompressionService
+}
+
+func NewAudioHandler(log *slog.Logger, s AudioCompressionService) *AudioHandler {
+	return &AudioHandler{handlers.BaseHandler{Log: log}, s}
+}
+
+// Compress сжимает загруженный WAV (поле wav, PCM16) выбранным кодеком.
+// В ответе — отчёт, восстановленный WAV и сжатые данные.
+func (h *AudioHandler) Compress() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := r.ParseMultipartForm(32 << 20) // 32 MB
+		if err != nil {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "error parse multipart form", Err: err}, responses.ErrBadRequest)
+			return
+		}
+
+		opts := audio.AudioOptions{
+			Codec:   r.URL.Query().Get("codec"),
+			Huffman: r.URL.Query().Get("huffman") == "true",
+		}
+		if opts.Codec == "" {
+			opts.Codec = audio.CodecADPCM
+		}
+
+		files := r.MultipartForm.File["wav"]
+		if len(files) == 0 {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "wav is required", Err: fmt.Errorf("no wav file")}, responses.ErrBadRequest)
+			return
+		}
+		data, err := readFile(files[0])
+		if err != nil {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "failed read wav", Err: err}, responses.ErrBadRequest)
+			return
+		}
+
+		compressed, err := h.s.Compress(data, opts)
+		if err != nil {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "failed compress", Err: err}, responses.ErrBadRequest)
+			return
+		}
+		h.Log.Debug("size compress", slog.Int("size", len(compressed.Data)))
+
+		mpw := multipart.NewWriter(w)
+		defer mpw.Close()
+		w.Header().Set("Content-Type", mpw.FormDataContentType())
+
+		detailsJson, err := json.Marshal(compressed.Details)
+		if err != nil {
+			h.RenderInternalError(w, r, handlers.HandlerError{Msg: "failed marshal details", Err: err})
+			return
+		}
+		mpw.WriteField("details", string(detailsJson))
+
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", `form-data; name="wav"; filename="decoded.wav"`)
+		header.Set("Content-Type", "audio/wav")
+		wavWriter, err := mpw.CreatePart(header)
+		if err != nil {
+			h.RenderInternalError(w, r, handlers.HandlerError{Msg: "failed create form", Err: err})
+			return
+		}
+		wavWriter.Write(compressed.WAV)
+
+		fWriter, err := mpw.CreateFormFile("data", "audio.clau")
+		if err != nil {
+			h.RenderInternalError(w, r, handlers.HandlerError{Msg: "failed create form", Err: err})
+			return
+		}
+		fWriter.Write(compressed.Data)
+	}
+}
+
+// Decompress восстанавливает сигнал из сжатых данных и возвращает его в WAV.
+func (h *AudioHandler) Decompress() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "invalid data", Err: err}, responses.ErrBadRequest)
+			return
+		}
+
+		wav, err := h.s.Decompress(data)
+		if err != nil {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "invalid data", Err: err}, responses.ErrBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "audio/wav")
+		w.Header().Set("Content-Disposition", "attachment; filename=audio.wav")
+		if _, err := w.Write(wav); err != nil {
+			h.Log.Error("failed write wav", slogger.Err(err))
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/PritOriginal/cryptolabs-back/internal/services/classical"
+	"github.com/PritOriginal/problem-map-server/pkg/handlers"
+	"github.com/PritOriginal/problem-map-server/pkg/responses"
+	"github.com/go-chi/chi/v5"
+)
+
+type ClassicalHandler struct {
+	handlers.BaseHandler
+	s classical.Service
+}
+
+func NewClassicalHandler(log *slog.Logger, s classical.Service) *ClassicalHandler {
+	return &ClassicalHandler{handlers.BaseHandler{Log: log}, s}
+}
+
+// Encrypt шифрует text шифром из пути. Параметры: key, alphabet_set (en по
+// умолчанию, ru или custom) и alphabet для alphabet_set=custom.
+func (h *ClassicalHandler) Encrypt() http.HandlerFunc {
+	return h.apply(classical.Service.Encrypt, "failed encrypt text")
+}
+
+func (h *ClassicalHandler) Decrypt() http.HandlerFunc {
+	return h.apply(classical.Service.Decrypt, "failed decrypt text")
+}
+
+func (h *ClassicalHandler) apply(f func(classical.Service, string, classical.Params) (classical.Result, error), msg string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		result, err := f(h.s, chi.URLParam(r, "cipher"), classicalParams(r))
+		if errors.Is(err, classical.ErrUnknownCipher) || errors.Is(err, classical.ErrInvalidAlphabet) || errors.Is(err, classical.ErrInvalidKey) {
+			h.RenderError(w, r, handlers.HandlerError{Msg: msg, Err: err}, responses.ErrBadRequest)
+			return
+		}
+		if err != nil {
+			h.RenderInternalError(w, r, handlers.HandlerError{Msg: msg, Err: err})
+			return
+		}
+
+		h.Render(w, r, responses.SucceededRenderer(result))
+	}
+}
+
+func classicalParams(r *http.Request) classical.Params {
+	params := classical.Params{
+		AlphabetSet: r.FormValue("alphabet_set"),
+		Alphabet:    r.FormValue("alphabet"),
+		Key:         r.FormValue("key"),
+		Text:        r.FormValue("text"),
+	}
+	if params.AlphabetSet == "" {
+		params.AlphabetSet = "en"
+	}
+	return params
+}
@@ -3,14 +3,21 @@ package handler
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"log/slog"
 	"mime/multipart"
 	"net/http"
+	"net/textproto"
+	"runtime"
+	"strconv"
 
 	"github.com/PritOriginal/cryptolabs-back/internal/services/compression"
 	"github.com/PritOriginal/problem-map-server/pkg/handlers"
+	slogger "github.com/PritOriginal/problem-map-server/pkg/logger"
 	"github.com/PritOriginal/problem-map-server/pkg/responses"
+	"github.com/go-chi/chi/v5"
 )
 
 type CompressionService interface {
@@ -20,6 +27,31 @@ type CompressionService interface {
 	DecompressWithDetails(data []byte) (compression.CompressionDetails, error)
 }
 
+// StreamCompressionService реализуют алгоритмы, которые умеют сжимать и
+// разжимать поток без загрузки всех данных в память.
+type StreamCompressionService interface {
+	NewWriter(w io.Writer) io.WriteCloser
+	NewReader(r io.Reader) io.Reader
+}
+
+// TracingCompressionService реализуют алгоритмы, которые умеют записывать
+// пошаговую трассировку сжатия.
+type TracingCompressionService interface {
+	CompressWithTrace(data []byte, limit int) (compression.CompressionDetails, error)
+}
+
+// SymbolSizeCompressionService реализуют алгоритмы, которые умеют кодировать
+// блоки из нескольких символов как один символ (расширение источника).
+type SymbolSizeCompressionService interface {
+	WithSymbolSize(k int) (compression.Service, error)
+}
+
+// LevelCompressionService реализуют алгоритмы с уровнями сжатия,
+// задающими компромисс между степенью и скоростью сжатия.
+type LevelCompressionService interface {
+	WithLevel(level int) (compression.Service, error)
+}
+
 type CompressionHandler struct {
 	handlers.BaseHandler
 	s CompressionService
@@ -31,6 +63,20 @@ func NewCompressionHandler(log *slog.Logger, s CompressionService) *CompressionH
 
 func (h *CompressionHandler) Compress() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		s, err := h.service(r)
+		if err != nil {
+			h.RenderError(w, r,
+				handlers.HandlerError{Msg: "invalid algorithm parameters", Err: err},
+				responses.ErrBadRequest,
+			)
+			return
+		}
+
+		if stream, ok := s.(StreamCompressionService); ok {
+			h.compressStream(w, r, stream)
+			return
+		}
+
 		data, err := io.ReadAll(r.Body)
 		if err != nil {
 			h.RenderError(w, r,
@@ -40,9 +86,9 @@ func (h *CompressionHandler) Compress() http.HandlerFunc {
 			return
 		}
 
-		dataCompressed, err := h.s.Compress(data)
+		dataCompressed, err := s.Compress(data)
 		if err != nil {
-			h.RenderInternalError(w, r, handlers.HandlerError{Msg: "failed compress", Err: err})
+			h.renderCompressError(w, r, err)
 			return
 		}
 
@@ -56,6 +102,15 @@ func (h *CompressionHandler) Compress() http.HandlerFunc {
 
 func (h *CompressionHandler) CompressWithDetails() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		s, err := h.service(r)
+		if err != nil {
+			h.RenderError(w, r,
+				handlers.HandlerError{Msg: "invalid algorithm parameters", Err: err},
+				responses.ErrBadRequest,
+			)
+			return
+		}
+
 		data, err := io.ReadAll(r.Body)
 		if err != nil {
 			h.RenderError(w, r,
@@ -65,9 +120,14 @@ func (h *CompressionHandler) CompressWithDetails() http.HandlerFunc {
 			return
 		}
 
-		details, err := h.s.CompressWithDetails(data)
+		if r.URL.Query().Get("trace") == "true" {
+			h.compressWithTrace(w, r, s, data)
+			return
+		}
+
+		details, err := s.CompressWithDetails(data)
 		if err != nil {
-			h.RenderInternalError(w, r, handlers.HandlerError{Msg: "failed compress", Err: err})
+			h.renderCompressError(w, r, err)
 			return
 		}
 
@@ -76,8 +136,42 @@ func (h *CompressionHandler) CompressWithDetails() http.HandlerFunc {
 	}
 }
 
+func (h *CompressionHandler) compressWithTrace(w http.ResponseWriter, r *http.Request, s CompressionService, data []byte) {
+	tracer, ok := s.(TracingCompressionService)
+	if !ok {
+		h.RenderError(w, r,
+			handlers.HandlerError{Msg: "trace is not supported", Err: fmt.Errorf("%T has no trace", s)},
+			responses.ErrBadRequest,
+		)
+		return
+	}
+
+	limit, err := intQueryParam(r, "trace_limit", compression.DefaultTraceLimit)
+	if err != nil {
+		h.RenderError(w, r,
+			handlers.HandlerError{Msg: "invalid trace limit", Err: err},
+			responses.ErrBadRequest,
+		)
+		return
+	}
+
+	details, err := tracer.CompressWithTrace(data, limit)
+	if err != nil {
+		h.renderCompressError(w, r, err)
+		return
+	}
+
+	h.Log.Debug("size compress", slog.Int("size", len(details.Data)))
+	h.renderDetails(w, r, details)
+}
+
 func (h *CompressionHandler) Decompress() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if stream, ok := h.s.(StreamCompressionService); ok {
+			h.decompressStream(w, r, stream)
+			return
+		}
+
 		dataCompressed, err := io.ReadAll(r.Body)
 		if err != nil {
 			h.RenderError(w, r,
@@ -127,7 +221,230 @@ func (h *CompressionHandler) DecompressWithDetails() http.HandlerFunc {
 	}
 }
 
+// Blocks применяет обработчик next к блочному варианту алгоритма. Размер блока
+// и число горутин задаются параметрами block_size и workers; параметры
+// level и block настраивают алгоритм внутри блоков.
+func (h *CompressionHandler) Blocks(next func(h *CompressionHandler) http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		blockService, err := h.blockService(r)
+		if err != nil {
+			h.RenderError(w, r,
+				handlers.HandlerError{Msg: "invalid block parameters", Err: err},
+				responses.ErrBadRequest,
+			)
+			return
+		}
+
+		// level и block уже применены к алгоритму блоков, и блочный
+		// обработчик не должен применять их к самому блочному сервису.
+		query := r.URL.Query()
+		query.Del("level")
+		query.Del("block")
+		u := *r.URL
+		u.RawQuery = query.Encode()
+		r = r.Clone(r.Context())
+		r.URL = &u
+
+		next(NewCompressionHandler(h.Log, blockService))(w, r)
+	}
+}
+
+// DecompressBlock разжимает один блок блочного контейнера по его номеру.
+func (h *CompressionHandler) DecompressBlock() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		blockService, err := h.blockService(r)
+		if err != nil {
+			h.RenderError(w, r,
+				handlers.HandlerError{Msg: "invalid block parameters", Err: err},
+				responses.ErrBadRequest,
+			)
+			return
+		}
+
+		index, err := strconv.Atoi(chi.URLParam(r, "index"))
+		if err != nil {
+			h.RenderError(w, r,
+				handlers.HandlerError{Msg: "invalid block index", Err: err},
+				responses.ErrBadRequest,
+			)
+			return
+		}
+
+		dataCompressed, err := io.ReadAll(r.Body)
+		if err != nil {
+			h.RenderError(w, r,
+				handlers.HandlerError{Msg: "invalid data", Err: err},
+				responses.ErrBadRequest,
+			)
+			return
+		}
+
+		data, err := blockService.DecompressBlock(dataCompressed, index)
+		if err != nil {
+			h.RenderError(w, r,
+				handlers.HandlerError{Msg: "invalid data", Err: err},
+				responses.ErrBadRequest,
+			)
+			return
+		}
+		h.Log.Debug("size decompress", slog.Int("size", len(data)))
+
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Content-Disposition", "attachment; filename=test.txt")
+		w.Write(data)
+	}
+}
+
+func (h *CompressionHandler) blockService(r *http.Request) (*compression.BlockService, error) {
+	blockSize, err := intQueryParam(r, "block_size", compression.DefaultBlockSize)
+	if err != nil {
+		return nil, err
+	}
+	workers, err := intQueryParam(r, "workers", runtime.NumCPU())
+	if err != nil {
+		return nil, err
+	}
+	s, err := h.service(r)
+	if err != nil {
+		return nil, err
+	}
+	return compression.NewBlockService(s, blockSize, workers)
+}
+
+// service возвращает алгоритм для сжатия с учётом параметров block и level:
+// при block=k символами источника считаются блоки по k исходных символов,
+// а level задаёт уровень сжатия.
+func (h *CompressionHandler) service(r *http.Request) (CompressionService, error) {
+	if r.URL.Query().Has("level") {
+		levelService, ok := h.s.(LevelCompressionService)
+		if !ok {
+			return nil, fmt.Errorf("%T does not support level", h.s)
+		}
+		level, err := intQueryParam(r, "level", compression.DefaultLZ77Level)
+		if err != nil {
+			return nil, err
+		}
+		return levelService.WithLevel(level)
+	}
+
+	if !r.URL.Query().Has("block") {
+		return h.s, nil
+	}
+
+	symbolSizeService, ok := h.s.(SymbolSizeCompressionService)
+	if !ok {
+		return nil, fmt.Errorf("%T does not support block", h.s)
+	}
+	k, err := intQueryParam(r, "block", 1)
+	if err != nil {
+		return nil, err
+	}
+	return symbolSizeService.WithSymbolSize(k)
+}
+
+// renderCompressError отвечает 400, если алгоритм не поддерживает такие
+// входные данные, и 500 в остальных случаях.
+func (h *CompressionHandler) renderCompressError(w http.ResponseWriter, r *http.Request, err error) {
+	handlerErr := handlers.HandlerError{Msg: "failed compress", Err: err}
+	if errors.Is(err, compression.ErrInvalidUTF8) || errors.Is(err, compression.ErrUnsupportedSymbol) ||
+		errors.Is(err, compression.ErrTooLarge) {
+		h.RenderError(w, r, handlerErr, responses.ErrBadRequest)
+		return
+	}
+	h.RenderInternalError(w, r, handlerErr)
+}
+
+func intQueryParam(r *http.Request, name string, defaultValue int) (int, error) {
+	param := r.URL.Query().Get(name)
+	if param == "" {
+		return defaultValue, nil
+	}
+	value, err := strconv.Atoi(param)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return value, nil
+}
+
+func (h *CompressionHandler) compressStream(w http.ResponseWriter, r *http.Request, s StreamCompressionService) {
+	aw := &attachmentWriter{w: w, filename: "test.txt"}
+	zw := s.NewWriter(aw)
+	_, err := io.Copy(zw, r.Body)
+	if err == nil {
+		err = zw.Close()
+	}
+	if err != nil {
+		h.renderStreamError(w, r, aw, handlers.HandlerError{Msg: "failed compress", Err: err})
+		return
+	}
+
+	h.Log.Debug("size compress", slog.Int64("size", aw.written))
+}
+
+func (h *CompressionHandler) decompressStream(w http.ResponseWriter, r *http.Request, s StreamCompressionService) {
+	aw := &attachmentWriter{w: w, filename: "test.txt"}
+	_, err := io.Copy(aw, compression.NewLimitReader(s.NewReader(r.Body), compression.MaxDecompressedSize))
+	if err != nil {
+		h.renderStreamError(w, r, aw, handlers.HandlerError{Msg: "invalid data", Err: err})
+		return
+	}
+
+	h.Log.Debug("size decompress", slog.Int64("size", aw.written))
+}
+
+// renderStreamError возвращает ошибку клиенту, если ответ ещё не начат.
+// Когда часть данных уже отправлена, статус изменить нельзя, и ошибка только логируется.
+func (h *CompressionHandler) renderStreamError(w http.ResponseWriter, r *http.Request, aw *attachmentWriter, handlerErr handlers.HandlerError) {
+	if aw.written == 0 {
+		h.RenderError(w, r, handlerErr, responses.ErrBadRequest)
+		return
+	}
+	h.Log.Error(handlerErr.Msg, slog.Int64("written", aw.written), slogger.Err(handlerErr.Err))
+}
+
+// attachmentWriter откладывает установку заголовков файла до первой записи,
+// чтобы до начала ответа ошибку ещё можно было вернуть обычным способом.
+type attachmentWriter struct {
+	w        http.ResponseWriter
+	filename string
+	written  int64
+}
+
+func (aw *attachmentWriter) Write(p []byte) (int, error) {
+	if aw.written == 0 && len(p) > 0 {
+		aw.w.Header().Set("Content-Type", "text/plain")
+		aw.w.Header().Set("Content-Disposition", "attachment; filename="+aw.filename)
+	}
+	n, err := aw.w.Write(p)
+	aw.written += int64(n)
+	return n, err
+}
+
 func (h *CompressionHandler) renderDetails(w http.ResponseWriter, r *http.Request, details compression.CompressionDetails) {
+	format := r.URL.Query().Get("export")
+	var tree []byte
+	var treeContentType string
+	if format != "" {
+		exporter, ok := details.Details.(compression.TreeExporter)
+		if !ok {
+			h.RenderError(w, r,
+				handlers.HandlerError{Msg: "tree export is not supported", Err: fmt.Errorf("%T has no tree", details.Details)},
+				responses.ErrBadRequest,
+			)
+			return
+		}
+
+		var err error
+		tree, treeContentType, err = compression.ExportTree(exporter.Tree(), format)
+		if err != nil {
+			h.RenderError(w, r,
+				handlers.HandlerError{Msg: "invalid export format", Err: err},
+				responses.ErrBadRequest,
+			)
+			return
+		}
+	}
+
 	mpw := multipart.NewWriter(w)
 	defer mpw.Close()
 	w.Header().Set("Content-Type", mpw.FormDataContentType())
@@ -139,6 +456,18 @@ func (h *CompressionHandler) renderDetails(w http.ResponseWriter, r *http.Reques
 	}
 	mpw.WriteField("details", string(detailsJson))
 
+	if tree != nil {
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="tree"; filename="tree.%s"`, format))
+		header.Set("Content-Type", treeContentType)
+		treeWriter, err := mpw.CreatePart(header)
+		if err != nil {
+			h.RenderInternalError(w, r, handlers.HandlerError{Msg: "failed create form", Err: err})
+			return
+		}
+		treeWriter.Write(tree)
+	}
+
 	fWriter, err := mpw.CreateFormFile("data", "test.txt")
 	if err != nil {
 		h.RenderInternalError(w, r, handlers.HandlerError{Msg: "failed create form", Err: err})
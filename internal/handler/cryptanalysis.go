@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/PritOriginal/cryptolabs-back/internal/services/classical"
+	"github.com/PritOriginal/cryptolabs-back/internal/services/cryptanalysis"
+	"github.com/PritOriginal/problem-map-server/pkg/handlers"
+	"github.com/PritOriginal/problem-map-server/pkg/responses"
+)
+
+type CryptanalysisHandler struct {
+	handlers.BaseHandler
+	s cryptanalysis.Service
+}
+
+func NewCryptanalysisHandler(log *slog.Logger, s cryptanalysis.Service) *CryptanalysisHandler {
+	return &CryptanalysisHandler{handlers.BaseHandler{Log: log}, s}
+}
+
+// Frequencies считает частоты букв и биграмм text. Параметры всех методов:
+// text, alphabet_set (en по умолчанию, ru или custom), alphabet для
+// alphabet_set=custom, top, min_length и max_key_length.
+func (h *CryptanalysisHandler) Frequencies() http.HandlerFunc {
+	return analyze(h, cryptanalysis.Service.Frequencies, "failed frequency analysis")
+}
+
+func (h *CryptanalysisHandler) Kasiski() http.HandlerFunc {
+	return analyze(h, cryptanalysis.Service.Kasiski, "failed Kasiski examination")
+}
+
+func (h *CryptanalysisHandler) IndexOfCoincidence() http.HandlerFunc {
+	return analyze(h, cryptanalysis.Service.IndexOfCoincidence, "failed index of coincidence")
+}
+
+func (h *CryptanalysisHandler) Caesar() http.HandlerFunc {
+	return analyze(h, cryptanalysis.Service.Caesar, "failed break Caesar cipher")
+}
+
+func (h *CryptanalysisHandler) Affine() http.HandlerFunc {
+	return analyze(h, cryptanalysis.Service.Affine, "failed break affine cipher")
+}
+
+func (h *CryptanalysisHandler) Vigenere() http.HandlerFunc {
+	return analyze(h, cryptanalysis.Service.Vigenere, "failed break Vigenere cipher")
+}
+
+// Substitution взламывает простую замену. Дополнительные параметры: ngram,
+// iterations, restarts, workers, method (hill_climbing или annealing) и seed.
+func (h *CryptanalysisHandler) Substitution() http.HandlerFunc {
+	return analyze(h, cryptanalysis.Service.Substitution, "failed break substitution cipher")
+}
+
+func analyze[T any](h *CryptanalysisHandler, f func(cryptanalysis.Service, cryptanalysis.Params) (T, error), msg string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params, err := cryptanalysisParams(r)
+		if err != nil {
+			h.RenderError(w, r, handlers.HandlerError{Msg: msg, Err: err}, responses.ErrBadRequest)
+			return
+		}
+
+		result, err := f(h.s, params)
+		if errors.Is(err, classical.ErrInvalidAlphabet) || errors.Is(err, cryptanalysis.ErrNoProfile) ||
+			errors.Is(err, cryptanalysis.ErrInvalidText) || errors.Is(err, cryptanalysis.ErrInvalidParams) {
+			h.RenderError(w, r, handlers.HandlerError{Msg: msg, Err: err}, responses.ErrBadRequest)
+			return
+		}
+		if err != nil {
+			h.RenderInternalError(w, r, handlers.HandlerError{Msg: msg, Err: err})
+			return
+		}
+
+		h.Render(w, r, responses.SucceededRenderer(result))
+	}
+}
+
+func cryptanalysisParams(r *http.Request) (cryptanalysis.Params, error) {
+	params := cryptanalysis.Params{
+		AlphabetSet: r.FormValue("alphabet_set"),
+		Alphabet:    r.FormValue("alphabet"),
+		Text:        r.FormValue("text"),
+		Method:      r.FormValue("method"),
+	}
+	if params.AlphabetSet == "" {
+		params.AlphabetSet = "en"
+	}
+	for _, p := range []struct {
+		name  string
+		value *int
+	}{
+		{"top", &params.Top}, {"min_length", &params.MinLength}, {"max_key_length", &params.MaxKeyLength},
+		{"ngram", &params.NGram}, {"iterations", &params.Iterations}, {"restarts", &params.Restarts}, {"workers", &params.Workers},
+	} {
+		param := r.FormValue(p.name)
+		if param == "" {
+			continue
+		}
+		value, err := strconv.Atoi(param)
+		if err != nil {
+			return cryptanalysis.Params{}, fmt.Errorf("invalid %s: %w", p.name, err)
+		}
+		*p.value = value
+	}
+	if seed := r.FormValue("seed"); seed != "" {
+		value, err := strconv.ParseUint(seed, 10, 64)
+		if err != nil {
+			return cryptanalysis.Params{}, fmt.Errorf("invalid seed: %w", err)
+		}
+		params.Seed = value
+	}
+	return params, nil
+}
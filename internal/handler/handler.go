@@ -5,8 +5,12 @@ import (
 
 	repository "github.com/PritOriginal/cryptolabs-back/internal/repository/alphabet"
 	"github.com/PritOriginal/cryptolabs-back/internal/services"
+	"github.com/PritOriginal/cryptolabs-back/internal/services/audio"
+	"github.com/PritOriginal/cryptolabs-back/internal/services/classical"
 	"github.com/PritOriginal/cryptolabs-back/internal/services/compression"
+	"github.com/PritOriginal/cryptolabs-back/internal/services/cryptanalysis"
 	"github.com/PritOriginal/cryptolabs-back/internal/services/crypto"
+	"github.com/PritOriginal/cryptolabs-back/internal/services/imaging"
 	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/chi/v5"
 )
@@ -29,33 +33,91 @@ func GetRouter(log *slog.Logger) *chi.Mux {
 		r.Get("/amount", infoHandler.GetAmountOfInformation())
 	})
 
-	type CompressionServiceItem struct {
-		name    string
-		service CompressionService
-	}
-
-	compressionServices := []CompressionServiceItem{
-		{name: "/rle", service: compression.NewRLEService()},
-		{name: "/huffman", service: compression.NewHuffmanService()},
-		{name: "/arithmetic", service: compression.NewArithmeticService()},
-		{name: "/lzw", service: compression.NewLZWService()},
-	}
-	for _, serviceItem := range compressionServices {
-		serviceHandler := NewCompressionHandler(log, serviceItem.service)
-		r.Route(serviceItem.name, func(r chi.Router) {
+	for _, algorithm := range compression.Algorithms() {
+		serviceHandler := NewCompressionHandler(log, algorithm.Service)
+		r.Route("/"+algorithm.Name, func(r chi.Router) {
 			r.Post("/compress", serviceHandler.Compress())
 			r.Post("/compress/details", serviceHandler.CompressWithDetails())
 			r.Post("/decompress", serviceHandler.Decompress())
 			r.Post("/decompress/details", serviceHandler.DecompressWithDetails())
+
+			r.Route("/blocks", func(r chi.Router) {
+				r.Post("/compress", serviceHandler.Blocks((*CompressionHandler).Compress))
+				r.Post("/compress/details", serviceHandler.Blocks((*CompressionHandler).CompressWithDetails))
+				r.Post("/decompress", serviceHandler.Blocks((*CompressionHandler).Decompress))
+				r.Post("/decompress/details", serviceHandler.Blocks((*CompressionHandler).DecompressWithDetails))
+				r.Post("/{index}", serviceHandler.DecompressBlock())
+			})
 		})
 	}
 
+	archiveService := compression.NewArchiveService()
+	archiveHandler := NewArchiveHandler(log, archiveService)
+	r.Route("/archive", func(r chi.Router) {
+		r.Post("/create", archiveHandler.Create())
+		r.Post("/list", archiveHandler.List())
+		r.Post("/extract", archiveHandler.ExtractAll())
+		r.Post("/extract/file", archiveHandler.Extract())
+	})
+
+	imageService := imaging.NewImageService()
+	imageHandler := NewImageHandler(log, imageService)
+	r.Route("/image", func(r chi.Router) {
+		r.Post("/compress", imageHandler.Compress())
+		r.Post("/decompress", imageHandler.Decompress())
+	})
+
+	audioService := audio.NewAudioService()
+	audioHandler := NewAudioHandler(log, audioService)
+	r.Route("/audio", func(r chi.Router) {
+		r.Post("/compress", audioHandler.Compress())
+		r.Post("/decompress", audioHandler.Decompress())
+	})
+
 	rsaService := crypto.NewRsaService()
 	rsaHandler := NewRsaHandler(log, rsaService)
 	r.Route("/rsa", func(r chi.Router) {
 		r.Get("/keys", rsaHandler.GenerateKeys())
+		r.Get("/textbook", rsaHandler.Textbook())
 		r.Post("/encrypt", rsaHandler.Encrypt())
 		r.Post("/decrypt", rsaHandler.Decrypt())
+		r.Post("/sign", rsaHandler.Sign())
+		r.Post("/verify", rsaHandler.Verify())
+		r.Post("/hybrid/encrypt", rsaHandler.HybridEncrypt())
+		r.Post("/hybrid/decrypt", rsaHandler.HybridDecrypt())
+	})
+
+	symmetricService := crypto.NewSymmetricService()
+	symmetricHandler := NewSymmetricHandler(log, symmetricService)
+	for _, algorithm := range crypto.BlockCiphers() {
+		r.Route("/"+algorithm, func(r chi.Router) {
+			r.Post("/encrypt", symmetricHandler.Encrypt(algorithm))
+			r.Post("/decrypt", symmetricHandler.Decrypt(algorithm))
+			r.Post("/mac", symmetricHandler.MAC(algorithm))
+		})
+	}
+	r.Route("/feistel", func(r chi.Router) {
+		r.Post("/encrypt", symmetricHandler.FeistelEncrypt())
+		r.Post("/decrypt", symmetricHandler.FeistelDecrypt())
+	})
+
+	classicalService := classical.NewClassicalService(alphabetRepo)
+	classicalHandler := NewClassicalHandler(log, classicalService)
+	r.Route("/classical/{cipher}", func(r chi.Router) {
+		r.Post("/encrypt", classicalHandler.Encrypt())
+		r.Post("/decrypt", classicalHandler.Decrypt())
+	})
+
+	cryptanalysisService := cryptanalysis.NewCryptanalysisService(alphabetRepo)
+	cryptanalysisHandler := NewCryptanalysisHandler(log, cryptanalysisService)
+	r.Route("/cryptanalysis", func(r chi.Router) {
+		r.Post("/frequencies", cryptanalysisHandler.Frequencies())
+		r.Post("/kasiski", cryptanalysisHandler.Kasiski())
+		r.Post("/ic", cryptanalysisHandler.IndexOfCoincidence())
+		r.Post("/caesar", cryptanalysisHandler.Caesar())
+		r.Post("/affine", cryptanalysisHandler.Affine())
+		r.Post("/vigenere", cryptanalysisHandler.Vigenere())
+		r.Post("/substitution", cryptanalysisHandler.Substitution())
 	})
 
 	return r
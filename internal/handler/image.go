@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+
+	"github.com/PritOriginal/cryptolabs-back/internal/services/imaging"
+	"github.com/PritOriginal/problem-map-server/pkg/handlers"
+	slogger "github.com/PritOriginal/problem-map-server/pkg/logger"
+	"github.com/PritOriginal/problem-map-server/pkg/responses"
+)
+
+type ImageCompressionService interface {
+	DecodeImage(data []byte) (image.Image, error)
+	Compress(img image.Image, opts imaging.ImageOptions) (imaging.ImageCompressionDetails, error)
+	Decompress(data []byte) (image.Image, error)
+}
+
+type ImageHandler struct {
+	handlers.BaseHandler
+	s ImageCompressionService
+}
+
+func NewImageHandler(log *slog.Logger, s ImageCompressionService) *ImageHandler {
+	return &ImageHandler{handlers.BaseHandler{Log: log}, s}
+}
+
+// Compress сжимает загруженное изображение (поле image, PNG или BMP) с потерями.
+// В ответе — отчёт, восстановленное изображение в PNG и сжатые данные.
+func (h *ImageHandler) Compress() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := r.ParseMultipartForm(32 << 20) // 32 MB
+		if err != nil {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "error parse multipart form", Err: err}, responses.ErrBadRequest)
+			return
+		}
+
+		opts, err := h.options(r)
+		if err != nil {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "invalid parameters", Err: err}, responses.ErrBadRequest)
+			return
+		}
+
+		files := r.MultipartForm.File["image"]
+		if len(files) == 0 {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "image is required", Err: fmt.Errorf("no image file")}, responses.ErrBadRequest)
+			return
+		}
+		data, err := readFile(files[0])
+		if err != nil {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "failed read image", Err: err}, responses.ErrBadRequest)
+			return
+		}
+		img, err := h.s.DecodeImage(data)
+		if err != nil {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "invalid image", Err: err}, responses.ErrBadRequest)
+			return
+		}
+
+		compressed, err := h.s.Compress(img, opts)
+		if err != nil {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "failed compress", Err: err}, responses.ErrBadRequest)
+			return
+		}
+		h.Log.Debug("size compress", slog.Int("size", len(compressed.Data)))
+
+		mpw := multipart.NewWriter(w)
+		defer mpw.Close()
+		w.Header().Set("Content-Type", mpw.FormDataContentType())
+
+		detailsJson, err := json.Marshal(compressed.Details)
+		if err != nil {
+			h.RenderInternalError(w, r, handlers.HandlerError{Msg: "failed marshal details", Err: err})
+			return
+		}
+		mpw.WriteField("details", string(detailsJson))
+
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", `form-data; name="image"; filename="reconstructed.png"`)
+		header.Set("Content-Type", "image/png")
+		imageWriter, err := mpw.CreatePart(header)
+		if err != nil {
+			h.RenderInternalError(w, r, handlers.HandlerError{Msg: "failed create form", Err: err})
+			return
+		}
+		if err := png.Encode(imageWriter, compressed.Image); err != nil {
+			h.RenderInternalError(w, r, handlers.HandlerError{Msg: "failed encode image", Err: err})
+			return
+		}
+
+		fWriter, err := mpw.CreateFormFile("data", "image.clim")
+		if err != nil {
+			h.RenderInternalError(w, r, handlers.HandlerError{Msg: "failed create form", Err: err})
+			return
+		}
+		fWriter.Write(compressed.Data)
+	}
+}
+
+// Decompress восстанавливает изображение из сжатых данных и возвращает его в PNG.
+func (h *ImageHandler) Decompress() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "invalid data", Err: err}, responses.ErrBadRequest)
+			return
+		}
+
+		img, err := h.s.Decompress(data)
+		if err != nil {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "invalid data", Err: err}, responses.ErrBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "image/png")
+		w.Header().Set("Content-Disposition", "attachment; filename=image.png")
+		if err := png.Encode(w, img); err != nil {
+			h.Log.Error("failed encode image", slogger.Err(err))
+		}
+	}
+}
+
+// options читает параметры quality, subsampling и, если задан block_component,
+// координаты блока block_x и block_y для вывода промежуточных результатов.
+func (h *ImageHandler) options(r *http.Request) (imaging.ImageOptions, error) {
+	quality, err := intQueryParam(r, "quality", imaging.DefaultQuality)
+	if err != nil {
+		return imaging.ImageOptions{}, err
+	}
+	opts := imaging.ImageOptions{
+		Quality:     quality,
+		Subsampling: r.URL.Query().Get("subsampling"),
+	}
+
+	component := r.URL.Query().Get("block_component")
+	if component == "" {
+		return opts, nil
+	}
+	x, err := intQueryParam(r, "block_x", 0)
+	if err != nil {
+		return imaging.ImageOptions{}, err
+	}
+	y, err := intQueryParam(r, "block_y", 0)
+	if err != nil {
+		return imaging.ImageOptions{}, err
+	}
+	opts.Block = &imaging.BlockPosition{Component: component, X: x, Y: y}
+	return opts, nil
+}
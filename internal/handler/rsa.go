@@ -3,6 +3,9 @@ package handler
 import (
 	"bytes"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"log/slog"
 	"math/big"
@@ -23,9 +26,33 @@ func NewRsaHandler(log *slog.Logger, s crypto.RSA) *RsaHandler {
 	return &RsaHandler{handlers.BaseHandler{Log: log}, s}
 }
 
+// GenerateKeys создаёт пару ключей размером bits (512..8192, по умолчанию 2048)
+// с экспонентой e (3, 17 или 65537 по умолчанию). Параметр format — pkcs8
+// (закрытый ключ PKCS#8, открытый SPKI, по умолчанию), pkcs1 или jwk;
+// encoding — pem (по умолчанию) или der, ключи в DER возвращаются в base64.
 func (h *RsaHandler) GenerateKeys() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		pub, priv, err := h.s.GenerateKeys(2048)
+		format, encoding := keyFormatParams(r)
+		publicFormat := format
+		if format == crypto.FormatPKCS8 {
+			publicFormat = crypto.FormatSPKI
+		}
+		bits, err := intQueryParam(r, "bits", crypto.DefaultKeyBits)
+		if err != nil {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "invalid parameters", Err: err}, responses.ErrBadRequest)
+			return
+		}
+		e, err := intQueryParam(r, "e", crypto.DefaultExponent)
+		if err != nil {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "invalid parameters", Err: err}, responses.ErrBadRequest)
+			return
+		}
+
+		pub, priv, err := h.s.GenerateKeys(bits, e)
+		if errors.Is(err, crypto.ErrInvalidParams) {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "invalid parameters", Err: err}, responses.ErrBadRequest)
+			return
+		}
 		if err != nil {
 			h.RenderInternalError(w, r, handlers.HandlerError{Msg: "failed generate keys", Err: err})
 			return
@@ -36,17 +63,55 @@ func (h *RsaHandler) GenerateKeys() http.HandlerFunc {
 			Private string `json:"private"`
 		}
 
-		keys := RsaKeys{}
-
-		publicKey := base64.StdEncoding.EncodeToString(append(pub.N.Bytes(), pub.E.Bytes()...))
-		keys.Public = publicKey
+		publicKey, err := crypto.MarshalPublicKey(pub, publicFormat, encoding)
+		if err != nil {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "invalid key format", Err: err}, responses.ErrBadRequest)
+			return
+		}
+		privateKey, err := crypto.MarshalPrivateKey(priv, format, encoding)
+		if err != nil {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "invalid key format", Err: err}, responses.ErrBadRequest)
+			return
+		}
 
-		privateKey := base64.StdEncoding.EncodeToString(append(priv.N.Bytes(), priv.D.Bytes()...))
-		keys.Private = privateKey
+		keys := RsaKeys{Public: string(publicKey), Private: string(privateKey)}
+		if format != crypto.FormatJWK && encoding == crypto.EncodingDER {
+			keys.Public = base64.StdEncoding.EncodeToString(publicKey)
+			keys.Private = base64.StdEncoding.EncodeToString(privateKey)
+		}
 
 		h.Render(w, r, responses.SucceededRenderer(keys))
 	}
 }
+
+// schemeParam возвращает схему шифрования из параметра scheme: pkcs1v15
+// (по умолчанию), oaep-sha1 или oaep-sha256. Метка OAEP задаётся параметром label.
+func schemeParam(r *http.Request) string {
+	if scheme := r.FormValue("scheme"); scheme != "" {
+		return scheme
+	}
+	return crypto.SchemePKCS1v15
+}
+
+func signatureSchemeParam(r *http.Request) string {
+	if scheme := r.FormValue("scheme"); scheme != "" {
+		return scheme
+	}
+	return crypto.SignaturePKCS1v15
+}
+
+func keyFormatParams(r *http.Request) (format, encoding string) {
+	format = r.URL.Query().Get("format")
+	if format == "" {
+		format = crypto.FormatPKCS8
+	}
+	encoding = r.URL.Query().Get("encoding")
+	if encoding == "" {
+		encoding = crypto.EncodingPEM
+	}
+	return format, encoding
+}
+
 func (h *RsaHandler) Encrypt() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		err := r.ParseMultipartForm(32 << 10) // 32 MB
@@ -61,8 +126,27 @@ func (h *RsaHandler) Encrypt() http.HandlerFunc {
 			return
 		}
 
-		pub := crypto.PublicKey{N: new(big.Int).SetBytes(key[:256]), E: new(big.Int).SetBytes(key[256:])}
-		ciphertext, err := h.s.Encrypt(&pub, data)
+		pub, err := crypto.ParsePublicKey(key)
+		if err != nil {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "invalid public key", Err: err}, responses.ErrBadRequest)
+			return
+		}
+		newHash, err := crypto.OAEPHash(schemeParam(r))
+		if err != nil {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "invalid parameters", Err: err}, responses.ErrBadRequest)
+			return
+		}
+
+		var ciphertext []byte
+		if newHash == nil {
+			ciphertext, err = h.s.Encrypt(pub, data)
+		} else {
+			ciphertext, err = h.s.EncryptOAEP(newHash, pub, data, []byte(r.FormValue("label")))
+		}
+		if errors.Is(err, crypto.ErrMessageTooLong) {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "failed encrypt data", Err: err}, responses.ErrBadRequest)
+			return
+		}
 		if err != nil {
 			h.RenderInternalError(w, r, handlers.HandlerError{Msg: "failed encrypt data", Err: err})
 			return
@@ -87,8 +171,28 @@ func (h *RsaHandler) Decrypt() http.HandlerFunc {
 			return
 		}
 
-		priv := crypto.PrivateKey{N: new(big.Int).SetBytes(key[:256]), D: new(big.Int).SetBytes(key[256:])}
-		data, err := h.s.Decrypt(&priv, ciphertext)
+		priv, err := crypto.ParsePrivateKey(key)
+		if err != nil {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "invalid private key", Err: err}, responses.ErrBadRequest)
+			return
+		}
+		newHash, err := crypto.OAEPHash(schemeParam(r))
+		if err != nil {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "invalid parameters", Err: err}, responses.ErrBadRequest)
+			return
+		}
+
+		var data []byte
+		if newHash == nil {
+			data, err = h.s.Decrypt(priv, ciphertext)
+		} else {
+			data, err = h.s.DecryptOAEP(newHash, priv, ciphertext, []byte(r.FormValue("label")))
+		}
+		// Все ошибки дополнения сообщаются одинаково, см. crypto.ErrDecryption.
+		if errors.Is(err, crypto.ErrDecryption) {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "failed decrypt data", Err: crypto.ErrDecryption}, responses.ErrBadRequest)
+			return
+		}
 		if err != nil {
 			h.RenderInternalError(w, r, handlers.HandlerError{Msg: "failed decrypt data", Err: err})
 			return
@@ -100,24 +204,276 @@ func (h *RsaHandler) Decrypt() http.HandlerFunc {
 	}
 }
 
+// Sign подписывает файл data закрытым ключом key по схеме scheme: pkcs1v15
+// (по умолчанию) или pss, хеш — SHA-256. В ответе — детали с хешем, блоком EM
+// и подписью как числом, а также файл подписи.
+func (h *RsaHandler) Sign() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := r.ParseMultipartForm(32 << 20) // 32 MB
+		if err != nil {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "error parse multipart form", Err: err}, responses.ErrBadRequest)
+			return
+		}
+
+		data, key, err := h.readDataAndKey(w, r)
+		if err != nil {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "failed read data and key", Err: err}, responses.ErrBadRequest)
+			return
+		}
+		priv, err := crypto.ParsePrivateKey(key)
+		if err != nil {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "invalid private key", Err: err}, responses.ErrBadRequest)
+			return
+		}
+
+		signature, details, err := h.s.Sign(priv, data, signatureSchemeParam(r))
+		// ErrDecryption при подписи означает, что закрытый ключ не согласован с открытым.
+		if errors.Is(err, crypto.ErrInvalidParams) || errors.Is(err, crypto.ErrInvalidKey) || errors.Is(err, crypto.ErrMessageTooLong) ||
+			errors.Is(err, crypto.ErrDecryption) {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "failed sign data", Err: err}, responses.ErrBadRequest)
+			return
+		}
+		if err != nil {
+			h.RenderInternalError(w, r, handlers.HandlerError{Msg: "failed sign data", Err: err})
+			return
+		}
+
+		mpw := multipart.NewWriter(w)
+		defer mpw.Close()
+		w.Header().Set("Content-Type", mpw.FormDataContentType())
+
+		detailsJson, err := json.Marshal(details)
+		if err != nil {
+			h.RenderInternalError(w, r, handlers.HandlerError{Msg: "failed marshal details", Err: err})
+			return
+		}
+		mpw.WriteField("details", string(detailsJson))
+
+		fWriter, err := mpw.CreateFormFile("signature", "signature.sig")
+		if err != nil {
+			h.RenderInternalError(w, r, handlers.HandlerError{Msg: "failed create form", Err: err})
+			return
+		}
+		fWriter.Write(signature)
+	}
+}
+
+// Verify проверяет подпись signature файла data открытым ключом key.
+// Неверная подпись — не ошибка запроса: в ответе детали с valid = false.
+func (h *RsaHandler) Verify() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := r.ParseMultipartForm(32 << 20) // 32 MB
+		if err != nil {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "error parse multipart form", Err: err}, responses.ErrBadRequest)
+			return
+		}
+
+		data, key, err := h.readDataAndKey(w, r)
+		if err != nil {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "failed read data and key", Err: err}, responses.ErrBadRequest)
+			return
+		}
+		files := r.MultipartForm.File["signature"]
+		if len(files) == 0 {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "signature is required", Err: fmt.Errorf("no signature file")}, responses.ErrBadRequest)
+			return
+		}
+		signature, err := readFile(files[0])
+		if err != nil {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "failed read signature", Err: err}, responses.ErrBadRequest)
+			return
+		}
+		pub, err := crypto.ParsePublicKey(key)
+		if err != nil {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "invalid public key", Err: err}, responses.ErrBadRequest)
+			return
+		}
+
+		details, err := h.s.Verify(pub, data, signature, signatureSchemeParam(r))
+		if err != nil && !errors.Is(err, crypto.ErrVerification) {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "failed verify signature", Err: err}, responses.ErrBadRequest)
+			return
+		}
+
+		h.Render(w, r, responses.SucceededRenderer(details))
+	}
+}
+
+// HybridEncrypt шифрует файл data произвольного размера: данные — случайным
+// сеансовым ключом AES-256-GCM, сеансовый ключ — открытым ключом key по схеме
+// scheme (pkcs1v15 по умолчанию, oaep-sha1 или oaep-sha256). В ответе — детали
+// и файл конверта, который принимает HybridDecrypt.
+func (h *RsaHandler) HybridEncrypt() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := r.ParseMultipartForm(32 << 20) // 32 MB
+		if err != nil {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "error parse multipart form", Err: err}, responses.ErrBadRequest)
+			return
+		}
+
+		data, key, err := h.readDataAndKey(w, r)
+		if err != nil {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "failed read data and key", Err: err}, responses.ErrBadRequest)
+			return
+		}
+		pub, err := crypto.ParsePublicKey(key)
+		if err != nil {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "invalid public key", Err: err}, responses.ErrBadRequest)
+			return
+		}
+
+		envelope, details, err := h.s.SealEnvelope(pub, data, schemeParam(r))
+		if errors.Is(err, crypto.ErrInvalidParams) || errors.Is(err, crypto.ErrMessageTooLong) {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "failed encrypt data", Err: err}, responses.ErrBadRequest)
+			return
+		}
+		if err != nil {
+			h.RenderInternalError(w, r, handlers.HandlerError{Msg: "failed encrypt data", Err: err})
+			return
+		}
+
+		mpw := multipart.NewWriter(w)
+		defer mpw.Close()
+		w.Header().Set("Content-Type", mpw.FormDataContentType())
+
+		detailsJson, err := json.Marshal(details)
+		if err != nil {
+			h.RenderInternalError(w, r, handlers.HandlerError{Msg: "failed marshal details", Err: err})
+			return
+		}
+		mpw.WriteField("details", string(detailsJson))
+
+		fWriter, err := mpw.CreateFormFile("file", "data.clhe")
+		if err != nil {
+			h.RenderInternalError(w, r, handlers.HandlerError{Msg: "failed create form", Err: err})
+			return
+		}
+		fWriter.Write(envelope)
+	}
+}
+
+// HybridDecrypt расшифровывает конверт data закрытым ключом key. Схема
+// берётся из заголовка конверта.
+func (h *RsaHandler) HybridDecrypt() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := r.ParseMultipartForm(32 << 20) // 32 MB
+		if err != nil {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "error parse multipart form", Err: err}, responses.ErrBadRequest)
+			return
+		}
+
+		envelope, key, err := h.readDataAndKey(w, r)
+		if err != nil {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "failed read data and key", Err: err}, responses.ErrBadRequest)
+			return
+		}
+		priv, err := crypto.ParsePrivateKey(key)
+		if err != nil {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "invalid private key", Err: err}, responses.ErrBadRequest)
+			return
+		}
+
+		data, _, err := h.s.OpenEnvelope(priv, envelope)
+		if errors.Is(err, crypto.ErrInvalidEnvelope) {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "failed decrypt data", Err: err}, responses.ErrBadRequest)
+			return
+		}
+		if errors.Is(err, crypto.ErrDecryption) {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "failed decrypt data", Err: crypto.ErrDecryption}, responses.ErrBadRequest)
+			return
+		}
+		if err != nil {
+			h.RenderInternalError(w, r, handlers.HandlerError{Msg: "failed decrypt data", Err: err})
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", "attachment; filename=data")
+		w.Write(data)
+	}
+}
+
+// Textbook показывает RSA на малых числах: параметры p, q, e (или bits для
+// генерации p и q) и сообщение m (число) или message (текст).
+func (h *RsaHandler) Textbook() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params, err := textbookParams(r)
+		if err != nil {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "invalid parameters", Err: err}, responses.ErrBadRequest)
+			return
+		}
+
+		details, err := h.s.Textbook(params)
+		if errors.Is(err, crypto.ErrInvalidParams) {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "invalid parameters", Err: err}, responses.ErrBadRequest)
+			return
+		}
+		if err != nil {
+			h.RenderInternalError(w, r, handlers.HandlerError{Msg: "failed textbook RSA", Err: err})
+			return
+		}
+
+		h.Render(w, r, responses.SucceededRenderer(details))
+	}
+}
+
+func textbookParams(r *http.Request) (crypto.TextbookParams, error) {
+	bits, err := intQueryParam(r, "bits", 0)
+	if err != nil {
+		return crypto.TextbookParams{}, err
+	}
+	params := crypto.TextbookParams{Bits: bits, Message: r.URL.Query().Get("message")}
+	for _, p := range []struct {
+		name  string
+		value **big.Int
+	}{{"p", &params.P}, {"q", &params.Q}, {"e", &params.E}, {"m", &params.M}} {
+		if *p.value, err = bigIntQueryParam(r, p.name); err != nil {
+			return crypto.TextbookParams{}, err
+		}
+	}
+	return params, nil
+}
+
+// bigIntQueryParam читает десятичное число; для отсутствующего параметра возвращает nil.
+func bigIntQueryParam(r *http.Request, name string) (*big.Int, error) {
+	param := r.URL.Query().Get(name)
+	if param == "" {
+		return nil, nil
+	}
+	value, ok := new(big.Int).SetString(param, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid %s: %q is not a decimal number", name, param)
+	}
+	return value, nil
+}
+
 func (h *RsaHandler) readDataAndKey(w http.ResponseWriter, r *http.Request) ([]byte, []byte, error) {
-	data, err := readFile(r.MultipartForm.File["data"][0])
+	data, err := formFile(r, "data")
 	if err != nil {
 		return nil, nil, err
 	}
-	keyBase64, err := readFile(r.MultipartForm.File["key"][0])
+	key, err := formFile(r, "key")
 	if err != nil {
 		return nil, nil, err
 	}
 
-	key, err := base64.StdEncoding.DecodeString(string(keyBase64))
-	if err != nil {
-		return nil, nil, err
+	// Ключ в DER мог быть передан в base64, как его возвращает GenerateKeys.
+	if decoded, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(key))); err == nil {
+		key = decoded
 	}
 
 	return data, key, nil
 }
 
+// formFile читает первый файл из поля name multipart-формы.
+func formFile(r *http.Request, name string) ([]byte, error) {
+	files := r.MultipartForm.File[name]
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no %s file", name)
+	}
+	return readFile(files[0])
+}
+
 func readFile(header *multipart.FileHeader) ([]byte, error) {
 	file, err := header.Open()
 	if err != nil {
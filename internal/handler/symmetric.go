@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/PritOriginal/cryptolabs-back/internal/services/crypto"
+	"github.com/PritOriginal/problem-map-server/pkg/handlers"
+	"github.com/PritOriginal/problem-map-server/pkg/responses"
+)
+
+type SymmetricHandler struct {
+	handlers.BaseHandler
+	s crypto.Symmetric
+}
+
+func NewSymmetricHandler(log *slog.Logger, s crypto.Symmetric) *SymmetricHandler {
+	return &SymmetricHandler{handlers.BaseHandler{Log: log}, s}
+}
+
+// Encrypt шифрует блочным шифром algorithm. Параметры: key, iv и aad в hex,
+// mode (cbc по умолчанию), padding (pkcs7, gost или none), trace=true для
+// трассы раундов первых блоков и данные — data в hex или text в UTF-8.
+func (h *SymmetricHandler) Encrypt(algorithm string) http.HandlerFunc {
+	return h.apply(algorithm, crypto.Symmetric.Encrypt, "failed encrypt data")
+}
+
+// Decrypt расшифровывает data (hex); для GCM к шифртексту дописывается тег.
+func (h *SymmetricHandler) Decrypt(algorithm string) http.HandlerFunc {
+	return h.apply(algorithm, crypto.Symmetric.Decrypt, "failed decrypt data")
+}
+
+// MAC вычисляет имитовставку data или text длиной mac_size байт.
+func (h *SymmetricHandler) MAC(algorithm string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params, err := symmetricParams(r)
+		if err != nil {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "invalid parameters", Err: err}, responses.ErrBadRequest)
+			return
+		}
+
+		result, err := h.s.MAC(algorithm, params)
+		if errors.Is(err, crypto.ErrInvalidCipherParams) {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "failed compute MAC", Err: err}, responses.ErrBadRequest)
+			return
+		}
+		if err != nil {
+			h.RenderInternalError(w, r, handlers.HandlerError{Msg: "failed compute MAC", Err: err})
+			return
+		}
+
+		h.Render(w, r, responses.SucceededRenderer(result))
+	}
+}
+
+// FeistelEncrypt пропускает блок через учебную сеть Фейстеля. Параметры:
+// rounds (16 по умолчанию), function (xor, add, rotate_xor, sbox или sha256),
+// key в hex и блок чётной длины — data в hex или text в UTF-8.
+func (h *SymmetricHandler) FeistelEncrypt() http.HandlerFunc {
+	return h.feistel(crypto.Symmetric.FeistelEncrypt, "failed encrypt block")
+}
+
+func (h *SymmetricHandler) FeistelDecrypt() http.HandlerFunc {
+	return h.feistel(crypto.Symmetric.FeistelDecrypt, "failed decrypt block")
+}
+
+func (h *SymmetricHandler) feistel(f func(crypto.Symmetric, crypto.FeistelParams) (crypto.FeistelResult, error), msg string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		symmetric, err := symmetricParams(r)
+		if err != nil {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "invalid parameters", Err: err}, responses.ErrBadRequest)
+			return
+		}
+		params := crypto.FeistelParams{Function: r.FormValue("function"), Key: symmetric.Key, Data: symmetric.Data}
+		if rounds := r.FormValue("rounds"); rounds != "" {
+			params.Rounds, err = strconv.Atoi(rounds)
+			if err != nil {
+				h.RenderError(w, r, handlers.HandlerError{Msg: "invalid parameters", Err: fmt.Errorf("invalid rounds: %w", err)}, responses.ErrBadRequest)
+				return
+			}
+		}
+
+		result, err := f(h.s, params)
+		if errors.Is(err, crypto.ErrInvalidCipherParams) {
+			h.RenderError(w, r, handlers.HandlerError{Msg: msg, Err: err}, responses.ErrBadRequest)
+			return
+		}
+		if err != nil {
+			h.RenderInternalError(w, r, handlers.HandlerError{Msg: msg, Err: err})
+			return
+		}
+
+		h.Render(w, r, responses.SucceededRenderer(result))
+	}
+}
+
+func (h *SymmetricHandler) apply(algorithm string, f func(crypto.Symmetric, string, crypto.SymmetricParams) (crypto.SymmetricResult, error), msg string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params, err := symmetricParams(r)
+		if err != nil {
+			h.RenderError(w, r, handlers.HandlerError{Msg: "invalid parameters", Err: err}, responses.ErrBadRequest)
+			return
+		}
+
+		result, err := f(h.s, algorithm, params)
+		if errors.Is(err, crypto.ErrInvalidCipherParams) || errors.Is(err, crypto.ErrInvalidPadding) || errors.Is(err, crypto.ErrAuthentication) {
+			h.RenderError(w, r, handlers.HandlerError{Msg: msg, Err: err}, responses.ErrBadRequest)
+			return
+		}
+		if err != nil {
+			h.RenderInternalError(w, r, handlers.HandlerError{Msg: msg, Err: err})
+			return
+		}
+
+		h.Render(w, r, responses.SucceededRenderer(result))
+	}
+}
+
+func symmetricParams(r *http.Request) (crypto.SymmetricParams, error) {
+	params := crypto.SymmetricParams{
+		Mode:    r.FormValue("mode"),
+		Padding: r.FormValue("padding"),
+	}
+	for _, p := range []struct {
+		name  string
+		value *[]byte
+	}{
+		{"key", &params.Key}, {"iv", &params.IV}, {"aad", &params.AAD}, {"data", &params.Data},
+	} {
+		value, err := hex.DecodeString(r.FormValue(p.name))
+		if err != nil {
+			return crypto.SymmetricParams{}, fmt.Errorf("invalid %s: %w", p.name, err)
+		}
+		*p.value = value
+	}
+	if text := r.FormValue("text"); text != "" {
+		params.Data = []byte(text)
+	}
+	if macSize := r.FormValue("mac_size"); macSize != "" {
+		value, err := strconv.Atoi(macSize)
+		if err != nil {
+			return crypto.SymmetricParams{}, fmt.Errorf("invalid mac_size: %w", err)
+		}
+		params.MACSize = value
+	}
+	if trace := r.FormValue("trace"); trace != "" {
+		value, err := strconv.ParseBool(trace)
+		if err != nil {
+			return crypto.SymmetricParams{}, fmt.Errorf("invalid trace: %w", err)
+		}
+		params.Trace = value
+	}
+	return params, nil
+}
@@ -7,6 +7,7 @@ import (
 
 type AlphabetRepository interface {
 	Get(name string) (string, error)
+	Corpus(name string) (string, error)
 }
 
 type AlphabetRepo struct {
@@ -27,3 +28,13 @@ func (repo *AlphabetRepo) Get(name string) (string, error) {
 
 	return alphabet, nil
 }
+
+// Corpus возвращает эталонный текст на языке алфавита name, по которому
+// строятся частотные профили для криптоанализа.
+func (repo *AlphabetRepo) Corpus(name string) (string, error) {
+	fContent, err := os.ReadFile(fmt.Sprintf("internal/repository/alphabet/data/%s_corpus.txt", name))
+	if err != nil {
+		return "", err
+	}
+	return string(fContent), nil
+}
@@ -17,6 +17,62 @@ func (_m *MockAlphabetRepository) EXPECT() *MockAlphabetRepository_Expecter {
 	return &MockAlphabetRepository_Expecter{mock: &_m.Mock}
 }
 
+// Corpus provides a mock function with given fields: name
+func (_m *MockAlphabetRepository) Corpus(name string) (string, error) {
+	ret := _m.Called(name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Corpus")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (string, error)); ok {
+		return rf(name)
+	}
+	if rf, ok := ret.Get(0).(func(string) string); ok {
+		r0 = rf(name)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(name)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockAlphabetRepository_Corpus_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Corpus'
+type MockAlphabetRepository_Corpus_Call struct {
+	*mock.Call
+}
+
+// Corpus is a helper method to define mock.On call
+//   - name string
+func (_e *MockAlphabetRepository_Expecter) Corpus(name interface{}) *MockAlphabetRepository_Corpus_Call {
+	return &MockAlphabetRepository_Corpus_Call{Call: _e.mock.On("Corpus", name)}
+}
+
+func (_c *MockAlphabetRepository_Corpus_Call) Run(run func(name string)) *MockAlphabetRepository_Corpus_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *MockAlphabetRepository_Corpus_Call) Return(_a0 string, _a1 error) *MockAlphabetRepository_Corpus_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockAlphabetRepository_Corpus_Call) RunAndReturn(run func(string) (string, error)) *MockAlphabetRepository_Corpus_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Get provides a mock function with given fields: name
 func (_m *MockAlphabetRepository) Get(name string) (string, error) {
 	ret := _m.Called(name)
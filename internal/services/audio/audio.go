@@ -0,0 +1,362 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"unicode/utf8"
+
+	"github.com/PritOriginal/cryptolabs-back/internal/services/compression"
+)
+
+// Сжатие звука кодированием с предсказанием и компандированием.
+// Каждый канал кодируется отдельно, после чего коды либо упаковываются
+// по ширине кодека, либо сжимаются методом Хаффмана. Формат контейнера:
+//
+//	"CLAU" | кодек uint8 | флаги uint8 | число каналов uint8 | частота uint32 |
+//	число кадров uint32 | заголовки каналов | коды
+//
+// Коды каналов идут друг за другом.
+
+const (
+	flagHuffman = 1 << iota
+)
+
+// MaxHistogramBins — наибольшее число интервалов гистограммы остатков.
+const MaxHistogramBins = 256
+
+var audioMagic = []byte("CLAU")
+
+type AudioService struct {
+	h *compression.HuffmanService
+}
+
+func NewAudioService() *AudioService {
+	return &AudioService{h: compression.NewHuffmanService()}
+}
+
+type AudioOptions struct {
+	Codec string
+	// Huffman включает сжатие кодов методом Хаффмана.
+	Huffman bool
+}
+
+type AudioCompressionDetails struct {
+	Details AudioDetails
+	Data    []byte
+	// WAV — сигнал, восстановленный из сжатых данных.
+	WAV []byte
+}
+
+type AudioDetails struct {
+	Codec            string          `json:"codec"`
+	Huffman          bool            `json:"huffman"`
+	Channels         int             `json:"channels"`
+	SampleRate       int             `json:"sample_rate"`
+	Frames           int             `json:"frames"`
+	Lossless         bool            `json:"lossless"`
+	SNR              *float64        `json:"snr"`
+	OriginalSize     int             `json:"original_size"`
+	Size             int             `json:"size"`
+	CompressionRatio float32         `json:"compression_ratio"`
+	BitsPerSample    float64         `json:"bits_per_sample"`
+	Entropy          float64         `json:"entropy"`
+	Histogram        []HistogramItem `json:"histogram"`
+}
+
+// HistogramItem — число кодов со значением остатка в [From, To].
+type HistogramItem struct {
+	From  int `json:"from"`
+	To    int `json:"to"`
+	Count int `json:"count"`
+}
+
+func (s *AudioService) Compress(wav []byte, opts AudioOptions) (AudioCompressionDetails, error) {
+	c, ok := codecNames[opts.Codec]
+	if !ok {
+		return AudioCompressionDetails{}, fmt.Errorf("unknown codec %q", opts.Codec)
+	}
+
+	signal, err := ReadWAV(wav)
+	if err != nil {
+		return AudioCompressionDetails{}, err
+	}
+	if signal.NumFrames() == 0 {
+		return AudioCompressionDetails{}, fmt.Errorf("wav has no samples")
+	}
+
+	var flags byte
+	if opts.Huffman {
+		flags |= flagHuffman
+	}
+
+	buf := new(bytes.Buffer)
+	buf.Write(audioMagic)
+	buf.WriteByte(c.id())
+	buf.WriteByte(flags)
+	buf.WriteByte(byte(len(signal.Channels)))
+	binary.Write(buf, binary.LittleEndian, uint32(signal.SampleRate))
+	binary.Write(buf, binary.LittleEndian, uint32(signal.NumFrames()))
+
+	codes := make([]uint16, 0, signal.NumFrames()*len(signal.Channels))
+	for _, channel := range signal.Channels {
+		header, channelCodes := c.encode(channel)
+		buf.Write(header)
+		codes = append(codes, channelCodes...)
+	}
+
+	payload, err := s.packCodes(c, codes, opts.Huffman)
+	if err != nil {
+		return AudioCompressionDetails{}, err
+	}
+	buf.Write(payload)
+	data := buf.Bytes()
+
+	decoded, err := s.decompress(data)
+	if err != nil {
+		return AudioCompressionDetails{}, err
+	}
+
+	numSamples := signal.NumFrames() * len(signal.Channels)
+	histogram, entropy := s.histogram(c, codes)
+	details := AudioDetails{
+		Codec:            opts.Codec,
+		Huffman:          opts.Huffman,
+		Channels:         len(signal.Channels),
+		SampleRate:       signal.SampleRate,
+		Frames:           signal.NumFrames(),
+		OriginalSize:     2 * numSamples,
+		Size:             len(data),
+		CompressionRatio: 1 - float32(len(data))/float32(2*numSamples),
+		BitsPerSample:    float64(8*len(data)) / float64(numSamples),
+		Entropy:          entropy,
+		Histogram:        histogram,
+	}
+	details.Lossless, details.SNR = snr(signal, decoded)
+
+	return AudioCompressionDetails{Details: details, Data: data, WAV: WriteWAV(decoded)}, nil
+}
+
+// Decompress восстанавливает сигнал и возвращает его в формате WAV.
+func (s *AudioService) Decompress(data []byte) ([]byte, error) {
+	signal, err := s.decompress(data)
+	if err != nil {
+		return nil, err
+	}
+	return WriteWAV(signal), nil
+}
+
+func (s *AudioService) decompress(data []byte) (Signal, error) {
+	if !bytes.HasPrefix(data, audioMagic) {
+		return Signal{}, fmt.Errorf("invalid audio container")
+	}
+	buf := bytes.NewBuffer(data[len(audioMagic):])
+
+	var header struct {
+		Codec      uint8
+		Flags      uint8
+		Channels   uint8
+		SampleRate uint32
+		Frames     uint32
+	}
+	if err := binary.Read(buf, binary.LittleEndian, &header); err != nil {
+		return Signal{}, err
+	}
+	c, err := codecByID(header.Codec)
+	if err != nil {
+		return Signal{}, err
+	}
+	numChannels, numFrames := int(header.Channels), int(header.Frames)
+	if numChannels == 0 || numChannels > MaxChannels || numFrames*numChannels > MaxSamples {
+		return Signal{}, fmt.Errorf("invalid audio header")
+	}
+
+	headers := make([][]byte, numChannels)
+	for i := range headers {
+		headers[i] = buf.Next(c.headerSize())
+		if len(headers[i]) != c.headerSize() {
+			return Signal{}, fmt.Errorf("audio channel header is truncated")
+		}
+	}
+
+	codes, err := s.unpackCodes(c, buf.Bytes(), numFrames*numChannels, header.Flags&flagHuffman != 0)
+	if err != nil {
+		return Signal{}, err
+	}
+
+	signal := Signal{SampleRate: int(header.SampleRate), Channels: make([][]int16, numChannels)}
+	for i := range signal.Channels {
+		signal.Channels[i], err = c.decode(headers[i], codes[i*numFrames:(i+1)*numFrames])
+		if err != nil {
+			return Signal{}, err
+		}
+	}
+	return signal, nil
+}
+
+// packCodes упаковывает коды по ширине кодека или сжимает их методом Хаффмана.
+// HuffmanService работает с символами Unicode, поэтому каждый код
+// предварительно переводится в руну.
+func (s *AudioService) packCodes(c codec, codes []uint16, huffman bool) ([]byte, error) {
+	if huffman {
+		runes := make([]rune, len(codes))
+		for i, code := range codes {
+			runes[i] = codeToRune(c, code)
+		}
+		return s.h.Compress([]byte(string(runes)))
+	}
+
+	payload := make([]byte, 0, (len(codes)*c.width()+7)/8)
+	switch c.width() {
+	case 16:
+		for _, code := range codes {
+			payload = binary.LittleEndian.AppendUint16(payload, code)
+		}
+	case 8:
+		for _, code := range codes {
+			payload = append(payload, byte(code))
+		}
+	case 4:
+		for i := 0; i < len(codes); i += 2 {
+			b := byte(codes[i]) << 4
+			if i+1 < len(codes) {
+				b |= byte(codes[i+1])
+			}
+			payload = append(payload, b)
+		}
+	}
+	return payload, nil
+}
+
+func (s *AudioService) unpackCodes(c codec, payload []byte, numCodes int, huffman bool) ([]uint16, error) {
+	var codes []uint16
+	if huffman {
+		data, err := s.h.Decompress(payload)
+		if err != nil {
+			return nil, err
+		}
+		// Каждый код занимает в UTF-8 хотя бы один байт.
+		if len(data) < numCodes {
+			return nil, fmt.Errorf("audio data has less than %d codes", numCodes)
+		}
+		codes = make([]uint16, 0, numCodes)
+		for len(data) > 0 && len(codes) <= numCodes {
+			r, size := utf8.DecodeRune(data)
+			code, err := runeToCode(c, r)
+			if err != nil {
+				return nil, err
+			}
+			codes = append(codes, code)
+			data = data[size:]
+		}
+	} else {
+		if len(payload) < (numCodes*c.width()+7)/8 {
+			return nil, fmt.Errorf("audio data is truncated")
+		}
+		codes = make([]uint16, 0, numCodes)
+		for i := range numCodes {
+			switch c.width() {
+			case 16:
+				codes = append(codes, binary.LittleEndian.Uint16(payload[2*i:]))
+			case 8:
+				codes = append(codes, uint16(payload[i]))
+			case 4:
+				codes = append(codes, uint16(payload[i/2]>>(4*(1-i%2))&0x0F))
+			}
+		}
+	}
+
+	if len(codes) != numCodes {
+		return nil, fmt.Errorf("audio data has %d codes, want %d", len(codes), numCodes)
+	}
+	return codes, nil
+}
+
+// codeToRune переводит код в руну. Остатки со знаком отображаются
+// зигзагом (0, -1, 1, -2, ...), чтобы частые малые остатки занимали один байт
+// UTF-8, а диапазон суррогатов, не представимый в UTF-8, пропускается.
+func codeToRune(c codec, code uint16) rune {
+	if c.width() < 16 {
+		return rune(code)
+	}
+	v := int32(int16(code))
+	r := rune(uint16(v<<1 ^ v>>31))
+	if r >= 0xD800 {
+		r += 0x800
+	}
+	return r
+}
+
+func runeToCode(c codec, r rune) (uint16, error) {
+	if c.width() < 16 {
+		if r >= 1<<c.width() {
+			return 0, fmt.Errorf("invalid audio code %d", r)
+		}
+		return uint16(r), nil
+	}
+
+	if r >= 0xD800+0x800 {
+		r -= 0x800
+	} else if r >= 0xD800 {
+		return 0, fmt.Errorf("invalid audio code %d", r)
+	}
+	if r > math.MaxUint16 {
+		return 0, fmt.Errorf("invalid audio code %d", r)
+	}
+	z := uint16(r)
+	return uint16(int16(z>>1) ^ -int16(z&1)), nil
+}
+
+// histogram строит гистограмму остатков и оценивает их энтропию в битах на отсчёт.
+// Если различных значений больше MaxHistogramBins, они группируются
+// в интервалы равной ширины.
+func (s *AudioService) histogram(c codec, codes []uint16) ([]HistogramItem, float64) {
+	counts := make(map[int]int)
+	minValue, maxValue := math.MaxInt, math.MinInt
+	for _, code := range codes {
+		v := c.residual(code)
+		counts[v]++
+		minValue, maxValue = min(minValue, v), max(maxValue, v)
+	}
+
+	entropy := 0.0
+	for _, count := range counts {
+		p := float64(count) / float64(len(codes))
+		entropy -= p * math.Log2(p)
+	}
+
+	width := (maxValue - minValue + MaxHistogramBins) / MaxHistogramBins
+	numBins := (maxValue-minValue)/width + 1
+	histogram := make([]HistogramItem, numBins)
+	for i := range histogram {
+		histogram[i].From = minValue + i*width
+		histogram[i].To = histogram[i].From + width - 1
+	}
+	for v, count := range counts {
+		histogram[(v-minValue)/width].Count += count
+	}
+	return histogram, entropy
+}
+
+// snr возвращает отношение сигнал/шум в дБ. Для точного восстановления
+// и для нулевого сигнала SNR не определено, и вместо него возвращается nil.
+func snr(original, decoded Signal) (bool, *float64) {
+	var signalPower, noisePower float64
+	for c, channel := range original.Channels {
+		for i, sample := range channel {
+			d := float64(sample) - float64(decoded.Channels[c][i])
+			signalPower += float64(sample) * float64(sample)
+			noisePower += d * d
+		}
+	}
+
+	if noisePower == 0 {
+		return true, nil
+	}
+	if signalPower == 0 {
+		return false, nil
+	}
+	value := 10 * math.Log10(signalPower/noisePower)
+	return false, &value
+}
@@ -0,0 +1,135 @@
+package audio
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAudioService_Compress(t *testing.T) {
+	signal := Signal{SampleRate: 8000, Channels: [][]int16{sine(4000, 12000), sine(4000, 3000)}}
+	wav := WriteWAV(signal)
+	s := NewAudioService()
+
+	for _, codecName := range []string{CodecDelta, CodecLPC, CodecADPCM, CodecMuLaw, CodecALaw} {
+		for _, huffman := range []bool{false, true} {
+			compressed, err := s.Compress(wav, AudioOptions{Codec: codecName, Huffman: huffman})
+			if err != nil {
+				t.Fatalf("AudioService.Compress(%s, %t) has err = %v", codecName, huffman, err)
+			}
+			details := compressed.Details
+
+			c := codecNames[codecName]
+			if details.Lossless != c.lossless() {
+				t.Errorf("%s: lossless = %t, want %t", codecName, details.Lossless, c.lossless())
+			}
+			if !c.lossless() && (details.SNR == nil || *details.SNR < 15) {
+				t.Errorf("%s: SNR = %v", codecName, details.SNR)
+			}
+			if details.Size >= details.OriginalSize && codecName != CodecDelta && codecName != CodecLPC {
+				t.Errorf("%s: size = %d, original size = %d", codecName, details.Size, details.OriginalSize)
+			}
+
+			decompressed, err := s.Decompress(compressed.Data)
+			if err != nil {
+				t.Fatalf("AudioService.Decompress() has err = %v", err)
+			}
+			if !reflect.DeepEqual(decompressed, compressed.WAV) {
+				t.Errorf("%s: AudioService.Decompress() differs from decoded WAV", codecName)
+			}
+			if c.lossless() && !reflect.DeepEqual(decompressed, wav) {
+				t.Errorf("%s: lossless codec changed the signal", codecName)
+			}
+		}
+	}
+}
+
+func TestAudioService_CompressHuffman(t *testing.T) {
+	s := NewAudioService()
+
+	// Остатки предсказания синусоиды малы, и код Хаффмана для них короче 16 бит.
+	wav := WriteWAV(Signal{SampleRate: 8000, Channels: [][]int16{sine(8000, 1000)}})
+	packed, err := s.Compress(wav, AudioOptions{Codec: CodecLPC})
+	if err != nil {
+		t.Fatal(err)
+	}
+	huffman, err := s.Compress(wav, AudioOptions{Codec: CodecLPC, Huffman: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if huffman.Details.Size >= packed.Details.Size {
+		t.Errorf("size with huffman = %d, without = %d", huffman.Details.Size, packed.Details.Size)
+	}
+
+	// Тишина даёт единственный символ.
+	silence := WriteWAV(Signal{SampleRate: 8000, Channels: [][]int16{make([]int16, 100)}})
+	compressed, err := s.Compress(silence, AudioOptions{Codec: CodecDelta, Huffman: true})
+	if err != nil {
+		t.Fatalf("AudioService.Compress() has err = %v", err)
+	}
+	if !reflect.DeepEqual(compressed.WAV, silence) {
+		t.Errorf("AudioService.Compress() changed silence")
+	}
+	if len(compressed.Details.Histogram) != 1 || compressed.Details.Histogram[0].Count != 100 {
+		t.Errorf("histogram = %v", compressed.Details.Histogram)
+	}
+}
+
+func TestAudioService_Histogram(t *testing.T) {
+	s := NewAudioService()
+	codes := make([]uint16, 0)
+	for v := -1000; v <= 1000; v++ {
+		codes = append(codes, uint16(int16(v)))
+	}
+
+	histogram, _ := s.histogram(deltaCodec{}, codes)
+	if len(histogram) > MaxHistogramBins {
+		t.Errorf("histogram has %d bins", len(histogram))
+	}
+	total := 0
+	for _, item := range histogram {
+		total += item.Count
+	}
+	if total != len(codes) || histogram[0].From != -1000 || histogram[len(histogram)-1].To < 1000 {
+		t.Errorf("histogram = %v", histogram)
+	}
+}
+
+func TestAudioService_DecompressInvalid(t *testing.T) {
+	s := NewAudioService()
+	wav := WriteWAV(Signal{SampleRate: 8000, Channels: [][]int16{sine(100, 1000)}})
+
+	for _, huffman := range []bool{false, true} {
+		compressed, err := s.Compress(wav, AudioOptions{Codec: CodecADPCM, Huffman: huffman})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, n := range []int{0, 3, 10, 17, len(compressed.Data) - 10} {
+			if _, err := s.Decompress(compressed.Data[:n]); err == nil {
+				t.Errorf("AudioService.Decompress() of %d bytes has no err", n)
+			}
+		}
+	}
+}
+
+func FuzzAudioService_Decompress(f *testing.F) {
+	s := NewAudioService()
+	wav := WriteWAV(Signal{SampleRate: 8000, Channels: [][]int16{sine(100, 200), sine(50, 200)}})
+	for codec := range codecNames {
+		for _, huffman := range []bool{false, true} {
+			compressed, err := s.Compress(wav, AudioOptions{Codec: codec, Huffman: huffman})
+			if err != nil {
+				f.Fatal(err)
+			}
+			f.Add(compressed.Data)
+		}
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		wav, err := s.Decompress(data)
+		if err != nil {
+			return
+		}
+		if _, err := ReadWAV(wav); err != nil {
+			t.Fatalf("AudioService.Decompress() returned invalid wav: %v", err)
+		}
+	})
+}
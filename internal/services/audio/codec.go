@@ -0,0 +1,343 @@
+package audio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+)
+
+// Кодеки переводят отсчёты канала в последовательность кодов фиксированной
+// ширины. Без энтропийного кодирования коды упаковываются по width бит.
+
+const (
+	CodecDelta = "delta"
+	CodecLPC   = "lpc"
+	CodecADPCM = "adpcm"
+	CodecMuLaw = "mulaw"
+	CodecALaw  = "alaw"
+)
+
+type codec interface {
+	id() byte
+	// width — число бит на код.
+	width() int
+	lossless() bool
+	// encode возвращает заголовок канала и коды его отсчётов.
+	encode(samples []int16) ([]byte, []uint16)
+	decode(header []byte, codes []uint16) ([]int16, error)
+	headerSize() int
+	// residual — значение кода для гистограммы: остаток предсказания со знаком
+	// или сам код для кодеков без предсказания.
+	residual(code uint16) int
+}
+
+var codecNames = map[string]codec{
+	CodecDelta: deltaCodec{},
+	CodecLPC:   lpcCodec{},
+	CodecADPCM: adpcmCodec{},
+	CodecMuLaw: muLawCodec{},
+	CodecALaw:  aLawCodec{},
+}
+
+func codecByID(id byte) (codec, error) {
+	for _, c := range codecNames {
+		if c.id() == id {
+			return c, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown codec %d", id)
+}
+
+// deltaCodec передаёт разность соседних отсчётов (предсказание первого порядка).
+// Разность вычисляется по модулю 2^16, поэтому кодирование обратимо.
+type deltaCodec struct{}
+
+func (deltaCodec) id() byte                 { return 1 }
+func (deltaCodec) width() int               { return 16 }
+func (deltaCodec) lossless() bool           { return true }
+func (deltaCodec) headerSize() int          { return 0 }
+func (deltaCodec) residual(code uint16) int { return int(int16(code)) }
+
+func (deltaCodec) encode(samples []int16) ([]byte, []uint16) {
+	codes := make([]uint16, len(samples))
+	var prev int16
+	for i, sample := range samples {
+		codes[i] = uint16(sample - prev)
+		prev = sample
+	}
+	return nil, codes
+}
+
+func (deltaCodec) decode(header []byte, codes []uint16) ([]int16, error) {
+	samples := make([]int16, len(codes))
+	var prev int16
+	for i, code := range codes {
+		prev += int16(code)
+		samples[i] = prev
+	}
+	return samples, nil
+}
+
+// lpcCodec — линейное предсказание второго порядка x̂[n] = 2x[n-1] - x[n-2],
+// точное для участков с постоянным наклоном.
+type lpcCodec struct{}
+
+func (lpcCodec) id() byte                 { return 2 }
+func (lpcCodec) width() int               { return 16 }
+func (lpcCodec) lossless() bool           { return true }
+func (lpcCodec) headerSize() int          { return 0 }
+func (lpcCodec) residual(code uint16) int { return int(int16(code)) }
+
+func (lpcCodec) encode(samples []int16) ([]byte, []uint16) {
+	codes := make([]uint16, len(samples))
+	var x1, x2 int16
+	for i, sample := range samples {
+		codes[i] = uint16(sample - (2*x1 - x2))
+		x1, x2 = sample, x1
+	}
+	return nil, codes
+}
+
+func (lpcCodec) decode(header []byte, codes []uint16) ([]int16, error) {
+	samples := make([]int16, len(codes))
+	var x1, x2 int16
+	for i, code := range codes {
+		sample := 2*x1 - x2 + int16(code)
+		samples[i] = sample
+		x1, x2 = sample, x1
+	}
+	return samples, nil
+}
+
+// adpcmCodec — IMA ADPCM: разность с предсказанием квантуется в 4 бита
+// (знак и три бита величины), а шаг квантования адаптируется по таблицам.
+// Заголовок канала: начальное предсказание int16 и индекс шага uint8.
+type adpcmCodec struct{}
+
+var adpcmIndexTable = [16]int{
+	-1, -1, -1, -1, 2, 4, 6, 8,
+	-1, -1, -1, -1, 2, 4, 6, 8,
+}
+
+var adpcmStepTable = [89]int{
+	7, 8, 9, 10, 11, 12, 13, 14, 16, 17,
+	19, 21, 23, 25, 28, 31, 34, 37, 41, 45,
+	50, 55, 60, 66, 73, 80, 88, 97, 107, 118,
+	130, 143, 157, 173, 190, 209, 230, 253, 279, 307,
+	337, 371, 408, 449, 494, 544, 598, 658, 724, 796,
+	876, 963, 1060, 1166, 1282, 1411, 1552, 1707, 1878, 2066,
+	2272, 2499, 2749, 3024, 3327, 3660, 4026, 4428, 4871, 5358,
+	5894, 6484, 7132, 7845, 8630, 9493, 10442, 11487, 12635, 13899,
+	15289, 16818, 18500, 20350, 22385, 24623, 27086, 29794, 32767,
+}
+
+func (adpcmCodec) id() byte        { return 3 }
+func (adpcmCodec) width() int      { return 4 }
+func (adpcmCodec) lossless() bool  { return false }
+func (adpcmCodec) headerSize() int { return 3 }
+
+func (adpcmCodec) residual(code uint16) int {
+	if code&8 != 0 {
+		return -int(code & 7)
+	}
+	return int(code)
+}
+
+type adpcmState struct {
+	predictor int
+	index     int
+}
+
+// update восстанавливает отсчёт по коду так же, как это сделает декодер.
+func (s *adpcmState) update(code int) int16 {
+	step := adpcmStepTable[s.index]
+	diff := step >> 3
+	if code&4 != 0 {
+		diff += step
+	}
+	if code&2 != 0 {
+		diff += step >> 1
+	}
+	if code&1 != 0 {
+		diff += step >> 2
+	}
+	if code&8 != 0 {
+		s.predictor -= diff
+	} else {
+		s.predictor += diff
+	}
+	s.predictor = min(max(s.predictor, -32768), 32767)
+	s.index = min(max(s.index+adpcmIndexTable[code], 0), len(adpcmStepTable)-1)
+	return int16(s.predictor)
+}
+
+func (adpcmCodec) encode(samples []int16) ([]byte, []uint16) {
+	state := adpcmState{}
+	if len(samples) > 0 {
+		state.predictor = int(samples[0])
+	}
+	header := binary.LittleEndian.AppendUint16(nil, uint16(state.predictor))
+	header = append(header, byte(state.index))
+
+	codes := make([]uint16, len(samples))
+	for i, sample := range samples {
+		diff := int(sample) - state.predictor
+		code := 0
+		if diff < 0 {
+			code = 8
+			diff = -diff
+		}
+		step := adpcmStepTable[state.index]
+		for bit := 4; bit > 0; bit >>= 1 {
+			if diff >= step {
+				code |= bit
+				diff -= step
+			}
+			step >>= 1
+		}
+		state.update(code)
+		codes[i] = uint16(code)
+	}
+	return header, codes
+}
+
+func (adpcmCodec) decode(header []byte, codes []uint16) ([]int16, error) {
+	state := adpcmState{
+		predictor: int(int16(binary.LittleEndian.Uint16(header))),
+		index:     int(header[2]),
+	}
+	if state.index >= len(adpcmStepTable) {
+		return nil, fmt.Errorf("invalid adpcm step index %d", state.index)
+	}
+
+	samples := make([]int16, len(codes))
+	for i, code := range codes {
+		samples[i] = state.update(int(code & 0x0F))
+	}
+	return samples, nil
+}
+
+// muLawCodec — логарифмическое сжатие по закону μ (G.711): 8 бит на отсчёт.
+type muLawCodec struct{}
+
+const (
+	muLawBias = 0x84
+	muLawClip = 32635
+)
+
+func (muLawCodec) id() byte                 { return 4 }
+func (muLawCodec) width() int               { return 8 }
+func (muLawCodec) lossless() bool           { return false }
+func (muLawCodec) headerSize() int          { return 0 }
+func (muLawCodec) residual(code uint16) int { return int(code) }
+
+func muLawEncode(sample int16) byte {
+	s := int(sample)
+	sign := 0
+	if s < 0 {
+		sign = 0x80
+		s = -s
+	}
+	s = min(s, muLawClip) + muLawBias
+
+	exponent := max(bits.Len(uint(s>>7))-1, 0)
+	mantissa := (s >> (exponent + 3)) & 0x0F
+	return ^byte(sign | exponent<<4 | mantissa)
+}
+
+func muLawDecode(code byte) int16 {
+	code = ^code
+	exponent := int(code>>4) & 0x07
+	mantissa := int(code & 0x0F)
+	s := (mantissa<<3+muLawBias)<<exponent - muLawBias
+	if code&0x80 != 0 {
+		s = -s
+	}
+	return int16(s)
+}
+
+func (muLawCodec) encode(samples []int16) ([]byte, []uint16) {
+	codes := make([]uint16, len(samples))
+	for i, sample := range samples {
+		codes[i] = uint16(muLawEncode(sample))
+	}
+	return nil, codes
+}
+
+func (muLawCodec) decode(header []byte, codes []uint16) ([]int16, error) {
+	samples := make([]int16, len(codes))
+	for i, code := range codes {
+		samples[i] = muLawDecode(byte(code))
+	}
+	return samples, nil
+}
+
+// aLawCodec — логарифмическое сжатие по закону A (G.711): 8 бит на отсчёт.
+type aLawCodec struct{}
+
+var aLawSegmentEnds = [8]int{0x1F, 0x3F, 0x7F, 0xFF, 0x1FF, 0x3FF, 0x7FF, 0xFFF}
+
+func (aLawCodec) id() byte                 { return 5 }
+func (aLawCodec) width() int               { return 8 }
+func (aLawCodec) lossless() bool           { return false }
+func (aLawCodec) headerSize() int          { return 0 }
+func (aLawCodec) residual(code uint16) int { return int(code) }
+
+func aLawEncode(sample int16) byte {
+	s := int(sample) >> 3
+	mask := 0xD5
+	if s < 0 {
+		mask = 0x55
+		s = -s - 1
+	}
+
+	segment := 0
+	for segment < len(aLawSegmentEnds) && s > aLawSegmentEnds[segment] {
+		segment++
+	}
+	if segment == len(aLawSegmentEnds) {
+		return byte(0x7F ^ mask)
+	}
+
+	code := segment << 4
+	if segment < 2 {
+		code |= (s >> 1) & 0x0F
+	} else {
+		code |= (s >> segment) & 0x0F
+	}
+	return byte(code ^ mask)
+}
+
+func aLawDecode(code byte) int16 {
+	code ^= 0x55
+	s := int(code&0x0F) << 4
+	segment := int(code&0x70) >> 4
+	switch segment {
+	case 0:
+		s += 8
+	case 1:
+		s += 0x108
+	default:
+		s = (s + 0x108) << (segment - 1)
+	}
+	if code&0x80 == 0 {
+		s = -s
+	}
+	return int16(s)
+}
+
+func (aLawCodec) encode(samples []int16) ([]byte, []uint16) {
+	codes := make([]uint16, len(samples))
+	for i, sample := range samples {
+		codes[i] = uint16(aLawEncode(sample))
+	}
+	return nil, codes
+}
+
+func (aLawCodec) decode(header []byte, codes []uint16) ([]int16, error) {
+	samples := make([]int16, len(codes))
+	for i, code := range codes {
+		samples[i] = aLawDecode(byte(code))
+	}
+	return samples, nil
+}
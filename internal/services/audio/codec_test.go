@@ -0,0 +1,86 @@
+package audio
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func sine(n int, amplitude float64) []int16 {
+	samples := make([]int16, n)
+	for i := range samples {
+		samples[i] = int16(amplitude * math.Sin(2*math.Pi*440*float64(i)/8000))
+	}
+	return samples
+}
+
+func TestCodec_Lossless(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	noise := make([]int16, 1000)
+	for i := range noise {
+		noise[i] = int16(r.Intn(1 << 16))
+	}
+
+	for _, c := range []codec{deltaCodec{}, lpcCodec{}} {
+		for _, samples := range [][]int16{sine(1000, 30000), noise} {
+			header, codes := c.encode(samples)
+			got, err := c.decode(header, codes)
+			if err != nil {
+				t.Fatalf("%T.decode() has err = %v", c, err)
+			}
+			if !reflect.DeepEqual(got, samples) {
+				t.Errorf("%T.decode(encode()) differs from samples", c)
+			}
+		}
+	}
+}
+
+func TestCodec_Lossy(t *testing.T) {
+	samples := sine(8000, 20000)
+
+	tests := []struct {
+		c      codec
+		minSNR float64
+	}{
+		{c: adpcmCodec{}, minSNR: 20},
+		{c: muLawCodec{}, minSNR: 30},
+		{c: aLawCodec{}, minSNR: 30},
+	}
+	for _, tt := range tests {
+		header, codes := tt.c.encode(samples)
+		for _, code := range codes {
+			if code >= 1<<tt.c.width() {
+				t.Fatalf("%T code %d is wider than %d bits", tt.c, code, tt.c.width())
+			}
+		}
+
+		decoded, err := tt.c.decode(header, codes)
+		if err != nil {
+			t.Fatalf("%T.decode() has err = %v", tt.c, err)
+		}
+		original := Signal{Channels: [][]int16{samples}}
+		if _, value := snr(original, Signal{Channels: [][]int16{decoded}}); value == nil || *value < tt.minSNR {
+			t.Errorf("%T SNR = %v, want >= %f", tt.c, value, tt.minSNR)
+		}
+	}
+}
+
+func TestG711(t *testing.T) {
+	if got := muLawEncode(0); got != 0xFF {
+		t.Errorf("muLawEncode(0) = 0x%02X, want 0xFF", got)
+	}
+	if got := aLawEncode(0); got != 0xD5 {
+		t.Errorf("aLawEncode(0) = 0x%02X, want 0xD5", got)
+	}
+
+	// Все 256 кодов восстанавливаются в отсчёты, которые кодируются обратно в тот же код.
+	for code := range 256 {
+		if got := muLawEncode(muLawDecode(byte(code))); got != byte(code) && code != 0x7F {
+			t.Errorf("muLawEncode(muLawDecode(0x%02X)) = 0x%02X", code, got)
+		}
+		if got := aLawEncode(aLawDecode(byte(code))); got != byte(code) {
+			t.Errorf("aLawEncode(aLawDecode(0x%02X)) = 0x%02X", code, got)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Чтение и запись WAV с несжатыми 16-битными отсчётами (PCM16).
+
+const (
+	wavFormatPCM        = 1
+	wavFormatExtensible = 0xFFFE
+
+	MaxChannels = 8
+	// MaxSamples ограничивает общее число отсчётов всех каналов.
+	MaxSamples = 1 << 26
+)
+
+// Signal — отсчёты сигнала, разложенные по каналам.
+type Signal struct {
+	SampleRate int
+	Channels   [][]int16
+}
+
+func (s Signal) NumFrames() int {
+	if len(s.Channels) == 0 {
+		return 0
+	}
+	return len(s.Channels[0])
+}
+
+// ReadWAV разбирает RIFF/WAVE. Неизвестные блоки пропускаются,
+// поддерживается только PCM с 16 битами на отсчёт.
+func ReadWAV(data []byte) (Signal, error) {
+	r := bytes.NewReader(data)
+
+	var riff struct {
+		ID     [4]byte
+		Size   uint32
+		Format [4]byte
+	}
+	if err := binary.Read(r, binary.LittleEndian, &riff); err != nil {
+		return Signal{}, fmt.Errorf("invalid wav header: %w", err)
+	}
+	if string(riff.ID[:]) != "RIFF" || string(riff.Format[:]) != "WAVE" {
+		return Signal{}, fmt.Errorf("invalid wav header")
+	}
+
+	var format struct {
+		AudioFormat   uint16
+		NumChannels   uint16
+		SampleRate    uint32
+		ByteRate      uint32
+		BlockAlign    uint16
+		BitsPerSample uint16
+	}
+	hasFormat := false
+	for {
+		var chunk struct {
+			ID   [4]byte
+			Size uint32
+		}
+		if err := binary.Read(r, binary.LittleEndian, &chunk); err != nil {
+			return Signal{}, fmt.Errorf("wav has no data chunk")
+		}
+		// Блоки нечётной длины дополняются одним байтом.
+		size := int64(chunk.Size) + int64(chunk.Size&1)
+
+		switch string(chunk.ID[:]) {
+		case "fmt ":
+			if chunk.Size < 16 {
+				return Signal{}, fmt.Errorf("invalid fmt chunk")
+			}
+			if err := binary.Read(r, binary.LittleEndian, &format); err != nil {
+				return Signal{}, err
+			}
+			if _, err := r.Seek(size-16, io.SeekCurrent); err != nil {
+				return Signal{}, err
+			}
+			hasFormat = true
+		case "data":
+			if !hasFormat {
+				return Signal{}, fmt.Errorf("wav data chunk before fmt chunk")
+			}
+			if format.AudioFormat != wavFormatPCM && format.AudioFormat != wavFormatExtensible || format.BitsPerSample != 16 {
+				return Signal{}, fmt.Errorf("unsupported wav format %d with %d bits, only PCM16 is supported",
+					format.AudioFormat, format.BitsPerSample)
+			}
+			if format.NumChannels == 0 || format.NumChannels > MaxChannels {
+				return Signal{}, fmt.Errorf("unsupported number of channels %d", format.NumChannels)
+			}
+			return readSamples(r, int(chunk.Size), int(format.NumChannels), int(format.SampleRate))
+		default:
+			if _, err := r.Seek(size, io.SeekCurrent); err != nil {
+				return Signal{}, err
+			}
+		}
+	}
+}
+
+func readSamples(r *bytes.Reader, size, numChannels, sampleRate int) (Signal, error) {
+	// Последний неполный кадр, если он есть, отбрасывается.
+	size = min(size, r.Len())
+	numFrames := size / (2 * numChannels)
+	if numFrames*numChannels > MaxSamples {
+		return Signal{}, fmt.Errorf("wav has more than %d samples", MaxSamples)
+	}
+
+	samples := make([]int16, numFrames*numChannels)
+	if err := binary.Read(r, binary.LittleEndian, samples); err != nil {
+		return Signal{}, err
+	}
+
+	signal := Signal{SampleRate: sampleRate, Channels: make([][]int16, numChannels)}
+	for c := range signal.Channels {
+		signal.Channels[c] = make([]int16, numFrames)
+		for i := range numFrames {
+			signal.Channels[c][i] = samples[i*numChannels+c]
+		}
+	}
+	return signal, nil
+}
+
+// WriteWAV записывает сигнал в WAV PCM16 с каналами, чередующимися по кадрам.
+func WriteWAV(signal Signal) []byte {
+	numChannels := len(signal.Channels)
+	numFrames := signal.NumFrames()
+	dataSize := numFrames * numChannels * 2
+
+	buf := new(bytes.Buffer)
+	buf.Grow(44 + dataSize)
+	buf.WriteString("RIFF")
+	binary.Write(buf, binary.LittleEndian, uint32(36+dataSize))
+	buf.WriteString("WAVE")
+
+	buf.WriteString("fmt ")
+	binary.Write(buf, binary.LittleEndian, uint32(16))
+	binary.Write(buf, binary.LittleEndian, uint16(wavFormatPCM))
+	binary.Write(buf, binary.LittleEndian, uint16(numChannels))
+	binary.Write(buf, binary.LittleEndian, uint32(signal.SampleRate))
+	binary.Write(buf, binary.LittleEndian, uint32(signal.SampleRate*numChannels*2))
+	binary.Write(buf, binary.LittleEndian, uint16(numChannels*2))
+	binary.Write(buf, binary.LittleEndian, uint16(16))
+
+	buf.WriteString("data")
+	binary.Write(buf, binary.LittleEndian, uint32(dataSize))
+	samples := make([]int16, numFrames*numChannels)
+	for c, channel := range signal.Channels {
+		for i, sample := range channel {
+			samples[i*numChannels+c] = sample
+		}
+	}
+	binary.Write(buf, binary.LittleEndian, samples)
+	return buf.Bytes()
+}
@@ -0,0 +1,68 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func TestWAV(t *testing.T) {
+	signal := Signal{
+		SampleRate: 8000,
+		Channels: [][]int16{
+			{0, 1, -1, 32767, -32768},
+			{5, 4, 3, 2, 1},
+		},
+	}
+
+	got, err := ReadWAV(WriteWAV(signal))
+	if err != nil {
+		t.Fatalf("ReadWAV() has err = %v", err)
+	}
+	if !reflect.DeepEqual(got, signal) {
+		t.Errorf("ReadWAV() = %v, want %v", got, signal)
+	}
+}
+
+func TestReadWAV_SkipsChunks(t *testing.T) {
+	data := WriteWAV(Signal{SampleRate: 44100, Channels: [][]int16{{1, 2, 3}}})
+
+	// Блок LIST нечётной длины между fmt и data.
+	list := []byte("LIST")
+	list = binary.LittleEndian.AppendUint32(list, 3)
+	list = append(list, 'a', 'b', 'c', 0)
+	withList := append(append(append([]byte(nil), data[:36]...), list...), data[36:]...)
+
+	got, err := ReadWAV(withList)
+	if err != nil {
+		t.Fatalf("ReadWAV() has err = %v", err)
+	}
+	if !reflect.DeepEqual(got.Channels, [][]int16{{1, 2, 3}}) {
+		t.Errorf("ReadWAV() = %v", got.Channels)
+	}
+}
+
+func TestReadWAV_Invalid(t *testing.T) {
+	data := WriteWAV(Signal{SampleRate: 8000, Channels: [][]int16{{1, 2, 3}}})
+
+	eightBit := bytes.Clone(data)
+	binary.LittleEndian.PutUint16(eightBit[34:], 8)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "empty", data: nil},
+		{name: "not wav", data: []byte("RIFF\x00\x00\x00\x00AVI ")},
+		{name: "no data chunk", data: data[:36]},
+		{name: "8 bit", data: eightBit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ReadWAV(tt.data); err == nil {
+				t.Errorf("ReadWAV() has no err")
+			}
+		})
+	}
+}
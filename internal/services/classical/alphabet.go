@@ -0,0 +1,155 @@
+package classical
+
+import (
+	"errors"
+	"fmt"
+	"unicode"
+)
+
+var (
+	ErrInvalidAlphabet = errors.New("invalid alphabet")
+	ErrInvalidKey      = errors.New("invalid key")
+	ErrUnknownCipher   = errors.New("unknown cipher")
+)
+
+// Alphabet — упорядоченный набор букв, над которым работают шифры.
+// Буквы ищутся без учёта регистра: заглавная буква шифруется как строчная,
+// а в результате регистр восстанавливается (если у полученной буквы он есть).
+type Alphabet struct {
+	letters []rune
+	index   map[rune]int
+}
+
+func NewAlphabet(s string) (*Alphabet, error) {
+	a := &Alphabet{letters: []rune(s), index: make(map[rune]int)}
+	if len(a.letters) < 2 {
+		return nil, fmt.Errorf("%w: want at least 2 letters, got %d", ErrInvalidAlphabet, len(a.letters))
+	}
+	for i, ch := range a.letters {
+		if _, ok := a.index[ch]; ok {
+			return nil, fmt.Errorf("%w: letter %q repeats", ErrInvalidAlphabet, ch)
+		}
+		a.index[ch] = i
+	}
+	return a, nil
+}
+
+func (a *Alphabet) Len() int {
+	return len(a.letters)
+}
+
+func (a *Alphabet) String() string {
+	return string(a.letters)
+}
+
+// lookup возвращает номер буквы ch и признак того, что она была найдена
+// только после перевода в нижний регистр.
+func (a *Alphabet) lookup(ch rune) (i int, upper bool, ok bool) {
+	if i, ok := a.index[ch]; ok {
+		return i, false, true
+	}
+	if i, ok := a.index[unicode.ToLower(ch)]; ok {
+		return i, true, true
+	}
+	return 0, false, false
+}
+
+func (a *Alphabet) letter(i int, upper bool) rune {
+	ch := a.letters[mod(i, len(a.letters))]
+	if upper {
+		return unicode.ToUpper(ch)
+	}
+	return ch
+}
+
+// mapText заменяет каждую букву текста на букву с номером f(n, i), где i —
+// номер буквы в алфавите, n — порядковый номер буквы в тексте. Символы вне
+// алфавита переносятся без изменений и не сдвигают ключ.
+func (a *Alphabet) mapText(text string, f func(n, i int) int) string {
+	out := make([]rune, 0, len(text))
+	n := 0
+	for _, ch := range text {
+		i, upper, ok := a.lookup(ch)
+		if !ok {
+			out = append(out, ch)
+			continue
+		}
+		out = append(out, a.letter(f(n, i), upper))
+		n++
+	}
+	return string(out)
+}
+
+// Indices возвращает номера букв текста, пропуская символы вне алфавита.
+func (a *Alphabet) Indices(text string) []int {
+	var out []int
+	for _, ch := range text {
+		if i, _, ok := a.lookup(ch); ok {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+func (a *Alphabet) Text(indices []int) string {
+	out := make([]rune, len(indices))
+	for j, i := range indices {
+		out[j] = a.letter(i, false)
+	}
+	return string(out)
+}
+
+// keyword возвращает номера букв ключевого слова. Символы вне алфавита
+// пропускаются; ключ без единой буквы алфавита недопустим.
+func (a *Alphabet) keyword(key string) ([]int, error) {
+	k := a.Indices(key)
+	if len(k) == 0 {
+		return nil, fmt.Errorf("%w: keyword %q has no letters of the alphabet", ErrInvalidKey, key)
+	}
+	return k, nil
+}
+
+// filler возвращает букву-заполнитель для шифров, работающих с блоками:
+// «x», «х» или «z», если есть в алфавите, иначе последнюю букву.
+func (a *Alphabet) filler() int {
+	for _, ch := range "xхz" {
+		if i, ok := a.index[ch]; ok {
+			return i
+		}
+	}
+	return len(a.letters) - 1
+}
+
+func mod(a, m int) int {
+	a %= m
+	if a < 0 {
+		a += m
+	}
+	return a
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+// modInverse возвращает обратный к a по модулю m элемент или false,
+// если НОД(a, m) ≠ 1.
+func modInverse(a, m int) (int, bool) {
+	t, newT := 0, 1
+	r, newR := m, mod(a, m)
+	for newR != 0 {
+		q := r / newR
+		t, newT = newT, t-q*newT
+		r, newR = newR, r-q*newR
+	}
+	if r != 1 {
+		return 0, false
+	}
+	return mod(t, m), true
+}
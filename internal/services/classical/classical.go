@@ -0,0 +1,147 @@
+package classical
+
+import (
+	"fmt"
+
+	repository "github.com/PritOriginal/cryptolabs-back/internal/repository/alphabet"
+)
+
+// CustomAlphabet — имя набора, при котором алфавит берётся из Params.Alphabet.
+const CustomAlphabet = "custom"
+
+// Cipher — классический шифр с заданными алфавитом и ключом.
+type Cipher interface {
+	Encrypt(text string) string
+	Decrypt(text string) string
+}
+
+// keyDescriber реализуют шифры, которые показывают ключ в разобранном виде:
+// таблицу Плейфера, матрицу Хилла и её обратную и т. п.
+type keyDescriber interface {
+	describeKey() any
+}
+
+// gridCipher реализуют перестановочные шифры: кроме результата они
+// возвращают заполненные таблицы, по которым шифр проверяется вручную.
+type gridCipher interface {
+	encryptGrid(text string) (string, []Grid)
+	decryptGrid(text string) (string, []Grid)
+}
+
+type cipherConstructor func(a *Alphabet, key string) (Cipher, error)
+
+var ciphers = []struct {
+	name string
+	new  cipherConstructor
+}{
+	{"caesar", newCaesar},
+	{"affine", newAffine},
+	{"atbash", newAtbash},
+	{"substitution", newSubstitution},
+	{"vigenere", newVigenere},
+	{"autokey", newAutokey},
+	{"beaufort", newBeaufort},
+	{"playfair", newPlayfair},
+	{"hill", newHill},
+	{"rail_fence", newRailFence},
+	{"columnar", newColumnar},
+	{"double_columnar", newDoubleColumnar},
+	{"route", newRoute},
+	{"scytale", newScytale},
+}
+
+// Ciphers возвращает имена всех шифров.
+func Ciphers() []string {
+	names := make([]string, len(ciphers))
+	for i, c := range ciphers {
+		names[i] = c.name
+	}
+	return names
+}
+
+// NewCipher создаёт шифр name над алфавитом a с ключом key.
+func NewCipher(name string, a *Alphabet, key string) (Cipher, error) {
+	for _, c := range ciphers {
+		if c.name == name {
+			return c.new(a, key)
+		}
+	}
+	return nil, fmt.Errorf("%w %q, want one of %v", ErrUnknownCipher, name, Ciphers())
+}
+
+// Params — параметры запроса. AlphabetSet — имя алфавита в репозитории
+// (en, ru) или CustomAlphabet.
+type Params struct {
+	AlphabetSet string
+	Alphabet    string
+	Key         string
+	Text        string
+}
+
+type Result struct {
+	Cipher   string `json:"cipher"`
+	Alphabet string `json:"alphabet"`
+	Key      any    `json:"key,omitempty"`
+	Grids    []Grid `json:"grids,omitempty"`
+	Text     string `json:"text"`
+}
+
+type Service interface {
+	Encrypt(cipher string, params Params) (Result, error)
+	Decrypt(cipher string, params Params) (Result, error)
+}
+
+type ClassicalService struct {
+	alphabetRepo repository.AlphabetRepository
+}
+
+func NewClassicalService(repo repository.AlphabetRepository) *ClassicalService {
+	return &ClassicalService{alphabetRepo: repo}
+}
+
+func (s *ClassicalService) Encrypt(cipher string, params Params) (Result, error) {
+	return s.apply(cipher, params, false)
+}
+
+func (s *ClassicalService) Decrypt(cipher string, params Params) (Result, error) {
+	return s.apply(cipher, params, true)
+}
+
+func (s *ClassicalService) apply(name string, params Params, decrypt bool) (Result, error) {
+	a, err := s.alphabet(params)
+	if err != nil {
+		return Result{}, err
+	}
+	c, err := NewCipher(name, a, params.Key)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result := Result{Cipher: name, Alphabet: a.String()}
+	gc, withGrid := c.(gridCipher)
+	switch {
+	case withGrid && decrypt:
+		result.Text, result.Grids = gc.decryptGrid(params.Text)
+	case withGrid:
+		result.Text, result.Grids = gc.encryptGrid(params.Text)
+	case decrypt:
+		result.Text = c.Decrypt(params.Text)
+	default:
+		result.Text = c.Encrypt(params.Text)
+	}
+	if d, ok := c.(keyDescriber); ok {
+		result.Key = d.describeKey()
+	}
+	return result, nil
+}
+
+func (s *ClassicalService) alphabet(params Params) (*Alphabet, error) {
+	if params.AlphabetSet == CustomAlphabet {
+		return NewAlphabet(params.Alphabet)
+	}
+	letters, err := s.alphabetRepo.Get(params.AlphabetSet)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAlphabet, err)
+	}
+	return NewAlphabet(letters)
+}
@@ -0,0 +1,153 @@
+package classical
+
+import (
+	"errors"
+	"testing"
+
+	repository "github.com/PritOriginal/cryptolabs-back/internal/repository/alphabet"
+)
+
+const (
+	latin26 = "abcdefghijklmnopqrstuvwxyz"
+	latin25 = "abcdefghiklmnopqrstuvwxyz"
+	en      = "abcdefghijklmnopqrstuvwxyz "
+	ru33    = "абвгдеёжзийклмнопрстуфхцчшщъыьэюя"
+	ru      = ru33 + " "
+)
+
+func newTestService(t *testing.T) *ClassicalService {
+	repo := repository.NewMockAlphabetRepository(t)
+	repo.EXPECT().Get("en").Return(en, nil).Maybe()
+	repo.EXPECT().Get("ru").Return(ru, nil).Maybe()
+	repo.EXPECT().Get("de").Return("", errors.New("no such file")).Maybe()
+	return NewClassicalService(repo)
+}
+
+func TestClassicalService_KnownVectors(t *testing.T) {
+	s := newTestService(t)
+	tests := []struct {
+		cipher   string
+		alphabet string
+		key      string
+		text     string
+		want     string
+	}{
+		{"caesar", latin26, "3", "Hello, World!", "Khoor, Zruog!"},
+		{"caesar", latin26, "d", "Hello, World!", "Khoor, Zruog!"},
+		{"caesar", latin26, "-1", "abc", "zab"},
+		{"affine", latin26, "5,8", "affine cipher", "ihhwvc swfrcp"},
+		{"atbash", latin26, "", "Abc xyz", "Zyx cba"},
+		{"substitution", latin26, "QWERTYUIOPASDFGHJKLZXCVBNM", "Hello, World!", "Itssg, Vgksr!"},
+		{"substitution", latin26, "zebras", "flee at once", "siaa zq lkba"},
+		{"vigenere", latin26, "LEMON", "ATTACK AT DAWN", "LXFOPV EF RNHR"},
+		{"autokey", latin26, "QUEENLY", "attack at dawn", "qnxepv yt wtwp"},
+		{"beaufort", latin26, "FORTIFICATION", "DEFENDTHEEASTWALLOFTHECASTLE", "CKMPVCPVWPIWUJOGIUAPVWRIWUUK"},
+		{"playfair", latin25, "playfair example", "Hide the gold in the tree stump", "bmodzbxdnabekudmuixmmouvif"},
+		{"hill", latin26, "GYBNQKURP", "ACT", "poh"},
+		{"hill", latin26, "6 24 1; 13 16 10; 20 17 15", "cat", "fin"},
+		{"caesar", ru33, "3", "Привет, мир!", "Тулезх, плу!"},
+		{"vigenere", ru33, "ключ", "шифр", "гфтз"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cipher+" "+tt.key, func(t *testing.T) {
+			params := Params{AlphabetSet: CustomAlphabet, Alphabet: tt.alphabet, Key: tt.key, Text: tt.text}
+			got, err := s.Encrypt(tt.cipher, params)
+			if err != nil {
+				t.Fatalf("ClassicalService.Encrypt() has err = %v", err)
+			}
+			if got.Text != tt.want {
+				t.Errorf("ClassicalService.Encrypt() = %q, want %q", got.Text, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassicalService_RoundTrip(t *testing.T) {
+	s := newTestService(t)
+	tests := []struct {
+		cipher string
+		set    string
+		key    string
+		text   string
+		want   string
+	}{
+		{"caesar", "en", "13", "the quick brown fox jumps over the lazy dog.", ""},
+		{"affine", "en", "5, 7", "the quick brown fox jumps over the lazy dog.", ""},
+		{"atbash", "en", "", "the quick brown fox jumps over the lazy dog.", ""},
+		{"substitution", "en", "the quick brown fox jumps over a lazy dog", "the quick brown fox jumps over the lazy dog.", ""},
+		{"vigenere", "en", "secret key", "the quick brown fox jumps over the lazy dog.", ""},
+		{"autokey", "en", "secret", "the quick brown fox jumps over the lazy dog.", ""},
+		{"beaufort", "en", "secret", "the quick brown fox jumps over the lazy dog.", ""},
+		{"playfair", "en", "monarchy", "the quick brown fox jumps over the lazy dogs", ""},
+		{"hill", "en", "2 3; 1 5", "the quick brown fox jumps over the lazy dogs", ""},
+		{"caesar", "ru", "ё", "Съешь же ещё этих мягких французских булок, да выпей чаю.", ""},
+		{"affine", "ru", "3,5", "Съешь же ещё этих мягких французских булок, да выпей чаю.", ""},
+		{"atbash", "ru", "", "Съешь же ещё этих мягких французских булок, да выпей чаю.", ""},
+		{"substitution", "ru", "шифр замены", "Съешь же ещё этих мягких французских булок, да выпей чаю.", ""},
+		{"vigenere", "ru", "криптография", "Съешь же ещё этих мягких французских булок, да выпей чаю.", ""},
+		{"autokey", "ru", "ключ", "Съешь же ещё этих мягких французских булок, да выпей чаю.", ""},
+		{"beaufort", "ru", "ключ", "Съешь же ещё этих мягких французских булок, да выпей чаю.", ""},
+		// Повторная буква и нечётный хвост дополняются заполнителем «х».
+		{"playfair", "ru", "шифр", "съешь же ещё", ""},
+		{"playfair", "ru", "шифр", "длинная шея", "длинная шеях"},
+		{"playfair", "ru", "шифр", "ссора", "схсора"},
+		{"hill", "ru", "1 2 0; 0 1 4; 5 0 1", "съешь же ещё этих мягких французских булок", ""},
+		{"hill", "ru", "шифратора", "съешьжеещёэтихмягкихфранцузскихбулокдавыпейчаю", "съешьжеещёэтихмягкихфранцузскихбулокдавыпейчаюхх"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cipher+" "+tt.set, func(t *testing.T) {
+			params := Params{AlphabetSet: tt.set, Key: tt.key, Text: tt.text}
+			encrypted, err := s.Encrypt(tt.cipher, params)
+			if err != nil {
+				t.Fatalf("ClassicalService.Encrypt() has err = %v", err)
+			}
+			if encrypted.Text == tt.text {
+				t.Errorf("ClassicalService.Encrypt() didn't change text")
+			}
+			params.Text = encrypted.Text
+			decrypted, err := s.Decrypt(tt.cipher, params)
+			if err != nil {
+				t.Fatalf("ClassicalService.Decrypt() has err = %v", err)
+			}
+			want := tt.want
+			if want == "" {
+				want = tt.text
+			}
+			if decrypted.Text != want {
+				t.Errorf("ClassicalService.Decrypt() = %q, want %q", decrypted.Text, want)
+			}
+		})
+	}
+}
+
+func TestClassicalService_Errors(t *testing.T) {
+	s := newTestService(t)
+	tests := []struct {
+		name   string
+		cipher string
+		params Params
+		want   error
+	}{
+		{"unknown cipher", "enigma", Params{AlphabetSet: "en"}, ErrUnknownCipher},
+		{"unknown alphabet", "caesar", Params{AlphabetSet: "de", Key: "1"}, ErrInvalidAlphabet},
+		{"repeated letter", "caesar", Params{AlphabetSet: CustomAlphabet, Alphabet: "abca", Key: "1"}, ErrInvalidAlphabet},
+		{"caesar key", "caesar", Params{AlphabetSet: "en", Key: "ab"}, ErrInvalidKey},
+		{"affine key", "affine", Params{AlphabetSet: "en", Key: "5"}, ErrInvalidKey},
+		{"affine not coprime", "affine", Params{AlphabetSet: "en", Key: "3,1"}, ErrInvalidKey},
+		{"affine not coprime ru", "affine", Params{AlphabetSet: "ru", Key: "17,1"}, ErrInvalidKey},
+		{"empty keyword", "vigenere", Params{AlphabetSet: "ru", Key: "key"}, ErrInvalidKey},
+		{"empty substitution key", "substitution", Params{AlphabetSet: "en", Key: "123"}, ErrInvalidKey},
+		{"playfair prime alphabet", "playfair", Params{AlphabetSet: CustomAlphabet, Alphabet: "abcdefg", Key: "a"}, ErrInvalidAlphabet},
+		{"hill singular", "hill", Params{AlphabetSet: "en", Key: "1 2; 2 4"}, ErrInvalidKey},
+		{"hill not coprime", "hill", Params{AlphabetSet: "en", Key: "3 0; 0 1"}, ErrInvalidKey},
+		{"hill not square", "hill", Params{AlphabetSet: "en", Key: "1 2 3; 4 5"}, ErrInvalidKey},
+		{"hill keyword length", "hill", Params{AlphabetSet: "en", Key: "hello"}, ErrInvalidKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.Encrypt(tt.cipher, tt.params); !errors.Is(err, tt.want) {
+				t.Errorf("ClassicalService.Encrypt() has err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
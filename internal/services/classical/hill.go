@@ -0,0 +1,166 @@
+package classical
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// MaxHillSize — наибольший размер матрицы ключа шифра Хилла.
+const MaxHillSize = 10
+
+// Шифр Хилла: блок из n букв x шифруется как y = K·x mod m. Ключ — матрица
+// чисел («3 3; 2 5») или слово из n² букв, записанное в матрицу по строкам.
+// Матрица обратима, если det K взаимно прост с m. Символы вне алфавита
+// отбрасываются, последний блок дополняется заполнителем.
+type hill struct {
+	a        *Alphabet
+	key, inv [][]int
+	det      int
+}
+
+func newHill(a *Alphabet, key string) (Cipher, error) {
+	k, err := hillMatrix(a, key)
+	if err != nil {
+		return nil, err
+	}
+	m := a.Len()
+	inv, det, ok := invertMatrix(k, m)
+	if !ok {
+		return nil, fmt.Errorf("%w: det=%d is not coprime with alphabet length %d", ErrInvalidKey, det, m)
+	}
+	return &hill{a: a, key: k, inv: inv, det: det}, nil
+}
+
+// hillMatrix разбирает ключ: числа через пробелы или запятые, строки через
+// «;» (или все n² чисел в одну строку), либо слово из n² букв.
+func hillMatrix(a *Alphabet, key string) ([][]int, error) {
+	if !strings.ContainsFunc(key, unicode.IsDigit) {
+		return squareMatrix(a.Indices(key), a.Len(), key)
+	}
+
+	var rows [][]int
+	for _, line := range strings.FieldsFunc(key, func(r rune) bool { return r == ';' || r == '\n' }) {
+		var row []int
+		for _, f := range strings.FieldsFunc(line, func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) {
+			v, err := strconv.Atoi(f)
+			if err != nil {
+				return nil, fmt.Errorf("%w: bad matrix element %q", ErrInvalidKey, f)
+			}
+			row = append(row, mod(v, a.Len()))
+		}
+		if len(row) > 0 {
+			rows = append(rows, row)
+		}
+	}
+	if len(rows) == 1 {
+		return squareMatrix(rows[0], a.Len(), key)
+	}
+	for _, row := range rows {
+		if len(row) != len(rows) {
+			return nil, fmt.Errorf("%w: matrix %q is not square", ErrInvalidKey, key)
+		}
+	}
+	if len(rows) < 2 || len(rows) > MaxHillSize {
+		return nil, fmt.Errorf("%w: want matrix size from 2 to %d, got %d", ErrInvalidKey, MaxHillSize, len(rows))
+	}
+	return rows, nil
+}
+
+func squareMatrix(values []int, m int, key string) ([][]int, error) {
+	n := int(math.Sqrt(float64(len(values))))
+	if n*n != len(values) || n < 2 || n > MaxHillSize {
+		return nil, fmt.Errorf("%w: key %q has %d elements, want n² for n from 2 to %d",
+			ErrInvalidKey, key, len(values), MaxHillSize)
+	}
+	k := make([][]int, n)
+	for i := range k {
+		k[i] = values[i*n : (i+1)*n]
+	}
+	return k, nil
+}
+
+// invertMatrix обращает матрицу по модулю m. Модуль может быть составным
+// (27, 33), поэтому ведущий элемент получается не делением, а алгоритмом
+// Евклида над строками: после исключения в нём остаётся НОД столбца.
+// Возвращает также определитель; ok = false, если он необратим.
+func invertMatrix(k [][]int, m int) (inv [][]int, det int, ok bool) {
+	n := len(k)
+	a := make([][]int, n)
+	inv = make([][]int, n)
+	for i := range k {
+		a[i] = make([]int, n)
+		for j := range k[i] {
+			a[i][j] = mod(k[i][j], m)
+		}
+		inv[i] = make([]int, n)
+		inv[i][i] = 1
+	}
+	subRow := func(dst, src, q int) {
+		for j := 0; j < n; j++ {
+			a[dst][j] = mod(a[dst][j]-q*a[src][j], m)
+			inv[dst][j] = mod(inv[dst][j]-q*inv[src][j], m)
+		}
+	}
+
+	det = 1
+	for c := 0; c < n; c++ {
+		for r := c + 1; r < n; r++ {
+			for a[r][c] != 0 {
+				subRow(c, r, a[c][c]/a[r][c])
+				a[c], a[r] = a[r], a[c]
+				inv[c], inv[r] = inv[r], inv[c]
+				det = -det
+			}
+		}
+		det = mod(det*a[c][c], m)
+	}
+	if gcd(det, m) != 1 {
+		return nil, det, false
+	}
+
+	// Произведение ведущих элементов обратимо, значит, обратим каждый.
+	for c := n - 1; c >= 0; c-- {
+		p, _ := modInverse(a[c][c], m)
+		for j := 0; j < n; j++ {
+			a[c][j] = mod(a[c][j]*p, m)
+			inv[c][j] = mod(inv[c][j]*p, m)
+		}
+		for r := 0; r < c; r++ {
+			subRow(r, c, a[r][c])
+		}
+	}
+	return inv, det, true
+}
+
+func (c *hill) Encrypt(text string) string {
+	return c.a.Text(c.apply(c.key, c.a.Indices(text)))
+}
+
+func (c *hill) Decrypt(text string) string {
+	return c.a.Text(c.apply(c.inv, c.a.Indices(text)))
+}
+
+func (c *hill) apply(k [][]int, x []int) []int {
+	n := len(k)
+	for len(x)%n != 0 {
+		x = append(x, c.a.filler())
+	}
+	out := make([]int, len(x))
+	for b := 0; b < len(x); b += n {
+		for i := 0; i < n; i++ {
+			sum := 0
+			for j := 0; j < n; j++ {
+				sum += k[i][j] * x[b+j]
+			}
+			out[b+i] = sum % c.a.Len()
+		}
+	}
+	return out
+}
+
+func (c *hill) describeKey() any {
+	return map[string]any{"matrix": c.key, "inverse": c.inv, "determinant": c.det}
+}
@@ -0,0 +1,40 @@
+package classical
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInvertMatrix(t *testing.T) {
+	tests := []struct {
+		name    string
+		k       [][]int
+		m       int
+		wantInv [][]int
+		wantDet int
+	}{
+		{"wikipedia 26", [][]int{{6, 24, 1}, {13, 16, 10}, {20, 17, 15}}, 26, [][]int{{8, 5, 10}, {21, 8, 21}, {21, 12, 8}}, 25},
+		{"2x2 mod 27", [][]int{{2, 3}, {1, 5}}, 27, [][]int{{20, 15}, {23, 8}}, 7},
+		{"singular mod 27", [][]int{{3, 3}, {2, 5}}, 27, nil, 9},
+		{"zero pivot mod 33", [][]int{{0, 1}, {1, 0}}, 33, [][]int{{0, 1}, {1, 0}}, 32},
+		// Ни один элемент первого столбца не обратим по модулю 6, а матрица обратима.
+		{"composite pivots mod 6", [][]int{{2, 1}, {3, 1}}, 6, [][]int{{5, 1}, {3, 4}}, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv, det, ok := invertMatrix(tt.k, tt.m)
+			if det != tt.wantDet {
+				t.Errorf("invertMatrix() det = %v, want %v", det, tt.wantDet)
+			}
+			if tt.wantInv == nil {
+				if ok {
+					t.Errorf("invertMatrix() ok = true for singular matrix")
+				}
+				return
+			}
+			if !ok || !reflect.DeepEqual(inv, tt.wantInv) {
+				t.Errorf("invertMatrix() = %v, %v, want %v", inv, ok, tt.wantInv)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package classical
+
+import "fmt"
+
+// Шифр Плейфера. Алфавит записывается в прямоугольник rows×cols, близкий
+// к квадрату (5×5 для 25 букв, 3×9 для 27, 3×11 для 33): сначала буквы
+// ключевого слова без повторов, затем остальные. Текст шифруется биграммами;
+// повторная буква в биграмме и нечётный хвост дополняются заполнителем.
+// Символы вне алфавита отбрасываются, результат — строчные буквы.
+type playfair struct {
+	a          *Alphabet
+	rows, cols int
+	grid       []int
+	pos        []int
+}
+
+func newPlayfair(a *Alphabet, key string) (Cipher, error) {
+	m := a.Len()
+	cols := 2
+	for cols*cols < m || m%cols != 0 {
+		cols++
+	}
+	rows := m / cols
+	if rows < 2 {
+		return nil, fmt.Errorf("%w: alphabet length %d can't form a rectangle", ErrInvalidAlphabet, m)
+	}
+
+	c := &playfair{a: a, rows: rows, cols: cols, pos: make([]int, m)}
+	used := make([]bool, m)
+	for _, i := range append(a.Indices(key), seq(m)...) {
+		if !used[i] {
+			used[i] = true
+			c.pos[i] = len(c.grid)
+			c.grid = append(c.grid, i)
+		}
+	}
+	return c, nil
+}
+
+func seq(n int) []int {
+	s := make([]int, n)
+	for i := range s {
+		s[i] = i
+	}
+	return s
+}
+
+func (c *playfair) Encrypt(text string) string {
+	return c.a.Text(c.apply(c.digrams(c.a.Indices(text)), 1))
+}
+
+func (c *playfair) Decrypt(text string) string {
+	x := c.a.Indices(text)
+	if len(x)%2 != 0 {
+		x = append(x, c.a.filler())
+	}
+	return c.a.Text(c.apply(x, -1))
+}
+
+// digrams разбивает текст на биграммы, вставляя заполнитель между двумя
+// одинаковыми буквами и в конец нечётного текста.
+func (c *playfair) digrams(x []int) []int {
+	filler := c.a.filler()
+	out := make([]int, 0, len(x)+len(x)/2+1)
+	for i := 0; i < len(x); {
+		first := x[i]
+		i++
+		f := filler
+		if first == filler {
+			f = (filler + 1) % c.a.Len()
+		}
+		if i < len(x) && x[i] != first {
+			out = append(out, first, x[i])
+			i++
+		} else {
+			out = append(out, first, f)
+		}
+	}
+	return out
+}
+
+// apply шифрует (dir = 1) или расшифровывает (dir = -1) биграммы: в одной
+// строке буквы сдвигаются вправо, в одном столбце — вниз, иначе берутся
+// противоположные углы прямоугольника.
+func (c *playfair) apply(x []int, dir int) []int {
+	out := make([]int, len(x))
+	for i := 0; i+1 < len(x); i += 2 {
+		r1, c1 := c.pos[x[i]]/c.cols, c.pos[x[i]]%c.cols
+		r2, c2 := c.pos[x[i+1]]/c.cols, c.pos[x[i+1]]%c.cols
+		switch {
+		case r1 == r2:
+			c1, c2 = mod(c1+dir, c.cols), mod(c2+dir, c.cols)
+		case c1 == c2:
+			r1, r2 = mod(r1+dir, c.rows), mod(r2+dir, c.rows)
+		default:
+			c1, c2 = c2, c1
+		}
+		out[i] = c.grid[r1*c.cols+c1]
+		out[i+1] = c.grid[r2*c.cols+c2]
+	}
+	return out
+}
+
+func (c *playfair) describeKey() any {
+	grid := make([]string, c.rows)
+	for r := range grid {
+		grid[r] = c.a.Text(c.grid[r*c.cols : (r+1)*c.cols])
+	}
+	return map[string]any{"grid": grid}
+}
@@ -0,0 +1,89 @@
+package classical
+
+// Шифр Виженера: y = x + k[n mod len(k)] mod m.
+type vigenere struct {
+	a   *Alphabet
+	key []int
+}
+
+func newVigenere(a *Alphabet, key string) (Cipher, error) {
+	k, err := a.keyword(key)
+	if err != nil {
+		return nil, err
+	}
+	return &vigenere{a, k}, nil
+}
+
+func (c *vigenere) Encrypt(text string) string {
+	return c.a.mapText(text, func(n, x int) int { return x + c.key[n%len(c.key)] })
+}
+
+func (c *vigenere) Decrypt(text string) string {
+	return c.a.mapText(text, func(n, y int) int { return y - c.key[n%len(c.key)] })
+}
+
+func (c *vigenere) describeKey() any {
+	return c.a.Text(c.key)
+}
+
+// Самоключ: после ключевого слова гаммой служит сам открытый текст.
+type autokey struct {
+	a   *Alphabet
+	key []int
+}
+
+func newAutokey(a *Alphabet, key string) (Cipher, error) {
+	k, err := a.keyword(key)
+	if err != nil {
+		return nil, err
+	}
+	return &autokey{a, k}, nil
+}
+
+func (c *autokey) Encrypt(text string) string {
+	stream := append([]int(nil), c.key...)
+	return c.a.mapText(text, func(n, x int) int {
+		stream = append(stream, x)
+		return x + stream[n]
+	})
+}
+
+func (c *autokey) Decrypt(text string) string {
+	stream := append([]int(nil), c.key...)
+	return c.a.mapText(text, func(n, y int) int {
+		x := mod(y-stream[n], c.a.Len())
+		stream = append(stream, x)
+		return x
+	})
+}
+
+func (c *autokey) describeKey() any {
+	return c.a.Text(c.key)
+}
+
+// Шифр Бофора: y = k[n mod len(k)] − x mod m, шифрование совпадает
+// с расшифрованием.
+type beaufort struct {
+	a   *Alphabet
+	key []int
+}
+
+func newBeaufort(a *Alphabet, key string) (Cipher, error) {
+	k, err := a.keyword(key)
+	if err != nil {
+		return nil, err
+	}
+	return &beaufort{a, k}, nil
+}
+
+func (c *beaufort) Encrypt(text string) string {
+	return c.a.mapText(text, func(n, x int) int { return c.key[n%len(c.key)] - x })
+}
+
+func (c *beaufort) Decrypt(text string) string {
+	return c.Encrypt(text)
+}
+
+func (c *beaufort) describeKey() any {
+	return c.a.Text(c.key)
+}
@@ -0,0 +1,131 @@
+package classical
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Шифр Цезаря: y = x + k mod m. Ключ — число или буква, номер которой
+// задаёт сдвиг.
+type caesar struct {
+	a     *Alphabet
+	shift int
+}
+
+func newCaesar(a *Alphabet, key string) (Cipher, error) {
+	key = strings.TrimSpace(key)
+	if shift, err := strconv.Atoi(key); err == nil {
+		return &caesar{a, mod(shift, a.Len())}, nil
+	}
+	if runes := []rune(key); len(runes) == 1 {
+		if i, _, ok := a.lookup(runes[0]); ok {
+			return &caesar{a, i}, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: want shift number or letter, got %q", ErrInvalidKey, key)
+}
+
+func (c *caesar) Encrypt(text string) string {
+	return c.a.mapText(text, func(_, i int) int { return i + c.shift })
+}
+
+func (c *caesar) Decrypt(text string) string {
+	return c.a.mapText(text, func(_, i int) int { return i - c.shift })
+}
+
+func (c *caesar) describeKey() any {
+	return map[string]int{"shift": c.shift}
+}
+
+// Аффинный шифр: y = a·x + b mod m, x = a⁻¹·(y − b) mod m.
+// Ключ — «a,b», a взаимно просто с m.
+type affine struct {
+	alphabet *Alphabet
+	a, b     int
+	aInv     int
+}
+
+func newAffine(alphabet *Alphabet, key string) (Cipher, error) {
+	fields := strings.FieldsFunc(key, func(r rune) bool { return r == ',' || r == ' ' || r == ';' })
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("%w: want \"a,b\", got %q", ErrInvalidKey, key)
+	}
+	a, errA := strconv.Atoi(fields[0])
+	b, errB := strconv.Atoi(fields[1])
+	if errA != nil || errB != nil {
+		return nil, fmt.Errorf("%w: want \"a,b\", got %q", ErrInvalidKey, key)
+	}
+	m := alphabet.Len()
+	aInv, ok := modInverse(a, m)
+	if !ok {
+		return nil, fmt.Errorf("%w: a=%d is not coprime with alphabet length %d", ErrInvalidKey, a, m)
+	}
+	return &affine{alphabet, mod(a, m), mod(b, m), aInv}, nil
+}
+
+func (c *affine) Encrypt(text string) string {
+	return c.alphabet.mapText(text, func(_, x int) int { return c.a*x + c.b })
+}
+
+func (c *affine) Decrypt(text string) string {
+	return c.alphabet.mapText(text, func(_, y int) int { return c.aInv * (y - c.b) })
+}
+
+func (c *affine) describeKey() any {
+	return map[string]int{"a": c.a, "b": c.b, "a_inv": c.aInv}
+}
+
+// Атбаш: y = m − 1 − x, шифрование совпадает с расшифрованием.
+type atbash struct {
+	a *Alphabet
+}
+
+func newAtbash(a *Alphabet, _ string) (Cipher, error) {
+	return &atbash{a}, nil
+}
+
+func (c *atbash) Encrypt(text string) string {
+	return c.a.mapText(text, func(_, i int) int { return c.a.Len() - 1 - i })
+}
+
+func (c *atbash) Decrypt(text string) string {
+	return c.Encrypt(text)
+}
+
+// Простая замена: ключ — алфавит замены, i-я буква которого заменяет i-ю
+// букву алфавита. Короткий ключ достраивается до полного (лозунговый шифр):
+// сначала буквы ключа без повторов, затем остальные буквы по порядку.
+type substitution struct {
+	a        *Alphabet
+	key, inv []int
+}
+
+func newSubstitution(a *Alphabet, key string) (Cipher, error) {
+	k, err := a.keyword(key)
+	if err != nil {
+		return nil, err
+	}
+	c := &substitution{a: a, inv: make([]int, a.Len())}
+	used := make([]bool, a.Len())
+	for _, i := range append(k, seq(a.Len())...) {
+		if !used[i] {
+			used[i] = true
+			c.inv[i] = len(c.key)
+			c.key = append(c.key, i)
+		}
+	}
+	return c, nil
+}
+
+func (c *substitution) Encrypt(text string) string {
+	return c.a.mapText(text, func(_, i int) int { return c.key[i] })
+}
+
+func (c *substitution) Decrypt(text string) string {
+	return c.a.mapText(text, func(_, i int) int { return c.inv[i] })
+}
+
+func (c *substitution) describeKey() any {
+	return c.a.Text(c.key)
+}
@@ -0,0 +1,388 @@
+package classical
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Перестановочные шифры не меняют буквы, а переставляют их. Каждый шифр
+// задаётся перестановкой perm для текста длины size: y[i] = x[perm[i]].
+// Символы вне алфавита отбрасываются; если шифру нужна полная таблица,
+// текст дополняется заполнителем алфавита.
+
+// Grid — заполненная таблица шифра. Key — ключ над столбцами, Order — номера
+// столбцов (с 1) в порядке чтения, пустые ячейки — пустые строки.
+type Grid struct {
+	Key   []string   `json:"key,omitempty"`
+	Order []int      `json:"order,omitempty"`
+	Route string     `json:"route,omitempty"`
+	Cells [][]string `json:"cells"`
+}
+
+type permutation interface {
+	layout(n int) (size int, perm []int)
+	grids(a *Alphabet, x []int) []Grid
+}
+
+type transposition struct {
+	a *Alphabet
+	p permutation
+}
+
+func (c *transposition) Encrypt(text string) string {
+	y, _ := c.encryptGrid(text)
+	return y
+}
+
+func (c *transposition) Decrypt(text string) string {
+	x, _ := c.decryptGrid(text)
+	return x
+}
+
+func (c *transposition) encryptGrid(text string) (string, []Grid) {
+	x := c.a.Indices(text)
+	size, perm := c.p.layout(len(x))
+	x = c.pad(x, size)
+	y := make([]int, size)
+	for i, j := range perm {
+		y[i] = x[j]
+	}
+	return c.a.Text(y), c.p.grids(c.a, x)
+}
+
+func (c *transposition) decryptGrid(text string) (string, []Grid) {
+	y := c.a.Indices(text)
+	size, perm := c.p.layout(len(y))
+	y = c.pad(y, size)
+	x := make([]int, size)
+	for i, j := range perm {
+		x[j] = y[i]
+	}
+	return c.a.Text(x), c.p.grids(c.a, x)
+}
+
+func (c *transposition) pad(x []int, size int) []int {
+	for len(x) < size {
+		x = append(x, c.a.filler())
+	}
+	return x
+}
+
+// cells записывает x в таблицу rows×cols по строкам.
+func cells(a *Alphabet, x []int, rows, cols int) [][]string {
+	out := make([][]string, rows)
+	for r := range out {
+		out[r] = make([]string, cols)
+		for c := range out[r] {
+			if i := r*cols + c; i < len(x) {
+				out[r][c] = string(a.letter(x[i], false))
+			}
+		}
+	}
+	return out
+}
+
+func ceilDiv(a, b int) int {
+	return (a + b - 1) / b
+}
+
+// fit ограничивает размер таблицы k (число строк или столбцов из ключа)
+// длиной текста n: при k > n лишние строки или столбцы остались бы пустыми,
+// а таблица по огромному ключу не поместилась бы в память.
+func fit(k, n int) int {
+	return min(k, max(n, 2))
+}
+
+func intKey(key, name string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(key))
+	if err != nil || n < 2 {
+		return 0, fmt.Errorf("%w: want %s at least 2, got %q", ErrInvalidKey, name, key)
+	}
+	return n, nil
+}
+
+// Шифр ограждения: буквы записываются зигзагом по rails строкам
+// и читаются построчно.
+type railFence struct {
+	rails int
+}
+
+func newRailFence(a *Alphabet, key string) (Cipher, error) {
+	rails, err := intKey(key, "number of rails")
+	if err != nil {
+		return nil, err
+	}
+	return &transposition{a, &railFence{rails}}, nil
+}
+
+func (c *railFence) rail(i int) int {
+	period := 2 * (c.rails - 1)
+	r := i % period
+	if r >= c.rails {
+		r = period - r
+	}
+	return r
+}
+
+func (c *railFence) layout(n int) (int, []int) {
+	c = &railFence{fit(c.rails, n)}
+	perm := make([]int, 0, n)
+	for r := 0; r < c.rails; r++ {
+		for i := 0; i < n; i++ {
+			if c.rail(i) == r {
+				perm = append(perm, i)
+			}
+		}
+	}
+	return n, perm
+}
+
+func (c *railFence) grids(a *Alphabet, x []int) []Grid {
+	c = &railFence{fit(c.rails, len(x))}
+	grid := cells(a, nil, c.rails, len(x))
+	for i, l := range x {
+		grid[c.rail(i)][i] = string(a.letter(l, false))
+	}
+	return []Grid{{Cells: grid}}
+}
+
+// Вертикальная перестановка: текст записывается по строкам под ключом,
+// столбцы читаются в порядке возрастания букв ключа (при равных — слева
+// направо) или чисел ключа «3 1 4 2».
+type columnar struct {
+	key   []string
+	order []int
+}
+
+func newColumnar(a *Alphabet, key string) (Cipher, error) {
+	c, err := columnOrder(a, key)
+	if err != nil {
+		return nil, err
+	}
+	return &transposition{a, c}, nil
+}
+
+func columnOrder(a *Alphabet, key string) (*columnar, error) {
+	c := &columnar{}
+	var ranks []int
+	if strings.ContainsFunc(key, unicode.IsDigit) {
+		c.key = strings.FieldsFunc(key, func(r rune) bool { return r == ',' || unicode.IsSpace(r) })
+		for _, f := range c.key {
+			v, err := strconv.Atoi(f)
+			if err != nil {
+				return nil, fmt.Errorf("%w: bad column number %q", ErrInvalidKey, f)
+			}
+			ranks = append(ranks, v)
+		}
+		sorted := slices.Sorted(slices.Values(ranks))
+		for i, v := range sorted {
+			if v != i+1 {
+				return nil, fmt.Errorf("%w: %q is not a permutation of 1..%d", ErrInvalidKey, key, len(ranks))
+			}
+		}
+	} else {
+		ranks = a.Indices(key)
+		for _, i := range ranks {
+			c.key = append(c.key, string(a.letter(i, false)))
+		}
+	}
+	if len(ranks) < 2 {
+		return nil, fmt.Errorf("%w: want at least 2 columns, got key %q", ErrInvalidKey, key)
+	}
+
+	c.order = make([]int, len(ranks))
+	for i := range c.order {
+		c.order[i] = i
+	}
+	slices.SortStableFunc(c.order, func(i, j int) int { return ranks[i] - ranks[j] })
+	return c, nil
+}
+
+func (c *columnar) layout(n int) (int, []int) {
+	w := len(c.order)
+	rows := ceilDiv(n, w)
+	perm := make([]int, 0, rows*w)
+	for _, col := range c.order {
+		for r := 0; r < rows; r++ {
+			perm = append(perm, r*w+col)
+		}
+	}
+	return rows * w, perm
+}
+
+func (c *columnar) grids(a *Alphabet, x []int) []Grid {
+	order := make([]int, len(c.order))
+	for i, col := range c.order {
+		order[i] = col + 1
+	}
+	w := len(c.order)
+	return []Grid{{Key: c.key, Order: order, Cells: cells(a, x, len(x)/w, w)}}
+}
+
+// Двойная вертикальная перестановка: ключи разделяются «;». Текст
+// дополняется до кратного НОК ширин, чтобы обе таблицы были полными.
+// MaxDoubleColumnarPeriod ограничивает этот НОК, а с ним и дополнение.
+const MaxDoubleColumnarPeriod = 1 << 16
+
+type doubleColumnar struct {
+	first, second *columnar
+}
+
+func newDoubleColumnar(a *Alphabet, key string) (Cipher, error) {
+	keys := strings.Split(key, ";")
+	if len(keys) != 2 {
+		return nil, fmt.Errorf("%w: want two keys separated by \";\", got %q", ErrInvalidKey, key)
+	}
+	first, err := columnOrder(a, keys[0])
+	if err != nil {
+		return nil, err
+	}
+	second, err := columnOrder(a, keys[1])
+	if err != nil {
+		return nil, err
+	}
+	c := &doubleColumnar{first, second}
+	if period := c.period(); period > MaxDoubleColumnarPeriod {
+		return nil, fmt.Errorf("%w: lcm of key widths is %d, want at most %d", ErrInvalidKey, period, MaxDoubleColumnarPeriod)
+	}
+	return &transposition{a, c}, nil
+}
+
+// period — НОК ширин таблиц: длина текста всегда кратна ему.
+func (c *doubleColumnar) period() int {
+	w1, w2 := len(c.first.order), len(c.second.order)
+	return w1 / gcd(w1, w2) * w2
+}
+
+func (c *doubleColumnar) layout(n int) (int, []int) {
+	period := c.period()
+	size := ceilDiv(n, period) * period
+	_, p1 := c.first.layout(size)
+	_, p2 := c.second.layout(size)
+	perm := make([]int, size)
+	for i := range perm {
+		perm[i] = p1[p2[i]]
+	}
+	return size, perm
+}
+
+func (c *doubleColumnar) grids(a *Alphabet, x []int) []Grid {
+	_, p1 := c.first.layout(len(x))
+	mid := make([]int, len(x))
+	for i, j := range p1 {
+		mid[i] = x[j]
+	}
+	return append(c.first.grids(a, x), c.second.grids(a, mid)...)
+}
+
+// Маршрутная перестановка: текст записывается по строкам в таблицу из cols
+// столбцов и читается по маршруту. Ключ — «cols» или «cols,маршрут»:
+// spiral — по спирали по часовой стрелке от левого верхнего угла
+// (по умолчанию), snake — змейкой по столбцам.
+const (
+	RouteSpiral = "spiral"
+	RouteSnake  = "snake"
+)
+
+type route struct {
+	cols  int
+	route string
+}
+
+func newRoute(a *Alphabet, key string) (Cipher, error) {
+	fields := strings.FieldsFunc(key, func(r rune) bool { return r == ',' || unicode.IsSpace(r) })
+	if len(fields) == 0 || len(fields) > 2 {
+		return nil, fmt.Errorf("%w: want \"columns[,route]\", got %q", ErrInvalidKey, key)
+	}
+	cols, err := intKey(fields[0], "number of columns")
+	if err != nil {
+		return nil, err
+	}
+	c := &route{cols: cols, route: RouteSpiral}
+	if len(fields) == 2 {
+		c.route = fields[1]
+	}
+	if c.route != RouteSpiral && c.route != RouteSnake {
+		return nil, fmt.Errorf("%w: unknown route %q, want %s or %s", ErrInvalidKey, c.route, RouteSpiral, RouteSnake)
+	}
+	return &transposition{a, c}, nil
+}
+
+func (c *route) layout(n int) (int, []int) {
+	c = &route{cols: fit(c.cols, n), route: c.route}
+	rows := ceilDiv(n, c.cols)
+	perm := make([]int, 0, rows*c.cols)
+	if c.route == RouteSnake {
+		for col := 0; col < c.cols; col++ {
+			for r := 0; r < rows; r++ {
+				row := r
+				if col%2 == 1 {
+					row = rows - 1 - r
+				}
+				perm = append(perm, row*c.cols+col)
+			}
+		}
+		return rows * c.cols, perm
+	}
+
+	top, bottom, left, right := 0, rows-1, 0, c.cols-1
+	for top <= bottom && left <= right {
+		for col := left; col <= right; col++ {
+			perm = append(perm, top*c.cols+col)
+		}
+		for r := top + 1; r <= bottom; r++ {
+			perm = append(perm, r*c.cols+right)
+		}
+		if top < bottom {
+			for col := right - 1; col >= left; col-- {
+				perm = append(perm, bottom*c.cols+col)
+			}
+		}
+		if left < right {
+			for r := bottom - 1; r > top; r-- {
+				perm = append(perm, r*c.cols+left)
+			}
+		}
+		top, bottom, left, right = top+1, bottom-1, left+1, right-1
+	}
+	return rows * c.cols, perm
+}
+
+func (c *route) grids(a *Alphabet, x []int) []Grid {
+	c = &route{cols: fit(c.cols, len(x)), route: c.route}
+	return []Grid{{Route: c.route, Cells: cells(a, x, len(x)/c.cols, c.cols)}}
+}
+
+// Сцитала: лента с текстом наматывается на жезл с rows гранями — текст
+// записывается по строкам в таблицу из rows строк и читается по столбцам.
+type scytale struct {
+	rows int
+}
+
+func newScytale(a *Alphabet, key string) (Cipher, error) {
+	rows, err := intKey(key, "number of rows")
+	if err != nil {
+		return nil, err
+	}
+	return &transposition{a, &scytale{rows}}, nil
+}
+
+func (c *scytale) layout(n int) (int, []int) {
+	c = &scytale{fit(c.rows, n)}
+	cols := ceilDiv(n, c.rows)
+	perm := make([]int, 0, c.rows*cols)
+	for col := 0; col < cols; col++ {
+		for r := 0; r < c.rows; r++ {
+			perm = append(perm, r*cols+col)
+		}
+	}
+	return c.rows * cols, perm
+}
+
+func (c *scytale) grids(a *Alphabet, x []int) []Grid {
+	c = &scytale{fit(c.rows, len(x))}
+	return []Grid{{Cells: cells(a, x, c.rows, len(x)/c.rows)}}
+}
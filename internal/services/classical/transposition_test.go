@@ -0,0 +1,157 @@
+package classical
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTransposition_KnownVectors(t *testing.T) {
+	s := newTestService(t)
+	tests := []struct {
+		cipher   string
+		alphabet string
+		key      string
+		text     string
+		want     string
+	}{
+		{"rail_fence", latin26, "3", "WE ARE DISCOVERED. FLEE AT ONCE", "wecrlteerdsoeefeaocaivden"},
+		{"columnar", latin26, "ZEBRAS", "WE ARE DISCOVERED. FLEE AT ONCE", "evlnxacdtxeseaxrofoxdeecxwiree"},
+		{"columnar", latin26, "6 3 2 4 1 5", "WE ARE DISCOVERED. FLEE AT ONCE", "evlnxacdtxeseaxrofoxdeecxwiree"},
+		{"route", latin26, "4", "abcdefghijkl", "abcdhlkjiefg"},
+		{"route", latin26, "4, snake", "abcdefghijkl", "aeijfbcgklhd"},
+		{"route", latin26, "3", "abcdefgh", "abcfxhgde"},
+		{"scytale", latin26, "4", "I am hurt very badly help", "iryyatbhmvaehedlurlp"},
+		{"scytale", latin26, "3", "abcdefg", "adgbexcfx"},
+		{"rail_fence", latin26, "2000000000", "abc", "abc"},
+		{"route", latin26, "2000000000", "abc", "abc"},
+		{"scytale", latin26, "2000000000", "abc", "abc"},
+		{"scytale", latin26, "2000000000", "a", "ax"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cipher+" "+tt.key, func(t *testing.T) {
+			params := Params{AlphabetSet: CustomAlphabet, Alphabet: tt.alphabet, Key: tt.key, Text: tt.text}
+			got, err := s.Encrypt(tt.cipher, params)
+			if err != nil {
+				t.Fatalf("ClassicalService.Encrypt() has err = %v", err)
+			}
+			if got.Text != tt.want {
+				t.Errorf("ClassicalService.Encrypt() = %q, want %q", got.Text, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransposition_RoundTrip(t *testing.T) {
+	s := newTestService(t)
+	tests := []struct {
+		cipher string
+		set    string
+		key    string
+		text   string
+		want   string
+	}{
+		{"rail_fence", "en", "4", "the quick brown fox", ""},
+		{"columnar", "en", "secret", "the quick brown fox", "the quick brown foxxxxxx"},
+		{"double_columnar", "en", "secret;key", "the quick brown fox", "the quick brown foxxxxxx"},
+		{"double_columnar", "en", "3 1 2; 2 1", "the quick brown fox", "the quick brown foxxxxxx"},
+		{"route", "en", "5", "the quick brown fox", "the quick brown foxx"},
+		{"route", "en", "5,snake", "the quick brown fox", "the quick brown foxx"},
+		{"scytale", "en", "3", "the quick brown fox", "the quick brown foxxx"},
+		{"rail_fence", "ru", "3", "съешь же ещё этих мягких булок", ""},
+		{"columnar", "ru", "шифр", "съешь же ещё этих мягких булок", "съешь же ещё этих мягких булокхх"},
+		{"double_columnar", "ru", "шифр;ключ", "съешь же ещё этих мягких булок", "съешь же ещё этих мягких булокхх"},
+		{"route", "ru", "6", "съешь же ещё этих мягких булок", ""},
+		{"scytale", "ru", "5", "съешь же ещё этих мягких булок", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cipher+" "+tt.set, func(t *testing.T) {
+			params := Params{AlphabetSet: tt.set, Key: tt.key, Text: tt.text}
+			encrypted, err := s.Encrypt(tt.cipher, params)
+			if err != nil {
+				t.Fatalf("ClassicalService.Encrypt() has err = %v", err)
+			}
+			params.Text = encrypted.Text
+			decrypted, err := s.Decrypt(tt.cipher, params)
+			if err != nil {
+				t.Fatalf("ClassicalService.Decrypt() has err = %v", err)
+			}
+			want := tt.want
+			if want == "" {
+				want = tt.text
+			}
+			if decrypted.Text != want {
+				t.Errorf("ClassicalService.Decrypt() = %q, want %q", decrypted.Text, want)
+			}
+			if !reflect.DeepEqual(decrypted.Grids, encrypted.Grids) {
+				t.Errorf("ClassicalService.Decrypt() grids = %v, want %v", decrypted.Grids, encrypted.Grids)
+			}
+		})
+	}
+}
+
+func TestTransposition_Grids(t *testing.T) {
+	s := newTestService(t)
+	tests := []struct {
+		cipher string
+		key    string
+		text   string
+		want   []Grid
+	}{
+		{"rail_fence", "3", "abcdefg", []Grid{{Cells: [][]string{
+			{"a", "", "", "", "e", "", ""},
+			{"", "b", "", "d", "", "f", ""},
+			{"", "", "c", "", "", "", "g"},
+		}}}},
+		{"columnar", "cab", "abcdefg", []Grid{{Key: []string{"c", "a", "b"}, Order: []int{2, 3, 1}, Cells: [][]string{
+			{"a", "b", "c"},
+			{"d", "e", "f"},
+			{"g", "x", "x"},
+		}}}},
+		{"double_columnar", "2 1;1 2", "abcd", []Grid{
+			{Key: []string{"2", "1"}, Order: []int{2, 1}, Cells: [][]string{{"a", "b"}, {"c", "d"}}},
+			{Key: []string{"1", "2"}, Order: []int{1, 2}, Cells: [][]string{{"b", "d"}, {"a", "c"}}},
+		}},
+		{"route", "2,snake", "abcd", []Grid{{Route: RouteSnake, Cells: [][]string{{"a", "b"}, {"c", "d"}}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cipher, func(t *testing.T) {
+			got, err := s.Encrypt(tt.cipher, Params{AlphabetSet: CustomAlphabet, Alphabet: latin26, Key: tt.key, Text: tt.text})
+			if err != nil {
+				t.Fatalf("ClassicalService.Encrypt() has err = %v", err)
+			}
+			if !reflect.DeepEqual(got.Grids, tt.want) {
+				t.Errorf("ClassicalService.Encrypt() grids = %v, want %v", got.Grids, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransposition_Errors(t *testing.T) {
+	s := newTestService(t)
+	tests := []struct {
+		cipher string
+		key    string
+	}{
+		{"rail_fence", "1"},
+		{"rail_fence", "many"},
+		{"columnar", "a"},
+		{"columnar", "1 2 2"},
+		{"columnar", "0 1"},
+		{"double_columnar", "key"},
+		{"double_columnar", "key;a"},
+		{"double_columnar", strings.Repeat("a", 257) + ";" + strings.Repeat("a", 256)},
+		{"route", "3,zigzag"},
+		{"route", ""},
+		{"scytale", "0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cipher+" "+tt.key[:min(len(tt.key), 16)], func(t *testing.T) {
+			_, err := s.Encrypt(tt.cipher, Params{AlphabetSet: "en", Key: tt.key, Text: "text"})
+			if !errors.Is(err, ErrInvalidKey) {
+				t.Errorf("ClassicalService.Encrypt() has err = %v, want %v", err, ErrInvalidKey)
+			}
+		})
+	}
+}
@@ -0,0 +1,316 @@
+package compression
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"math"
+)
+
+// Архив из нескольких файлов, каждый из которых сжат своим алгоритмом.
+// Оглавление записывается после данных, как в ZIP:
+//
+//	"CLAR" | сжатые данные файлов | оглавление | смещение оглавления uint32 | число файлов uint32
+//
+// Запись оглавления: длина имени uint16 | имя | алгоритм uint8 |
+// исходный размер uint32 | сжатый размер uint32 | CRC-32 исходных данных uint32.
+// Данные файлов идут подряд в порядке оглавления.
+
+const (
+	// ArchiveAuto выбирает для файла алгоритм, дающий наименьший размер.
+	ArchiveAuto = "auto"
+	// ArchiveStore сохраняет файл без сжатия.
+	ArchiveStore = "store"
+
+	archiveStoreID = 0
+
+	MaxArchiveEntries = 1 << 16
+)
+
+var archiveMagic = []byte("CLAR")
+
+type ArchiveService struct{}
+
+func NewArchiveService() *ArchiveService {
+	return &ArchiveService{}
+}
+
+type ArchiveFile struct {
+	Name string
+	Data []byte
+	// Algorithm — имя алгоритма из реестра, ArchiveStore или ArchiveAuto (по умолчанию).
+	Algorithm string
+}
+
+type ArchiveEntry struct {
+	Name             string  `json:"name"`
+	Algorithm        string  `json:"algorithm"`
+	AlgorithmID      uint8   `json:"algorithm_id"`
+	Size             int     `json:"size"`
+	CompressedSize   int     `json:"compressed_size"`
+	CRC32            uint32  `json:"crc32"`
+	Offset           int     `json:"offset"`
+	CompressionRatio float32 `json:"compression_ratio"`
+}
+
+func (a *ArchiveService) Create(files []ArchiveFile) ([]byte, []ArchiveEntry, error) {
+	if len(files) == 0 || len(files) > MaxArchiveEntries {
+		return nil, nil, fmt.Errorf("archive must have between 1 and %d files", MaxArchiveEntries)
+	}
+
+	names := make(map[string]bool, len(files))
+	entries := make([]ArchiveEntry, len(files))
+	buf := new(bytes.Buffer)
+	buf.Write(archiveMagic)
+	for i, file := range files {
+		if file.Name == "" || len(file.Name) > math.MaxUint16 {
+			return nil, nil, fmt.Errorf("file %d has invalid name", i)
+		}
+		if names[file.Name] {
+			return nil, nil, fmt.Errorf("duplicate file name %q", file.Name)
+		}
+		names[file.Name] = true
+		if uint64(len(file.Data)) > math.MaxUint32 {
+			return nil, nil, fmt.Errorf("file %q is too large", file.Name)
+		}
+
+		id, compressedData, err := a.compress(file)
+		if err != nil {
+			return nil, nil, fmt.Errorf("file %q: %w", file.Name, err)
+		}
+
+		entries[i] = ArchiveEntry{
+			Name:           file.Name,
+			AlgorithmID:    id,
+			Size:           len(file.Data),
+			CompressedSize: len(compressedData),
+			CRC32:          crc32.ChecksumIEEE(file.Data),
+			Offset:         buf.Len(),
+		}
+		entries[i].Algorithm, _ = archiveAlgorithmName(id)
+		entries[i].CompressionRatio = compressionRatio(entries[i])
+		buf.Write(compressedData)
+	}
+
+	directoryOffset := buf.Len()
+	for _, entry := range entries {
+		binary.Write(buf, binary.LittleEndian, uint16(len(entry.Name)))
+		buf.WriteString(entry.Name)
+		buf.WriteByte(entry.AlgorithmID)
+		binary.Write(buf, binary.LittleEndian, uint32(entry.Size))
+		binary.Write(buf, binary.LittleEndian, uint32(entry.CompressedSize))
+		binary.Write(buf, binary.LittleEndian, entry.CRC32)
+	}
+	binary.Write(buf, binary.LittleEndian, uint32(directoryOffset))
+	binary.Write(buf, binary.LittleEndian, uint32(len(entries)))
+
+	return buf.Bytes(), entries, nil
+}
+
+// compress сжимает файл выбранным алгоритмом. Результат каждого алгоритма
+// проверяется разжатием: посимвольные алгоритмы не восстанавливают
+// произвольные двоичные данные. В режиме ArchiveAuto среди алгоритмов,
+// восстанавливающих файл, выбирается дающий наименьший размер, а если
+// ни один не уменьшает файл, он сохраняется без сжатия.
+func (a *ArchiveService) compress(file ArchiveFile) (uint8, []byte, error) {
+	switch file.Algorithm {
+	case ArchiveStore:
+		return archiveStoreID, file.Data, nil
+	case "", ArchiveAuto:
+	default:
+		algorithm, err := AlgorithmByName(file.Algorithm)
+		if err != nil {
+			return 0, nil, err
+		}
+		compressedData, ok := a.tryCompress(algorithm, file.Data)
+		if !ok {
+			return 0, nil, fmt.Errorf("algorithm %s cannot restore the file", algorithm.Name)
+		}
+		return algorithm.ID, compressedData, nil
+	}
+
+	var id uint8 = archiveStoreID
+	best := file.Data
+	if len(file.Data) == 0 {
+		return id, best, nil
+	}
+	for _, algorithm := range autoAlgorithms() {
+		compressedData, ok := a.tryCompress(algorithm, file.Data)
+		if ok && len(compressedData) < len(best) {
+			id, best = algorithm.ID, compressedData
+		}
+	}
+	return id, best, nil
+}
+
+// autoAlgorithms возвращает алгоритмы, которые пробует режим ArchiveAuto.
+// Арифметическое кодирование в него не входит: время его работы квадратично
+// по длине файла, а сжимает оно не лучше интервального кодера, устроенного
+// так же, но с целочисленной арифметикой.
+func autoAlgorithms() []Algorithm {
+	var auto []Algorithm
+	for _, algorithm := range algorithms {
+		if algorithm.Name != "arithmetic" {
+			auto = append(auto, algorithm)
+		}
+	}
+	return auto
+}
+
+func (a *ArchiveService) tryCompress(algorithm Algorithm, data []byte) ([]byte, bool) {
+	compressedData, err := algorithm.Service.Compress(data)
+	if err != nil {
+		return nil, false
+	}
+	decompressedData, err := algorithm.Service.Decompress(compressedData)
+	if err != nil || !bytes.Equal(decompressedData, data) {
+		return nil, false
+	}
+	return compressedData, true
+}
+
+// List читает оглавление архива, не разжимая файлы.
+func (a *ArchiveService) List(archive []byte) ([]ArchiveEntry, error) {
+	if !bytes.HasPrefix(archive, archiveMagic) || len(archive) < len(archiveMagic)+8 {
+		return nil, fmt.Errorf("%w: invalid archive", ErrCorrupt)
+	}
+	trailer := archive[len(archive)-8:]
+	directoryOffset := int(binary.LittleEndian.Uint32(trailer))
+	numEntries := int(binary.LittleEndian.Uint32(trailer[4:]))
+	if directoryOffset < len(archiveMagic) || directoryOffset > len(archive)-8 || numEntries > MaxArchiveEntries {
+		return nil, fmt.Errorf("%w: invalid archive directory", ErrCorrupt)
+	}
+
+	buf := bytes.NewBuffer(archive[directoryOffset : len(archive)-8])
+	entries := make([]ArchiveEntry, numEntries)
+	offset := len(archiveMagic)
+	for i := range entries {
+		var nameLen uint16
+		if err := binary.Read(buf, binary.LittleEndian, &nameLen); err != nil {
+			return nil, ErrTruncated
+		}
+		name := buf.Next(int(nameLen))
+		var record struct {
+			AlgorithmID    uint8
+			Size           uint32
+			CompressedSize uint32
+			CRC32          uint32
+		}
+		if len(name) != int(nameLen) {
+			return nil, ErrTruncated
+		}
+		if err := binary.Read(buf, binary.LittleEndian, &record); err != nil {
+			return nil, ErrTruncated
+		}
+
+		entry := ArchiveEntry{
+			Name:           string(name),
+			AlgorithmID:    record.AlgorithmID,
+			Size:           int(record.Size),
+			CompressedSize: int(record.CompressedSize),
+			CRC32:          record.CRC32,
+			Offset:         offset,
+		}
+		algorithmName, err := archiveAlgorithmName(entry.AlgorithmID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: file %q: %w", ErrCorrupt, entry.Name, err)
+		}
+		entry.Algorithm = algorithmName
+		entry.CompressionRatio = compressionRatio(entry)
+		offset += entry.CompressedSize
+		if offset > directoryOffset {
+			return nil, fmt.Errorf("%w: invalid archive directory", ErrCorrupt)
+		}
+		entries[i] = entry
+	}
+	if offset != directoryOffset || buf.Len() != 0 {
+		return nil, fmt.Errorf("%w: invalid archive directory", ErrCorrupt)
+	}
+	return entries, nil
+}
+
+// Extract разжимает один файл архива по имени и проверяет его размер и CRC.
+func (a *ArchiveService) Extract(archive []byte, name string) (ArchiveFile, error) {
+	entries, err := a.List(archive)
+	if err != nil {
+		return ArchiveFile{}, err
+	}
+	for _, entry := range entries {
+		if entry.Name == name {
+			return a.extract(archive, entry)
+		}
+	}
+	return ArchiveFile{}, fmt.Errorf("file %q not found in archive", name)
+}
+
+func (a *ArchiveService) ExtractAll(archive []byte) ([]ArchiveFile, error) {
+	entries, err := a.List(archive)
+	if err != nil {
+		return nil, err
+	}
+	size := 0
+	for _, entry := range entries {
+		size += entry.Size
+	}
+	if size > MaxDecompressedSize {
+		return nil, ErrTooLarge
+	}
+	files := make([]ArchiveFile, len(entries))
+	for i, entry := range entries {
+		files[i], err = a.extract(archive, entry)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+func (a *ArchiveService) extract(archive []byte, entry ArchiveEntry) (ArchiveFile, error) {
+	if entry.Size > MaxDecompressedSize {
+		return ArchiveFile{}, fmt.Errorf("file %q: %w", entry.Name, ErrTooLarge)
+	}
+	data := archive[entry.Offset : entry.Offset+entry.CompressedSize]
+	if entry.AlgorithmID != archiveStoreID {
+		algorithm, err := AlgorithmByID(entry.AlgorithmID)
+		if err != nil {
+			return ArchiveFile{}, err
+		}
+		data, err = algorithm.Service.Decompress(data)
+		if err != nil {
+			return ArchiveFile{}, fmt.Errorf("file %q: %w", entry.Name, err)
+		}
+	}
+
+	if len(data) != entry.Size {
+		return ArchiveFile{}, fmt.Errorf("%w: file %q: size = %d, want %d", ErrCorrupt, entry.Name, len(data), entry.Size)
+	}
+	if crc := crc32.ChecksumIEEE(data); crc != entry.CRC32 {
+		return ArchiveFile{}, fmt.Errorf("%w: file %q: crc32 = %08x, want %08x", ErrCorrupt, entry.Name, crc, entry.CRC32)
+	}
+	return ArchiveFile{Name: entry.Name, Data: data, Algorithm: entry.Algorithm}, nil
+}
+
+func archiveAlgorithmName(id uint8) (string, error) {
+	if id == archiveStoreID {
+		return ArchiveStore, nil
+	}
+	algorithm, err := AlgorithmByID(id)
+	if err != nil {
+		return "", err
+	}
+	return algorithm.Name, nil
+}
+
+func compressionRatio(entry ArchiveEntry) float32 {
+	return ratio(entry.CompressedSize, entry.Size)
+}
+
+// ratio — степень сжатия 1 - compressedSize/size; для пустых данных 0,
+// иначе получились бы NaN или -Inf, которые не кодируются в JSON.
+func ratio(compressedSize, size int) float32 {
+	if size == 0 {
+		return 0
+	}
+	return 1 - float32(compressedSize)/float32(size)
+}
@@ -0,0 +1,137 @@
+package compression
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestArchiveService(t *testing.T) {
+	random := make([]byte, 4096)
+	rand.New(rand.NewSource(1)).Read(random)
+
+	files := []ArchiveFile{
+		{Name: "текст.txt", Data: []byte(strings.Repeat("Сжатие_Хаффмана просто лучшее, я вам отвечаю. ", 20))},
+		{Name: "repeat.txt", Data: bytes.Repeat([]byte("a"), 1000)},
+		{Name: "random.bin", Data: random},
+		{Name: "empty", Data: nil},
+		{Name: "lzw.txt", Data: []byte("abracadabra abracadabra abracadabra"), Algorithm: "lzw"},
+		{Name: "store.txt", Data: []byte("stored"), Algorithm: ArchiveStore},
+	}
+
+	a := NewArchiveService()
+	archive, entries, err := a.Create(files)
+	if err != nil {
+		t.Fatalf("ArchiveService.Create() has err = %v", err)
+	}
+
+	listed, err := a.List(archive)
+	if err != nil {
+		t.Fatalf("ArchiveService.List() has err = %v", err)
+	}
+	if !reflect.DeepEqual(listed, entries) {
+		t.Errorf("ArchiveService.List() = %v, want %v", listed, entries)
+	}
+
+	wantAlgorithms := map[string]string{
+		"random.bin": ArchiveStore,
+		"empty":      ArchiveStore,
+		"lzw.txt":    "lzw",
+		"store.txt":  ArchiveStore,
+	}
+	for i, entry := range entries {
+		if entry.Name != files[i].Name || entry.Size != len(files[i].Data) {
+			t.Errorf("entry %d = %v", i, entry)
+		}
+		want, ok := wantAlgorithms[entry.Name]
+		if ok && entry.Algorithm != want {
+			t.Errorf("%s: algorithm = %s, want %s", entry.Name, entry.Algorithm, want)
+		}
+		if !ok && entry.CompressedSize >= entry.Size {
+			t.Errorf("%s: auto algorithm %s has not compressed the file", entry.Name, entry.Algorithm)
+		}
+	}
+
+	extracted, err := a.ExtractAll(archive)
+	if err != nil {
+		t.Fatalf("ArchiveService.ExtractAll() has err = %v", err)
+	}
+	for i, file := range extracted {
+		if file.Name != files[i].Name || !bytes.Equal(file.Data, files[i].Data) {
+			t.Errorf("ArchiveService.ExtractAll() file %d = %s", i, file.Name)
+		}
+	}
+
+	file, err := a.Extract(archive, "repeat.txt")
+	if err != nil {
+		t.Fatalf("ArchiveService.Extract() has err = %v", err)
+	}
+	if !bytes.Equal(file.Data, files[1].Data) {
+		t.Errorf("ArchiveService.Extract() = %s", file.Data)
+	}
+	if _, err := a.Extract(archive, "missing"); err == nil {
+		t.Errorf("ArchiveService.Extract() of missing file has no err")
+	}
+}
+
+func TestArchiveService_AutoAlgorithms(t *testing.T) {
+	auto := autoAlgorithms()
+	if len(auto) != len(Algorithms())-1 {
+		t.Errorf("autoAlgorithms() = %v", auto)
+	}
+	for _, algorithm := range auto {
+		if algorithm.Name == "arithmetic" {
+			t.Errorf("autoAlgorithms() contains arithmetic")
+		}
+	}
+
+	_, entries, err := NewArchiveService().Create([]ArchiveFile{
+		{Name: "arithmetic.txt", Data: []byte("арифметическое сжатие"), Algorithm: "arithmetic"},
+	})
+	if err != nil || entries[0].Algorithm != "arithmetic" {
+		t.Errorf("ArchiveService.Create() with arithmetic = %v, %v", entries, err)
+	}
+}
+
+func TestArchiveService_CreateInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []ArchiveFile
+	}{
+		{name: "no files", files: nil},
+		{name: "empty name", files: []ArchiveFile{{Name: "", Data: []byte("a")}}},
+		{name: "duplicate", files: []ArchiveFile{{Name: "a"}, {Name: "a"}}},
+		{name: "unknown algorithm", files: []ArchiveFile{{Name: "a", Algorithm: "zip"}}},
+		// LZW работает с символами UTF-8 и не восстанавливает такие данные.
+		{name: "lossy algorithm", files: []ArchiveFile{{Name: "a", Data: []byte{0xFF, 0xFE}, Algorithm: "lzw"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := NewArchiveService().Create(tt.files); err == nil {
+				t.Errorf("ArchiveService.Create() has no err")
+			}
+		})
+	}
+}
+
+func TestArchiveService_Corrupt(t *testing.T) {
+	a := NewArchiveService()
+	archive, entries, err := a.Create([]ArchiveFile{{Name: "a.txt", Data: []byte("hello"), Algorithm: ArchiveStore}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	corrupt := bytes.Clone(archive)
+	corrupt[entries[0].Offset] ^= 0xFF
+	if _, err := a.Extract(corrupt, "a.txt"); err == nil {
+		t.Errorf("ArchiveService.Extract() with wrong crc has no err")
+	}
+
+	for _, n := range []int{0, 4, 11, len(archive) - 1} {
+		if _, err := a.List(archive[:n]); err == nil {
+			t.Errorf("ArchiveService.List() of %d bytes has no err", n)
+		}
+	}
+}
@@ -3,9 +3,11 @@ package compression
 import (
 	"bytes"
 	"encoding/binary"
+	"fmt"
 	"maps"
 	"math"
 	"math/big"
+	"math/bits"
 	"slices"
 	"sort"
 	"unicode/utf8"
@@ -26,8 +28,19 @@ type ArithmeticDetails struct {
 	FrequencyTable   []FrequencyTableItem `json:"frequency_table"`
 	CompressionRatio float32              `json:"compression_ratio"`
 	Size             int                  `json:"size"`
+	Trace            *Trace               `json:"trace,omitempty"`
 }
 
+// ArithmeticNarrowEvent — сужение рабочего интервала после кодирования символа.
+type ArithmeticNarrowEvent struct {
+	Symbol string `json:"symbol"`
+	Left   string `json:"left"`
+	Right  string `json:"right"`
+}
+
+// arithmeticTraceDigits — число значащих цифр границ интервала в трассировке.
+const arithmeticTraceDigits = 30
+
 type FrequencyTableItem struct {
 	Val       string `json:"value"`
 	Frequency int    `json:"frequency"`
@@ -46,10 +59,26 @@ func NewArithmeticService() *ArithmeticService {
 	return &ArithmeticService{}
 }
 
-type FrequencyTable map[rune]uint16
+type FrequencyTable map[rune]uint32
+
+// arithmeticVersion — первый байт сжатых данных. Данные версии 0 не имеют
+// этого байта: они начинаются с длины сообщения, частоты в таблице
+// занимают uint16, а точность зависит только от длины. Такие данные
+// по-прежнему читаются; если длина начинается с того же байта, что и
+// версия, формат определяется тем, какой из разборов завершился успешно.
+const arithmeticVersion = 0x81
+
+// MaxArithmeticLength ограничивает число символов сообщения, а
+// MaxArithmeticWork — произведение числа символов на точность вычислений
+// в битах: на каждом символе декодер делит числа этой точности, а она
+// растёт вместе с длиной, поэтому время работы квадратично по длине.
+const (
+	MaxArithmeticLength = 1 << 18
+	MaxArithmeticWork   = 1 << 32
+)
 
 func (a *ArithmeticService) Compress(data []byte) ([]byte, error) {
-	arithmeticData, err := a.compressData(data)
+	arithmeticData, err := a.compressData(data, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -57,7 +86,16 @@ func (a *ArithmeticService) Compress(data []byte) ([]byte, error) {
 }
 
 func (a *ArithmeticService) CompressWithDetails(data []byte) (CompressionDetails, error) {
-	arithmeticData, err := a.compressData(data)
+	return a.compressWithDetails(data, nil)
+}
+
+// CompressWithTrace сжимает данные, записывая границы интервала после каждого символа.
+func (a *ArithmeticService) CompressWithTrace(data []byte, limit int) (CompressionDetails, error) {
+	return a.compressWithDetails(data, NewTrace(limit))
+}
+
+func (a *ArithmeticService) compressWithDetails(data []byte, trace *Trace) (CompressionDetails, error) {
+	arithmeticData, err := a.compressData(data, trace)
 	if err != nil {
 		return CompressionDetails{}, err
 	}
@@ -70,21 +108,30 @@ func (a *ArithmeticService) CompressWithDetails(data []byte) (CompressionDetails
 			FrequencyTable:   frequencyList,
 			CompressionRatio: 1 - float32(len(arithmeticData.data))/float32(len(data)),
 			Size:             len(arithmeticData.data),
+			Trace:            trace,
 		},
 	}
 
 	return arithmeticDetails, nil
 }
 
-func (a *ArithmeticService) compressData(data []byte) (ArithmeticData, error) {
+func (a *ArithmeticService) compressData(data []byte, trace *Trace) (ArithmeticData, error) {
+	if !utf8.Valid(data) {
+		return ArithmeticData{}, ErrInvalidUTF8
+	}
 	dataStr := string(data)
+	if utf8.RuneCountInString(dataStr) > MaxArithmeticLength {
+		return ArithmeticData{}, fmt.Errorf("%w: arithmetic coding is limited to %d symbols", ErrTooLarge, MaxArithmeticLength)
+	}
 	dataLength := uint32(utf8.RuneCountInString(dataStr))
 
-	var precision uint = calcPrecision(dataLength)
-
 	frequencyTable := a.frequencyTable(dataStr)
+	precision := calcPrecision(dataLength, frequencyTable)
+	if err := checkArithmeticWork(dataLength, precision); err != nil {
+		return ArithmeticData{}, err
+	}
 	probabilityIntervals := a.probabilityIntervals(precision, dataLength, frequencyTable)
-	n := a.compress(precision, dataStr, probabilityIntervals)
+	n := a.compress(precision, dataStr, probabilityIntervals, trace)
 
 	dataPayload, err := n.GobEncode()
 	if err != nil {
@@ -103,8 +150,29 @@ func (a *ArithmeticService) compressData(data []byte) (ArithmeticData, error) {
 	return arithmeticData, nil
 }
 
-func calcPrecision(length uint32) uint {
-	return uint(math.Round(float64(length)*math.Log2(10)) * 1.42)
+// calcPrecision возвращает число бит мантиссы, которого хватает, чтобы различить
+// итоговый интервал шириной 2^-I, где I — количество информации в сообщении.
+// Запас покрывает накопление ошибок округления за dataLength шагов.
+func calcPrecision(dataLength uint32, frequencyTable FrequencyTable) uint {
+	information := 0.0
+	for _, frequency := range frequencyTable {
+		information -= float64(frequency) * math.Log2(float64(frequency)/float64(dataLength))
+	}
+	return uint(math.Ceil(max(information, 0))) + 2*uint(bits.Len32(dataLength)) + 64
+}
+
+// calcPrecisionVersion0 — точность, с которой сжимались данные версии 0.
+func calcPrecisionVersion0(dataLength uint32) uint {
+	return uint(math.Round(float64(dataLength)*math.Log2(10)) * 1.42)
+}
+
+// checkArithmeticWork проверяет бюджет MaxArithmeticWork до того, как
+// начнутся вычисления с большой точностью.
+func checkArithmeticWork(dataLength uint32, precision uint) error {
+	if uint64(dataLength)*uint64(precision) > MaxArithmeticWork {
+		return fmt.Errorf("%w: arithmetic coding of %d symbols needs %d-bit precision", ErrTooLarge, dataLength, precision)
+	}
+	return nil
 }
 
 func (a *ArithmeticService) frequencyTable(dataStr string) FrequencyTable {
@@ -149,6 +217,7 @@ func (a *ArithmeticService) allCompressedData(dataLength uint32, frequencyTable
 	}
 
 	compressedData := new(bytes.Buffer)
+	compressedData.WriteByte(arithmeticVersion)
 	binary.Write(compressedData, binary.LittleEndian, dataLength)
 	binary.Write(compressedData, binary.LittleEndian, frequencyTableSize)
 	compressedData.Write(binaryFrequencyTable)
@@ -191,7 +260,7 @@ func (a *ArithmeticService) probabilityIntervals(precision uint, dataLength uint
 	return probabilityIntervals
 }
 
-func (a *ArithmeticService) compress(precision uint, dataStr string, probabilityIntervals map[rune]Interval) *big.Float {
+func (a *ArithmeticService) compress(precision uint, dataStr string, probabilityIntervals map[rune]Interval, trace *Trace) *big.Float {
 	var left, right *big.Float = big.NewFloat(0).SetPrec(precision), big.NewFloat(1).SetPrec(precision)
 
 	for _, r := range dataStr {
@@ -205,6 +274,14 @@ func (a *ArithmeticService) compress(precision uint, dataStr string, probability
 
 		right = newRight
 		left = newLeft
+
+		if trace != nil && !trace.Skip() {
+			trace.Add("narrow", ArithmeticNarrowEvent{
+				Symbol: string(r),
+				Left:   left.Text('g', arithmeticTraceDigits),
+				Right:  right.Text('g', arithmeticTraceDigits),
+			})
+		}
 	}
 
 	// return (left + right) / 2
@@ -212,7 +289,15 @@ func (a *ArithmeticService) compress(precision uint, dataStr string, probability
 }
 
 func (a *ArithmeticService) Decompress(compressedData []byte) ([]byte, error) {
-	arithmeticData, err := a.decompressData(compressedData)
+	arithmeticData, err := a.decompressData(compressedData, MaxDecompressedSize)
+	if err != nil {
+		return nil, err
+	}
+	return arithmeticData.data, nil
+}
+
+func (a *ArithmeticService) decompressLimit(compressedData []byte, limit int) ([]byte, error) {
+	arithmeticData, err := a.decompressData(compressedData, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -220,7 +305,7 @@ func (a *ArithmeticService) Decompress(compressedData []byte) ([]byte, error) {
 }
 
 func (a *ArithmeticService) DecompressWithDetails(compressedData []byte) (CompressionDetails, error) {
-	arithmeticData, err := a.decompressData(compressedData)
+	arithmeticData, err := a.decompressData(compressedData, MaxDecompressedSize)
 	if err != nil {
 		return CompressionDetails{}, err
 	}
@@ -238,35 +323,73 @@ func (a *ArithmeticService) DecompressWithDetails(compressedData []byte) (Compre
 	return arithmeticDetails, nil
 }
 
-func (a *ArithmeticService) decompressData(compressedData []byte) (ArithmeticData, error) {
+// decompressData разжимает сообщение, если в нём не больше limit байт.
+// Каждый символ занимает хотя бы байт, поэтому длина из заголовка
+// проверяется до декодирования.
+func (a *ArithmeticService) decompressData(compressedData []byte, limit int) (ArithmeticData, error) {
+	if len(compressedData) == 0 || compressedData[0] != arithmeticVersion {
+		return a.decodeData(compressedData, limit, 0)
+	}
+	arithmeticData, err := a.decodeData(compressedData[1:], limit, arithmeticVersion)
+	if err != nil {
+		if legacyData, legacyErr := a.decodeData(compressedData, limit, 0); legacyErr == nil {
+			return legacyData, nil
+		}
+	}
+	return arithmeticData, err
+}
+
+// decodeData разбирает данные без байта версии в формате version.
+func (a *ArithmeticService) decodeData(compressedData []byte, limit int, version byte) (ArithmeticData, error) {
 	buf := bytes.NewBuffer(compressedData)
 	var dataLength uint32
 	err := binary.Read(buf, binary.LittleEndian, &dataLength)
 	if err != nil {
-		return ArithmeticData{}, err
+		return ArithmeticData{}, truncated(err)
+	}
+	if dataLength > MaxArithmeticLength || int(dataLength) > limit {
+		return ArithmeticData{}, ErrTooLarge
 	}
-
-	var precision uint = calcPrecision(dataLength)
 
 	var frequencyTableSize uint16
 	err = binary.Read(buf, binary.LittleEndian, &frequencyTableSize)
 	if err != nil {
-		return ArithmeticData{}, err
+		return ArithmeticData{}, truncated(err)
 	}
 
-	frequencyTable, err := a.binaryToFrequencyTable(buf, frequencyTableSize)
+	frequencyTable, err := a.binaryToFrequencyTable(buf, frequencyTableSize, dataLength, version)
 	if err != nil {
 		return ArithmeticData{}, err
 	}
+	precision := calcPrecision(dataLength, frequencyTable)
+	if version == 0 {
+		precision = calcPrecisionVersion0(dataLength)
+	}
+	if err := checkArithmeticWork(dataLength, precision); err != nil {
+		return ArithmeticData{}, err
+	}
 	probabilityIntervals := a.probabilityIntervals(precision, dataLength, frequencyTable)
 
+	// Компрессор записывает число с точностью precision, и его мантисса
+	// занимает около precision бит; допускается несколько нулевых слов в конце.
+	payload := buf.Bytes()
+	if precision > uint(8*len(payload))+256 {
+		return ArithmeticData{}, ErrTruncated
+	}
 	decode := new(big.Float)
-	err = decode.GobDecode(buf.Bytes())
+	err = decode.GobDecode(payload)
 	if err != nil {
-		return ArithmeticData{}, err
+		return ArithmeticData{}, fmt.Errorf("%w: %w", ErrCorrupt, err)
+	}
+	// В версии 0 точность числа не меньше 64 бит и может не совпадать с precision.
+	if dataLength > 0 && ((version != 0 && decode.Prec() != precision) || decode.IsInf()) {
+		return ArithmeticData{}, fmt.Errorf("%w: invalid arithmetic code", ErrCorrupt)
 	}
 
-	data := a.decompress(decode, int(dataLength), probabilityIntervals)
+	data, err := a.decompress(decode, int(dataLength), probabilityIntervals)
+	if err != nil {
+		return ArithmeticData{}, err
+	}
 
 	arithmeticData := ArithmeticData{
 		data:           data,
@@ -275,24 +398,43 @@ func (a *ArithmeticService) decompressData(compressedData []byte) (ArithmeticDat
 	return arithmeticData, nil
 }
 
-func (a *ArithmeticService) binaryToFrequencyTable(buf *bytes.Buffer, size uint16) (FrequencyTable, error) {
+// binaryToFrequencyTable читает таблицу частот. Частоты должны быть
+// положительными и в сумме давать длину сообщения dataLength.
+func (a *ArithmeticService) binaryToFrequencyTable(buf *bytes.Buffer, size uint16, dataLength uint32, version byte) (FrequencyTable, error) {
 	frequencyTable := make(FrequencyTable)
+	var sum uint64
 	for range size {
-		r, _, err := buf.ReadRune()
+		r, n, err := buf.ReadRune()
 		if err != nil {
-			return nil, err
+			return nil, truncated(err)
 		}
-		var frequency uint16
-		if err := binary.Read(buf, binary.LittleEndian, &frequency); err != nil {
-			return nil, err
+		if r == utf8.RuneError && n == 1 {
+			return nil, fmt.Errorf("%w: invalid symbol in frequency table", ErrCorrupt)
+		}
+		var frequency uint32
+		if version == 0 {
+			var frequency16 uint16
+			if err := binary.Read(buf, binary.LittleEndian, &frequency16); err != nil {
+				return nil, truncated(err)
+			}
+			frequency = uint32(frequency16)
+		} else if err := binary.Read(buf, binary.LittleEndian, &frequency); err != nil {
+			return nil, truncated(err)
+		}
+		if _, exist := frequencyTable[r]; exist || frequency == 0 {
+			return nil, fmt.Errorf("%w: invalid frequency table", ErrCorrupt)
 		}
 
 		frequencyTable[r] = frequency
+		sum += uint64(frequency)
+	}
+	if sum != uint64(dataLength) {
+		return nil, fmt.Errorf("%w: frequencies sum to %d, want %d", ErrCorrupt, sum, dataLength)
 	}
 	return frequencyTable, nil
 }
 
-func (a *ArithmeticService) decompress(n *big.Float, dataLength int, probabilityIntervals map[rune]Interval) []byte {
+func (a *ArithmeticService) decompress(n *big.Float, dataLength int, probabilityIntervals map[rune]Interval) ([]byte, error) {
 	probabilityIntervalsSorted := slices.Collect(maps.Values(probabilityIntervals))
 	sort.Slice(probabilityIntervalsSorted, func(i, j int) bool {
 		return probabilityIntervalsSorted[i].left.Cmp(probabilityIntervalsSorted[j].left) < 0
@@ -301,16 +443,22 @@ func (a *ArithmeticService) decompress(n *big.Float, dataLength int, probability
 	buf := bytes.Buffer{}
 
 	for range dataLength {
-		interval := a.findInterval(probabilityIntervalsSorted, n)
+		interval, ok := a.findInterval(probabilityIntervalsSorted, n)
+		if !ok {
+			return nil, fmt.Errorf("%w: arithmetic code is out of [0, 1)", ErrCorrupt)
+		}
 		buf.WriteRune(interval.val)
 
 		n = new(big.Float).Sub(n, interval.left)
 		n = new(big.Float).Quo(n, new(big.Float).Sub(interval.right, interval.left))
 	}
-	return buf.Bytes()
+	return buf.Bytes(), nil
 }
 
-func (a *ArithmeticService) findInterval(probabilityIntervals []Interval, n *big.Float) Interval {
+// findInterval ищет интервал, содержащий n. Из-за округления n может
+// оказаться на правой границе последнего интервала; если же n вне [0, 1],
+// данные повреждены и возвращается false.
+func (a *ArithmeticService) findInterval(probabilityIntervals []Interval, n *big.Float) (Interval, bool) {
 	leftPointer := 0
 	rightPointer := len(probabilityIntervals) - 1
 	for leftPointer <= rightPointer {
@@ -319,12 +467,16 @@ func (a *ArithmeticService) findInterval(probabilityIntervals []Interval, n *big
 
 		moreThanLeft := n.Cmp(midInterval.left) >= 0
 		if moreThanLeft && n.Cmp(midInterval.right) < 0 {
-			return midInterval
+			return midInterval, true
 		} else if moreThanLeft {
 			leftPointer = midPointer + 1
 		} else {
 			rightPointer = midPointer - 1
 		}
 	}
-	return probabilityIntervals[leftPointer]
+	if leftPointer == len(probabilityIntervals) {
+		last := probabilityIntervals[leftPointer-1]
+		return last, n.Cmp(last.right) <= 0
+	}
+	return probabilityIntervals[leftPointer], leftPointer > 0
 }
@@ -1,8 +1,13 @@
 package compression
 
 import (
+	"bytes"
+	"encoding/binary"
+	"errors"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestArithmeticService_CompressAndDecompress(t *testing.T) {
@@ -169,3 +174,53 @@ II
 		})
 	}
 }
+
+// Заголовок наибольшего сообщения с 4096 равновероятными символами требует
+// точности в миллионы бит; он должен отклоняться до начала вычислений.
+func TestArithmeticService_DecompressMaxHeader(t *testing.T) {
+	buf := bytes.Buffer{}
+	buf.WriteByte(arithmeticVersion)
+	binary.Write(&buf, binary.LittleEndian, uint32(MaxArithmeticLength))
+	binary.Write(&buf, binary.LittleEndian, uint16(4096))
+	for i := range 4096 {
+		buf.WriteRune(rune(0x4e00 + i))
+		binary.Write(&buf, binary.LittleEndian, uint32(MaxArithmeticLength/4096))
+	}
+	buf.Write(make([]byte, MaxArithmeticLength*12/8))
+
+	start := time.Now()
+	_, err := NewArithmeticService().Decompress(buf.Bytes())
+	if !errors.Is(err, ErrTooLarge) {
+		t.Errorf("ArithmeticService.Decompress() has err = %v, want %v", err, ErrTooLarge)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("ArithmeticService.Decompress() took %v", elapsed)
+	}
+}
+
+func TestArithmeticService_DecompressVersion0(t *testing.T) {
+	// 129 символов: длина начинается с того же байта, что и версия.
+	longRun := append([]byte{0x81, 0, 0, 0, 1, 0, 'a', 0x81, 0, 0x1, 0xa, 0, 0, 0x2, 0x61, 0, 0, 0, 0, 0x80}, make([]byte, 79)...)
+	tests := []struct {
+		want           string
+		compressedData []byte
+	}{
+		{"abracadabra", []byte{0xb, 0x0, 0x0, 0x0, 0x5, 0x0, 0x61, 0x5, 0x0, 0x62, 0x2, 0x0, 0x72, 0x2, 0x0, 0x63, 0x1, 0x0, 0x64, 0x1, 0x0, 0x1, 0xa, 0x0, 0x0, 0x0, 0x40, 0xff, 0xff, 0xff, 0xff, 0x8e, 0xbd, 0x65, 0xc5, 0xc9, 0x37, 0xd6, 0x44}},
+		{"банан_бандана", []byte{0xd, 0x0, 0x0, 0x0, 0x5, 0x0, 0xd0, 0xb1, 0x2, 0x0, 0xd0, 0xb0, 0x5, 0x0, 0xd0, 0xbd, 0x4, 0x0, 0x5f, 0x1, 0x0, 0xd0, 0xb4, 0x1, 0x0, 0x1, 0xa, 0x0, 0x0, 0x0, 0x40, 0x0, 0x0, 0x0, 0x0, 0x85, 0x4a, 0xd9, 0x67, 0x5f, 0xd3, 0x7d, 0xc1}},
+		{strings.Repeat("a", 129), longRun},
+	}
+	for _, tt := range tests {
+		got, err := NewArithmeticService().Decompress(tt.compressedData)
+		if err != nil {
+			t.Fatalf("ArithmeticService.Decompress() has err = %v", err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("ArithmeticService.Decompress() = %q, want %q", got, tt.want)
+		}
+	}
+
+	compressedData, _ := NewArithmeticService().Compress([]byte("abracadabra"))
+	if compressedData[0] != arithmeticVersion {
+		t.Errorf("ArithmeticService.Compress() starts with %#x, want version %#x", compressedData[0], arithmeticVersion)
+	}
+}
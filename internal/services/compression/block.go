@@ -0,0 +1,368 @@
+package compression
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Блочный режим: вход делится на блоки, которые сжимаются независимо
+// пулом горутин. Результат записывается в контейнер с индексом:
+//
+//	"CLBK" | число блоков uint32 | индекс: (исходный размер uint32, сжатый размер uint32) * N | блоки
+//
+// Индекс позволяет разжимать блоки параллельно и обращаться к любому блоку отдельно.
+
+const (
+	DefaultBlockSize = 64 << 10
+	MaxBlockSize     = 64 << 20
+	MaxBlockWorkers  = 64
+)
+
+var blockMagic = []byte("CLBK")
+
+type BlockCompressor interface {
+	Compress(data []byte) ([]byte, error)
+	Decompress(compressedData []byte) ([]byte, error)
+}
+
+// streamDecompressor реализуют алгоритмы с потоковым декомпрессором: блок
+// читается через него не дальше размера, записанного в индексе.
+type streamDecompressor interface {
+	NewReader(r io.Reader) io.Reader
+}
+
+// limitedDecompressor реализуют алгоритмы, которые знают размер результата
+// из заголовка и отказываются разжимать больше limit байт.
+type limitedDecompressor interface {
+	decompressLimit(compressedData []byte, limit int) ([]byte, error)
+}
+
+type BlockService struct {
+	s         BlockCompressor
+	blockSize int
+	workers   int
+}
+
+type BlockDetails struct {
+	Blocks             []BlockInfo `json:"blocks"`
+	BlockSize          int         `json:"block_size"`
+	Workers            int         `json:"workers"`
+	CompressionRatio   float32     `json:"compression_ratio"`
+	Size               int         `json:"size"`
+	Duration           float64     `json:"duration_ms"`
+	SequentialDuration float64     `json:"sequential_duration_ms"`
+	Speedup            float64     `json:"speedup"`
+}
+
+type BlockInfo struct {
+	Index          int     `json:"index"`
+	Size           int     `json:"size"`
+	CompressedSize int     `json:"compressed_size"`
+	Offset         int     `json:"offset"`
+	Duration       float64 `json:"duration_ms"`
+}
+
+type blockIndexItem struct {
+	size           uint32
+	compressedSize uint32
+	offset         int
+}
+
+type blockResult struct {
+	data     []byte
+	duration time.Duration
+	err      error
+}
+
+type BlockData struct {
+	data     []byte
+	index    []blockIndexItem
+	results  []blockResult
+	duration time.Duration
+}
+
+func NewBlockService(s BlockCompressor, blockSize, workers int) (*BlockService, error) {
+	if blockSize <= 0 || blockSize > MaxBlockSize {
+		return nil, fmt.Errorf("block size must be between 1 and %d", MaxBlockSize)
+	}
+	if workers <= 0 || workers > MaxBlockWorkers {
+		return nil, fmt.Errorf("workers must be between 1 and %d", MaxBlockWorkers)
+	}
+	return &BlockService{s: s, blockSize: blockSize, workers: workers}, nil
+}
+
+func (b *BlockService) Compress(data []byte) ([]byte, error) {
+	blockData, err := b.compressData(data)
+	if err != nil {
+		return nil, err
+	}
+	return blockData.data, nil
+}
+
+func (b *BlockService) CompressWithDetails(data []byte) (CompressionDetails, error) {
+	blockData, err := b.compressData(data)
+	if err != nil {
+		return CompressionDetails{}, err
+	}
+
+	details := b.makeDetails(blockData)
+	details.CompressionRatio = ratio(len(blockData.data), len(data))
+	details.Size = len(blockData.data)
+
+	return CompressionDetails{Data: blockData.data, Details: details}, nil
+}
+
+func (b *BlockService) compressData(data []byte) (BlockData, error) {
+	blocks := b.split(data)
+
+	start := time.Now()
+	results := b.run(len(blocks), func(i int) ([]byte, error) {
+		return b.s.Compress(blocks[i])
+	})
+	duration := time.Since(start)
+
+	index := make([]blockIndexItem, len(blocks))
+	offset := 0
+	for i, result := range results {
+		if result.err != nil {
+			return BlockData{}, fmt.Errorf("block %d: %w", i, result.err)
+		}
+		index[i] = blockIndexItem{
+			size:           uint32(len(blocks[i])),
+			compressedSize: uint32(len(result.data)),
+			offset:         offset,
+		}
+		offset += len(result.data)
+	}
+
+	buf := new(bytes.Buffer)
+	buf.Write(blockMagic)
+	binary.Write(buf, binary.LittleEndian, uint32(len(blocks)))
+	for _, item := range index {
+		binary.Write(buf, binary.LittleEndian, item.size)
+		binary.Write(buf, binary.LittleEndian, item.compressedSize)
+	}
+	for _, result := range results {
+		buf.Write(result.data)
+	}
+
+	blockData := BlockData{
+		data:     buf.Bytes(),
+		index:    index,
+		results:  results,
+		duration: duration,
+	}
+	return blockData, nil
+}
+
+// split делит данные на блоки размера blockSize, сдвигая границы так,
+// чтобы не разрывать UTF-8 символы: посимвольные алгоритмы иначе их испортят.
+func (b *BlockService) split(data []byte) [][]byte {
+	blocks := make([][]byte, 0, len(data)/b.blockSize+1)
+	for len(data) > 0 {
+		n := runeBoundary(data, b.blockSize)
+		if n == 0 {
+			n = min(b.blockSize, len(data))
+		}
+		blocks = append(blocks, data[:n])
+		data = data[n:]
+	}
+	return blocks
+}
+
+// run выполняет fn для каждого блока в пуле из b.workers горутин.
+func (b *BlockService) run(numBlocks int, fn func(i int) ([]byte, error)) []blockResult {
+	results := make([]blockResult, numBlocks)
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for range min(b.workers, numBlocks) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				start := time.Now()
+				data, err := fn(i)
+				results[i] = blockResult{data: data, duration: time.Since(start), err: err}
+			}
+		}()
+	}
+
+	for i := range numBlocks {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+func (b *BlockService) Decompress(compressedData []byte) ([]byte, error) {
+	blockData, err := b.decompressData(compressedData)
+	if err != nil {
+		return nil, err
+	}
+	return blockData.data, nil
+}
+
+func (b *BlockService) DecompressWithDetails(compressedData []byte) (CompressionDetails, error) {
+	blockData, err := b.decompressData(compressedData)
+	if err != nil {
+		return CompressionDetails{}, err
+	}
+
+	details := b.makeDetails(blockData)
+	details.CompressionRatio = ratio(len(compressedData), len(blockData.data))
+	details.Size = len(blockData.data)
+
+	return CompressionDetails{Data: blockData.data, Details: details}, nil
+}
+
+func (b *BlockService) decompressData(compressedData []byte) (BlockData, error) {
+	index, payload, err := b.readIndex(compressedData)
+	if err != nil {
+		return BlockData{}, err
+	}
+
+	start := time.Now()
+	results := b.run(len(index), func(i int) ([]byte, error) {
+		return b.decompressBlock(index[i], payload)
+	})
+	duration := time.Since(start)
+
+	data := make([]byte, 0)
+	for i, result := range results {
+		if result.err != nil {
+			return BlockData{}, fmt.Errorf("block %d: %w", i, result.err)
+		}
+		data = append(data, result.data...)
+	}
+
+	blockData := BlockData{
+		data:     data,
+		index:    index,
+		results:  results,
+		duration: duration,
+	}
+	return blockData, nil
+}
+
+// DecompressBlock разжимает только блок с номером i, используя индекс контейнера.
+func (b *BlockService) DecompressBlock(compressedData []byte, i int) ([]byte, error) {
+	index, payload, err := b.readIndex(compressedData)
+	if err != nil {
+		return nil, err
+	}
+	if i < 0 || i >= len(index) {
+		return nil, fmt.Errorf("block %d out of range [0, %d)", i, len(index))
+	}
+	return b.decompressBlock(index[i], payload)
+}
+
+// decompressBlock разжимает блок, не давая ему развернуться больше размера
+// из индекса: иначе маленький контейнер мог бы заставить каждую горутину
+// разжимать по MaxDecompressedSize байт.
+func (b *BlockService) decompressBlock(item blockIndexItem, payload []byte) ([]byte, error) {
+	block := payload[item.offset : item.offset+int(item.compressedSize)]
+
+	var data []byte
+	var err error
+	switch s := b.s.(type) {
+	case streamDecompressor:
+		data, err = io.ReadAll(NewLimitReader(s.NewReader(bytes.NewReader(block)), int64(item.size)))
+	case limitedDecompressor:
+		data, err = s.decompressLimit(block, int(item.size))
+	default:
+		data, err = b.s.Decompress(block)
+	}
+	if errors.Is(err, ErrTooLarge) {
+		return nil, fmt.Errorf("%w: block is larger than %d bytes", ErrCorrupt, item.size)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != int(item.size) {
+		return nil, fmt.Errorf("%w: block size = %d, want %d", ErrCorrupt, len(data), item.size)
+	}
+	return data, nil
+}
+
+func (b *BlockService) readIndex(compressedData []byte) ([]blockIndexItem, []byte, error) {
+	buf := bytes.NewBuffer(compressedData)
+	if !bytes.HasPrefix(compressedData, blockMagic) {
+		return nil, nil, fmt.Errorf("%w: invalid block container", ErrCorrupt)
+	}
+	buf.Next(len(blockMagic))
+
+	var numBlocks uint32
+	if err := binary.Read(buf, binary.LittleEndian, &numBlocks); err != nil {
+		return nil, nil, truncated(err)
+	}
+	if int(numBlocks) > buf.Len()/8 {
+		return nil, nil, ErrTruncated
+	}
+
+	index := make([]blockIndexItem, numBlocks)
+	offset, size := 0, 0
+	for i := range index {
+		var item blockIndexItem
+		binary.Read(buf, binary.LittleEndian, &item.size)
+		binary.Read(buf, binary.LittleEndian, &item.compressedSize)
+		item.offset = offset
+		offset += int(item.compressedSize)
+		size += int(item.size)
+		index[i] = item
+	}
+	if size > MaxDecompressedSize {
+		return nil, nil, ErrTooLarge
+	}
+
+	payload := buf.Bytes()
+	if offset > len(payload) {
+		return nil, nil, ErrTruncated
+	}
+	if offset != len(payload) {
+		return nil, nil, fmt.Errorf("%w: invalid block index", ErrCorrupt)
+	}
+	return index, payload, nil
+}
+
+func (b *BlockService) makeDetails(blockData BlockData) BlockDetails {
+	blocks := make([]BlockInfo, len(blockData.index))
+	var sequential time.Duration
+	for i, item := range blockData.index {
+		blocks[i] = BlockInfo{
+			Index:          i,
+			Size:           int(item.size),
+			CompressedSize: int(item.compressedSize),
+			Offset:         item.offset,
+			Duration:       milliseconds(blockData.results[i].duration),
+		}
+		sequential += blockData.results[i].duration
+	}
+
+	// Ускорение оценивается как отношение суммарного времени обработки блоков
+	// (времени последовательного выполнения) к фактическому времени работы пула.
+	speedup := 1.0
+	if blockData.duration > 0 {
+		speedup = float64(sequential) / float64(blockData.duration)
+	}
+
+	return BlockDetails{
+		Blocks:             blocks,
+		BlockSize:          b.blockSize,
+		Workers:            b.workers,
+		Duration:           milliseconds(blockData.duration),
+		SequentialDuration: milliseconds(sequential),
+		Speedup:            speedup,
+	}
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
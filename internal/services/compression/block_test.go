@@ -0,0 +1,195 @@
+package compression
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestBlockService_CompressAndDecompress(t *testing.T) {
+	services := []struct {
+		name    string
+		service BlockCompressor
+	}{
+		{name: "rle", service: NewRLEService()},
+		{name: "huffman", service: NewHuffmanService()},
+		{name: "arithmetic", service: NewArithmeticService()},
+		{name: "lzw", service: NewLZWService()},
+		{name: "range", service: NewRangeCoderService()},
+	}
+	data := []byte(strings.Repeat("Сжатие_Хаффмана просто лучшее, я вам отвечаю. слово даю!! ", 20))
+
+	for _, s := range services {
+		t.Run(s.name, func(t *testing.T) {
+			b, err := NewBlockService(s.service, 101, 4)
+			if err != nil {
+				t.Fatalf("NewBlockService() has err = %v", err)
+			}
+			compressedData, err := b.Compress(data)
+			if err != nil {
+				t.Fatalf("BlockService.Compress() has err = %v", err)
+			}
+			decompressedData, err := b.Decompress(compressedData)
+			if err != nil {
+				t.Fatalf("BlockService.Decompress() has err = %v", err)
+			}
+			if !bytes.Equal(decompressedData, data) {
+				t.Errorf("BlockService.Decompress() = %s, want %s", decompressedData, data)
+			}
+		})
+	}
+}
+
+func TestBlockService_DecompressBlock(t *testing.T) {
+	data := []byte(strings.Repeat("banana_bandana ", 100))
+	b, err := NewBlockService(NewLZWService(), 256, 3)
+	if err != nil {
+		t.Fatalf("NewBlockService() has err = %v", err)
+	}
+
+	details, err := b.CompressWithDetails(data)
+	if err != nil {
+		t.Fatalf("BlockService.CompressWithDetails() has err = %v", err)
+	}
+	blocks := details.Details.(BlockDetails).Blocks
+	if len(blocks) != 6 {
+		t.Fatalf("len(blocks) = %v, want %v", len(blocks), 6)
+	}
+
+	offset := 0
+	for _, block := range blocks {
+		got, err := b.DecompressBlock(details.Data, block.Index)
+		if err != nil {
+			t.Fatalf("BlockService.DecompressBlock(%d) has err = %v", block.Index, err)
+		}
+		want := data[offset : offset+block.Size]
+		if !bytes.Equal(got, want) {
+			t.Errorf("BlockService.DecompressBlock(%d) = %s, want %s", block.Index, got, want)
+		}
+		offset += block.Size
+	}
+
+	if _, err := b.DecompressBlock(details.Data, len(blocks)); err == nil {
+		t.Errorf("BlockService.DecompressBlock() out of range has no err")
+	}
+}
+
+func TestBlockService_DecompressInvalid(t *testing.T) {
+	b, _ := NewBlockService(NewRLEService(), DefaultBlockSize, 2)
+	compressedData, err := b.Compress([]byte("WWWWBBBWBB"))
+	if err != nil {
+		t.Fatalf("BlockService.Compress() has err = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "empty", data: []byte{}},
+		{name: "magic", data: []byte("XXXX")},
+		{name: "truncated", data: compressedData[:len(compressedData)-1]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := b.Decompress(tt.data); err == nil {
+				t.Errorf("BlockService.Decompress() has no err")
+			}
+		})
+	}
+}
+
+func TestBlockService_Empty(t *testing.T) {
+	b, _ := NewBlockService(NewRLEService(), DefaultBlockSize, 2)
+	compressed, err := b.CompressWithDetails(nil)
+	if err != nil {
+		t.Fatalf("BlockService.CompressWithDetails() has err = %v", err)
+	}
+	decompressed, err := b.DecompressWithDetails(compressed.Data)
+	if err != nil {
+		t.Fatalf("BlockService.DecompressWithDetails() has err = %v", err)
+	}
+	for _, details := range []any{compressed.Details, decompressed.Details} {
+		if _, err := json.Marshal(details); err != nil {
+			t.Errorf("json.Marshal(%T) has err = %v", details, err)
+		}
+	}
+}
+
+// blockContainer собирает контейнер из одного блока с заданным размером в индексе.
+func blockContainer(size uint32, block []byte) []byte {
+	buf := new(bytes.Buffer)
+	buf.Write(blockMagic)
+	binary.Write(buf, binary.LittleEndian, uint32(1))
+	binary.Write(buf, binary.LittleEndian, size)
+	binary.Write(buf, binary.LittleEndian, uint32(len(block)))
+	buf.Write(block)
+	return buf.Bytes()
+}
+
+// endlessService разжимает любой блок в бесконечный поток нулей.
+type endlessService struct{}
+
+func (endlessService) Compress(data []byte) ([]byte, error) { return data, nil }
+
+func (endlessService) Decompress(compressedData []byte) ([]byte, error) {
+	return nil, errors.New("unbounded decompression")
+}
+
+func (endlessService) NewReader(r io.Reader) io.Reader { return endlessReader{} }
+
+type endlessReader struct{}
+
+func (endlessReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+func TestBlockService_DecompressLargerBlock(t *testing.T) {
+	services := []struct {
+		name    string
+		service BlockCompressor
+	}{
+		{name: "rle", service: NewRLEService()},
+		{name: "huffman", service: NewHuffmanService()},
+		{name: "shannon_fano", service: NewShannonFanoService()},
+		{name: "arithmetic", service: NewArithmeticService()},
+		{name: "lzw", service: NewLZWService()},
+		{name: "range", service: NewRangeCoderService()},
+		{name: "lz77", service: NewLZ77Service()},
+		{name: "endless", service: endlessService{}},
+	}
+	data := []byte(strings.Repeat("a", 10000))
+
+	for _, s := range services {
+		t.Run(s.name, func(t *testing.T) {
+			block, err := s.service.Compress(data)
+			if err != nil {
+				t.Fatalf("Compress() has err = %v", err)
+			}
+			b, _ := NewBlockService(s.service, DefaultBlockSize, 2)
+			_, err = b.Decompress(blockContainer(16, block))
+			if !errors.Is(err, ErrCorrupt) {
+				t.Errorf("BlockService.Decompress() err = %v, want %v", err, ErrCorrupt)
+			}
+		})
+	}
+}
+
+func TestBlockService_DecompressTooLarge(t *testing.T) {
+	buf := new(bytes.Buffer)
+	buf.Write(blockMagic)
+	binary.Write(buf, binary.LittleEndian, uint32(2))
+	for range 2 {
+		binary.Write(buf, binary.LittleEndian, uint32(MaxDecompressedSize/2+1))
+		binary.Write(buf, binary.LittleEndian, uint32(0))
+	}
+
+	b, _ := NewBlockService(NewRLEService(), DefaultBlockSize, 2)
+	if _, err := b.Decompress(buf.Bytes()); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("BlockService.Decompress() err = %v, want %v", err, ErrTooLarge)
+	}
+}
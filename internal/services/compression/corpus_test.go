@@ -0,0 +1,148 @@
+package compression
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// Корпус testdata/corpus по образцу Canterbury/Calgary:
+//
+//	tolstoy.txt    — русский текст
+//	huffman.go.txt — исходный код
+//	sine.wav       — двоичные данные (WAV PCM16)
+//	random.bin     — случайные байты
+//	alphabet.txt   — повторяющийся алфавит
+//	aaa.txt        — один символ 32768 раз
+//	a.txt          — один байт
+//	empty.txt      — пустой файл
+//
+// TestCorpus сжимает каждый файл каждым алгоритмом из реестра, проверяет
+// восстановление и сравнивает размеры с отчётом testdata/ratios.golden
+// (ratio, как и CompressionRatio, равен 1 - compressed/size).
+// После намеренного изменения алгоритма отчёт обновляется командой
+//
+//	go test ./internal/services/compression/ -run TestCorpus -update
+
+var update = flag.Bool("update", false, "update testdata/ratios.golden")
+
+var goldenReport = filepath.Join("testdata", "ratios.golden")
+
+func TestCorpus(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "corpus", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("corpus is empty")
+	}
+
+	var report strings.Builder
+	fmt.Fprintf(&report, "%-14s %-16s %8s %10s %9s\n", "algorithm", "file", "size", "compressed", "ratio")
+	for _, algorithm := range Algorithms() {
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			name := filepath.Base(file)
+			line := corpusReportLine(t, algorithm, name, data)
+			fmt.Fprintf(&report, "%-14s %-16s %8d %s\n", algorithm.Name, name, len(data), line)
+		}
+	}
+
+	if *update {
+		if err := os.WriteFile(goldenReport, []byte(report.String()), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(goldenReport)
+	if err != nil {
+		t.Fatalf("%v; run the test with -update to create the report", err)
+	}
+	gotLines := strings.Split(report.String(), "\n")
+	wantLines := strings.Split(string(want), "\n")
+	for i := range max(len(gotLines), len(wantLines)) {
+		var got, want string
+		if i < len(gotLines) {
+			got = gotLines[i]
+		}
+		if i < len(wantLines) {
+			want = wantLines[i]
+		}
+		if got != want {
+			t.Errorf("%s line %d:\n got: %s\nwant: %s", goldenReport, i+1, got, want)
+		}
+	}
+}
+
+// corpusReportLine сжимает и разжимает файл и возвращает размер и степень
+// сжатия для отчёта. Алгоритмы, кодирующие символы Unicode, должны
+// отказываться от данных не в UTF-8 или от символов вне своего алфавита,
+// а не портить их; в отчёте такие файлы, как и слишком большие для
+// алгоритма, отмечаются прочерком.
+func corpusReportLine(t *testing.T, algorithm Algorithm, name string, data []byte) string {
+	var line string
+	t.Run(algorithm.Name+"/"+name, func(t *testing.T) {
+		compressedData, err := algorithm.Service.Compress(data)
+		if errors.Is(err, ErrInvalidUTF8) && !utf8.Valid(data) || errors.Is(err, ErrUnsupportedSymbol) || errors.Is(err, ErrTooLarge) {
+			line = fmt.Sprintf("%10s %9s", "-", "-")
+			return
+		}
+		if err != nil {
+			t.Fatalf("Compress() has err = %v", err)
+		}
+		ratio := 0.0
+		if len(data) > 0 {
+			ratio = 1 - float64(len(compressedData))/float64(len(data))
+		}
+		line = fmt.Sprintf("%10d %9.4f", len(compressedData), ratio)
+
+		decompressedData, err := algorithm.Service.Decompress(compressedData)
+		if err != nil {
+			t.Fatalf("Decompress() has err = %v", err)
+		}
+		if !bytes.Equal(decompressedData, data) {
+			t.Errorf("Decompress() differs from data")
+		}
+	})
+	return line
+}
+
+// BenchmarkCorpus измеряет скорость сжатия и восстановления tolstoy.txt
+// каждым алгоритмом реестра.
+func BenchmarkCorpus(b *testing.B) {
+	data, err := os.ReadFile(filepath.Join("testdata", "corpus", "tolstoy.txt"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, algorithm := range Algorithms() {
+		compressedData, err := algorithm.Service.Compress(data)
+		if errors.Is(err, ErrTooLarge) {
+			continue
+		}
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(algorithm.Name+"/compress", func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				algorithm.Service.Compress(data)
+			}
+		})
+		b.Run(algorithm.Name+"/decompress", func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				algorithm.Service.Decompress(compressedData)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package compression
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrInvalidUTF8 возвращают алгоритмы, которые кодируют символы Unicode,
+// а не байты: данные не в UTF-8 они не смогут восстановить.
+var ErrInvalidUTF8 = errors.New("data is not valid UTF-8")
+
+// ErrUnsupportedSymbol возвращают алгоритмы с фиксированным начальным
+// алфавитом, если в данных есть символ вне его.
+var ErrUnsupportedSymbol = errors.New("symbol is not in the alphabet")
+
+// ErrCorrupt возвращают декомпрессоры, если сжатые данные не могли быть
+// получены соответствующим компрессором.
+var ErrCorrupt = errors.New("compressed data is corrupt")
+
+// ErrTruncated возвращают декомпрессоры, если сжатые данные обрываются
+// раньше, чем закончится закодированное сообщение.
+var ErrTruncated = errors.New("compressed data is truncated")
+
+// ErrTooLarge возвращается, если разжатые данные превышают MaxDecompressedSize.
+var ErrTooLarge = errors.New("decompressed data is too large")
+
+// MaxDecompressedSize ограничивает размер результата Decompress и защищает
+// от «бомб» — небольших входов, которые разворачиваются в гигабайты.
+const MaxDecompressedSize = 64 << 20
+
+// NewLimitReader возвращает Reader, который читает из r не больше n байт
+// и возвращает ErrTooLarge, если данных больше.
+func NewLimitReader(r io.Reader, n int64) io.Reader {
+	return &limitReader{r: r, n: n}
+}
+
+type limitReader struct {
+	r io.Reader
+	n int64
+}
+
+func (lr *limitReader) Read(p []byte) (int, error) {
+	if lr.n <= 0 {
+		// Данных ровно n байт, если следующее чтение сразу заканчивается EOF.
+		var b [1]byte
+		n, err := lr.r.Read(b[:])
+		if n > 0 {
+			return 0, ErrTooLarge
+		}
+		return 0, err
+	}
+	if int64(len(p)) > lr.n {
+		p = p[:lr.n]
+	}
+	n, err := lr.r.Read(p)
+	lr.n -= int64(n)
+	return n, err
+}
+
+// readAll читает весь вывод декомпрессора, соблюдая MaxDecompressedSize.
+func readAll(r io.Reader) ([]byte, error) {
+	return io.ReadAll(NewLimitReader(r, MaxDecompressedSize))
+}
+
+// truncated заменяет io.ErrUnexpectedEOF и io.EOF, полученные посреди
+// сообщения, на ErrTruncated.
+func truncated(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return ErrTruncated
+	}
+	return err
+}
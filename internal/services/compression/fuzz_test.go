@@ -0,0 +1,190 @@
+package compression
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// Фаззинг декомпрессоров: на любом входе они должны либо вернуть данные
+// не больше MaxDecompressedSize, либо одну из типизированных ошибок,
+// но не паниковать и не зависать. Запуск:
+//
+//	go test ./internal/services/compression/ -run '^$' -fuzz FuzzHuffmanDecompress
+
+var fuzzSeeds = [][]byte{
+	nil,
+	[]byte("a"),
+	[]byte("abracadabra"),
+	[]byte("Съешь же ещё этих мягких французских булок, да выпей чаю"),
+	bytes.Repeat([]byte("ab"), 300),
+}
+
+func addFuzzSeeds(f *testing.F, s Service) {
+	for _, data := range fuzzSeeds {
+		compressedData, err := s.Compress(data)
+		if err != nil {
+			f.Fatalf("%T.Compress(%q) has err = %v", s, data, err)
+		}
+		f.Add(compressedData)
+		if len(compressedData) > 1 {
+			f.Add(compressedData[:len(compressedData)/2])
+			corrupted := bytes.Clone(compressedData)
+			corrupted[len(corrupted)/2] ^= 0x5A
+			f.Add(corrupted)
+		}
+	}
+}
+
+func checkDecompressError(t *testing.T, err error) {
+	t.Helper()
+	if !errors.Is(err, ErrCorrupt) && !errors.Is(err, ErrTruncated) && !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("Decompress() has untyped err = %v", err)
+	}
+}
+
+func fuzzDecompress(f *testing.F, s Service) {
+	addFuzzSeeds(f, s)
+	f.Fuzz(func(t *testing.T, compressedData []byte) {
+		data, err := s.Decompress(compressedData)
+		if err != nil {
+			checkDecompressError(t, err)
+			return
+		}
+		if len(data) > MaxDecompressedSize {
+			t.Fatalf("Decompress() = %d bytes, limit is %d", len(data), MaxDecompressedSize)
+		}
+
+		details, err := s.DecompressWithDetails(compressedData)
+		if err != nil {
+			t.Fatalf("DecompressWithDetails() has err = %v, Decompress() has none", err)
+		}
+		if !bytes.Equal(details.Data, data) {
+			t.Fatalf("DecompressWithDetails() differs from Decompress()")
+		}
+	})
+}
+
+func FuzzRLEDecompress(f *testing.F) {
+	fuzzDecompress(f, NewRLEService())
+}
+
+func FuzzHuffmanDecompress(f *testing.F) {
+	fuzzDecompress(f, NewHuffmanService())
+}
+
+func FuzzShannonFanoDecompress(f *testing.F) {
+	fuzzDecompress(f, NewShannonFanoService())
+}
+
+func FuzzArithmeticDecompress(f *testing.F) {
+	fuzzDecompress(f, NewArithmeticService())
+}
+
+func FuzzLZWDecompress(f *testing.F) {
+	fuzzDecompress(f, NewLZWService())
+}
+
+func FuzzRangeCoderDecompress(f *testing.F) {
+	fuzzDecompress(f, NewRangeCoderService())
+}
+
+func FuzzLZ77Decompress(f *testing.F) {
+	fuzzDecompress(f, NewLZ77Service())
+}
+
+func FuzzBlockDecompress(f *testing.F) {
+	s, err := NewBlockService(NewHuffmanService(), 16, 2)
+	if err != nil {
+		f.Fatal(err)
+	}
+	fuzzDecompress(f, s)
+}
+
+func FuzzArchiveExtract(f *testing.F) {
+	a := NewArchiveService()
+	archive, _, err := a.Create([]ArchiveFile{
+		{Name: "a.txt", Data: fuzzSeeds[2]},
+		{Name: "b.txt", Data: fuzzSeeds[3], Algorithm: "lz77"},
+		{Name: "c.bin", Data: fuzzSeeds[4], Algorithm: ArchiveStore},
+	})
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(archive)
+	f.Add(archive[:len(archive)/2])
+
+	f.Fuzz(func(t *testing.T, archive []byte) {
+		files, err := a.ExtractAll(archive)
+		if err != nil {
+			checkDecompressError(t, err)
+			return
+		}
+		for _, file := range files {
+			if _, err := a.Extract(archive, file.Name); err != nil {
+				t.Fatalf("ArchiveService.Extract(%q) has err = %v", file.Name, err)
+			}
+		}
+	})
+}
+
+// FuzzRoundTrip проверяет, что каждый алгоритм восстанавливает то, что сжал.
+func FuzzRoundTrip(f *testing.F) {
+	for _, data := range fuzzSeeds {
+		f.Add(data)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, algorithm := range Algorithms() {
+			compressedData, err := algorithm.Service.Compress(data)
+			if errors.Is(err, ErrInvalidUTF8) || errors.Is(err, ErrUnsupportedSymbol) {
+				continue
+			}
+			if err != nil {
+				t.Fatalf("%s: Compress() has err = %v", algorithm.Name, err)
+			}
+			decompressedData, err := algorithm.Service.Decompress(compressedData)
+			if err != nil {
+				t.Fatalf("%s: Decompress() has err = %v", algorithm.Name, err)
+			}
+			if !bytes.Equal(decompressedData, data) {
+				t.Fatalf("%s: Decompress() = %q, want %q", algorithm.Name, decompressedData, data)
+			}
+		}
+	})
+}
+
+func TestDecompressErrors(t *testing.T) {
+	tests := []struct {
+		name           string
+		service        Service
+		compressedData []byte
+		want           error
+	}{
+		{"rle without counter", NewRLEService(), []byte("W"), ErrCorrupt},
+		{"rle zero counter", NewRLEService(), []byte("0W"), ErrCorrupt},
+		{"rle truncated counter", NewRLEService(), []byte("3W12"), ErrTruncated},
+		{"rle bomb", NewRLEService(), []byte("999999999999W"), ErrTooLarge},
+		{"huffman truncated tree", NewHuffmanService(), []byte{huffmanVersion, 4, 0, 0, 0, 0x00, 0x00, 0x00, 0x00}, ErrTruncated},
+		{"huffman truncated block", NewHuffmanService(), []byte{huffmanVersion, 9, 0, 0, 0, 1}, ErrTruncated},
+		{"huffman truncated length", NewHuffmanService(), []byte{huffmanVersion, 9}, ErrTruncated},
+		{"huffman version", NewHuffmanService(), []byte{0x82, 1, 0, 0, 0, 0}, ErrCorrupt},
+		{"lzw unknown code", NewLZWService(), []byte{0xFF, 0xFF}, ErrCorrupt},
+		{"lzw truncated", NewLZWService(), []byte{0x30}, ErrTruncated},
+		{"arithmetic frequencies", NewArithmeticService(), []byte{arithmeticVersion, 2, 0, 0, 0, 1, 0, 'a', 1, 0, 0, 0}, ErrCorrupt},
+		{"arithmetic length", NewArithmeticService(), []byte{arithmeticVersion, 0xFF, 0xFF, 0xFF, 0xFF, 0, 0}, ErrTooLarge},
+		{"arithmetic version 0 frequencies", NewArithmeticService(), []byte{2, 0, 0, 0, 1, 0, 'a', 1, 0}, ErrCorrupt},
+		{"arithmetic version 0 length", NewArithmeticService(), []byte{0xFF, 0xFF, 0xFF, 0xFF, 0, 0}, ErrTooLarge},
+		{"range truncated", NewRangeCoderService(), []byte{0, 0}, ErrTruncated},
+		{"lz77 size", NewLZ77Service(), []byte{0xFF, 0xFF, 0xFF, 0xFF, 0x0F}, ErrTooLarge},
+		{"lz77 distance", NewLZ77Service(), []byte{4, 0xF0, 0x00, 0x00}, ErrCorrupt},
+		{"lz77 size bound", NewLZ77Service(), []byte{0x83, 0x02, 0xFF}, ErrCorrupt},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.service.Decompress(tt.compressedData)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Decompress() has err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -3,11 +3,39 @@ package compression
 import (
 	"bytes"
 	"container/heap"
+	"encoding/binary"
 	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"unicode/utf8"
 
 	"github.com/PritOriginal/cryptolabs-back/pkg/bitsio"
 )
 
+// huffmanBlockSize — размер блока, на которые делится вход при потоковом сжатии.
+// Каждый блок кодируется независимо со своим деревом.
+const huffmanBlockSize = 1 << 20
+
+// MaxHuffmanSymbolSize — наибольшее число исходных символов в одном символе
+// расширенного источника. Размер хранится в старших битах первого байта блока,
+// поэтому данные, сжатые посимвольно, читаются без изменений.
+const MaxHuffmanSymbolSize = 8
+
+const (
+	huffmanSkipBitsMask    = 0x07
+	huffmanSymbolSizeShift = 3
+)
+
+// huffmanVersion — версия формата, которой начинается каждый блок, перед
+// его длиной. Данные версии 0 — один блок без этого заголовка — начинаются
+// с числа бит выравнивания от 0 до 7, поэтому у номеров версий установлен
+// старший бит и старые данные отличаются от новых по первому байту.
+const (
+	huffmanVersionFlag = 0x80
+	huffmanVersion     = huffmanVersionFlag | 1
+)
+
 // type Huffman interface {
 // 	Compress(data []byte) ([]byte, error)
 // 	CompressWithDetails(data []byte) CompressionDetails
@@ -15,23 +43,73 @@ import (
 // }
 
 type HuffmanService struct {
+	symbolSize int
 }
 
 func NewHuffmanService() *HuffmanService {
 	return &HuffmanService{}
 }
 
+// WithSymbolSize возвращает сервис, кодирующий блоки по k символов
+// как отдельные символы (k-е расширение источника).
+func (h *HuffmanService) WithSymbolSize(k int) (Service, error) {
+	if k < 1 || k > MaxHuffmanSymbolSize {
+		return nil, fmt.Errorf("symbol size must be between 1 and %d", MaxHuffmanSymbolSize)
+	}
+	return &HuffmanService{symbolSize: k}, nil
+}
+
+// size возвращает число рун в символе источника. Нулевое значение
+// сервиса кодирует данные посимвольно.
+func (h *HuffmanService) size() int {
+	if h.symbolSize == 0 {
+		return 1
+	}
+	return h.symbolSize
+}
+
 type HuffmanData struct {
 	data           []byte
-	frequencyTable map[rune]int
+	symbolSize     int
+	frequencyTable map[string]int
 	rootNode       *Node
-	huffmanCode    map[rune]string
+	huffmanCode    map[string]string
 }
 
 type HuffmanDetails struct {
-	Codes            []HuffmanCode `json:"codes"`
-	CompressionRatio float32       `json:"compression_ratio"`
-	Size             int           `json:"size"`
+	Codes            []HuffmanCode      `json:"codes"`
+	CompressionRatio float32            `json:"compression_ratio"`
+	Size             int                `json:"size"`
+	SymbolSize       int                `json:"symbol_size"`
+	Entropy          float64            `json:"entropy"`
+	Extensions       []HuffmanExtension `json:"extensions"`
+	Trace            *Trace             `json:"trace,omitempty"`
+
+	rootNode *Node
+}
+
+// HuffmanExtension — код Хаффмана для k-го расширения источника. Длины
+// приведены в битах на исходный символ, чтобы их можно было сравнить с H(X).
+type HuffmanExtension struct {
+	SymbolSize    int     `json:"symbol_size"`
+	Symbols       int     `json:"symbols"`
+	AvgCodeLength float64 `json:"avg_code_length"`
+	BlockEntropy  float64 `json:"block_entropy"`
+	Redundancy    float64 `json:"redundancy"`
+}
+
+func (d HuffmanDetails) Tree() *TreeNode {
+	return nodeToTree(d.rootNode, "", "")
+}
+
+// HuffmanMergeEvent — шаг построения дерева: два узла с наименьшими
+// весами объединяются в новый узел.
+type HuffmanMergeEvent struct {
+	Left        string `json:"left"`
+	LeftWeight  int    `json:"left_weight"`
+	Right       string `json:"right"`
+	RightWeight int    `json:"right_weight"`
+	Weight      int    `json:"weight"`
 }
 
 type HuffmanCode struct {
@@ -91,20 +169,37 @@ func (pq *PriorityQueue) update(item *Item, value Node, priority int) {
 }
 
 type Node struct {
-	value rune
-	left  *Node
-	right *Node
+	value  string
+	weight int
+	left   *Node
+	right  *Node
 }
 
 func (h *HuffmanService) Compress(data []byte) ([]byte, error) {
-	huffmanData := h.compressData(data)
+	huffmanData, err := h.compressData(data, nil)
+	if err != nil {
+		return nil, err
+	}
 	return huffmanData.data, nil
 }
 
 func (h *HuffmanService) CompressWithDetails(data []byte) (CompressionDetails, error) {
-	huffmanData := h.compressData(data)
+	return h.compressWithDetails(data, nil)
+}
+
+// CompressWithTrace сжимает данные, записывая каждое слияние узлов при построении дерева.
+func (h *HuffmanService) CompressWithTrace(data []byte, limit int) (CompressionDetails, error) {
+	return h.compressWithDetails(data, NewTrace(limit))
+}
+
+func (h *HuffmanService) compressWithDetails(data []byte, trace *Trace) (CompressionDetails, error) {
+	huffmanData, err := h.compressData(data, trace)
+	if err != nil {
+		return CompressionDetails{}, err
+	}
 
 	codes := h.makeHuffmanCodeList(huffmanData.frequencyTable, huffmanData.huffmanCode)
+	entropy, extensions := h.extensions(string(data), h.size())
 
 	huffmanDetails := CompressionDetails{
 		Data: huffmanData.data,
@@ -112,16 +207,80 @@ func (h *HuffmanService) CompressWithDetails(data []byte) (CompressionDetails, e
 			Codes:            codes,
 			CompressionRatio: 1 - float32(len(huffmanData.data))/float32(len(data)),
 			Size:             len(huffmanData.data),
+			SymbolSize:       h.size(),
+			Entropy:          entropy,
+			Extensions:       extensions,
+			Trace:            trace,
+			rootNode:         huffmanData.rootNode,
 		},
 	}
 	return huffmanDetails, nil
 }
 
-func (h *HuffmanService) compressData(data []byte) HuffmanData {
+// extensions вычисляет энтропию источника H(X) и для k = 1..maxSymbolSize
+// среднюю длину кода Хаффмана k-го расширения на один исходный символ.
+// С ростом k средняя длина приближается к H(X) сверху.
+func (h *HuffmanService) extensions(dataStr string, maxSymbolSize int) (float64, []HuffmanExtension) {
+	numSymbols := utf8.RuneCountInString(dataStr)
+	if numSymbols == 0 {
+		return 0, nil
+	}
+
+	entropy := 0.0
+	extensions := make([]HuffmanExtension, 0, maxSymbolSize)
+	for k := 1; k <= maxSymbolSize; k++ {
+		frequencyTable := h.frequencyTable(dataStr, k)
+		huffmanCode := h.makeHuffmanCode(h.buildTree(frequencyTable, nil))
+
+		bits, blockEntropy := 0, 0.0
+		numBlocks := (numSymbols + k - 1) / k
+		for symbol, frequency := range frequencyTable {
+			// Единственному символу дерево назначает пустой код,
+			// хотя любому префиксному коду нужен хотя бы один бит.
+			bits += frequency * max(len(huffmanCode[symbol]), 1)
+
+			p := float64(frequency) / float64(numBlocks)
+			blockEntropy -= float64(frequency) * math.Log2(p)
+		}
+		if k == 1 {
+			entropy = blockEntropy / float64(numSymbols)
+		}
+
+		avgCodeLength := float64(bits) / float64(numSymbols)
+		extensions = append(extensions, HuffmanExtension{
+			SymbolSize:    k,
+			Symbols:       len(frequencyTable),
+			AvgCodeLength: avgCodeLength,
+			BlockEntropy:  blockEntropy / float64(numSymbols),
+			Redundancy:    avgCodeLength - entropy,
+		})
+	}
+	return entropy, extensions
+}
+
+func (h *HuffmanService) compressData(data []byte, trace *Trace) (HuffmanData, error) {
+	return h.compressWithTree(data, func(frequencyTable map[string]int) Node {
+		return h.buildTree(frequencyTable, trace)
+	})
+}
+
+// compressWithTree кодирует данные префиксным кодом, дерево которого строит
+// buildTree. Формат результата не зависит от способа построения дерева.
+// Пустые данные кодируются пустой последовательностью блоков.
+func (h *HuffmanService) compressWithTree(data []byte, buildTree func(frequencyTable map[string]int) Node) (HuffmanData, error) {
+	if !utf8.Valid(data) {
+		return HuffmanData{}, ErrInvalidUTF8
+	}
+	if len(data) == 0 {
+		return HuffmanData{data: []byte{}, symbolSize: h.size()}, nil
+	}
 	dataStr := string(data)
 
-	frequencyTable := h.frequencyTable(dataStr)
-	rootNode := h.buildTree(frequencyTable)
+	frequencyTable := h.frequencyTable(dataStr, h.size())
+	rootNode := buildTree(frequencyTable)
+	if rootNode.left == nil && rootNode.right == nil {
+		rootNode = singleSymbolTree(rootNode)
+	}
 	huffmanCode := h.makeHuffmanCode(rootNode)
 
 	dataPayload, numSkipBits := h.compress(dataStr, huffmanCode)
@@ -129,17 +288,32 @@ func (h *HuffmanService) compressData(data []byte) HuffmanData {
 
 	return HuffmanData{
 		data:           compressedData,
+		symbolSize:     h.size(),
 		frequencyTable: frequencyTable,
 		rootNode:       &rootNode,
 		huffmanCode:    huffmanCode,
+	}, nil
+}
+
+// singleSymbolTree достраивает дерево из одного листа: иначе код символа
+// пуст и данные не восстановить. Второй лист с нулевым весом в данных
+// не встречается, а код единственного символа становится однобитовым.
+func singleSymbolTree(leaf Node) Node {
+	dummy := "\x00"
+	if leaf.value == dummy {
+		dummy = "\x01"
 	}
+	return Node{weight: leaf.weight, left: &leaf, right: &Node{value: dummy}}
 }
 
-func (h *HuffmanService) makeHuffmanCodeList(frequencyTable map[rune]int, huffmanCode map[rune]string) []HuffmanCode {
+func (h *HuffmanService) makeHuffmanCodeList(frequencyTable map[string]int, huffmanCode map[string]string) []HuffmanCode {
 	codes := make([]HuffmanCode, 0, len(huffmanCode))
 	for ch := range huffmanCode {
+		if frequencyTable[ch] == 0 {
+			continue
+		}
 		huffmanCodeItem := HuffmanCode{
-			Val:       string(ch),
+			Val:       ch,
 			Frequency: frequencyTable[ch],
 			Code:      huffmanCode[ch],
 		}
@@ -148,21 +322,44 @@ func (h *HuffmanService) makeHuffmanCodeList(frequencyTable map[rune]int, huffma
 	return codes
 }
 
-func (h *HuffmanService) frequencyTable(dataStr string) map[rune]int {
-	frequencyTable := make(map[rune]int)
-	for _, ch := range dataStr {
-		frequencyTable[ch] += 1
-	}
+func (h *HuffmanService) frequencyTable(dataStr string, k int) map[string]int {
+	return SymbolFrequencies(dataStr, k)
+}
+
+// SymbolFrequencies считает частоты символов источника, составленных из k
+// подряд идущих рун. При k = 1 символом является отдельная руна.
+func SymbolFrequencies(dataStr string, k int) map[string]int {
+	frequencyTable := make(map[string]int)
+	forEachSymbol(dataStr, k, func(symbol string) {
+		frequencyTable[symbol] += 1
+	})
 
 	return frequencyTable
 }
 
-func (h *HuffmanService) buildTree(frequencyTable map[rune]int) Node {
+// forEachSymbol делит строку на блоки по k рун и вызывает fn для каждого.
+// Если длина строки не кратна k, последний блок короче остальных
+// и кодируется как самостоятельный символ.
+func forEachSymbol(dataStr string, k int, fn func(symbol string)) {
+	start, n := 0, 0
+	for i := range dataStr {
+		if n == k {
+			fn(dataStr[start:i])
+			start, n = i, 0
+		}
+		n++
+	}
+	if n > 0 {
+		fn(dataStr[start:])
+	}
+}
+
+func (h *HuffmanService) buildTree(frequencyTable map[string]int, trace *Trace) Node {
 	pq := make(PriorityQueue, len(frequencyTable))
 	i := 0
 	for ch, frequency := range frequencyTable {
 		pq[i] = &Item{
-			value:    Node{value: ch},
+			value:    Node{value: ch, weight: frequency},
 			priority: frequency,
 			index:    i,
 		}
@@ -175,10 +372,20 @@ func (h *HuffmanService) buildTree(frequencyTable map[rune]int) Node {
 		right := heap.Pop(&pq).(*Item)
 
 		sum := left.priority + right.priority
+		if trace != nil && !trace.Skip() {
+			trace.Add("merge", HuffmanMergeEvent{
+				Left:        nodeSymbols(left.value),
+				LeftWeight:  left.priority,
+				Right:       nodeSymbols(right.value),
+				RightWeight: right.priority,
+				Weight:      sum,
+			})
+		}
 		newItem := &Item{
 			value: Node{
-				left:  &left.value,
-				right: &right.value,
+				weight: sum,
+				left:   &left.value,
+				right:  &right.value,
 			},
 			priority: sum,
 		}
@@ -189,8 +396,62 @@ func (h *HuffmanService) buildTree(frequencyTable map[rune]int) Node {
 	return heap.Pop(&pq).(*Item).value
 }
 
-func (h *HuffmanService) makeHuffmanCode(rootNode Node) map[rune]string {
-	huffmanCode := make(map[rune]string, 0)
+// nodeSymbols возвращает символы всех листьев поддерева слева направо.
+func nodeSymbols(node Node) string {
+	if node.left == nil && node.right == nil {
+		return node.value
+	}
+	symbols := ""
+	if node.left != nil {
+		symbols += nodeSymbols(*node.left)
+	}
+	if node.right != nil {
+		symbols += nodeSymbols(*node.right)
+	}
+	return symbols
+}
+
+// nodeToTree преобразует дерево кодов в TreeNode для экспорта.
+// Левая ветвь соответствует биту 0, правая — 1.
+func nodeToTree(node *Node, code, edge string) *TreeNode {
+	if node == nil {
+		return nil
+	}
+	tree := &TreeNode{Weight: node.weight, Edge: edge}
+	if node.left == nil && node.right == nil {
+		tree.Label = node.value
+		tree.Code = code
+		return tree
+	}
+	for _, child := range []struct {
+		node *Node
+		bit  string
+	}{{node.left, "0"}, {node.right, "1"}} {
+		if child.node != nil {
+			tree.Children = append(tree.Children, nodeToTree(child.node, code+child.bit, child.bit))
+		}
+	}
+	return tree
+}
+
+// setWeights восстанавливает веса узлов дерева, прочитанного из сжатых данных.
+func setWeights(node *Node, frequencyTable map[string]int) int {
+	if node.left == nil && node.right == nil {
+		node.weight = frequencyTable[node.value]
+		return node.weight
+	}
+	node.weight = 0
+	if node.left != nil {
+		node.weight += setWeights(node.left, frequencyTable)
+	}
+	if node.right != nil {
+		node.weight += setWeights(node.right, frequencyTable)
+	}
+	return node.weight
+}
+
+func (h *HuffmanService) makeHuffmanCode(rootNode Node) map[string]string {
+	huffmanCode := make(map[string]string, 0)
 	type StackItem struct {
 		node Node
 		way  string
@@ -227,19 +488,27 @@ func (h *HuffmanService) makeHuffmanCode(rootNode Node) map[rune]string {
 	return huffmanCode
 }
 
-func (h *HuffmanService) compress(dataStr string, huffmanCode map[rune]string) ([]byte, byte) {
-	bitWriter := bitsio.NewBitWriter()
-	for _, ch := range dataStr {
-		code := huffmanCode[ch]
-		for _, code_ch := range code {
-			if code_ch == '1' {
-				bitWriter.WriteBit(true)
-			} else {
-				bitWriter.WriteBit(false)
-			}
-		}
+// huffmanBitCode — код символа, готовый для записи одним вызовом WriteBits.
+// Длина кода блока из не более 2^32 символов меньше 64 бит: для кода длины n
+// нужно не меньше F(n+2) символов, где F — числа Фибоначчи.
+type huffmanBitCode struct {
+	bits uint64
+	n    int
+}
+
+func (h *HuffmanService) compress(dataStr string, huffmanCode map[string]string) ([]byte, byte) {
+	bitCodes := make(map[string]huffmanBitCode, len(huffmanCode))
+	for symbol, code := range huffmanCode {
+		bits, _ := strconv.ParseUint(code, 2, 64)
+		bitCodes[symbol] = huffmanBitCode{bits: bits, n: len(code)}
 	}
 
+	bitWriter := bitsio.NewBitWriter()
+	forEachSymbol(dataStr, h.size(), func(symbol string) {
+		code := bitCodes[symbol]
+		bitWriter.WriteBits(code.bits, code.n)
+	})
+
 	numSkipBits := bitWriter.BitsLeftToByte()
 	if numSkipBits == 8 {
 		numSkipBits = 0
@@ -251,8 +520,14 @@ func (h *HuffmanService) compress(dataStr string, huffmanCode map[rune]string) (
 func (h *HuffmanService) allCompressedData(numSkipBits byte, rootNode Node, dataPayload []byte) []byte {
 	binaryTree := h.tree2binary(rootNode)
 
-	compressedData := make([]byte, 0)
-	compressedData = append(compressedData, numSkipBits)
+	// Блок предваряется версией и своей длиной, чтобы блоки можно было
+	// записывать подряд.
+	blockSize := 1 + len(binaryTree) + len(dataPayload)
+
+	compressedData := make([]byte, 0, 5+blockSize)
+	compressedData = append(compressedData, huffmanVersion)
+	compressedData = binary.LittleEndian.AppendUint32(compressedData, uint32(blockSize))
+	compressedData = append(compressedData, numSkipBits|byte(h.size()-1)<<huffmanSymbolSizeShift)
 	compressedData = append(compressedData, binaryTree...)
 	compressedData = append(compressedData, dataPayload...)
 	return compressedData
@@ -272,7 +547,7 @@ func (h *HuffmanService) tree2binary(rootNode Node) []byte {
 		if !isFirst {
 			if currentNode.left == nil && currentNode.right == nil {
 				bitWriter.WriteBit(true)
-				bitWriter.WtiteRune(currentNode.value)
+				h.writeSymbol(bitWriter, currentNode.value)
 			} else {
 				bitWriter.WriteBit(false)
 			}
@@ -291,6 +566,18 @@ func (h *HuffmanService) tree2binary(rootNode Node) []byte {
 	return bitWriter.Bytes()
 }
 
+// writeSymbol записывает символ листа дерева. Отдельная руна пишется как есть,
+// а блок из нескольких рун — длиной в байтах и самими байтами.
+func (h *HuffmanService) writeSymbol(bitWriter *bitsio.BitWriter, symbol string) {
+	if h.size() == 1 {
+		r, _ := utf8.DecodeRuneInString(symbol)
+		bitWriter.WriteRune(r)
+		return
+	}
+	bitWriter.WriteByte(byte(len(symbol)))
+	bitWriter.Write([]byte(symbol))
+}
+
 func (h *HuffmanService) Decompress(compressedData []byte) ([]byte, error) {
 	huffmanData, err := h.decompressData(compressedData)
 	if err != nil {
@@ -307,6 +594,7 @@ func (h *HuffmanService) DecompressWithDetails(compressedData []byte) (Compressi
 	}
 
 	codes := h.makeHuffmanCodeList(huffmanData.frequencyTable, huffmanData.huffmanCode)
+	entropy, extensions := h.extensions(string(huffmanData.data), huffmanData.symbolSize)
 
 	huffmanDetails := CompressionDetails{
 		Data: huffmanData.data,
@@ -314,62 +602,100 @@ func (h *HuffmanService) DecompressWithDetails(compressedData []byte) (Compressi
 			Codes:            codes,
 			CompressionRatio: 1 - float32(len(compressedData))/float32(len(huffmanData.data)),
 			Size:             len(huffmanData.data),
+			SymbolSize:       huffmanData.symbolSize,
+			Entropy:          entropy,
+			Extensions:       extensions,
+			rootNode:         huffmanData.rootNode,
 		},
 	}
 	return huffmanDetails, nil
 }
 
+// decompressData разжимает последовательность блоков. Таблица кодов
+// для многоблочных данных приводится по первому блоку.
 func (h *HuffmanService) decompressData(compressedData []byte) (HuffmanData, error) {
-	bitReader := bitsio.NewBitReader(compressedData)
+	hr := h.newReader(bytes.NewReader(compressedData))
+	data, err := readAll(hr)
+	if err != nil {
+		return HuffmanData{}, err
+	}
 
-	numSkipBits := h.numSkipBits(bitReader)
-	rootNode := h.restoreTree(bitReader)
-	payloadData := compressedData[bitReader.NumReadByte():]
+	symbolSize := hr.symbolSize
+	if symbolSize == 0 {
+		symbolSize = 1
+	}
 
-	huffmanCode := h.makeHuffmanCode(*rootNode)
+	huffmanData := HuffmanData{
+		data:           data,
+		symbolSize:     symbolSize,
+		frequencyTable: hr.frequencyTable,
+		rootNode:       hr.rootNode,
+		huffmanCode:    hr.huffmanCode,
+	}
+	return huffmanData, nil
+}
+
+func (h *HuffmanService) decompressBlock(block []byte) (HuffmanData, error) {
+	bitReader := bitsio.NewBitReader(block)
+
+	header, err := bitReader.ReadByte()
+	if err != nil {
+		return HuffmanData{}, ErrTruncated
+	}
+	numSkipBits := header & huffmanSkipBitsMask
+	symbolSize := int(header>>huffmanSymbolSizeShift) + 1
+	if symbolSize > MaxHuffmanSymbolSize {
+		return HuffmanData{}, fmt.Errorf("%w: invalid symbol size %d", ErrCorrupt, symbolSize)
+	}
 
-	for ch, code := range huffmanCode {
-		fmt.Printf("%s:%s\n", string(ch), code)
+	rootNode, err := h.restoreTree(bitReader, symbolSize)
+	if err != nil {
+		return HuffmanData{}, err
 	}
 
-	data, err := h.decompress(rootNode, payloadData, numSkipBits)
+	// Коды занимают оставшиеся байты блока без numSkipBits бит выравнивания.
+	numBits := 8*int64(len(block)) - bitReader.BitsRead() - int64(numSkipBits)
+	if numBits < 0 {
+		return HuffmanData{}, fmt.Errorf("%w: block has no codes", ErrCorrupt)
+	}
+	data, err := h.decompress(rootNode, bitReader, numBits)
 	if err != nil {
 		return HuffmanData{}, err
 	}
 
+	frequencyTable := h.frequencyTable(string(data), symbolSize)
+	setWeights(rootNode, frequencyTable)
+
 	huffmanData := HuffmanData{
-		data:        data,
-		rootNode:    rootNode,
-		huffmanCode: huffmanCode,
+		data:           data,
+		symbolSize:     symbolSize,
+		frequencyTable: frequencyTable,
+		rootNode:       rootNode,
 	}
 	return huffmanData, nil
 }
 
-func (h *HuffmanService) numSkipBits(bitReader *bitsio.BitReader) byte {
-	// bitWriter := bitsio.NewBitWriter()
-	// for range 4 {
-	// 	bitWriter.WriteBit(false)
-	// }
-	// for range 4
-	// 	bitWriter.WriteBit(bitReader.ReadBit())
-	// }
-	// return bitWriter.Bytes()[0]
-	return bitReader.ReadByte()
-}
-
-func (h *HuffmanService) restoreTree(bitReader *bitsio.BitReader) *Node {
+// restoreTree восстанавливает дерево из прямого обхода: 0 — внутренний узел,
+// 1 и символ — лист. Каждый узел занимает хотя бы один бит, поэтому
+// при оборванных данных чтение заканчивается ошибкой, а не зацикливается.
+func (h *HuffmanService) restoreTree(bitReader *bitsio.BitReader, symbolSize int) (*Node, error) {
 	rootNode := &Node{}
 
 	stack := make([]*Node, 0)
 	stack = append(stack, rootNode)
 
 	for !h.checkIsFull(stack) {
-		bit := bitReader.ReadBit()
+		bit, err := bitReader.ReadBit()
+		if err != nil {
+			return nil, ErrTruncated
+		}
 		var newNode *Node
 		if bit {
-			r := bitReader.ReadRune()
-			newNode = &Node{value: r}
-
+			value, err := h.readSymbol(bitReader, symbolSize)
+			if err != nil {
+				return nil, err
+			}
+			newNode = &Node{value: value}
 		} else {
 			newNode = &Node{}
 		}
@@ -391,9 +717,31 @@ func (h *HuffmanService) restoreTree(bitReader *bitsio.BitReader) *Node {
 			stack = append(stack, newNode)
 		}
 	}
-	bitReader.FinishByte()
+	bitReader.Align()
+
+	return rootNode, nil
+}
 
-	return rootNode
+func (h *HuffmanService) readSymbol(bitReader *bitsio.BitReader, symbolSize int) (string, error) {
+	if symbolSize == 1 {
+		r, _, err := bitReader.ReadRune()
+		if err != nil {
+			return "", ErrTruncated
+		}
+		return string(r), nil
+	}
+	size, err := bitReader.ReadByte()
+	if err != nil {
+		return "", ErrTruncated
+	}
+	if size == 0 || int(size) > symbolSize*utf8.UTFMax {
+		return "", fmt.Errorf("%w: invalid symbol length %d", ErrCorrupt, size)
+	}
+	symbol := make([]byte, size)
+	if _, err := io.ReadFull(bitReader, symbol); err != nil {
+		return "", ErrTruncated
+	}
+	return string(symbol), nil
 }
 
 func (h *HuffmanService) checkIsFull(stack []*Node) bool {
@@ -406,17 +754,15 @@ func (h *HuffmanService) checkIsFull(stack []*Node) bool {
 	return true
 }
 
-func (h *HuffmanService) decompress(rootNode *Node, compressedData []byte, numSkipBits byte) ([]byte, error) {
+// decompress декодирует numBits бит, спускаясь по дереву от корня к листу.
+func (h *HuffmanService) decompress(rootNode *Node, bitReader *bitsio.BitReader, numBits int64) ([]byte, error) {
 	var data bytes.Buffer
-	bitReader := bitsio.NewBitReader(compressedData)
-	numLastBitsInLastByte := 8 - numSkipBits
 	node := rootNode
-	for !bitReader.IsLastByte() || (bitReader.IsLastByte() && numLastBitsInLastByte > 0) {
-		if bitReader.IsLastByte() {
-			numLastBitsInLastByte--
+	for range numBits {
+		currentBit, err := bitReader.ReadBit()
+		if err != nil {
+			return nil, ErrTruncated
 		}
-
-		currentBit := bitReader.ReadBit()
 		if currentBit {
 			if node.right != nil {
 				node = node.right
@@ -428,13 +774,143 @@ func (h *HuffmanService) decompress(rootNode *Node, compressedData []byte, numSk
 		}
 
 		if node.left == nil && node.right == nil {
-			_, err := data.WriteRune(node.value)
-			if err != nil {
-				return nil, err
+			if data.Len()+len(node.value) > MaxDecompressedSize {
+				return nil, ErrTooLarge
 			}
+			data.WriteString(node.value)
 			node = rootNode
 		}
 	}
 
 	return data.Bytes(), nil
 }
+
+// NewWriter возвращает потоковый компрессор, который кодирует вход
+// блоками по huffmanBlockSize байт, не разрывая UTF-8 символы.
+func (h *HuffmanService) NewWriter(w io.Writer) io.WriteCloser {
+	return &huffmanWriter{h: h, w: w}
+}
+
+// NewReader возвращает потоковый декомпрессор, разжимающий данные поблочно.
+func (h *HuffmanService) NewReader(r io.Reader) io.Reader {
+	return h.newReader(r)
+}
+
+func (h *HuffmanService) newReader(r io.Reader) *huffmanReader {
+	return &huffmanReader{h: h, r: r}
+}
+
+type huffmanWriter struct {
+	h   *HuffmanService
+	w   io.Writer
+	buf []byte
+	err error
+}
+
+func (hw *huffmanWriter) Write(p []byte) (int, error) {
+	if hw.err != nil {
+		return 0, hw.err
+	}
+
+	hw.buf = append(hw.buf, p...)
+	for len(hw.buf) > huffmanBlockSize {
+		n := runeBoundary(hw.buf, huffmanBlockSize)
+		if err := hw.writeBlock(hw.buf[:n]); err != nil {
+			return 0, err
+		}
+		hw.buf = append(hw.buf[:0], hw.buf[n:]...)
+	}
+	return len(p), nil
+}
+
+func (hw *huffmanWriter) writeBlock(block []byte) error {
+	huffmanData, err := hw.h.compressData(block, nil)
+	if err != nil {
+		hw.err = err
+		return err
+	}
+	_, hw.err = hw.w.Write(huffmanData.data)
+	return hw.err
+}
+
+func (hw *huffmanWriter) Close() error {
+	if hw.err != nil {
+		return hw.err
+	}
+	if len(hw.buf) == 0 {
+		return nil
+	}
+	err := hw.writeBlock(hw.buf)
+	hw.buf = nil
+	return err
+}
+
+type huffmanReader struct {
+	h              *HuffmanService
+	r              io.Reader
+	symbolSize     int
+	frequencyTable map[string]int
+	rootNode       *Node
+	huffmanCode    map[string]string
+	out            []byte
+	err            error
+}
+
+func (hr *huffmanReader) Read(p []byte) (int, error) {
+	for len(hr.out) == 0 {
+		if hr.err != nil {
+			return 0, hr.err
+		}
+		hr.readBlock()
+	}
+
+	n := copy(p, hr.out)
+	hr.out = hr.out[n:]
+	return n, nil
+}
+
+func (hr *huffmanReader) readBlock() {
+	var version [1]byte
+	if _, err := io.ReadFull(hr.r, version[:]); err != nil {
+		// Поток может закончиться только на границе блока.
+		hr.err = err
+		return
+	}
+
+	block := new(bytes.Buffer)
+	switch {
+	case version[0] < huffmanVersionFlag && hr.rootNode == nil:
+		// Данные версии 0 — единственный блок до конца потока.
+		block.WriteByte(version[0])
+		if _, err := block.ReadFrom(hr.r); err != nil {
+			hr.err = err
+			return
+		}
+	case version[0] == huffmanVersion:
+		var blockSize uint32
+		if err := binary.Read(hr.r, binary.LittleEndian, &blockSize); err != nil {
+			hr.err = truncated(err)
+			return
+		}
+		if _, err := io.CopyN(block, hr.r, int64(blockSize)); err != nil {
+			hr.err = truncated(err)
+			return
+		}
+	default:
+		hr.err = fmt.Errorf("%w: unsupported format version %#x", ErrCorrupt, version[0])
+		return
+	}
+
+	huffmanData, err := hr.h.decompressBlock(block.Bytes())
+	if err != nil {
+		hr.err = err
+		return
+	}
+	if hr.rootNode == nil {
+		hr.symbolSize = huffmanData.symbolSize
+		hr.frequencyTable = huffmanData.frequencyTable
+		hr.rootNode = huffmanData.rootNode
+		hr.huffmanCode = hr.h.makeHuffmanCode(*huffmanData.rootNode)
+	}
+	hr.out = huffmanData.data
+}
@@ -811,6 +811,14 @@ XVIII
    -- Прощай, душа моя, -- сказала она графине, которая провожала ее до двери. -- Пожелай мне успеха, -- прибавила она шепотом от сына.
    -- Вы к князю Кириллу Владимировичу, моя милая, -- сказал граф из столовой, выходя тоже в переднюю. -- Коли ему лучше, зовите Пьера ко мне обедать. Ведь он у меня бывал, с детьми танцевал. Зовите непременно... Hy, посмотрим, как-то отличится нынче Тарас. Говорят, что у графа Орлова такого обеда не бывало, какой у нас будет.`),
 		},
+		{
+			name: "single symbol",
+			data: []byte("aaaa"),
+		},
+		{
+			name: "single character",
+			data: []byte("\x00"),
+		},
 	}
 
 	for _, tt := range tests {
@@ -833,6 +841,96 @@ XVIII
 	}
 }
 
+func TestHuffmanService_SymbolSize(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		k    int
+	}{
+		{
+			name: "pairs",
+			data: []byte("Сжатие_Хаффмана_по_парам"),
+			k:    2,
+		},
+		{
+			name: "remainder",
+			data: []byte("Сжатие_Хаффмана"),
+			k:    2,
+		},
+		{
+			name: "triples with remainder",
+			data: []byte("abracadabra_abracadabra"),
+			k:    3,
+		},
+		{
+			name: "longer than data",
+			data: []byte("абвгд_абв"),
+			k:    MaxHuffmanSymbolSize,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := NewHuffmanService().WithSymbolSize(tt.k)
+			if err != nil {
+				t.Fatalf("HuffmanService.WithSymbolSize() has err = %v", err)
+			}
+			compressed, err := h.CompressWithDetails(tt.data)
+			if err != nil {
+				t.Fatalf("HuffmanService.CompressWithDetails() has err = %v", err)
+			}
+
+			// Размер символа хранится в сжатых данных.
+			decompressed, err := NewHuffmanService().DecompressWithDetails(compressed.Data)
+			if err != nil {
+				t.Fatalf("HuffmanService.DecompressWithDetails() has err = %v", err)
+			}
+			if !reflect.DeepEqual(tt.data, decompressed.Data) {
+				t.Errorf("HuffmanService.Decompress() = %s, want %s", decompressed.Data, tt.data)
+			}
+
+			details := decompressed.Details.(HuffmanDetails)
+			if details.SymbolSize != tt.k || len(details.Extensions) != tt.k {
+				t.Fatalf("symbol size = %d, extensions = %d, want %d", details.SymbolSize, len(details.Extensions), tt.k)
+			}
+			for _, extension := range details.Extensions {
+				if extension.AvgCodeLength < extension.BlockEntropy || extension.BlockEntropy > details.Entropy+1e-9 {
+					t.Errorf("extension %d: avg code length = %f, block entropy = %f, entropy = %f",
+						extension.SymbolSize, extension.AvgCodeLength, extension.BlockEntropy, details.Entropy)
+				}
+			}
+		})
+	}
+}
+
+func TestHuffmanService_WithSymbolSize(t *testing.T) {
+	for _, k := range []int{0, -1, MaxHuffmanSymbolSize + 1} {
+		if _, err := NewHuffmanService().WithSymbolSize(k); err == nil {
+			t.Errorf("HuffmanService.WithSymbolSize(%d) has no err", k)
+		}
+	}
+}
+
+// Данные из одного символа: дерево достраивается вторым листом с нулевым
+// весом, а в список кодов попадает только символ из данных.
+func TestHuffmanService_SingleSymbol(t *testing.T) {
+	for _, data := range []string{"aaaa", "\x00", "\x01\x01"} {
+		h := NewHuffmanService()
+		details, err := h.CompressWithDetails([]byte(data))
+		if err != nil {
+			t.Fatalf("HuffmanService.CompressWithDetails(%q) has err = %v", data, err)
+		}
+		codes := details.Details.(HuffmanDetails).Codes
+		if len(codes) != 1 || codes[0].Val != data[:1] || codes[0].Frequency != len(data) || len(codes[0].Code) != 1 {
+			t.Errorf("HuffmanService.CompressWithDetails(%q) codes = %+v", data, codes)
+		}
+		decompressedData, err := h.Decompress(details.Data)
+		if err != nil || string(decompressedData) != data {
+			t.Errorf("HuffmanService.Decompress() = %q, %v, want %q", decompressedData, err, data)
+		}
+	}
+}
+
 func Benchmark(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		data := []byte("Сжатие_Хаффмана")
@@ -841,3 +939,28 @@ func Benchmark(b *testing.B) {
 		h.Decompress(compressedData)
 	}
 }
+
+// Данные версии 0 — без версии и длины блока — по-прежнему читаются.
+func TestHuffmanService_DecompressVersion0(t *testing.T) {
+	tests := []struct {
+		want           string
+		compressedData []byte
+	}{
+		{"банан_бандана", []byte{0x5, 0xe8, 0x58, 0x3a, 0x17, 0xae, 0x85, 0x8b, 0xa1, 0x69, 0x5f, 0xc9, 0x7e, 0x5c, 0x80}},
+		{"abracadabra", []byte{0x1, 0xb0, 0xac, 0x4b, 0x92, 0xc9, 0x63, 0x59, 0xee, 0x58}},
+	}
+	for _, tt := range tests {
+		got, err := NewHuffmanService().Decompress(tt.compressedData)
+		if err != nil {
+			t.Fatalf("HuffmanService.Decompress() has err = %v", err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("HuffmanService.Decompress() = %q, want %q", got, tt.want)
+		}
+	}
+
+	compressedData, _ := NewHuffmanService().Compress([]byte("abracadabra"))
+	if compressedData[0] != huffmanVersion {
+		t.Errorf("HuffmanService.Compress() starts with %#x, want version %#x", compressedData[0], huffmanVersion)
+	}
+}
@@ -0,0 +1,424 @@
+package compression
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+
+	"github.com/PritOriginal/cryptolabs-back/pkg/bitsio"
+)
+
+// LZ77 со скользящим окном 32 КБ. Данные заменяются последовательностью
+// литералов и ссылок (длина, расстояние) на предыдущее вхождение.
+// Формат: исходный размер (uvarint) | токены, где
+//
+//	литерал: 0 | байт (8 бит)
+//	ссылка:  1 | гамма-код Элиаса (длина - 2) | n = bits.Len(расстояние - 1) (4 бита) | младшие n - 1 бит
+//
+// Уровни 1–9, как в zlib, задают глубину поиска совпадений и способ разбора:
+// жадный (1–3), ленивый (4–6) и оптимальный (7–9).
+
+const (
+	MinLZ77Level     = 1
+	MaxLZ77Level     = 9
+	DefaultLZ77Level = 6
+
+	lz77LiteralPrice = 9
+	lz77DistanceBits = 4
+
+	// lz77MinTokenBits — самый короткий токен: ссылка наименьшей длины
+	// (гамма-код в 1 бит) на соседний байт.
+	lz77MinTokenBits = 1 + 1 + lz77DistanceBits
+)
+
+const (
+	lz77Greedy = iota
+	lz77Lazy
+	lz77Optimal
+)
+
+var lz77StrategyNames = []string{"greedy", "lazy", "optimal"}
+
+type lz77Level struct {
+	strategy   int
+	maxChain   int
+	niceLength int
+	// maxLazy — при ленивом разборе совпадение не короче maxLazy принимается сразу.
+	maxLazy int
+}
+
+var lz77Levels = [MaxLZ77Level + 1]lz77Level{
+	1: {strategy: lz77Greedy, maxChain: 4, niceLength: 8},
+	2: {strategy: lz77Greedy, maxChain: 8, niceLength: 16},
+	3: {strategy: lz77Greedy, maxChain: 32, niceLength: 32},
+	4: {strategy: lz77Lazy, maxChain: 32, niceLength: 32, maxLazy: 16},
+	5: {strategy: lz77Lazy, maxChain: 32, niceLength: 128, maxLazy: 32},
+	6: {strategy: lz77Lazy, maxChain: 128, niceLength: 128, maxLazy: 128},
+	7: {strategy: lz77Optimal, maxChain: 64, niceLength: 128},
+	8: {strategy: lz77Optimal, maxChain: 256, niceLength: lz77MaxMatch},
+	9: {strategy: lz77Optimal, maxChain: 4096, niceLength: lz77MaxMatch},
+}
+
+type LZ77Service struct {
+	level int
+}
+
+type LZ77Details struct {
+	Level            int     `json:"level"`
+	Strategy         string  `json:"strategy"`
+	Literals         int     `json:"literals"`
+	Matches          int     `json:"matches"`
+	AvgMatchLength   float64 `json:"avg_match_length"`
+	AvgMatchDistance float64 `json:"avg_match_distance"`
+	CompressionRatio float32 `json:"compression_ratio"`
+	Size             int     `json:"size"`
+	Trace            *Trace  `json:"trace,omitempty"`
+}
+
+// LZ77TokenEvent — литерал или ссылка и число бит, которыми они закодированы.
+type LZ77TokenEvent struct {
+	Position int    `json:"position"`
+	Literal  string `json:"literal,omitempty"`
+	Length   int    `json:"length,omitempty"`
+	Distance int    `json:"distance,omitempty"`
+	Bits     int    `json:"bits"`
+}
+
+// lz77Token — литерал при length == 0, иначе ссылка.
+type lz77Token struct {
+	literal  byte
+	length   int
+	distance int
+}
+
+func NewLZ77Service() *LZ77Service {
+	return &LZ77Service{level: DefaultLZ77Level}
+}
+
+// WithLevel возвращает LZ77 с уровнем сжатия level.
+func (s *LZ77Service) WithLevel(level int) (Service, error) {
+	if level < MinLZ77Level || level > MaxLZ77Level {
+		return nil, fmt.Errorf("level must be between %d and %d", MinLZ77Level, MaxLZ77Level)
+	}
+	return &LZ77Service{level: level}, nil
+}
+
+func (s *LZ77Service) Compress(data []byte) ([]byte, error) {
+	compressedData, _ := s.compress(data, nil)
+	return compressedData, nil
+}
+
+func (s *LZ77Service) CompressWithDetails(data []byte) (CompressionDetails, error) {
+	return s.compressWithDetails(data, nil)
+}
+
+// CompressWithTrace сжимает данные, записывая каждый литерал и каждую ссылку.
+func (s *LZ77Service) CompressWithTrace(data []byte, limit int) (CompressionDetails, error) {
+	return s.compressWithDetails(data, NewTrace(limit))
+}
+
+func (s *LZ77Service) compressWithDetails(data []byte, trace *Trace) (CompressionDetails, error) {
+	compressedData, tokens := s.compress(data, trace)
+	details := s.makeDetails(tokens)
+	details.CompressionRatio = ratio(len(compressedData), len(data))
+	details.Size = len(compressedData)
+	details.Trace = trace
+	return CompressionDetails{Data: compressedData, Details: details}, nil
+}
+
+func (s *LZ77Service) compress(data []byte, trace *Trace) ([]byte, []lz77Token) {
+	tokens := s.parse(data)
+
+	bw := bitsio.NewBitWriter()
+	pos := 0
+	for _, token := range tokens {
+		n := writeLZ77Token(bw, token)
+		if trace != nil && !trace.Skip() {
+			event := LZ77TokenEvent{Position: pos, Length: token.length, Distance: token.distance, Bits: n}
+			if token.length == 0 {
+				event.Literal = byteToString(token.literal)
+			}
+			trace.Add("token", event)
+		}
+		pos += max(token.length, 1)
+	}
+
+	compressedData := binary.AppendUvarint(nil, uint64(len(data)))
+	return append(compressedData, bw.Bytes()...), tokens
+}
+
+func (s *LZ77Service) config() lz77Level {
+	if s.level == 0 {
+		return lz77Levels[DefaultLZ77Level]
+	}
+	return lz77Levels[s.level]
+}
+
+func (s *LZ77Service) parse(data []byte) []lz77Token {
+	level := s.config()
+	finder := newLZ77MatchFinder(data, level.maxChain, level.niceLength)
+	switch level.strategy {
+	case lz77Optimal:
+		return parseOptimal(data, finder)
+	case lz77Lazy:
+		return parseLazy(data, finder, level.maxLazy)
+	default:
+		return parseLazy(data, finder, 0)
+	}
+}
+
+// parseLazy разбирает данные жадно, а при maxLazy > 0 — лениво: найдя
+// совпадение короче maxLazy, проверяет следующую позицию и, если там
+// совпадение длиннее, выводит текущий байт литералом.
+func parseLazy(data []byte, finder *lz77MatchFinder, maxLazy int) []lz77Token {
+	tokens := make([]lz77Token, 0)
+	var found []lz77Match
+	var match lz77Match
+	for pos := 0; pos < len(data); {
+		match, found = finder.longest(pos, found)
+		for match.length >= lz77MinMatch && match.length < maxLazy && pos+1 < len(data) {
+			var next lz77Match
+			next, found = finder.longest(pos+1, found)
+			if next.length <= match.length {
+				break
+			}
+			tokens = append(tokens, lz77Token{literal: data[pos]})
+			pos++
+			match = next
+		}
+
+		if match.length >= lz77MinMatch {
+			tokens = append(tokens, lz77Token{length: match.length, distance: match.distance})
+			pos += match.length
+		} else {
+			tokens = append(tokens, lz77Token{literal: data[pos]})
+			pos++
+		}
+	}
+	return tokens
+}
+
+// parseOptimal находит разбор наименьшей длины в битах динамическим
+// программированием: cost[i] — цена кодирования первых i байтов,
+// а переходы из i — литерал и ссылки любой допустимой длины.
+func parseOptimal(data []byte, finder *lz77MatchFinder) []lz77Token {
+	n := len(data)
+	cost := make([]int, n+1)
+	for i := 1; i <= n; i++ {
+		cost[i] = math.MaxInt
+	}
+	// from[i] — последний токен лучшего разбора первых i байтов.
+	from := make([]lz77Match, n+1)
+
+	var found []lz77Match
+	for i := range n {
+		if c := cost[i] + lz77LiteralPrice; c < cost[i+1] {
+			cost[i+1] = c
+			from[i+1] = lz77Match{}
+		}
+
+		found = finder.matches(i, found)
+		length := lz77MinMatch
+		for _, match := range found {
+			for ; length <= match.length; length++ {
+				c := cost[i] + lz77MatchPrice(length, match.distance)
+				if c < cost[i+length] {
+					cost[i+length] = c
+					from[i+length] = match
+					from[i+length].length = length
+				}
+			}
+		}
+	}
+
+	tokens := make([]lz77Token, 0)
+	for i := n; i > 0; {
+		match := from[i]
+		if match.length == 0 {
+			tokens = append(tokens, lz77Token{literal: data[i-1]})
+			i--
+		} else {
+			tokens = append(tokens, lz77Token{length: match.length, distance: match.distance})
+			i -= match.length
+		}
+	}
+	for l, r := 0, len(tokens)-1; l < r; l, r = l+1, r-1 {
+		tokens[l], tokens[r] = tokens[r], tokens[l]
+	}
+	return tokens
+}
+
+func lz77MatchPrice(length, distance int) int {
+	n := bits.Len(uint(distance - 1))
+	return 1 + gammaLen(length-lz77MinMatch+1) + lz77DistanceBits + max(n-1, 0)
+}
+
+func gammaLen(x int) int {
+	return 2*bits.Len(uint(x)) - 1
+}
+
+func writeLZ77Token(bw *bitsio.BitWriter, token lz77Token) int {
+	if token.length == 0 {
+		bw.WriteBit(false)
+		bw.WriteByte(token.literal)
+		return lz77LiteralPrice
+	}
+
+	bw.WriteBit(true)
+	x := token.length - lz77MinMatch + 1
+	numBits := bits.Len(uint(x))
+	bw.WriteBits(0, numBits-1)
+	bw.WriteBits(uint64(x), numBits)
+
+	d := token.distance - 1
+	n := bits.Len(uint(d))
+	bw.WriteBits(uint64(n), lz77DistanceBits)
+	if n > 1 {
+		bw.WriteBits(uint64(d), n-1)
+	}
+	return lz77MatchPrice(token.length, token.distance)
+}
+
+func readLZ77Bits(br *bitsio.BitReader, n int) (int, error) {
+	v, err := br.ReadBits(n)
+	if err != nil {
+		return 0, ErrTruncated
+	}
+	return int(v), nil
+}
+
+func (s *LZ77Service) Decompress(compressedData []byte) ([]byte, error) {
+	data, _, err := s.decompress(compressedData, MaxDecompressedSize)
+	return data, err
+}
+
+func (s *LZ77Service) decompressLimit(compressedData []byte, limit int) ([]byte, error) {
+	data, _, err := s.decompress(compressedData, limit)
+	return data, err
+}
+
+func (s *LZ77Service) DecompressWithDetails(compressedData []byte) (CompressionDetails, error) {
+	data, tokens, err := s.decompress(compressedData, MaxDecompressedSize)
+	if err != nil {
+		return CompressionDetails{}, err
+	}
+
+	details := s.makeDetails(tokens)
+	details.CompressionRatio = ratio(len(compressedData), len(data))
+	details.Size = len(data)
+	return CompressionDetails{Data: data, Details: details}, nil
+}
+
+func (s *LZ77Service) decompress(compressedData []byte, limit int) ([]byte, []lz77Token, error) {
+	size, n := binary.Uvarint(compressedData)
+	if n == 0 {
+		return nil, nil, ErrTruncated
+	}
+	if n < 0 {
+		return nil, nil, fmt.Errorf("%w: invalid lz77 header", ErrCorrupt)
+	}
+	if size > uint64(limit) {
+		return nil, nil, ErrTooLarge
+	}
+	// Каждый токен занимает не меньше lz77MinTokenBits бит и даёт не больше
+	// lz77MaxMatch байтов.
+	if size > uint64(len(compressedData)-n)*8/lz77MinTokenBits*lz77MaxMatch {
+		return nil, nil, fmt.Errorf("%w: invalid lz77 size %d", ErrCorrupt, size)
+	}
+
+	br := bitsio.NewBitReader(compressedData[n:])
+	data := make([]byte, 0, size)
+	tokens := make([]lz77Token, 0)
+	for len(data) < int(size) {
+		flag, err := readLZ77Bits(br, 1)
+		if err != nil {
+			return nil, nil, err
+		}
+		if flag == 0 {
+			literal, err := readLZ77Bits(br, 8)
+			if err != nil {
+				return nil, nil, err
+			}
+			data = append(data, byte(literal))
+			tokens = append(tokens, lz77Token{literal: byte(literal)})
+			continue
+		}
+
+		token, err := readLZ77Match(br)
+		if err != nil {
+			return nil, nil, err
+		}
+		if token.distance > len(data) || token.length > int(size)-len(data) {
+			return nil, nil, fmt.Errorf("%w: invalid lz77 match (%d, %d) at %d", ErrCorrupt, token.length, token.distance, len(data))
+		}
+		// Ссылка может перекрывать выводимые байты, поэтому копирование побайтовое.
+		start := len(data) - token.distance
+		for i := range token.length {
+			data = append(data, data[start+i])
+		}
+		tokens = append(tokens, token)
+	}
+	return data, tokens, nil
+}
+
+func readLZ77Match(br *bitsio.BitReader) (lz77Token, error) {
+	zeros := 0
+	for {
+		bit, err := readLZ77Bits(br, 1)
+		if err != nil {
+			return lz77Token{}, err
+		}
+		if bit == 1 {
+			break
+		}
+		zeros++
+		if zeros >= bits.Len(lz77MaxMatch) {
+			return lz77Token{}, fmt.Errorf("%w: invalid lz77 length", ErrCorrupt)
+		}
+	}
+	low, err := readLZ77Bits(br, zeros)
+	if err != nil {
+		return lz77Token{}, err
+	}
+	length := (1<<zeros | low) + lz77MinMatch - 1
+	if length > lz77MaxMatch {
+		return lz77Token{}, fmt.Errorf("%w: invalid lz77 length %d", ErrCorrupt, length)
+	}
+
+	n, err := readLZ77Bits(br, lz77DistanceBits)
+	if err != nil {
+		return lz77Token{}, err
+	}
+	d := n
+	if n > 1 {
+		low, err := readLZ77Bits(br, n-1)
+		if err != nil {
+			return lz77Token{}, err
+		}
+		d = 1<<(n-1) | low
+	}
+	return lz77Token{length: length, distance: d + 1}, nil
+}
+
+func (s *LZ77Service) makeDetails(tokens []lz77Token) LZ77Details {
+	details := LZ77Details{Level: s.level, Strategy: lz77StrategyNames[s.config().strategy]}
+	if details.Level == 0 {
+		details.Level = DefaultLZ77Level
+	}
+	var totalLength, totalDistance int
+	for _, token := range tokens {
+		if token.length == 0 {
+			details.Literals++
+			continue
+		}
+		details.Matches++
+		totalLength += token.length
+		totalDistance += token.distance
+	}
+	if details.Matches > 0 {
+		details.AvgMatchLength = float64(totalLength) / float64(details.Matches)
+		details.AvgMatchDistance = float64(totalDistance) / float64(details.Matches)
+	}
+	return details
+}
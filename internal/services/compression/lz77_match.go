@@ -0,0 +1,102 @@
+package compression
+
+// Поиск совпадений для LZ77 по хеш-цепочкам: для каждой позиции хранится
+// предыдущая позиция с тем же хешем первых трёх байтов, а head — последняя
+// позиция для каждого хеша. Цепочка просматривается от ближайших позиций
+// к дальним, не дальше окна и не больше maxChain шагов.
+
+const (
+	lz77WindowSize = 1 << 15
+	lz77MinMatch   = 3
+	lz77MaxMatch   = 258
+
+	lz77HashBits = 15
+)
+
+type lz77Match struct {
+	length   int
+	distance int
+}
+
+type lz77MatchFinder struct {
+	data []byte
+	head []int32
+	prev []int32
+	// next — первая позиция, ещё не добавленная в цепочки.
+	next       int
+	maxChain   int
+	niceLength int
+}
+
+func newLZ77MatchFinder(data []byte, maxChain, niceLength int) *lz77MatchFinder {
+	head := make([]int32, 1<<lz77HashBits)
+	for i := range head {
+		head[i] = -1
+	}
+	return &lz77MatchFinder{
+		data:       data,
+		head:       head,
+		prev:       make([]int32, len(data)),
+		maxChain:   maxChain,
+		niceLength: niceLength,
+	}
+}
+
+func (f *lz77MatchFinder) hash(pos int) int {
+	v := uint32(f.data[pos])<<16 | uint32(f.data[pos+1])<<8 | uint32(f.data[pos+2])
+	return int(v * 2654435761 >> (32 - lz77HashBits))
+}
+
+// insertUpTo добавляет в цепочки все позиции до pos, не включая её.
+func (f *lz77MatchFinder) insertUpTo(pos int) {
+	for ; f.next < pos; f.next++ {
+		if f.next+lz77MinMatch > len(f.data) {
+			continue
+		}
+		h := f.hash(f.next)
+		f.prev[f.next] = f.head[h]
+		f.head[h] = int32(f.next)
+	}
+}
+
+// matches возвращает совпадения для позиции pos в порядке возрастания длины.
+// Каждое следующее совпадение длиннее предыдущего и находится дальше,
+// поэтому для любой длины ближайшее совпадение — первое не короче её.
+func (f *lz77MatchFinder) matches(pos int, found []lz77Match) []lz77Match {
+	found = found[:0]
+	f.insertUpTo(pos)
+	maxLength := min(lz77MaxMatch, len(f.data)-pos)
+	if maxLength < lz77MinMatch {
+		return found
+	}
+
+	bestLength := lz77MinMatch - 1
+	candidate := int(f.head[f.hash(pos)])
+	for chain := 0; candidate >= 0 && pos-candidate <= lz77WindowSize && chain < f.maxChain; chain++ {
+		// Совпадение длиннее лучшего должно совпадать и в байте bestLength.
+		if f.data[candidate+bestLength] == f.data[pos+bestLength] {
+			length := 0
+			for length < maxLength && f.data[candidate+length] == f.data[pos+length] {
+				length++
+			}
+			if length > bestLength {
+				bestLength = length
+				found = append(found, lz77Match{length: length, distance: pos - candidate})
+				if length >= f.niceLength || length == maxLength {
+					break
+				}
+			}
+		}
+		candidate = int(f.prev[candidate])
+	}
+	return found
+}
+
+// longest возвращает самое длинное совпадение или совпадение нулевой длины.
+func (f *lz77MatchFinder) longest(pos int, found []lz77Match) (lz77Match, []lz77Match) {
+	found = f.matches(pos, found)
+	if len(found) == 0 {
+		return lz77Match{}, found
+	}
+	return found[len(found)-1], found
+}
@@ -0,0 +1,172 @@
+package compression
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLZ77Service_CompressAndDecompress(t *testing.T) {
+	random := make([]byte, 40000)
+	rand.New(rand.NewSource(1)).Read(random)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "empty", data: []byte{}},
+		{name: "single byte", data: []byte("a")},
+		{name: "abracadabra", data: []byte("abracadabra abracadabra abracadabra")},
+		{name: "overlap", data: bytes.Repeat([]byte("a"), 1000)},
+		{name: "text", data: []byte(strings.Repeat("Сжатие_Хаффмана просто лучшее, я вам отвечаю. слово даю!! ", 50))},
+		{name: "random", data: random},
+		{name: "far", data: append(append(bytes.Clone(random[:100]), random[:lz77WindowSize+100]...), random[:100]...)},
+	}
+	for level := MinLZ77Level; level <= MaxLZ77Level; level++ {
+		s, err := NewLZ77Service().WithLevel(level)
+		if err != nil {
+			t.Fatalf("LZ77Service.WithLevel(%d) has err = %v", level, err)
+		}
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("%d/%s", level, tt.name), func(t *testing.T) {
+				compressedData, err := s.Compress(tt.data)
+				if err != nil {
+					t.Fatalf("LZ77Service.Compress() has err = %v", err)
+				}
+				decompressedData, err := s.Decompress(compressedData)
+				if err != nil {
+					t.Fatalf("LZ77Service.Decompress() has err = %v", err)
+				}
+				if !bytes.Equal(decompressedData, tt.data) {
+					t.Errorf("LZ77Service.Decompress() differs from data")
+				}
+			})
+		}
+	}
+}
+
+func TestLZ77Service_Compress(t *testing.T) {
+	s, _ := NewLZ77Service().WithLevel(1)
+	// Литерал 'a' (0 01100001) и ссылка длины 3 на расстояние 1 (1 | 1 | 0000).
+	got, _ := s.Compress([]byte("aaaa"))
+	if want := []byte{4, 0b00110000, 0b11100000}; !reflect.DeepEqual(got, want) {
+		t.Errorf("LZ77Service.Compress() = %08b, want %08b", got, want)
+	}
+}
+
+func TestLZ77Service_Levels(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "corpus", "tolstoy.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sizes := make(map[int]int)
+	for _, level := range []int{1, 6, 9} {
+		s, _ := NewLZ77Service().WithLevel(level)
+		compressed, err := s.CompressWithDetails(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		details := compressed.Details.(LZ77Details)
+		if details.Level != level || details.Literals+details.Matches == 0 {
+			t.Errorf("level %d details = %+v", level, details)
+		}
+		sizes[level] = len(compressed.Data)
+	}
+	if sizes[9] > sizes[6] || sizes[6] > sizes[1] {
+		t.Errorf("sizes by level = %v, want non-increasing", sizes)
+	}
+}
+
+func TestLZ77Service_Empty(t *testing.T) {
+	s := NewLZ77Service()
+	compressed, err := s.CompressWithDetails(nil)
+	if err != nil {
+		t.Fatalf("LZ77Service.CompressWithDetails() has err = %v", err)
+	}
+	decompressed, err := s.DecompressWithDetails(compressed.Data)
+	if err != nil {
+		t.Fatalf("LZ77Service.DecompressWithDetails() has err = %v", err)
+	}
+	for _, details := range []any{compressed.Details, decompressed.Details} {
+		if _, err := json.Marshal(details); err != nil {
+			t.Errorf("json.Marshal(%T) has err = %v", details, err)
+		}
+	}
+}
+
+func TestLZ77Service_WithLevel(t *testing.T) {
+	for _, level := range []int{0, -1, MaxLZ77Level + 1} {
+		if _, err := NewLZ77Service().WithLevel(level); err == nil {
+			t.Errorf("LZ77Service.WithLevel(%d) has no err", level)
+		}
+	}
+}
+
+func TestLZ77Service_DecompressInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "empty", data: []byte{}},
+		{name: "truncated", data: []byte{4, 0b00110000}},
+		{name: "distance before start", data: []byte{3, 0b11000000}},
+		{name: "size too large", data: []byte{0xFF, 0xFF, 0xFF, 0x0F, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewLZ77Service().Decompress(tt.data); err == nil {
+				t.Errorf("LZ77Service.Decompress() has no err")
+			}
+		})
+	}
+}
+
+func TestLZ77Service_Trace(t *testing.T) {
+	details, err := NewLZ77Service().CompressWithTrace([]byte("aaaa"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trace := details.Details.(LZ77Details).Trace
+	want := []TraceEvent{
+		{Step: 1, Type: "token", Data: LZ77TokenEvent{Position: 0, Literal: "a", Bits: 9}},
+		{Step: 2, Type: "token", Data: LZ77TokenEvent{Position: 1, Length: 3, Distance: 1, Bits: 6}},
+	}
+	if !reflect.DeepEqual(trace.Events, want) {
+		t.Errorf("trace = %+v, want %+v", trace.Events, want)
+	}
+}
+
+// BenchmarkLZ77 сжимает каждый файл корпуса testdata/corpus на каждом уровне
+// и сообщает степень сжатия в метрике ratio.
+func BenchmarkLZ77(b *testing.B) {
+	files, err := filepath.Glob(filepath.Join("testdata", "corpus", "*"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			b.Fatal(err)
+		}
+		for level := MinLZ77Level; level <= MaxLZ77Level; level++ {
+			s, _ := NewLZ77Service().WithLevel(level)
+			b.Run(fmt.Sprintf("%s/level=%d", filepath.Base(file), level), func(b *testing.B) {
+				b.SetBytes(int64(len(data)))
+				var compressedData []byte
+				for i := 0; i < b.N; i++ {
+					compressedData, _ = s.Compress(data)
+				}
+				if len(data) > 0 {
+					b.ReportMetric(float64(len(compressedData))/float64(len(data)), "ratio")
+				}
+			})
+		}
+	}
+}
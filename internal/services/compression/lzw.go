@@ -1,15 +1,20 @@
 package compression
 
 import (
-	"encoding/binary"
+	"bytes"
 	"fmt"
-	"math"
+	"io"
 	"sort"
+	"strconv"
 	"unicode/utf8"
 
 	"github.com/PritOriginal/cryptolabs-back/pkg/bitsio"
 )
 
+// lzwMaxDictionarySize ограничивает рост словаря, чтобы потоковое сжатие
+// работало в ограниченном объёме памяти. После заполнения словарь замораживается.
+const lzwMaxDictionarySize = 1 << 16
+
 type LZW interface {
 	Compress(data []byte) ([]byte, error)
 	CompressWithDetails(data []byte) (CompressionDetails, error)
@@ -23,12 +28,76 @@ type LZWData struct {
 	data              []byte
 	dictionary        map[string]int
 	reverseDictionary map[int]string
+	usage             map[int]int
 }
 
 type LZWDetails struct {
 	Dictionary       []LZWDictionaryItem `json:"dictionary"`
 	CompressionRatio float32             `json:"compression_ratio"`
 	Size             int                 `json:"size"`
+	Trace            *Trace              `json:"trace,omitempty"`
+
+	usage map[int]int
+}
+
+// Tree возвращает префиксное дерево словаря. Вес узла — сколько раз код
+// строки был выведен; неиспользованные односимвольные строки опускаются.
+func (d LZWDetails) Tree() *TreeNode {
+	root := &TreeNode{}
+	nodes := make(map[string]*TreeNode, len(d.Dictionary))
+	for _, item := range d.Dictionary {
+		nodes[item.Val] = &TreeNode{
+			Label:  item.Val,
+			Code:   strconv.Itoa(item.Num),
+			Weight: d.usage[item.Num],
+		}
+	}
+
+	// Словарь отсортирован по номерам, а каждая строка LZW добавляется после
+	// своего префикса, поэтому родитель всегда уже находится в дереве.
+	for _, item := range d.Dictionary {
+		node := nodes[item.Val]
+		last, size := utf8.DecodeLastRuneInString(item.Val)
+		node.Edge = strconv.QuoteRune(last)
+		parent := root
+		if prefix := item.Val[:len(item.Val)-size]; prefix != "" {
+			parent = nodes[prefix]
+		}
+		if parent != nil {
+			parent.Children = append(parent.Children, node)
+		}
+	}
+
+	pruneTree(root)
+	for _, child := range root.Children {
+		root.Weight += child.Weight
+	}
+	return root
+}
+
+// pruneTree удаляет листья с нулевым весом и возвращает, остался ли узел в дереве.
+func pruneTree(node *TreeNode) bool {
+	children := node.Children[:0]
+	for _, child := range node.Children {
+		if pruneTree(child) {
+			children = append(children, child)
+		}
+	}
+	node.Children = children
+	return node.Weight > 0 || len(node.Children) > 0
+}
+
+// LZWEmitEvent — вывод кода самой длинной найденной в словаре строки.
+type LZWEmitEvent struct {
+	Val     string `json:"value"`
+	Code    int    `json:"code"`
+	SizeBit int    `json:"size_bit"`
+}
+
+// LZWInsertEvent — добавление новой строки в словарь.
+type LZWInsertEvent struct {
+	Val  string `json:"value"`
+	Code int    `json:"code"`
 }
 
 type LZWDictionaryItem struct {
@@ -41,12 +110,27 @@ func NewLZWService() *LZWService {
 }
 
 func (l *LZWService) Compress(data []byte) ([]byte, error) {
-	compressedData := l.compressData(data)
+	compressedData, err := l.compressData(data, nil)
+	if err != nil {
+		return nil, err
+	}
 	return compressedData.data, nil
 }
 
 func (l *LZWService) CompressWithDetails(data []byte) (CompressionDetails, error) {
-	lzwData := l.compressData(data)
+	return l.compressWithDetails(data, nil)
+}
+
+// CompressWithTrace сжимает данные, записывая выводимые коды и пополнение словаря.
+func (l *LZWService) CompressWithTrace(data []byte, limit int) (CompressionDetails, error) {
+	return l.compressWithDetails(data, NewTrace(limit))
+}
+
+func (l *LZWService) compressWithDetails(data []byte, trace *Trace) (CompressionDetails, error) {
+	lzwData, err := l.compressData(data, trace)
+	if err != nil {
+		return CompressionDetails{}, err
+	}
 
 	dictionaryList := l.dictionaryToList(lzwData.dictionary)
 
@@ -56,20 +140,29 @@ func (l *LZWService) CompressWithDetails(data []byte) (CompressionDetails, error
 			Dictionary:       dictionaryList,
 			CompressionRatio: 1 - float32(len(lzwData.data))/float32(len(data)),
 			Size:             len(lzwData.data),
+			Trace:            trace,
+			usage:            lzwData.usage,
 		},
 	}
 	return lzwDetails, nil
 }
 
-func (l *LZWService) compressData(data []byte) LZWData {
-	dataStr := string(data)
-	dictionary := l.makeDictionary()
-	compressedData := l.compress(dataStr, dictionary)
+func (l *LZWService) compressData(data []byte, trace *Trace) (LZWData, error) {
+	buf := new(bytes.Buffer)
+	lw := l.newWriter(buf)
+	lw.trace = trace
+	if _, err := lw.Write(data); err != nil {
+		return LZWData{}, err
+	}
+	if err := lw.Close(); err != nil {
+		return LZWData{}, err
+	}
 
 	return LZWData{
-		data:       compressedData,
-		dictionary: dictionary,
-	}
+		data:       buf.Bytes(),
+		dictionary: lw.dictionary,
+		usage:      lw.usage,
+	}, nil
 }
 
 func (l *LZWService) makeDictionary() map[string]int {
@@ -84,58 +177,6 @@ func (l *LZWService) makeDictionary() map[string]int {
 	return dictionary
 }
 
-func (l *LZWService) compress(dataStr string, dictionary map[string]int) []byte {
-	bitWriter := bitsio.NewBitWriter()
-	sizeBit := 9
-
-	writeCode := func(s string) {
-		codeStr := fmt.Sprintf("%b", dictionary[s])
-		for range sizeBit - len(codeStr) {
-			bitWriter.WriteBit(false)
-		}
-		for _, code_ch := range codeStr {
-			if code_ch == '1' {
-				bitWriter.WriteBit(true)
-			} else {
-				bitWriter.WriteBit(false)
-			}
-		}
-	}
-
-	s := ""
-	for _, ch := range dataStr {
-		newStr := s + string(ch)
-		if _, exsist := dictionary[newStr]; exsist {
-			s += string(ch)
-		} else {
-			writeCode(s)
-			dictionary[newStr] = len(dictionary)
-			if len(dictionary) > int(math.Pow(2, float64(sizeBit))) {
-				sizeBit++
-			}
-
-			s = string(ch)
-		}
-	}
-	writeCode(s)
-
-	return bitWriter.Bytes()
-}
-
-func (l *LZWService) writeCode(bitWriter *bitsio.BitWriter, sizeBit, code int) {
-	codeStr := fmt.Sprintf("%b", code)
-	for range sizeBit - len(codeStr) {
-		bitWriter.WriteBit(false)
-	}
-	for _, code_ch := range codeStr {
-		if code_ch == '1' {
-			bitWriter.WriteBit(true)
-		} else {
-			bitWriter.WriteBit(false)
-		}
-	}
-}
-
 func (l *LZWService) dictionaryToList(dictionary map[string]int) []LZWDictionaryItem {
 	list := make([]LZWDictionaryItem, 0, len(dictionary))
 	for val, num := range dictionary {
@@ -185,21 +226,23 @@ func (l *LZWService) DecompressWithDetails(compressedData []byte) (CompressionDe
 			Dictionary:       dictionaryList,
 			CompressionRatio: 1 - float32(len(compressedData))/float32(len(lzwData.data)),
 			Size:             len(lzwData.data),
+			usage:            lzwData.usage,
 		},
 	}
 	return lzwDetails, nil
 }
 
 func (l *LZWService) decompressData(compressedData []byte) (LZWData, error) {
-	dictionary := l.makeReverseDictionary()
-	data, err := l.decompress(compressedData, dictionary)
+	lr := l.newReader(bytes.NewReader(compressedData))
+	data, err := readAll(lr)
 	if err != nil {
 		return LZWData{}, err
 	}
 
 	lzwData := LZWData{
 		data:              data,
-		reverseDictionary: dictionary,
+		reverseDictionary: lr.dictionary,
+		usage:             lr.usage,
 	}
 	return lzwData, nil
 }
@@ -216,68 +259,211 @@ func (l *LZWService) makeReverseDictionary() map[int]string {
 	return dictionary
 }
 
-func (l *LZWService) decompress(compressedData []byte, dictionary map[int]string) ([]byte, error) {
-	bitReader := bitsio.NewBitReader(compressedData)
-	sizeBit := 9
+// NewWriter возвращает потоковый компрессор: данные, записанные в него,
+// сжимаются и по мере готовности передаются в w. Close дописывает последний код.
+func (l *LZWService) NewWriter(w io.Writer) io.WriteCloser {
+	return l.newWriter(w)
+}
+
+func (l *LZWService) newWriter(w io.Writer) *lzwWriter {
+	return &lzwWriter{
+		bw:         bitsio.NewWriter(w, bitsio.MSBFirst),
+		dictionary: l.makeDictionary(),
+		usage:      make(map[int]int),
+		sizeBit:    9,
+	}
+}
 
-	prevcode, _ := l.readCode(bitReader, sizeBit)
-	s := dictionary[prevcode]
+// NewReader возвращает потоковый декомпрессор данных, сжатых LZW.
+func (l *LZWService) NewReader(r io.Reader) io.Reader {
+	return l.newReader(r)
+}
 
-	c, _ := utf8.DecodeRuneInString(s)
-	// c := s[0]
+func (l *LZWService) newReader(r io.Reader) *lzwReader {
+	return &lzwReader{
+		br:         bitsio.NewReader(r, bitsio.MSBFirst),
+		dictionary: l.makeReverseDictionary(),
+		usage:      make(map[int]int),
+		sizeBit:    9,
+	}
+}
 
-	data := make([]byte, 0)
-	data = append(data, []byte(s)...)
-	// dataStr := s
-	// sizeBit++
-	for !bitReader.IsEmpty() {
-		code, err := l.readCode(bitReader, sizeBit)
-		if err != nil {
-			break
-		}
+type lzwWriter struct {
+	bw         *bitsio.BitWriter
+	dictionary map[string]int
+	usage      map[int]int
+	s          string
+	pending    []byte // неполная руна, оставшаяся от предыдущего Write
+	sizeBit    int
+	err        error
+	trace      *Trace
+}
+
+func (lw *lzwWriter) Write(p []byte) (int, error) {
+	if lw.err != nil {
+		return 0, lw.err
+	}
 
-		if _, exist := dictionary[code]; !exist {
-			s = dictionary[prevcode]
-			s = s + string(c)
-		} else {
-			s = dictionary[code]
+	lw.pending = append(lw.pending, p...)
+	i := 0
+	for i < len(lw.pending) && utf8.FullRune(lw.pending[i:]) {
+		ch, size := utf8.DecodeRune(lw.pending[i:])
+		if ch == utf8.RuneError && size == 1 {
+			lw.err = ErrInvalidUTF8
+			return 0, lw.err
 		}
+		if _, exist := lw.dictionary[string(ch)]; !exist {
+			lw.err = fmt.Errorf("%w: %q", ErrUnsupportedSymbol, ch)
+			return 0, lw.err
+		}
+		if err := lw.writeRune(ch); err != nil {
+			lw.err = err
+			return 0, err
+		}
+		i += size
+	}
+	lw.pending = append(lw.pending[:0], lw.pending[i:]...)
+	return len(p), nil
+}
+
+func (lw *lzwWriter) writeRune(ch rune) error {
+	newStr := lw.s + string(ch)
+	if _, exist := lw.dictionary[newStr]; exist {
+		lw.s = newStr
+		return nil
+	}
 
-		data = append(data, []byte(s)...)
-		// dataStr += s
-		c, _ = utf8.DecodeRuneInString(s)
-		dictionary[len(dictionary)] = dictionary[prevcode] + string(c)
-		if len(dictionary) >= int(math.Pow(2, float64(sizeBit))) {
-			sizeBit++
+	if err := lw.writeCode(lw.s); err != nil {
+		return err
+	}
+	if len(lw.dictionary) < lzwMaxDictionarySize {
+		if lw.trace != nil && !lw.trace.Skip() {
+			lw.trace.Add("insert", LZWInsertEvent{Val: newStr, Code: len(lw.dictionary)})
+		}
+		lw.dictionary[newStr] = len(lw.dictionary)
+		if len(lw.dictionary) > 1<<lw.sizeBit {
+			lw.sizeBit++
 		}
+	}
+	lw.s = string(ch)
+	return nil
+}
+
+func (lw *lzwWriter) writeCode(s string) error {
+	code := lw.dictionary[s]
+	lw.usage[code]++
+	if lw.trace != nil && !lw.trace.Skip() {
+		lw.trace.Add("emit", LZWEmitEvent{Val: s, Code: code, SizeBit: lw.sizeBit})
+	}
+	return lw.bw.WriteBits(uint64(code), lw.sizeBit)
+}
+
+func (lw *lzwWriter) Close() error {
+	if lw.err != nil {
+		return lw.err
+	}
 
-		prevcode = code
+	// Оборванная в конце последовательность байт не является символом UTF-8.
+	if len(lw.pending) > 0 {
+		lw.err = ErrInvalidUTF8
+		return lw.err
 	}
 
-	return data, nil
-	// return []byte(dataStr), nil
+	if lw.s != "" {
+		if err := lw.writeCode(lw.s); err != nil {
+			lw.err = err
+			return err
+		}
+		lw.s = ""
+	}
+	lw.err = lw.bw.Flush()
+	return lw.err
 }
 
-func (l *LZWService) readCode(bitReader *bitsio.BitReader, sizeBit int) (int, error) {
-	bitWriter := bitsio.NewBitWriter()
+type lzwReader struct {
+	br         *bitsio.BitReader
+	dictionary map[int]string
+	usage      map[int]int
+	sizeBit    int
+	prevcode   int
+	c          rune
+	started    bool
+	out        []byte
+	err        error
+}
 
-	for range (64 - sizeBit) / 8 {
-		bitWriter.WriteByte(0x0)
+func (lr *lzwReader) Read(p []byte) (int, error) {
+	for len(lr.out) == 0 {
+		if lr.err != nil {
+			return 0, lr.err
+		}
+		lr.decodeNext()
 	}
 
-	numZeroBits := 8 - sizeBit%8
-	if numZeroBits < 8 {
-		for range numZeroBits {
-			bitWriter.WriteBit(false)
+	n := copy(p, lr.out)
+	lr.out = lr.out[n:]
+	return n, nil
+}
+
+func (lr *lzwReader) decodeNext() {
+	code, err := lr.readCode()
+	if err != nil {
+		lr.err = err
+		return
+	}
+	lr.usage[code]++
+
+	if !lr.started {
+		lr.started = true
+		s, exist := lr.dictionary[code]
+		if !exist {
+			lr.err = fmt.Errorf("%w: unknown lzw code %d", ErrCorrupt, code)
+			return
 		}
+		lr.c, _ = utf8.DecodeRuneInString(s)
+		lr.out = append(lr.out, s...)
+		lr.prevcode = code
+		return
+	}
+
+	// Код ещё не добавленной в словарь строки допустим, только если это
+	// следующий свободный код (случай строки вида cScSc).
+	var s string
+	if _, exist := lr.dictionary[code]; exist {
+		s = lr.dictionary[code]
+	} else if code == len(lr.dictionary) && len(lr.dictionary) < lzwMaxDictionarySize {
+		s = lr.dictionary[lr.prevcode] + string(lr.c)
+	} else {
+		lr.err = fmt.Errorf("%w: unknown lzw code %d", ErrCorrupt, code)
+		return
 	}
-	for range sizeBit {
-		if bitReader.IsEmpty() {
-			return 0, fmt.Errorf("err")
+	lr.out = append(lr.out, s...)
+
+	lr.c, _ = utf8.DecodeRuneInString(s)
+	if len(lr.dictionary) < lzwMaxDictionarySize {
+		lr.dictionary[len(lr.dictionary)] = lr.dictionary[lr.prevcode] + string(lr.c)
+		if len(lr.dictionary) >= 1<<lr.sizeBit && len(lr.dictionary) < lzwMaxDictionarySize {
+			lr.sizeBit++
 		}
-		bitWriter.WriteBit(bitReader.ReadBit())
 	}
 
-	code := int(binary.BigEndian.Uint64(bitWriter.Bytes()))
-	return code, nil
+	lr.prevcode = code
+}
+
+// readCode читает очередной код. Неполный код в конце потока — это
+// выравнивание последнего байта, поэтому в этом случае возвращается io.EOF.
+// Выравнивание короче байта; если не хватает большего числа бит, данные оборваны.
+func (lr *lzwReader) readCode() (int, error) {
+	code, err := lr.br.ReadBits(lr.sizeBit)
+	if err == bitsio.ErrUnexpectedEOF {
+		lr.br.Align()
+		if lr.br.IsEmpty() {
+			return 0, io.EOF
+		}
+		return 0, ErrTruncated
+	}
+	if err != nil {
+		return 0, err
+	}
+	return int(code), nil
 }
@@ -0,0 +1,390 @@
+package compression
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"unicode/utf8"
+)
+
+// Интервальное кодирование (range coder) с адаптивной моделью нулевого порядка.
+// В отличие от ArithmeticService оно работает с целыми числами фиксированной
+// разрядности, поэтому данные можно кодировать потоком, не зная их длины заранее.
+// Конец потока обозначается отдельным символом rcEOF.
+
+type RangeCoder interface {
+	Compress(data []byte) ([]byte, error)
+	CompressWithDetails(data []byte) (CompressionDetails, error)
+	Decompress(compressedData []byte) ([]byte, error)
+}
+
+type RangeCoderService struct {
+}
+
+type RangeCoderDetails struct {
+	FrequencyTable   []FrequencyTableItem `json:"frequency_table"`
+	CompressionRatio float32              `json:"compression_ratio"`
+	Size             int                  `json:"size"`
+	Trace            *Trace               `json:"trace,omitempty"`
+}
+
+// RangeEncodeEvent — кодирование символа: его интервал в модели
+// и состояние кодера после сужения и нормализации.
+type RangeEncodeEvent struct {
+	Symbol  string `json:"symbol"`
+	CumFreq uint32 `json:"cum_freq"`
+	Freq    uint32 `json:"freq"`
+	TotFreq uint32 `json:"tot_freq"`
+	Low     uint32 `json:"low"`
+	Range   uint32 `json:"range"`
+	Shifted int    `json:"shifted_bytes"`
+}
+
+func NewRangeCoderService() *RangeCoderService {
+	return &RangeCoderService{}
+}
+
+const (
+	rcTop = 1 << 24
+	rcBot = 1 << 16
+
+	rcEOF        = 256
+	rcNumSymbols = 257
+
+	rcIncrement = 32
+	rcMaxTotal  = rcBot - 1
+)
+
+func (s *RangeCoderService) Compress(data []byte) ([]byte, error) {
+	return s.compress(data, nil)
+}
+
+func (s *RangeCoderService) compress(data []byte, trace *Trace) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	rw := s.newWriter(buf)
+	rw.trace = trace
+	if _, err := rw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := rw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *RangeCoderService) CompressWithDetails(data []byte) (CompressionDetails, error) {
+	return s.compressWithDetails(data, nil)
+}
+
+// CompressWithTrace сжимает данные, записывая состояние кодера после каждого символа.
+func (s *RangeCoderService) CompressWithTrace(data []byte, limit int) (CompressionDetails, error) {
+	return s.compressWithDetails(data, NewTrace(limit))
+}
+
+func (s *RangeCoderService) compressWithDetails(data []byte, trace *Trace) (CompressionDetails, error) {
+	compressedData, err := s.compress(data, trace)
+	if err != nil {
+		return CompressionDetails{}, err
+	}
+
+	rangeCoderDetails := CompressionDetails{
+		Data: compressedData,
+		Details: RangeCoderDetails{
+			FrequencyTable:   s.frequencyTableToList(data),
+			CompressionRatio: ratio(len(compressedData), len(data)),
+			Size:             len(compressedData),
+			Trace:            trace,
+		},
+	}
+	return rangeCoderDetails, nil
+}
+
+func (s *RangeCoderService) Decompress(compressedData []byte) ([]byte, error) {
+	return readAll(s.NewReader(bytes.NewReader(compressedData)))
+}
+
+func (s *RangeCoderService) DecompressWithDetails(compressedData []byte) (CompressionDetails, error) {
+	data, err := s.Decompress(compressedData)
+	if err != nil {
+		return CompressionDetails{}, err
+	}
+
+	rangeCoderDetails := CompressionDetails{
+		Data: data,
+		Details: RangeCoderDetails{
+			FrequencyTable:   s.frequencyTableToList(data),
+			CompressionRatio: ratio(len(compressedData), len(data)),
+			Size:             len(data),
+		},
+	}
+	return rangeCoderDetails, nil
+}
+
+func (s *RangeCoderService) frequencyTableToList(data []byte) []FrequencyTableItem {
+	var frequencies [256]int
+	for _, b := range data {
+		frequencies[b]++
+	}
+
+	list := make([]FrequencyTableItem, 0)
+	for b, frequency := range frequencies {
+		if frequency == 0 {
+			continue
+		}
+		item := FrequencyTableItem{
+			Val:       byteToString(byte(b)),
+			Frequency: frequency,
+		}
+		list = append(list, item)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Frequency > list[j].Frequency
+	})
+	return list
+}
+
+// byteToString возвращает печатное представление байта: ASCII-символ
+// или шестнадцатеричную запись для остальных значений.
+func byteToString(b byte) string {
+	if b < utf8.RuneSelf {
+		return string(rune(b))
+	}
+	return fmt.Sprintf("0x%02X", b)
+}
+
+// NewWriter возвращает потоковый компрессор. Close кодирует символ конца
+// потока и сбрасывает состояние кодера.
+func (s *RangeCoderService) NewWriter(w io.Writer) io.WriteCloser {
+	return s.newWriter(w)
+}
+
+func (s *RangeCoderService) newWriter(w io.Writer) *rangeWriter {
+	return &rangeWriter{w: w, model: newRangeModel(), rng: 0xFFFFFFFF}
+}
+
+// NewReader возвращает потоковый декомпрессор.
+func (s *RangeCoderService) NewReader(r io.Reader) io.Reader {
+	return &rangeReader{r: bufio.NewReader(r), model: newRangeModel(), rng: 0xFFFFFFFF}
+}
+
+// rangeModel — адаптивная таблица частот. После каждого символа его частота
+// увеличивается, а при переполнении все частоты делятся пополам.
+type rangeModel struct {
+	freq  [rcNumSymbols]uint32
+	total uint32
+}
+
+func newRangeModel() *rangeModel {
+	m := &rangeModel{}
+	for i := range m.freq {
+		m.freq[i] = 1
+	}
+	m.total = rcNumSymbols
+	return m
+}
+
+func (m *rangeModel) cumFreq(symbol int) uint32 {
+	var cum uint32
+	for _, f := range m.freq[:symbol] {
+		cum += f
+	}
+	return cum
+}
+
+// find возвращает символ, в интервал которого попадает count, и начало этого интервала.
+func (m *rangeModel) find(count uint32) (int, uint32) {
+	var cum uint32
+	for symbol, f := range m.freq {
+		if count < cum+f {
+			return symbol, cum
+		}
+		cum += f
+	}
+	return rcNumSymbols - 1, cum - m.freq[rcNumSymbols-1]
+}
+
+func (m *rangeModel) update(symbol int) {
+	m.freq[symbol] += rcIncrement
+	m.total += rcIncrement
+	if m.total > rcMaxTotal {
+		m.total = 0
+		for i := range m.freq {
+			m.freq[i] = (m.freq[i] + 1) / 2
+			m.total += m.freq[i]
+		}
+	}
+}
+
+type rangeWriter struct {
+	w     io.Writer
+	model *rangeModel
+	low   uint32
+	rng   uint32
+	out   []byte
+	err   error
+	trace *Trace
+}
+
+func (rw *rangeWriter) Write(p []byte) (int, error) {
+	if rw.err != nil {
+		return 0, rw.err
+	}
+
+	for _, b := range p {
+		rw.encodeSymbol(int(b))
+	}
+
+	if len(rw.out) >= streamFlushSize {
+		if err := rw.flush(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (rw *rangeWriter) encodeSymbol(symbol int) {
+	cumFreq, freq, totFreq := rw.model.cumFreq(symbol), rw.model.freq[symbol], rw.model.total
+	n := len(rw.out)
+	rw.encode(cumFreq, freq, totFreq)
+	rw.model.update(symbol)
+
+	if rw.trace != nil && !rw.trace.Skip() {
+		val := "EOF"
+		if symbol != rcEOF {
+			val = byteToString(byte(symbol))
+		}
+		rw.trace.Add("encode", RangeEncodeEvent{
+			Symbol:  val,
+			CumFreq: cumFreq,
+			Freq:    freq,
+			TotFreq: totFreq,
+			Low:     rw.low,
+			Range:   rw.rng,
+			Shifted: len(rw.out) - n,
+		})
+	}
+}
+
+func (rw *rangeWriter) encode(cumFreq, freq, totFreq uint32) {
+	rw.rng /= totFreq
+	rw.low += cumFreq * rw.rng
+	rw.rng *= freq
+
+	for {
+		if rw.low^(rw.low+rw.rng) >= rcTop {
+			if rw.rng >= rcBot {
+				break
+			}
+			rw.rng = -rw.low & (rcBot - 1)
+		}
+		rw.out = append(rw.out, byte(rw.low>>24))
+		rw.low <<= 8
+		rw.rng <<= 8
+	}
+}
+
+func (rw *rangeWriter) flush() error {
+	if len(rw.out) == 0 {
+		return nil
+	}
+	_, rw.err = rw.w.Write(rw.out)
+	rw.out = rw.out[:0]
+	return rw.err
+}
+
+func (rw *rangeWriter) Close() error {
+	if rw.err != nil {
+		return rw.err
+	}
+
+	rw.encodeSymbol(rcEOF)
+	for range 4 {
+		rw.out = append(rw.out, byte(rw.low>>24))
+		rw.low <<= 8
+	}
+	return rw.flush()
+}
+
+type rangeReader struct {
+	r       io.ByteReader
+	model   *rangeModel
+	low     uint32
+	rng     uint32
+	code    uint32
+	started bool
+	err     error
+}
+
+func (rr *rangeReader) Read(p []byte) (int, error) {
+	if rr.err != nil {
+		return 0, rr.err
+	}
+	if !rr.started {
+		rr.started = true
+		for range 4 {
+			if err := rr.shiftByte(); err != nil {
+				rr.err = err
+				return 0, err
+			}
+		}
+	}
+
+	n := 0
+	for n < len(p) {
+		symbol, err := rr.decodeSymbol()
+		if err != nil {
+			rr.err = err
+			break
+		}
+		if symbol == rcEOF {
+			rr.err = io.EOF
+			break
+		}
+		p[n] = byte(symbol)
+		n++
+	}
+
+	if n == 0 {
+		return 0, rr.err
+	}
+	return n, nil
+}
+
+func (rr *rangeReader) decodeSymbol() (int, error) {
+	rr.rng /= rr.model.total
+	count := (rr.code - rr.low) / rr.rng
+	if count >= rr.model.total {
+		return 0, fmt.Errorf("%w: symbol is out of range", ErrCorrupt)
+	}
+
+	symbol, cumFreq := rr.model.find(count)
+	rr.low += cumFreq * rr.rng
+	rr.rng *= rr.model.freq[symbol]
+	rr.model.update(symbol)
+
+	for {
+		if rr.low^(rr.low+rr.rng) >= rcTop {
+			if rr.rng >= rcBot {
+				break
+			}
+			rr.rng = -rr.low & (rcBot - 1)
+		}
+		if err := rr.shiftByte(); err != nil {
+			return 0, err
+		}
+		rr.low <<= 8
+		rr.rng <<= 8
+	}
+	return symbol, nil
+}
+
+func (rr *rangeReader) shiftByte() error {
+	b, err := rr.r.ReadByte()
+	if err != nil {
+		return truncated(err)
+	}
+	rr.code = rr.code<<8 | uint32(b)
+	return nil
+}
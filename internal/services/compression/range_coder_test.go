@@ -0,0 +1,79 @@
+package compression
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestRangeCoderService_CompressAndDecompress(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{
+			name: "empty",
+			data: []byte{},
+		},
+		{
+			name: "",
+			data: []byte("интервальное кодирование"),
+		},
+		{
+			name: "",
+			data: []byte("AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAB"),
+		},
+		{
+			name: "binary",
+			data: []byte{0x00, 0xFF, 0x10, 0x00, 0x00, 0x80, 0xFE, 0x01},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &RangeCoderService{}
+			compressedData, err := s.Compress(tt.data)
+			if err != nil {
+				t.Errorf("RangeCoderService.Compress() has err = %v", err.Error())
+				return
+			}
+			decompressedData, err := s.Decompress(compressedData)
+			if err != nil {
+				t.Errorf("RangeCoderService.Decompress() has err = %v", err.Error())
+				return
+			}
+			if !reflect.DeepEqual(decompressedData, tt.data) {
+				t.Errorf("RangeCoderService.Compress() = %v, want %v", decompressedData, tt.data)
+			}
+		})
+	}
+}
+
+func TestRangeCoderService_DecompressTruncated(t *testing.T) {
+	s := &RangeCoderService{}
+	compressedData, err := s.Compress([]byte("интервальное кодирование"))
+	if err != nil {
+		t.Fatalf("RangeCoderService.Compress() has err = %v", err)
+	}
+	if _, err := s.Decompress(compressedData[:len(compressedData)-3]); !errors.Is(err, ErrTruncated) {
+		t.Errorf("RangeCoderService.Decompress() of truncated data has err = %v, want %v", err, ErrTruncated)
+	}
+}
+
+func TestRangeCoderService_Empty(t *testing.T) {
+	s := NewRangeCoderService()
+	compressed, err := s.CompressWithDetails(nil)
+	if err != nil {
+		t.Fatalf("RangeCoderService.CompressWithDetails() has err = %v", err)
+	}
+	decompressed, err := s.DecompressWithDetails(compressed.Data)
+	if err != nil {
+		t.Fatalf("RangeCoderService.DecompressWithDetails() has err = %v", err)
+	}
+	for _, details := range []any{compressed.Details, decompressed.Details} {
+		if _, err := json.Marshal(details); err != nil {
+			t.Errorf("json.Marshal(%T) has err = %v", details, err)
+		}
+	}
+}
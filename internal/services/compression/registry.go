@@ -0,0 +1,53 @@
+package compression
+
+import "fmt"
+
+// Реестр алгоритмов сжатия. Идентификаторы записываются в архивы,
+// поэтому их нельзя менять или использовать повторно.
+
+// Service — алгоритм сжатия с отчётами о сжатии и разжатии.
+type Service interface {
+	Compress(data []byte) ([]byte, error)
+	CompressWithDetails(data []byte) (CompressionDetails, error)
+	Decompress(compressedData []byte) ([]byte, error)
+	DecompressWithDetails(data []byte) (CompressionDetails, error)
+}
+
+type Algorithm struct {
+	ID      uint8
+	Name    string
+	Service Service
+}
+
+var algorithms = []Algorithm{
+	{ID: 1, Name: "rle", Service: NewRLEService()},
+	{ID: 2, Name: "huffman", Service: NewHuffmanService()},
+	{ID: 3, Name: "shannon_fano", Service: NewShannonFanoService()},
+	{ID: 4, Name: "arithmetic", Service: NewArithmeticService()},
+	{ID: 5, Name: "lzw", Service: NewLZWService()},
+	{ID: 6, Name: "range", Service: NewRangeCoderService()},
+	{ID: 7, Name: "lz77", Service: NewLZ77Service()},
+}
+
+// Algorithms возвращает все зарегистрированные алгоритмы.
+func Algorithms() []Algorithm {
+	return append([]Algorithm(nil), algorithms...)
+}
+
+func AlgorithmByName(name string) (Algorithm, error) {
+	for _, algorithm := range algorithms {
+		if algorithm.Name == name {
+			return algorithm, nil
+		}
+	}
+	return Algorithm{}, fmt.Errorf("unknown algorithm %q", name)
+}
+
+func AlgorithmByID(id uint8) (Algorithm, error) {
+	for _, algorithm := range algorithms {
+		if algorithm.ID == id {
+			return algorithm, nil
+		}
+	}
+	return Algorithm{}, fmt.Errorf("unknown algorithm %d", id)
+}
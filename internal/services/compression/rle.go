@@ -1,8 +1,10 @@
 package compression
 
 import (
+	"bufio"
 	"bytes"
 	"fmt"
+	"io"
 	"strconv"
 )
 
@@ -18,6 +20,14 @@ type RLEService struct {
 type RLEDetails struct {
 	CompressionRatio float32 `json:"compression_ratio"`
 	Size             int     `json:"size"`
+	Trace            *Trace  `json:"trace,omitempty"`
+}
+
+// RLERunEvent — найденная серия одинаковых байт.
+type RLERunEvent struct {
+	Char   string `json:"char"`
+	Count  int    `json:"count"`
+	Output string `json:"output"`
 }
 
 func NewRLEService() *RLEService {
@@ -25,31 +35,32 @@ func NewRLEService() *RLEService {
 }
 
 func (s *RLEService) Compress(data []byte) ([]byte, error) {
-	compressedData := make([]byte, 0)
-
-	add := func(counter int, char byte) {
-		compressedData = append(compressedData, []byte(strconv.Itoa(counter))...)
-		compressedData = append(compressedData, char)
-	}
-
-	char := data[0]
-	counter := 1
-	for _, c := range data[1:] {
-		if c == char {
-			counter++
-		} else {
-			add(counter, char)
-			char = c
-			counter = 1
-		}
-	}
-	add(counter, char)
+	return s.compress(data, nil)
+}
 
-	return compressedData, nil
+func (s *RLEService) compress(data []byte, trace *Trace) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	rw := &rleWriter{w: buf, trace: trace}
+	if _, err := rw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := rw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
 }
 
 func (s *RLEService) CompressWithDetails(data []byte) (CompressionDetails, error) {
-	compressedData, err := s.Compress(data)
+	return s.compressWithDetails(data, nil)
+}
+
+// CompressWithTrace сжимает данные, записывая каждую найденную серию.
+func (s *RLEService) CompressWithTrace(data []byte, limit int) (CompressionDetails, error) {
+	return s.compressWithDetails(data, NewTrace(limit))
+}
+
+func (s *RLEService) compressWithDetails(data []byte, trace *Trace) (CompressionDetails, error) {
+	compressedData, err := s.compress(data, trace)
 	if err != nil {
 		return CompressionDetails{}, err
 	}
@@ -59,48 +70,14 @@ func (s *RLEService) CompressWithDetails(data []byte) (CompressionDetails, error
 		Details: RLEDetails{
 			CompressionRatio: 1 - float32(len(compressedData))/float32(len(data)),
 			Size:             len(compressedData),
+			Trace:            trace,
 		},
 	}
 	return rleDetalis, nil
 }
 
 func (s *RLEService) Decompress(compressedData []byte) ([]byte, error) {
-	data := make([]byte, 0)
-
-	const (
-		GET_COUNTER = iota
-		GET_BYTE
-	)
-
-	counter := 1
-	stepDecompress := 0
-	buffer := bytes.NewBufferString("")
-	for _, c := range compressedData {
-		if _, err := strconv.Atoi(string(c)); err == nil {
-			if stepDecompress == GET_BYTE {
-				stepDecompress = GET_COUNTER
-			}
-			buffer.WriteByte(c)
-		} else if stepDecompress == GET_COUNTER {
-			stepDecompress = GET_BYTE
-			counter, err = strconv.Atoi(buffer.String())
-			if err != nil {
-				return data, err
-			}
-			buffer.Reset()
-
-			for i := 0; i < counter; i++ {
-				data = append(data, c)
-			}
-		} else {
-			return data, fmt.Errorf("invalid data")
-		}
-	}
-	if stepDecompress == GET_COUNTER {
-		return data, fmt.Errorf("invalid data")
-	}
-
-	return data, nil
+	return readAll(s.NewReader(bytes.NewReader(compressedData)))
 }
 
 func (s *RLEService) DecompressWithDetails(compressedData []byte) (CompressionDetails, error) {
@@ -118,3 +95,182 @@ func (s *RLEService) DecompressWithDetails(compressedData []byte) (CompressionDe
 	}
 	return rleDetalis, nil
 }
+
+// NewWriter возвращает потоковый RLE-компрессор. Серия, начатая в одном
+// вызове Write, может продолжаться в следующем; Close записывает последнюю серию.
+func (s *RLEService) NewWriter(w io.Writer) io.WriteCloser {
+	return &rleWriter{w: w}
+}
+
+// NewReader возвращает потоковый RLE-декомпрессор. Длинные серии
+// разворачиваются по частям, не занимая память целиком.
+func (s *RLEService) NewReader(r io.Reader) io.Reader {
+	return &rleReader{r: bufio.NewReader(r)}
+}
+
+type rleWriter struct {
+	w       io.Writer
+	char    byte
+	counter int
+	out     []byte
+	err     error
+	trace   *Trace
+}
+
+func (rw *rleWriter) Write(p []byte) (int, error) {
+	if rw.err != nil {
+		return 0, rw.err
+	}
+
+	for _, c := range p {
+		if rw.counter > 0 && c == rw.char {
+			rw.counter++
+			continue
+		}
+		if rw.counter > 0 {
+			rw.add()
+		}
+		rw.char = c
+		rw.counter = 1
+	}
+
+	if len(rw.out) >= streamFlushSize {
+		if err := rw.flush(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (rw *rleWriter) add() {
+	start := len(rw.out)
+	rw.out = strconv.AppendInt(rw.out, int64(rw.counter), 10)
+	if rleNeedsEscape(rw.char) {
+		rw.out = append(rw.out, rleEscape)
+	}
+	rw.out = append(rw.out, rw.char)
+
+	if rw.trace != nil && !rw.trace.Skip() {
+		output := ""
+		for _, b := range rw.out[start:] {
+			output += byteToString(b)
+		}
+		rw.trace.Add("run", RLERunEvent{
+			Char:   byteToString(rw.char),
+			Count:  rw.counter,
+			Output: output,
+		})
+	}
+}
+
+// rleEscape предваряет байт серии, который иначе был бы прочитан как цифра
+// счётчика. В UTF-8 байт 0xFF не встречается, поэтому для текста формат не меняется.
+const rleEscape = 0xFF
+
+func rleNeedsEscape(c byte) bool {
+	return c >= '0' && c <= '9' || c == rleEscape
+}
+
+func (rw *rleWriter) flush() error {
+	if len(rw.out) == 0 {
+		return nil
+	}
+	_, rw.err = rw.w.Write(rw.out)
+	rw.out = rw.out[:0]
+	return rw.err
+}
+
+func (rw *rleWriter) Close() error {
+	if rw.err != nil {
+		return rw.err
+	}
+	if rw.counter > 0 {
+		rw.add()
+		rw.counter = 0
+	}
+	return rw.flush()
+}
+
+// rleMaxCounterDigits ограничивает длину счётчика, чтобы не копить
+// в памяти бесконечную последовательность цифр.
+const rleMaxCounterDigits = 18
+
+type rleReader struct {
+	r       io.ByteReader
+	digits  []byte
+	char    byte
+	counter int
+	err     error
+}
+
+func (rr *rleReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if rr.counter > 0 {
+			k := min(rr.counter, len(p)-n)
+			for i := range k {
+				p[n+i] = rr.char
+			}
+			n += k
+			rr.counter -= k
+			continue
+		}
+		if rr.err != nil {
+			break
+		}
+		rr.readRun()
+	}
+
+	if n == 0 && rr.err != nil {
+		return 0, rr.err
+	}
+	return n, nil
+}
+
+func (rr *rleReader) readRun() {
+	for {
+		c, err := rr.r.ReadByte()
+		if err == io.EOF {
+			if len(rr.digits) > 0 {
+				rr.err = ErrTruncated
+			} else {
+				rr.err = io.EOF
+			}
+			return
+		}
+		if err != nil {
+			rr.err = err
+			return
+		}
+
+		if c >= '0' && c <= '9' {
+			if len(rr.digits) == rleMaxCounterDigits {
+				rr.err = fmt.Errorf("%w: run counter is too long", ErrCorrupt)
+				return
+			}
+			rr.digits = append(rr.digits, c)
+			continue
+		}
+
+		if len(rr.digits) == 0 {
+			rr.err = fmt.Errorf("%w: run without counter", ErrCorrupt)
+			return
+		}
+		if c == rleEscape {
+			c, err = rr.r.ReadByte()
+			if err != nil {
+				rr.err = truncated(err)
+				return
+			}
+		}
+		counter, err := strconv.Atoi(string(rr.digits))
+		if err != nil || counter == 0 {
+			rr.err = fmt.Errorf("%w: invalid run counter %q", ErrCorrupt, rr.digits)
+			return
+		}
+		rr.digits = rr.digits[:0]
+		rr.char = c
+		rr.counter = counter
+		return
+	}
+}
@@ -31,6 +31,16 @@ func TestRLEService_Compress(t *testing.T) {
 			data: []byte("WWBWBWBBWBB"),
 			want: []byte("2W1B1W1B1W2B1W2B"),
 		},
+		{
+			name: "digits",
+			data: []byte("W1122222222222"),
+			want: []byte("1W2\xFF111\xFF2"),
+		},
+		{
+			name: "escape",
+			data: []byte{0xFF, 0xFF, 'W'},
+			want: []byte("2\xFF\xFF1W"),
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -85,6 +95,17 @@ func TestRLEServiceDecompress(t *testing.T) {
 			want:    []byte("WWBBB"),
 			wantErr: true,
 		},
+		{
+			name:    "digits",
+			data:    []byte("1W2\xFF111\xFF2"),
+			want:    []byte("W1122222222222"),
+			wantErr: false,
+		},
+		{
+			name:    "truncated escape",
+			data:    []byte("2\xFF"),
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
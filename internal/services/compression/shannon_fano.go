@@ -0,0 +1,182 @@
+package compression
+
+import (
+	"io"
+	"sort"
+)
+
+// Метод Шеннона-Фано: символы, упорядоченные по убыванию частоты, рекурсивно
+// делятся на две группы с возможно более близкими суммарными частотами.
+// Сжатые данные имеют тот же формат, что и у метода Хаффмана.
+
+type ShannonFanoService struct {
+	h *HuffmanService
+}
+
+func NewShannonFanoService() *ShannonFanoService {
+	return &ShannonFanoService{h: NewHuffmanService()}
+}
+
+type ShannonFanoDetails struct {
+	Codes            []HuffmanCode `json:"codes"`
+	CompressionRatio float32       `json:"compression_ratio"`
+	Size             int           `json:"size"`
+	Trace            *Trace        `json:"trace,omitempty"`
+
+	rootNode *Node
+}
+
+func (d ShannonFanoDetails) Tree() *TreeNode {
+	return nodeToTree(d.rootNode, "", "")
+}
+
+// ShannonFanoSplitEvent — разбиение группы символов на две части.
+type ShannonFanoSplitEvent struct {
+	Left        string `json:"left"`
+	LeftWeight  int    `json:"left_weight"`
+	Right       string `json:"right"`
+	RightWeight int    `json:"right_weight"`
+}
+
+func (s *ShannonFanoService) Compress(data []byte) ([]byte, error) {
+	shannonFanoData, err := s.compressData(data, nil)
+	if err != nil {
+		return nil, err
+	}
+	return shannonFanoData.data, nil
+}
+
+func (s *ShannonFanoService) CompressWithDetails(data []byte) (CompressionDetails, error) {
+	return s.compressWithDetails(data, nil)
+}
+
+// CompressWithTrace сжимает данные, записывая каждое разбиение группы символов.
+func (s *ShannonFanoService) CompressWithTrace(data []byte, limit int) (CompressionDetails, error) {
+	return s.compressWithDetails(data, NewTrace(limit))
+}
+
+func (s *ShannonFanoService) compressWithDetails(data []byte, trace *Trace) (CompressionDetails, error) {
+	shannonFanoData, err := s.compressData(data, trace)
+	if err != nil {
+		return CompressionDetails{}, err
+	}
+
+	codes := s.h.makeHuffmanCodeList(shannonFanoData.frequencyTable, shannonFanoData.huffmanCode)
+
+	shannonFanoDetails := CompressionDetails{
+		Data: shannonFanoData.data,
+		Details: ShannonFanoDetails{
+			Codes:            codes,
+			CompressionRatio: ratio(len(shannonFanoData.data), len(data)),
+			Size:             len(shannonFanoData.data),
+			Trace:            trace,
+			rootNode:         shannonFanoData.rootNode,
+		},
+	}
+	return shannonFanoDetails, nil
+}
+
+func (s *ShannonFanoService) compressData(data []byte, trace *Trace) (HuffmanData, error) {
+	return s.h.compressWithTree(data, func(frequencyTable map[string]int) Node {
+		return s.buildTree(frequencyTable, trace)
+	})
+}
+
+type shannonFanoSymbol struct {
+	value     string
+	frequency int
+}
+
+func (s *ShannonFanoService) buildTree(frequencyTable map[string]int, trace *Trace) Node {
+	symbols := make([]shannonFanoSymbol, 0, len(frequencyTable))
+	for ch, frequency := range frequencyTable {
+		symbols = append(symbols, shannonFanoSymbol{value: ch, frequency: frequency})
+	}
+	sort.Slice(symbols, func(i, j int) bool {
+		if symbols[i].frequency != symbols[j].frequency {
+			return symbols[i].frequency > symbols[j].frequency
+		}
+		return symbols[i].value < symbols[j].value
+	})
+
+	return s.split(symbols, trace)
+}
+
+func (s *ShannonFanoService) split(symbols []shannonFanoSymbol, trace *Trace) Node {
+	if len(symbols) == 1 {
+		return Node{value: symbols[0].value, weight: symbols[0].frequency}
+	}
+
+	total := 0
+	for _, symbol := range symbols {
+		total += symbol.frequency
+	}
+
+	// Граница выбирается так, чтобы суммы частот двух частей были как можно ближе.
+	splitIndex, leftWeight := 1, symbols[0].frequency
+	bestDiff := abs(total - 2*leftWeight)
+	prefix := leftWeight
+	for i := 2; i < len(symbols); i++ {
+		prefix += symbols[i-1].frequency
+		if diff := abs(total - 2*prefix); diff < bestDiff {
+			splitIndex, leftWeight, bestDiff = i, prefix, diff
+		}
+	}
+
+	if trace != nil && !trace.Skip() {
+		trace.Add("split", ShannonFanoSplitEvent{
+			Left:        shannonFanoSymbols(symbols[:splitIndex]),
+			LeftWeight:  leftWeight,
+			Right:       shannonFanoSymbols(symbols[splitIndex:]),
+			RightWeight: total - leftWeight,
+		})
+	}
+
+	left := s.split(symbols[:splitIndex], trace)
+	right := s.split(symbols[splitIndex:], trace)
+	return Node{weight: total, left: &left, right: &right}
+}
+
+func shannonFanoSymbols(symbols []shannonFanoSymbol) string {
+	values := ""
+	for _, symbol := range symbols {
+		values += symbol.value
+	}
+	return values
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func (s *ShannonFanoService) Decompress(compressedData []byte) ([]byte, error) {
+	return s.h.Decompress(compressedData)
+}
+
+// NewReader возвращает потоковый декомпрессор: формат тот же, что у Хаффмана.
+func (s *ShannonFanoService) NewReader(r io.Reader) io.Reader {
+	return s.h.NewReader(r)
+}
+
+func (s *ShannonFanoService) DecompressWithDetails(compressedData []byte) (CompressionDetails, error) {
+	shannonFanoData, err := s.h.decompressData(compressedData)
+	if err != nil {
+		return CompressionDetails{}, err
+	}
+
+	codes := s.h.makeHuffmanCodeList(shannonFanoData.frequencyTable, shannonFanoData.huffmanCode)
+
+	shannonFanoDetails := CompressionDetails{
+		Data: shannonFanoData.data,
+		Details: ShannonFanoDetails{
+			Codes:            codes,
+			CompressionRatio: ratio(len(compressedData), len(shannonFanoData.data)),
+			Size:             len(shannonFanoData.data),
+			rootNode:         shannonFanoData.rootNode,
+		},
+	}
+	return shannonFanoDetails, nil
+}
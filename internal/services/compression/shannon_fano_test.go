@@ -0,0 +1,80 @@
+package compression
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestShannonFanoService_Codes(t *testing.T) {
+	data := []byte(strings.Repeat("A", 15) + strings.Repeat("B", 7) + strings.Repeat("C", 6) + strings.Repeat("D", 6) + strings.Repeat("E", 5))
+	want := map[string]string{
+		"A": "00",
+		"B": "01",
+		"C": "10",
+		"D": "110",
+		"E": "111",
+	}
+
+	s := NewShannonFanoService()
+	shannonFanoData, err := s.compressData(data, nil)
+	if err != nil {
+		t.Fatalf("ShannonFanoService.compressData() has err = %v", err)
+	}
+	if !reflect.DeepEqual(shannonFanoData.huffmanCode, want) {
+		t.Errorf("ShannonFanoService codes = %v, want %v", shannonFanoData.huffmanCode, want)
+	}
+}
+
+func TestShannonFanoService_CompressAndDecompress(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{
+			name: "",
+			data: []byte("Сжатие_Шеннона_Фано"),
+		},
+		{
+			name: "",
+			data: []byte("Сжатие Шеннона-Фано не всегда оптимально, в отличие от метода Хаффмана."),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewShannonFanoService()
+			compressedData, err := s.Compress(tt.data)
+			if err != nil {
+				t.Errorf("ShannonFanoService.Compress() has err = %s", err.Error())
+				return
+			}
+			decompressedData, err := s.Decompress(compressedData)
+			if err != nil {
+				t.Errorf("ShannonFanoService.Decompress() has err = %s", err.Error())
+				return
+			}
+			if !reflect.DeepEqual(tt.data, decompressedData) {
+				t.Errorf("ShannonFanoService.Compress() = %s, want %s", decompressedData, tt.data)
+			}
+		})
+	}
+}
+
+func TestShannonFanoService_Empty(t *testing.T) {
+	s := NewShannonFanoService()
+	compressed, err := s.CompressWithDetails(nil)
+	if err != nil {
+		t.Fatalf("ShannonFanoService.CompressWithDetails() has err = %v", err)
+	}
+	decompressed, err := s.DecompressWithDetails(compressed.Data)
+	if err != nil {
+		t.Fatalf("ShannonFanoService.DecompressWithDetails() has err = %v", err)
+	}
+	for _, details := range []any{compressed.Details, decompressed.Details} {
+		if _, err := json.Marshal(details); err != nil {
+			t.Errorf("json.Marshal(%T) has err = %v", details, err)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package compression
+
+import "unicode/utf8"
+
+// streamFlushSize — объём накопленного сжатого вывода, после которого
+// потоковые компрессоры передают его в нижележащий io.Writer.
+const streamFlushSize = 32 << 10
+
+// runeBoundary возвращает позицию не больше n, на которой можно разрезать b,
+// не разрывая UTF-8 последовательность. Если рядом с n нет начала руны
+// (данные не в UTF-8), возвращается n.
+func runeBoundary(b []byte, n int) int {
+	if n >= len(b) {
+		return len(b)
+	}
+	for i := n; i > 0 && i > n-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			return i
+		}
+	}
+	return n
+}
@@ -0,0 +1,163 @@
+package compression
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+type streamService interface {
+	Compress(data []byte) ([]byte, error)
+	Decompress(compressedData []byte) ([]byte, error)
+	NewWriter(w io.Writer) io.WriteCloser
+	NewReader(r io.Reader) io.Reader
+}
+
+// writeInChunks пишет data порциями по chunk байт, чтобы границы Write
+// попадали внутрь серий и многобайтовых символов.
+func writeInChunks(w io.Writer, data []byte, chunk int) error {
+	for len(data) > 0 {
+		n := min(chunk, len(data))
+		if _, err := w.Write(data[:n]); err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+func TestStream_CompressAndDecompress(t *testing.T) {
+	services := []struct {
+		name    string
+		service streamService
+	}{
+		{name: "rle", service: NewRLEService()},
+		{name: "lzw", service: NewLZWService()},
+		{name: "huffman", service: NewHuffmanService()},
+		{name: "range", service: NewRangeCoderService()},
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{
+			name: "short",
+			data: []byte("banana_bandana"),
+		},
+		{
+			name: "cyrillic",
+			data: []byte("Сжатие_Хаффмана просто лучшее, я вам отвечаю. слово даю!!"),
+		},
+		{
+			name: "runs",
+			data: []byte(strings.Repeat("W", 100) + strings.Repeat("B", 3) + "W"),
+		},
+		{
+			name: "multi_block",
+			data: []byte(strings.Repeat("Простой Текст - example. ", huffmanBlockSize/16)),
+		},
+	}
+
+	for _, s := range services {
+		for _, tt := range tests {
+			t.Run(s.name+"_"+tt.name, func(t *testing.T) {
+				compressed := new(bytes.Buffer)
+				sw := s.service.NewWriter(compressed)
+				if err := writeInChunks(sw, tt.data, 7); err != nil {
+					t.Fatalf("Write() has err = %v", err)
+				}
+				if err := sw.Close(); err != nil {
+					t.Fatalf("Close() has err = %v", err)
+				}
+
+				decompressed := new(bytes.Buffer)
+				buf := make([]byte, 5)
+				if _, err := io.CopyBuffer(decompressed, struct{ io.Reader }{s.service.NewReader(compressed)}, buf); err != nil {
+					t.Fatalf("Read() has err = %v", err)
+				}
+				if !bytes.Equal(decompressed.Bytes(), tt.data) {
+					t.Errorf("stream round trip = %q, want %q", firstBytes(decompressed.Bytes()), firstBytes(tt.data))
+				}
+			})
+		}
+	}
+}
+
+func TestStream_CompatibleWithCompress(t *testing.T) {
+	services := []struct {
+		name    string
+		service streamService
+	}{
+		{name: "rle", service: NewRLEService()},
+		{name: "lzw", service: NewLZWService()},
+		{name: "huffman", service: NewHuffmanService()},
+		{name: "range", service: NewRangeCoderService()},
+	}
+	data := []byte("арифметическое сжатие лучше хаффмана, я вам отвечаю. слово даю!!")
+
+	for _, s := range services {
+		t.Run(s.name, func(t *testing.T) {
+			compressed := new(bytes.Buffer)
+			sw := s.service.NewWriter(compressed)
+			sw.Write(data)
+			sw.Close()
+
+			got, err := s.service.Decompress(compressed.Bytes())
+			if err != nil {
+				t.Fatalf("Decompress() has err = %v", err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("Decompress() = %s, want %s", got, data)
+			}
+
+			compressedData, err := s.service.Compress(data)
+			if err != nil {
+				t.Fatalf("Compress() has err = %v", err)
+			}
+			got, err = io.ReadAll(s.service.NewReader(bytes.NewReader(compressedData)))
+			if err != nil {
+				t.Fatalf("NewReader() has err = %v", err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("NewReader() = %s, want %s", got, data)
+			}
+		})
+	}
+}
+
+func TestLZWService_DictionaryLimit(t *testing.T) {
+	alphabet := []rune("абвгдеёжзийклмнопрстуфхцчшщъыьэюя ")
+	rnd := rand.New(rand.NewSource(1))
+	var sb strings.Builder
+	for range 1 << 19 {
+		sb.WriteRune(alphabet[rnd.Intn(len(alphabet))])
+	}
+	data := []byte(sb.String())
+
+	l := NewLZWService()
+	lzwData, err := l.compressData(data, nil)
+	if err != nil {
+		t.Fatalf("LZWService.compressData() has err = %v", err)
+	}
+	if len(lzwData.dictionary) != lzwMaxDictionarySize {
+		t.Fatalf("len(dictionary) = %v, want %v", len(lzwData.dictionary), lzwMaxDictionarySize)
+	}
+
+	decompressedData, err := l.Decompress(lzwData.data)
+	if err != nil {
+		t.Fatalf("LZWService.Decompress() has err = %v", err)
+	}
+	if !bytes.Equal(decompressedData, data) {
+		t.Errorf("LZWService.Decompress() differs from input after dictionary is full")
+	}
+}
+
+func firstBytes(b []byte) []byte {
+	if len(b) > 64 {
+		return b[:64]
+	}
+	return b
+}
@@ -0,0 +1,52 @@
+package compression
+
+// Трассировка выполнения алгоритма для учебных целей: каждый шаг алгоритма
+// записывается как событие. Число событий ограничено, чтобы трассировка
+// больших входов не разрасталась до размеров самих данных.
+
+const (
+	DefaultTraceLimit = 1000
+	MaxTraceLimit     = 100000
+)
+
+type Trace struct {
+	Events    []TraceEvent `json:"events"`
+	Limit     int          `json:"limit"`
+	Total     int          `json:"total"`
+	Truncated bool         `json:"truncated"`
+}
+
+type TraceEvent struct {
+	Step int    `json:"step"`
+	Type string `json:"type"`
+	Data any    `json:"data"`
+}
+
+func NewTrace(limit int) *Trace {
+	if limit <= 0 {
+		limit = DefaultTraceLimit
+	}
+	limit = min(limit, MaxTraceLimit)
+	return &Trace{Events: make([]TraceEvent, 0), Limit: limit}
+}
+
+// Add записывает событие. Когда лимит исчерпан, событие только учитывается в Total.
+func (t *Trace) Add(eventType string, data any) {
+	if t.Skip() {
+		return
+	}
+	t.Total++
+	t.Events = append(t.Events, TraceEvent{Step: t.Total, Type: eventType, Data: data})
+}
+
+// Skip учитывает событие в Total, если лимит исчерпан, и сообщает об этом.
+// Алгоритмы вызывают его до того, как строить данные события для Add:
+// после усечения трассировки они никому не нужны.
+func (t *Trace) Skip() bool {
+	if len(t.Events) < t.Limit {
+		return false
+	}
+	t.Total++
+	t.Truncated = true
+	return true
+}
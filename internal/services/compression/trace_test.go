@@ -0,0 +1,115 @@
+package compression
+
+import (
+	"reflect"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestCompressWithTrace(t *testing.T) {
+	data := []byte("банан_бандана")
+	numRunes := utf8.RuneCount(data)
+
+	tests := []struct {
+		name       string
+		compress   func(data []byte, limit int) (CompressionDetails, error)
+		trace      func(details any) *Trace
+		eventTypes map[string]int
+	}{
+		{
+			name:       "rle",
+			compress:   NewRLEService().CompressWithTrace,
+			trace:      func(details any) *Trace { return details.(RLEDetails).Trace },
+			eventTypes: map[string]int{"run": len(data)},
+		},
+		{
+			name:       "huffman",
+			compress:   NewHuffmanService().CompressWithTrace,
+			trace:      func(details any) *Trace { return details.(HuffmanDetails).Trace },
+			eventTypes: map[string]int{"merge": 4},
+		},
+		{
+			name:       "arithmetic",
+			compress:   NewArithmeticService().CompressWithTrace,
+			trace:      func(details any) *Trace { return details.(ArithmeticDetails).Trace },
+			eventTypes: map[string]int{"narrow": numRunes},
+		},
+		{
+			name:       "lzw",
+			compress:   NewLZWService().CompressWithTrace,
+			trace:      func(details any) *Trace { return details.(LZWDetails).Trace },
+			eventTypes: map[string]int{"emit": 10, "insert": 9},
+		},
+		{
+			name:       "range",
+			compress:   NewRangeCoderService().CompressWithTrace,
+			trace:      func(details any) *Trace { return details.(RangeCoderDetails).Trace },
+			eventTypes: map[string]int{"encode": len(data) + 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			details, err := tt.compress(data, 0)
+			if err != nil {
+				t.Fatalf("CompressWithTrace() has err = %v", err)
+			}
+			trace := tt.trace(details.Details)
+			if trace == nil {
+				t.Fatalf("CompressWithTrace() has no trace")
+			}
+
+			eventTypes := make(map[string]int)
+			for i, event := range trace.Events {
+				if event.Step != i+1 {
+					t.Errorf("event %d has step %d", i, event.Step)
+				}
+				eventTypes[event.Type]++
+			}
+			if !reflect.DeepEqual(eventTypes, tt.eventTypes) {
+				t.Errorf("CompressWithTrace() events = %v, want %v", eventTypes, tt.eventTypes)
+			}
+
+			limited, err := tt.compress(data, 3)
+			if err != nil {
+				t.Fatalf("CompressWithTrace() has err = %v", err)
+			}
+			limitedTrace := tt.trace(limited.Details)
+			if len(limitedTrace.Events) != 3 || !limitedTrace.Truncated || limitedTrace.Total != trace.Total {
+				t.Errorf("CompressWithTrace() with limit = %d events, truncated %v, total %d; want 3, true, %d",
+					len(limitedTrace.Events), limitedTrace.Truncated, limitedTrace.Total, trace.Total)
+			}
+		})
+	}
+}
+
+func TestTrace_Skip(t *testing.T) {
+	trace := NewTrace(2)
+	for i := range 5 {
+		if skipped := trace.Skip(); skipped != (i >= 2) {
+			t.Fatalf("Trace.Skip() before event %d = %v", i+1, skipped)
+		} else if !skipped {
+			trace.Add("event", i)
+		}
+	}
+	if len(trace.Events) != 2 || trace.Total != 5 || !trace.Truncated || trace.Events[1].Step != 2 {
+		t.Errorf("Trace = %+v, want 2 of 5 events, truncated", trace)
+	}
+}
+
+func TestRLEService_CompressWithTrace(t *testing.T) {
+	s := NewRLEService()
+	details, err := s.CompressWithTrace([]byte("WWWWBBBW"), 0)
+	if err != nil {
+		t.Fatalf("RLEService.CompressWithTrace() has err = %v", err)
+	}
+
+	want := []TraceEvent{
+		{Step: 1, Type: "run", Data: RLERunEvent{Char: "W", Count: 4, Output: "4W"}},
+		{Step: 2, Type: "run", Data: RLERunEvent{Char: "B", Count: 3, Output: "3B"}},
+		{Step: 3, Type: "run", Data: RLERunEvent{Char: "W", Count: 1, Output: "1W"}},
+	}
+	if got := details.Details.(RLEDetails).Trace.Events; !reflect.DeepEqual(got, want) {
+		t.Errorf("RLEService.CompressWithTrace() = %v, want %v", got, want)
+	}
+}
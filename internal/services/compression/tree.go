@@ -0,0 +1,210 @@
+package compression
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Экспорт деревьев кодирования (Хаффман, Шеннон-Фано) и префиксного дерева
+// словаря LZW для вставки в отчёты: вложенный JSON, текст Graphviz DOT или SVG.
+
+const (
+	TreeFormatJSON = "json"
+	TreeFormatDOT  = "dot"
+	TreeFormatSVG  = "svg"
+)
+
+// TreeExporter реализуют детали алгоритмов, у которых есть дерево кодов.
+type TreeExporter interface {
+	Tree() *TreeNode
+}
+
+type TreeNode struct {
+	Label    string      `json:"label,omitempty"`
+	Weight   int         `json:"weight"`
+	Code     string      `json:"code,omitempty"`
+	Edge     string      `json:"edge,omitempty"`
+	Children []*TreeNode `json:"children,omitempty"`
+}
+
+// ExportTree сериализует дерево в заданном формате и возвращает
+// данные вместе с MIME-типом.
+func ExportTree(tree *TreeNode, format string) ([]byte, string, error) {
+	switch format {
+	case TreeFormatJSON:
+		data, err := json.Marshal(tree)
+		return data, "application/json", err
+	case TreeFormatDOT:
+		return []byte(tree.DOT()), "text/vnd.graphviz", nil
+	case TreeFormatSVG:
+		return []byte(tree.SVG()), "image/svg+xml", nil
+	default:
+		return nil, "", fmt.Errorf("unknown tree format %q", format)
+	}
+}
+
+func (n *TreeNode) isLeaf() bool {
+	return len(n.Children) == 0
+}
+
+// text возвращает подпись узла: символ (если есть), код и вес.
+func (n *TreeNode) text() []string {
+	lines := make([]string, 0, 3)
+	if n.Label != "" {
+		lines = append(lines, strconv.Quote(n.Label))
+	}
+	if n.Code != "" {
+		lines = append(lines, n.Code)
+	}
+	lines = append(lines, strconv.Itoa(n.Weight))
+	return lines
+}
+
+func (n *TreeNode) DOT() string {
+	var sb strings.Builder
+	sb.WriteString("digraph tree {\n")
+	sb.WriteString("\tnode [shape=circle, fontname=\"monospace\"];\n")
+
+	id := 0
+	var walk func(node *TreeNode) int
+	walk = func(node *TreeNode) int {
+		nodeID := id
+		id++
+
+		shape := ""
+		if node.isLeaf() {
+			shape = ", shape=box"
+		}
+		fmt.Fprintf(&sb, "\tn%d [label=%s%s];\n", nodeID, dotQuote(strings.Join(node.text(), "\n")), shape)
+
+		for _, child := range node.Children {
+			childID := walk(child)
+			fmt.Fprintf(&sb, "\tn%d -> n%d [label=%s];\n", nodeID, childID, dotQuote(child.Edge))
+		}
+		return nodeID
+	}
+	walk(n)
+
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
+
+const (
+	svgNodeWidth   = 72
+	svgLevelHeight = 80
+	svgLineHeight  = 14
+	svgMargin      = 20
+)
+
+type svgNode struct {
+	node  *TreeNode
+	x, y  float64
+	lines []string
+}
+
+// SVG рисует дерево без внешних зависимостей: листья располагаются
+// слева направо с равным шагом, а внутренний узел — над серединой своих детей.
+func (n *TreeNode) SVG() string {
+	nodes := make([]svgNode, 0)
+	edges := make([][2]int, 0)
+
+	leaves := 0
+	depth := 0
+	var layout func(node *TreeNode, level int) int
+	layout = func(node *TreeNode, level int) int {
+		depth = max(depth, level)
+		index := len(nodes)
+		nodes = append(nodes, svgNode{node: node, y: float64(level)})
+
+		if node.isLeaf() {
+			nodes[index].x = float64(leaves)
+			leaves++
+			return index
+		}
+
+		var sumX float64
+		for _, child := range node.Children {
+			childIndex := layout(child, level+1)
+			edges = append(edges, [2]int{index, childIndex})
+			sumX += nodes[childIndex].x
+		}
+		nodes[index].x = sumX / float64(len(node.Children))
+		return index
+	}
+	layout(n, 0)
+
+	for i := range nodes {
+		nodes[i].x = svgMargin + nodes[i].x*svgNodeWidth + svgNodeWidth/2
+		nodes[i].y = svgMargin + nodes[i].y*svgLevelHeight + svgLineHeight*2
+		nodes[i].lines = nodes[i].node.text()
+	}
+
+	width := 2*svgMargin + leaves*svgNodeWidth
+	height := 2*svgMargin + depth*svgLevelHeight + svgLineHeight*4
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="monospace" font-size="12">`+"\n",
+		width, height, width, height)
+
+	for _, edge := range edges {
+		from, to := nodes[edge[0]], nodes[edge[1]]
+		fmt.Fprintf(&sb, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="black"/>`+"\n",
+			from.x, from.y, to.x, to.y)
+		if to.node.Edge != "" {
+			fmt.Fprintf(&sb, `<text x="%.1f" y="%.1f" text-anchor="middle" fill="#555">%s</text>`+"\n",
+				(from.x+to.x)/2, (from.y+to.y)/2-4, svgEscape(to.node.Edge))
+		}
+	}
+
+	for _, node := range nodes {
+		boxHeight := float64(len(node.lines)*svgLineHeight + 6)
+		boxWidth := float64(svgNodeWidth - 12)
+		radius := boxHeight / 2
+		if node.node.isLeaf() {
+			radius = 3
+		}
+		fmt.Fprintf(&sb, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" rx="%.1f" fill="white" stroke="black"/>`+"\n",
+			node.x-boxWidth/2, node.y-boxHeight/2, boxWidth, boxHeight, radius)
+		for i, line := range node.lines {
+			y := node.y - boxHeight/2 + 3 + float64((i+1)*svgLineHeight) - 3
+			fmt.Fprintf(&sb, `<text x="%.1f" y="%.1f" text-anchor="middle">%s</text>`+"\n", node.x, y, svgEscape(line))
+		}
+	}
+
+	sb.WriteString("</svg>\n")
+	return sb.String()
+}
+
+func svgEscape(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		switch r {
+		case '<':
+			sb.WriteString("&lt;")
+		case '>':
+			sb.WriteString("&gt;")
+		case '&':
+			sb.WriteString("&amp;")
+		case '"':
+			sb.WriteString("&quot;")
+		default:
+			if r < 0x20 {
+				// Управляющие символы недопустимы в XML даже в виде ссылок.
+				sb.WriteRune(utf8.RuneError)
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+	}
+	return sb.String()
+}
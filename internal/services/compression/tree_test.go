@@ -0,0 +1,129 @@
+package compression
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+func checkTreeWeights(t *testing.T, node *TreeNode) int {
+	t.Helper()
+	if len(node.Children) == 0 {
+		return node.Weight
+	}
+	sum := 0
+	for _, child := range node.Children {
+		sum += checkTreeWeights(t, child)
+	}
+	if sum != node.Weight {
+		t.Errorf("node %q weight = %d, want sum of children %d", node.Label, node.Weight, sum)
+	}
+	return node.Weight
+}
+
+func TestHuffmanDetails_Tree(t *testing.T) {
+	data := []byte("Сжатие_Хаффмана")
+	h := NewHuffmanService()
+
+	compressed, err := h.CompressWithDetails(data)
+	if err != nil {
+		t.Fatalf("HuffmanService.CompressWithDetails() has err = %v", err)
+	}
+	tree := compressed.Details.(HuffmanDetails).Tree()
+	if tree.Weight != len([]rune(string(data))) {
+		t.Errorf("root weight = %d, want %d", tree.Weight, len([]rune(string(data))))
+	}
+	checkTreeWeights(t, tree)
+
+	decompressed, err := h.DecompressWithDetails(compressed.Data)
+	if err != nil {
+		t.Fatalf("HuffmanService.DecompressWithDetails() has err = %v", err)
+	}
+	restored := decompressed.Details.(HuffmanDetails).Tree()
+
+	want, _ := json.Marshal(tree)
+	got, _ := json.Marshal(restored)
+	if string(got) != string(want) {
+		t.Errorf("restored tree = %s, want %s", got, want)
+	}
+}
+
+func TestLZWDetails_Tree(t *testing.T) {
+	l := NewLZWService()
+	details, err := l.CompressWithDetails([]byte("banana_bandana"))
+	if err != nil {
+		t.Fatalf("LZWService.CompressWithDetails() has err = %v", err)
+	}
+	tree := details.Details.(LZWDetails).Tree()
+
+	labels := make([]string, 0)
+	var walk func(node *TreeNode)
+	walk = func(node *TreeNode) {
+		for _, child := range node.Children {
+			if !strings.HasPrefix(child.Label, node.Label) {
+				t.Errorf("child %q is not prefixed by %q", child.Label, node.Label)
+			}
+			labels = append(labels, child.Label)
+			walk(child)
+		}
+	}
+	walk(tree)
+
+	// В дереве остаются только строки, коды которых попали в выход.
+	want := []string{"_", "a", "an", "ana", "b", "ba", "d", "n"}
+	got := strings.Join(labels, ",")
+	for _, label := range want {
+		if !strings.Contains(","+got+",", ","+label+",") {
+			t.Errorf("trie has no %q, got %v", label, labels)
+		}
+	}
+	if len(labels) != len(want) {
+		t.Errorf("trie nodes = %v, want %v", labels, want)
+	}
+}
+
+func TestExportTree(t *testing.T) {
+	details, err := NewShannonFanoService().CompressWithDetails([]byte(`a<b>&"c"`))
+	if err != nil {
+		t.Fatalf("ShannonFanoService.CompressWithDetails() has err = %v", err)
+	}
+	tree := details.Details.(ShannonFanoDetails).Tree()
+
+	for _, format := range []string{TreeFormatJSON, TreeFormatDOT, TreeFormatSVG} {
+		t.Run(format, func(t *testing.T) {
+			data, _, err := ExportTree(tree, format)
+			if err != nil {
+				t.Fatalf("ExportTree() has err = %v", err)
+			}
+
+			switch format {
+			case TreeFormatJSON:
+				var got TreeNode
+				if err := json.Unmarshal(data, &got); err != nil {
+					t.Errorf("ExportTree() is not valid JSON: %v", err)
+				}
+			case TreeFormatDOT:
+				if !strings.HasPrefix(string(data), "digraph tree {") || strings.Count(string(data), "->") != 2*(7-1) {
+					t.Errorf("ExportTree() = %s, want digraph with %d edges", data, 2*(7-1))
+				}
+			case TreeFormatSVG:
+				decoder := xml.NewDecoder(strings.NewReader(string(data)))
+				for {
+					_, err := decoder.Token()
+					if err == io.EOF {
+						break
+					}
+					if err != nil {
+						t.Fatalf("ExportTree() is not valid SVG: %v", err)
+					}
+				}
+			}
+		})
+	}
+
+	if _, _, err := ExportTree(tree, "png"); err == nil {
+		t.Errorf("ExportTree() with unknown format has no err")
+	}
+}
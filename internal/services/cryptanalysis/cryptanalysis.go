@@ -0,0 +1,151 @@
+package cryptanalysis
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	repository "github.com/PritOriginal/cryptolabs-back/internal/repository/alphabet"
+	"github.com/PritOriginal/cryptolabs-back/internal/services/classical"
+)
+
+var (
+	ErrNoProfile     = errors.New("no language profile")
+	ErrInvalidText   = errors.New("invalid text")
+	ErrInvalidParams = errors.New("invalid params")
+)
+
+const (
+	DefaultTop          = 5
+	DefaultMinLength    = 3
+	DefaultMaxKeyLength = 20
+	MaxKeyLengthLimit   = 100
+)
+
+// Params — параметры анализа. AlphabetSet — имя алфавита в репозитории
+// (en, ru) или classical.CustomAlphabet; для собственного алфавита нет
+// эталонного профиля языка. Нулевые числовые параметры и пустой Method
+// заменяются значениями по умолчанию.
+type Params struct {
+	AlphabetSet  string
+	Alphabet     string
+	Text         string
+	Top          int
+	MinLength    int
+	MaxKeyLength int
+
+	// Параметры взлома простой замены.
+	NGram      int
+	Iterations int
+	Restarts   int
+	Workers    int
+	Method     string
+	Seed       uint64
+}
+
+type Service interface {
+	Frequencies(params Params) (FrequencyResult, error)
+	Kasiski(params Params) (KasiskiResult, error)
+	IndexOfCoincidence(params Params) (ICResult, error)
+	Caesar(params Params) (SolveResult, error)
+	Affine(params Params) (SolveResult, error)
+	Vigenere(params Params) (SolveResult, error)
+	Substitution(params Params) (SubstitutionResult, error)
+}
+
+type CryptanalysisService struct {
+	alphabetRepo repository.AlphabetRepository
+
+	mu       sync.Mutex
+	profiles map[string]*profile
+}
+
+func NewCryptanalysisService(repo repository.AlphabetRepository) *CryptanalysisService {
+	return &CryptanalysisService{alphabetRepo: repo, profiles: make(map[string]*profile)}
+}
+
+// analysis — разобранный запрос: алфавит, номера букв шифртекста и профиль
+// языка (nil для собственного алфавита).
+type analysis struct {
+	Params
+	a       *classical.Alphabet
+	x       []int
+	profile *profile
+}
+
+func (s *CryptanalysisService) prepare(params Params) (*analysis, error) {
+	if params.Top == 0 {
+		params.Top = DefaultTop
+	}
+	if params.MinLength == 0 {
+		params.MinLength = DefaultMinLength
+	}
+	if params.MaxKeyLength == 0 {
+		params.MaxKeyLength = DefaultMaxKeyLength
+	}
+	switch {
+	case params.Top < 1:
+		return nil, fmt.Errorf("%w: top must be positive, got %d", ErrInvalidParams, params.Top)
+	case params.MinLength < 2:
+		return nil, fmt.Errorf("%w: min_length must be at least 2, got %d", ErrInvalidParams, params.MinLength)
+	case params.MaxKeyLength < 1 || params.MaxKeyLength > MaxKeyLengthLimit:
+		return nil, fmt.Errorf("%w: max_key_length must be 1..%d, got %d", ErrInvalidParams, MaxKeyLengthLimit, params.MaxKeyLength)
+	}
+
+	an := &analysis{Params: params}
+	var err error
+	if params.AlphabetSet == classical.CustomAlphabet {
+		an.a, err = classical.NewAlphabet(params.Alphabet)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		letters, err := s.alphabetRepo.Get(params.AlphabetSet)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", classical.ErrInvalidAlphabet, err)
+		}
+		if an.a, err = classical.NewAlphabet(letters); err != nil {
+			return nil, err
+		}
+		if an.profile, err = s.profile(params.AlphabetSet, an.a); err != nil {
+			return nil, err
+		}
+	}
+
+	an.x = an.a.Indices(params.Text)
+	if len(an.x) == 0 {
+		return nil, fmt.Errorf("%w: text has no letters of the alphabet", ErrInvalidText)
+	}
+	return an, nil
+}
+
+// prepareWithProfile — prepare для анализа, которому нужен профиль языка.
+func (s *CryptanalysisService) prepareWithProfile(params Params) (*analysis, error) {
+	an, err := s.prepare(params)
+	if err != nil {
+		return nil, err
+	}
+	if an.profile == nil {
+		return nil, fmt.Errorf("%w for custom alphabet", ErrNoProfile)
+	}
+	return an, nil
+}
+
+// profile строит профиль языка по корпусу из репозитория один раз на набор.
+func (s *CryptanalysisService) profile(set string, a *classical.Alphabet) (*profile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p, ok := s.profiles[set]; ok {
+		return p, nil
+	}
+	corpus, err := s.alphabetRepo.Corpus(set)
+	if err != nil {
+		return nil, fmt.Errorf("%w for %q: %v", ErrNoProfile, set, err)
+	}
+	p, err := newProfile(a, corpus)
+	if err != nil {
+		return nil, fmt.Errorf("%w for %q: %v", ErrNoProfile, set, err)
+	}
+	s.profiles[set] = p
+	return p, nil
+}
@@ -0,0 +1,331 @@
+package cryptanalysis
+
+import (
+	"errors"
+	"math"
+	"os"
+	"strings"
+	"testing"
+
+	repository "github.com/PritOriginal/cryptolabs-back/internal/repository/alphabet"
+	"github.com/PritOriginal/cryptolabs-back/internal/services/classical"
+)
+
+const (
+	en = "abcdefghijklmnopqrstuvwxyz "
+	ru = "абвгдеёжзийклмнопрстуфхцчшщъыьэюя "
+
+	enText = "The old lighthouse keeper climbed the narrow stairs every evening at sunset. " +
+		"He trimmed the wick, polished the great lens and wrote a short note in his logbook " +
+		"about the wind, the waves and the ships that passed along the rocky coast. " +
+		"In winter the storms were so strong that the whole tower seemed to shake, " +
+		"but the light never went out while he was on duty."
+	ruText = "Старый смотритель маяка каждый вечер поднимался по узкой лестнице на самый верх. " +
+		"Он чистил стекло, зажигал огонь и записывал в журнал, какой была погода и какие " +
+		"корабли прошли мимо скалистого берега. Зимой бури были такими сильными, что башня " +
+		"будто качалась, но свет ни разу не погас, пока он был на посту."
+)
+
+func newTestService(t *testing.T) *CryptanalysisService {
+	repo := repository.NewMockAlphabetRepository(t)
+	for set, letters := range map[string]string{"en": en, "ru": ru} {
+		corpus, err := os.ReadFile("../../repository/alphabet/data/" + set + "_corpus.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		repo.EXPECT().Get(set).Return(letters, nil).Maybe()
+		repo.EXPECT().Corpus(set).Return(string(corpus), nil).Maybe()
+	}
+	repo.EXPECT().Get("de").Return("", errors.New("no such file")).Maybe()
+	repo.EXPECT().Get("xx").Return(en, nil).Maybe()
+	repo.EXPECT().Corpus("xx").Return("", errors.New("no such file")).Maybe()
+	return NewCryptanalysisService(repo)
+}
+
+func encrypt(t *testing.T, cipher, letters, key, text string) string {
+	a, err := classical.NewAlphabet(letters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := classical.NewCipher(cipher, a, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c.Encrypt(text)
+}
+
+func TestCryptanalysisService_Frequencies(t *testing.T) {
+	s := newTestService(t)
+	got, err := s.Frequencies(Params{AlphabetSet: classical.CustomAlphabet, Alphabet: "abc", Text: "Abca, ab!", Top: 2})
+	if err != nil {
+		t.Fatalf("CryptanalysisService.Frequencies() has err = %v", err)
+	}
+	if got.Length != 6 || len(got.Letters) != 3 {
+		t.Fatalf("CryptanalysisService.Frequencies() length = %d, letters = %v", got.Length, got.Letters)
+	}
+	if l := got.Letters[0]; l.Letters != "a" || l.Count != 3 || l.Frequency != 0.5 {
+		t.Errorf("CryptanalysisService.Frequencies() top letter = %+v, want a: 3", l)
+	}
+	if b := got.Bigrams; len(b) != 2 || b[0].Letters != "ab" || b[0].Count != 2 {
+		t.Errorf("CryptanalysisService.Frequencies() bigrams = %+v, want ab: 2 first", b)
+	}
+	// a: 3, b: 2, c: 1 — Σ n(n−1) = 6 + 2 = 8 из 30 пар.
+	if want := 8.0 / 30; math.Abs(got.IC-want) > 1e-12 {
+		t.Errorf("CryptanalysisService.Frequencies() IC = %v, want %v", got.IC, want)
+	}
+	if got.ChiSquared != 0 || got.LanguageIC != 0 || got.Letters[0].Expected != 0 {
+		t.Errorf("CryptanalysisService.Frequencies() has language profile for custom alphabet: %+v", got)
+	}
+
+	for set, text := range map[string]string{"en": enText, "ru": ruText} {
+		got, err := s.Frequencies(Params{AlphabetSet: set, Text: text})
+		if err != nil {
+			t.Fatalf("CryptanalysisService.Frequencies(%s) has err = %v", set, err)
+		}
+		if got.Letters[0].Letters != " " || got.Letters[0].Expected == 0 {
+			t.Errorf("CryptanalysisService.Frequencies(%s) top letter = %+v, want space", set, got.Letters[0])
+		}
+		if len(got.Bigrams) != DefaultTop {
+			t.Errorf("CryptanalysisService.Frequencies(%s) has %d bigrams, want %d", set, len(got.Bigrams), DefaultTop)
+		}
+		if got.IC < got.RandomIC || math.Abs(got.IC-got.LanguageIC) > 0.02 {
+			t.Errorf("CryptanalysisService.Frequencies(%s) IC = %v, language IC = %v", set, got.IC, got.LanguageIC)
+		}
+	}
+}
+
+func TestCryptanalysisService_Kasiski(t *testing.T) {
+	s := newTestService(t)
+	got, err := s.Kasiski(Params{AlphabetSet: classical.CustomAlphabet, Alphabet: "abcdefghijklmnopqrstuvwxyz", Text: "abcxyz abcqq abc", MaxKeyLength: 6})
+	if err != nil {
+		t.Fatalf("CryptanalysisService.Kasiski() has err = %v", err)
+	}
+	if len(got.Repeats) != 1 {
+		t.Fatalf("CryptanalysisService.Kasiski() repeats = %+v, want only abc", got.Repeats)
+	}
+	r := got.Repeats[0]
+	if r.Sequence != "abc" || len(r.Positions) != 3 || r.Distances[0] != 6 || r.Distances[1] != 5 {
+		t.Errorf("CryptanalysisService.Kasiski() repeat = %+v, want abc at 0, 6, 11", r)
+	}
+	counts := map[int]int{2: 1, 3: 1, 4: 0, 5: 1, 6: 1}
+	for _, f := range got.Factors {
+		if f.Count != counts[f.Length] {
+			t.Errorf("CryptanalysisService.Kasiski() factor %d count = %d, want %d", f.Length, f.Count, counts[f.Length])
+		}
+	}
+
+	ciphertext := encrypt(t, "vigenere", en, "lantern", enText)
+	got, err = s.Kasiski(Params{AlphabetSet: "en", Text: ciphertext, Top: 100})
+	if err != nil {
+		t.Fatalf("CryptanalysisService.Kasiski() has err = %v", err)
+	}
+	best := got.Factors[0]
+	for _, f := range got.Factors {
+		if f.Length > 2 && f.Count > best.Count {
+			best = f
+		}
+	}
+	if best.Length%7 != 0 {
+		t.Errorf("CryptanalysisService.Kasiski() most common factor = %d, want multiple of 7; factors = %v", best.Length, got.Factors)
+	}
+}
+
+func TestCryptanalysisService_IndexOfCoincidence(t *testing.T) {
+	s := newTestService(t)
+	ciphertext := encrypt(t, "vigenere", ru, "маяк", ruText)
+	got, err := s.IndexOfCoincidence(Params{AlphabetSet: "ru", Text: ciphertext, MaxKeyLength: 12})
+	if err != nil {
+		t.Fatalf("CryptanalysisService.IndexOfCoincidence() has err = %v", err)
+	}
+	if len(got.KeyLengths) != 12 {
+		t.Fatalf("CryptanalysisService.IndexOfCoincidence() has %d key lengths, want 12", len(got.KeyLengths))
+	}
+	for _, ic := range got.KeyLengths {
+		if multiple := ic.Length%4 == 0; multiple != (ic.IC > (got.RandomIC+got.LanguageIC)/2) {
+			t.Errorf("CryptanalysisService.IndexOfCoincidence() length %d IC = %v, language IC = %v", ic.Length, ic.IC, got.LanguageIC)
+		}
+	}
+}
+
+func TestCryptanalysisService_Solve(t *testing.T) {
+	s := newTestService(t)
+	tests := []struct {
+		cipher string
+		set    string
+		key    string
+		text   string
+		want   string
+	}{
+		{"caesar", "en", "7", enText, "7"},
+		{"caesar", "ru", "к", ruText, "11"},
+		{"affine", "en", "5,8", enText, "5,8"},
+		{"affine", "ru", "3,5", ruText, "3,5"},
+		{"vigenere", "en", "lantern", enText, "lantern"},
+		{"vigenere", "ru", "маяк", ruText, "маяк"},
+		{"vigenere", "en", "sea", enText + " " + enText, "sea"},
+	}
+	solvers := map[string]func(Params) (SolveResult, error){
+		"caesar":   s.Caesar,
+		"affine":   s.Affine,
+		"vigenere": s.Vigenere,
+	}
+	for _, tt := range tests {
+		t.Run(tt.cipher+" "+tt.set+" "+tt.key, func(t *testing.T) {
+			got, err := solvers[tt.cipher](Params{AlphabetSet: tt.set, Text: encrypt(t, tt.cipher, map[string]string{"en": en, "ru": ru}[tt.set], tt.key, tt.text)})
+			if err != nil {
+				t.Fatalf("CryptanalysisService.%s() has err = %v", tt.cipher, err)
+			}
+			// Заглавная буква, зашифрованная пробелом, теряет регистр.
+			if got.Key != tt.want || !strings.EqualFold(got.Text, tt.text) {
+				t.Errorf("CryptanalysisService.%s() key = %q, text = %q, want %q, %q", tt.cipher, got.Key, got.Text, tt.want, tt.text)
+			}
+			if got.Confidence < 0.5 || got.Confidence > 1 {
+				t.Errorf("CryptanalysisService.%s() confidence = %v", tt.cipher, got.Confidence)
+			}
+			if len(got.Candidates) == 0 || got.Candidates[0].Key != got.Key && tt.cipher != "vigenere" {
+				t.Errorf("CryptanalysisService.%s() candidates = %+v", tt.cipher, got.Candidates)
+			}
+		})
+	}
+}
+
+func TestCryptanalysisService_Errors(t *testing.T) {
+	s := newTestService(t)
+	tests := []struct {
+		name    string
+		f       func(Params) (SolveResult, error)
+		params  Params
+		wantErr error
+	}{
+		{"unknown alphabet", s.Caesar, Params{AlphabetSet: "de", Text: "abc"}, classical.ErrInvalidAlphabet},
+		{"bad custom alphabet", s.Caesar, Params{AlphabetSet: classical.CustomAlphabet, Alphabet: "a", Text: "abc"}, classical.ErrInvalidAlphabet},
+		{"custom alphabet", s.Vigenere, Params{AlphabetSet: classical.CustomAlphabet, Alphabet: "abc", Text: "abc"}, ErrNoProfile},
+		{"no corpus", s.Affine, Params{AlphabetSet: "xx", Text: "abc"}, ErrNoProfile},
+		{"no letters", s.Caesar, Params{AlphabetSet: "en", Text: "123"}, ErrInvalidText},
+		{"one letter", s.Vigenere, Params{AlphabetSet: "en", Text: "a"}, ErrInvalidText},
+		{"negative top", s.Caesar, Params{AlphabetSet: "en", Text: "abc", Top: -1}, ErrInvalidParams},
+		{"short min length", s.Caesar, Params{AlphabetSet: "en", Text: "abc", MinLength: 1}, ErrInvalidParams},
+		{"negative max key length", s.Vigenere, Params{AlphabetSet: "en", Text: "abc", MaxKeyLength: -2}, ErrInvalidParams},
+		{"long max key length", s.Vigenere, Params{AlphabetSet: "en", Text: "abc", MaxKeyLength: MaxKeyLengthLimit + 1}, ErrInvalidParams},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.f(tt.params); !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPeriod(t *testing.T) {
+	tests := []struct {
+		key  []int
+		want int
+	}{
+		{[]int{1}, 1},
+		{[]int{1, 2, 3}, 3},
+		{[]int{1, 2, 1, 2, 1, 2}, 2},
+		{[]int{4, 4, 4, 4}, 1},
+		{[]int{1, 2, 1}, 3},
+		{[]int{1, 2, 3, 1, 2}, 5},
+	}
+	for _, tt := range tests {
+		if got := period(tt.key); got != tt.want {
+			t.Errorf("period(%v) = %d, want %d", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestCryptanalysisService_Substitution(t *testing.T) {
+	s := newTestService(t)
+	tests := []struct {
+		set    string
+		key    string
+		text   string
+		method string
+	}{
+		{"en", "qwertyuiop asdfghjklzxcvbnm", enText, MethodHillClimbing},
+		{"en", "qwertyuiop asdfghjklzxcvbnm", enText, MethodAnnealing},
+		{"ru", "шифр замены", ruText, MethodHillClimbing},
+		{"ru", "шифр замены", ruText, MethodAnnealing},
+	}
+	for _, tt := range tests {
+		t.Run(tt.set+" "+tt.method, func(t *testing.T) {
+			letters := map[string]string{"en": en, "ru": ru}[tt.set]
+			params := Params{AlphabetSet: tt.set, Text: encrypt(t, "substitution", letters, tt.key, tt.text), Method: tt.method, Seed: 1}
+			got, err := s.Substitution(params)
+			if err != nil {
+				t.Fatalf("CryptanalysisService.Substitution() has err = %v", err)
+			}
+			// Заглавная буква, зашифрованная пробелом, теряет регистр.
+			if !strings.EqualFold(got.Text, tt.text) {
+				t.Errorf("CryptanalysisService.Substitution() = %q, want %q", got.Text, tt.text)
+			}
+			// Замена букв, которых нет в тексте, не определяется.
+			wantKey := []rune(encrypt(t, "substitution", letters, tt.key, letters))
+			for i, p := range []rune(letters) {
+				if strings.ContainsRune(strings.ToLower(tt.text), p) && []rune(got.Key)[i] != wantKey[i] {
+					t.Errorf("CryptanalysisService.Substitution() key = %q, want %q for %q", got.Key, string(wantKey), p)
+				}
+			}
+			if len(got.Restarts) != DefaultRestarts || got.Restarts[got.Best].Score != got.Score {
+				t.Errorf("CryptanalysisService.Substitution() best = %d, restarts = %d", got.Best, len(got.Restarts))
+			}
+			for r, restart := range got.Restarts {
+				h := restart.History
+				if len(h) != historyPoints+1 || h[0].Iteration != 0 || h[len(h)-1].Iteration != DefaultIterations || h[len(h)-1].Score != restart.Score {
+					t.Fatalf("restart %d history = %v", r, h)
+				}
+				for i := 1; i < len(h); i++ {
+					if h[i].Score < h[i-1].Score {
+						t.Errorf("restart %d best score decreased at %d: %v", r, h[i].Iteration, h)
+					}
+				}
+			}
+
+			again, err := s.Substitution(params)
+			if err != nil || again.Key != got.Key || again.Score != got.Score {
+				t.Errorf("CryptanalysisService.Substitution() with the same seed = %q, %v, want %q", again.Key, err, got.Key)
+			}
+		})
+	}
+
+	for _, params := range []Params{
+		{AlphabetSet: "en", Text: "abc", NGram: 5},
+		{AlphabetSet: "en", Text: "abc", Iterations: MaxIterations + 1},
+		{AlphabetSet: "en", Text: "abc", Restarts: -1},
+		{AlphabetSet: "en", Text: "abc", Workers: -1},
+		{AlphabetSet: "en", Text: "abc", Method: "genetic"},
+		{AlphabetSet: "en", Text: strings.Repeat("abcd", 100), Iterations: MaxIterations, Restarts: MaxRestarts},
+	} {
+		if _, err := s.Substitution(params); !errors.Is(err, ErrInvalidParams) {
+			t.Errorf("CryptanalysisService.Substitution(%+v) err = %v, want %v", params, err, ErrInvalidParams)
+		}
+	}
+	if _, err := s.Substitution(Params{AlphabetSet: "en", Text: "abc"}); !errors.Is(err, ErrInvalidText) {
+		t.Errorf("CryptanalysisService.Substitution() of short text err = %v, want %v", err, ErrInvalidText)
+	}
+	if _, err := s.Substitution(Params{AlphabetSet: "en", Text: strings.Repeat("a", MaxSubstitutionText+1)}); !errors.Is(err, ErrInvalidText) {
+		t.Errorf("CryptanalysisService.Substitution() of long text err = %v, want %v", err, ErrInvalidText)
+	}
+}
+
+func TestNGramCounts(t *testing.T) {
+	text := "абракадабра"
+	for n := 1; n <= 4; n++ {
+		runes := []rune(text)
+		want := make(map[string]int)
+		for i := 0; i+n <= len(runes); i++ {
+			want[string(runes[i:i+n])]++
+		}
+		got := ngramCounts(text, n)
+		if len(got) != len(want) {
+			t.Errorf("ngramCounts(%d) = %v, want %v", n, got, want)
+		}
+		for g, c := range want {
+			if got[g] != c {
+				t.Errorf("ngramCounts(%d)[%q] = %d, want %d", n, g, got[g], c)
+			}
+		}
+	}
+}
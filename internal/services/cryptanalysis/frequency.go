@@ -0,0 +1,83 @@
+package cryptanalysis
+
+import (
+	"cmp"
+	"slices"
+)
+
+// Frequency — частота буквы или биграммы. Expected — её частота в языке,
+// если для алфавита есть профиль.
+type Frequency struct {
+	Letters   string  `json:"letters"`
+	Count     int     `json:"count"`
+	Frequency float64 `json:"frequency"`
+	Expected  float64 `json:"expected,omitempty"`
+}
+
+type FrequencyResult struct {
+	Alphabet   string      `json:"alphabet"`
+	Length     int         `json:"length"`
+	Letters    []Frequency `json:"letters"`
+	Bigrams    []Frequency `json:"bigrams"`
+	ChiSquared float64     `json:"chi_squared,omitempty"`
+	IC         float64     `json:"ic"`
+	LanguageIC float64     `json:"language_ic,omitempty"`
+	RandomIC   float64     `json:"random_ic"`
+}
+
+// Frequencies считает частоты всех букв алфавита и Top самых частых биграмм
+// текста (по убыванию) и сравнивает их с профилем языка.
+func (s *CryptanalysisService) Frequencies(params Params) (FrequencyResult, error) {
+	an, err := s.prepare(params)
+	if err != nil {
+		return FrequencyResult{}, err
+	}
+	m, n := an.a.Len(), len(an.x)
+	counts := letterCounts(an.x, m)
+	result := FrequencyResult{
+		Alphabet: an.a.String(),
+		Length:   n,
+		IC:       indexOfCoincidence(counts, n),
+		RandomIC: 1 / float64(m),
+	}
+
+	for i, c := range counts {
+		f := Frequency{Letters: an.a.Text([]int{i}), Count: c, Frequency: float64(c) / float64(n)}
+		if an.profile != nil {
+			f.Expected = an.profile.letters[i]
+		}
+		result.Letters = append(result.Letters, f)
+	}
+	sortFrequencies(result.Letters)
+
+	bigrams := make([]int, m*m)
+	for i := 0; i+1 < n; i++ {
+		bigrams[an.x[i]*m+an.x[i+1]]++
+	}
+	for b, c := range bigrams {
+		if c == 0 {
+			continue
+		}
+		f := Frequency{Letters: an.a.Text([]int{b / m, b % m}), Count: c, Frequency: float64(c) / float64(n-1)}
+		if an.profile != nil {
+			f.Expected = an.profile.bigrams[b]
+		}
+		result.Bigrams = append(result.Bigrams, f)
+	}
+	sortFrequencies(result.Bigrams)
+	result.Bigrams = result.Bigrams[:min(len(result.Bigrams), an.Top)]
+
+	if an.profile != nil {
+		result.ChiSquared = chiSquared(counts, n, func(i int) float64 { return an.profile.letters[i] })
+		result.LanguageIC = an.profile.ic
+	}
+	return result, nil
+}
+
+// sortFrequencies упорядочивает по убыванию количества, при равных — по
+// убыванию ожидаемой частоты, затем в порядке алфавита.
+func sortFrequencies(f []Frequency) {
+	slices.SortStableFunc(f, func(a, b Frequency) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(b.Expected, a.Expected))
+	})
+}
@@ -0,0 +1,142 @@
+package cryptanalysis
+
+import (
+	"cmp"
+	"slices"
+)
+
+// Repeat — повторяющаяся в шифртексте n-грамма. Positions — номера её
+// вхождений среди букв алфавита (с 0), Distances — расстояния между
+// соседними вхождениями.
+type Repeat struct {
+	Sequence  string `json:"sequence"`
+	Positions []int  `json:"positions"`
+	Distances []int  `json:"distances"`
+}
+
+// FactorCount — сколько расстояний между повторами делится на Length.
+type FactorCount struct {
+	Length int `json:"length"`
+	Count  int `json:"count"`
+}
+
+type KasiskiResult struct {
+	Alphabet string        `json:"alphabet"`
+	Length   int           `json:"length"`
+	Repeats  []Repeat      `json:"repeats"`
+	Factors  []FactorCount `json:"factors"`
+}
+
+// Kasiski ищет n-граммы длины MinLength, встречающиеся в тексте несколько
+// раз, и для каждой длины ключа от 2 до MaxKeyLength, но не больше половины
+// текста, считает, сколько расстояний между повторами на неё делится.
+// Одинаковые фрагменты открытого текста, зашифрованные одним и тем же
+// участком ключа, дают повторы на расстояниях, кратных длине ключа.
+func (s *CryptanalysisService) Kasiski(params Params) (KasiskiResult, error) {
+	an, err := s.prepare(params)
+	if err != nil {
+		return KasiskiResult{}, err
+	}
+	result := KasiskiResult{Alphabet: an.a.String(), Length: len(an.x), Repeats: []Repeat{}}
+
+	positions := make(map[string][]int)
+	var order []string
+	for i := 0; i+an.MinLength <= len(an.x); i++ {
+		seq := an.a.Text(an.x[i : i+an.MinLength])
+		if _, ok := positions[seq]; !ok {
+			order = append(order, seq)
+		}
+		positions[seq] = append(positions[seq], i)
+	}
+
+	for _, seq := range order {
+		pos := positions[seq]
+		if len(pos) < 2 {
+			continue
+		}
+		repeat := Repeat{Sequence: seq, Positions: pos}
+		for i := 1; i < len(pos); i++ {
+			repeat.Distances = append(repeat.Distances, pos[i]-pos[i-1])
+		}
+		result.Repeats = append(result.Repeats, repeat)
+	}
+
+	for l := 2; l <= min(an.MaxKeyLength, len(an.x)/2); l++ {
+		f := FactorCount{Length: l}
+		for _, r := range result.Repeats {
+			for _, d := range r.Distances {
+				if d%l == 0 {
+					f.Count++
+				}
+			}
+		}
+		result.Factors = append(result.Factors, f)
+	}
+
+	slices.SortStableFunc(result.Repeats, func(a, b Repeat) int {
+		return cmp.Compare(len(b.Positions), len(a.Positions))
+	})
+	result.Repeats = result.Repeats[:min(len(result.Repeats), an.Top)]
+	return result, nil
+}
+
+// KeyLengthIC — средний индекс совпадений столбцов текста, разбитого на
+// Length столбцов (каждая Length-я буква).
+type KeyLengthIC struct {
+	Length int     `json:"length"`
+	IC     float64 `json:"ic"`
+}
+
+type ICResult struct {
+	Alphabet   string        `json:"alphabet"`
+	Length     int           `json:"length"`
+	IC         float64       `json:"ic"`
+	LanguageIC float64       `json:"language_ic,omitempty"`
+	RandomIC   float64       `json:"random_ic"`
+	KeyLengths []KeyLengthIC `json:"key_lengths"`
+}
+
+// IndexOfCoincidence считает индекс совпадений текста и его столбцов для
+// длин ключа от 1 до MaxKeyLength. При верной длине каждый столбец зашифрован
+// одним сдвигом, и его индекс близок к индексу языка, а не к 1/m.
+func (s *CryptanalysisService) IndexOfCoincidence(params Params) (ICResult, error) {
+	an, err := s.prepare(params)
+	if err != nil {
+		return ICResult{}, err
+	}
+	m := an.a.Len()
+	result := ICResult{
+		Alphabet:   an.a.String(),
+		Length:     len(an.x),
+		IC:         indexOfCoincidence(letterCounts(an.x, m), len(an.x)),
+		RandomIC:   1 / float64(m),
+		KeyLengths: keyLengthICs(an.x, m, an.MaxKeyLength),
+	}
+	if an.profile != nil {
+		result.LanguageIC = an.profile.ic
+	}
+	return result, nil
+}
+
+// keyLengthICs считает индексы для длин, при которых в каждом столбце не
+// меньше двух букв.
+func keyLengthICs(x []int, m, maxLength int) []KeyLengthIC {
+	ics := []KeyLengthIC{}
+	for l := 1; l <= min(maxLength, len(x)/2); l++ {
+		sum := 0.0
+		for _, col := range columns(x, l) {
+			sum += indexOfCoincidence(letterCounts(col, m), len(col))
+		}
+		ics = append(ics, KeyLengthIC{Length: l, IC: sum / float64(l)})
+	}
+	return ics
+}
+
+// columns разбивает текст на l столбцов: в столбце j буквы j, j+l, j+2l, ...
+func columns(x []int, l int) [][]int {
+	cols := make([][]int, l)
+	for i, v := range x {
+		cols[i%l] = append(cols[i%l], v)
+	}
+	return cols
+}
@@ -0,0 +1,107 @@
+package cryptanalysis
+
+import (
+	"math"
+	"unicode/utf8"
+
+	"github.com/PritOriginal/cryptolabs-back/internal/services/classical"
+	"github.com/PritOriginal/cryptolabs-back/internal/services/compression"
+)
+
+// MaxNGram — наибольшая длина n-грамм модели: таблица занимает mⁿ чисел,
+// для русского алфавита с пробелом при n = 4 это 1,3 млн.
+const MaxNGram = 4
+
+// ngramModel — логарифмы вероятностей n-грамм корпуса. Индекс n-граммы
+// x₁…xₙ — число x₁…xₙ в системе счисления по основанию m. Не встреченной
+// в корпусе n-грамме достаётся оценка backoff·P(x₁…xₙ₋₁)·P(xₙ): на
+// маленьком корпусе большинство n-грамм не встречается, и с постоянной
+// оценкой почти все ключи получили бы одинаковую.
+type ngramModel struct {
+	n, m    int
+	logp    []float64
+	average float64 // средний логарифм вероятности n-граммы самого корпуса
+}
+
+const backoff = 0.4
+
+// model строит модель n-грамм один раз на профиль.
+func (p *profile) model(a *classical.Alphabet, n int) *ngramModel {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.buildModel(a, n)
+}
+
+func (p *profile) buildModel(a *classical.Alphabet, n int) *ngramModel {
+	if md, ok := p.models[n]; ok {
+		return md
+	}
+
+	m := a.Len()
+	md := &ngramModel{n: n, m: m, logp: make([]float64, int(math.Pow(float64(m), float64(n))))}
+	if n == 1 {
+		for i, lp := range p.letters {
+			md.logp[i] = math.Log(lp)
+		}
+	} else {
+		lower, unigram := p.buildModel(a, n-1), p.buildModel(a, 1)
+		for i := range md.logp {
+			md.logp[i] = math.Log(backoff) + lower.logp[i/m] + unigram.logp[i%m]
+		}
+	}
+
+	counts := ngramCounts(p.text, n)
+	total := 0
+	for _, c := range counts {
+		total += c
+	}
+	for g, c := range counts {
+		lp := math.Log(float64(c) / float64(total))
+		if n > 1 {
+			md.logp[md.index(a.Indices(g))] = lp
+		}
+		md.average += float64(c) * lp / float64(total)
+	}
+	p.models[n] = md
+	return md
+}
+
+// ngramCounts считает перекрывающиеся n-граммы текста. Таблица частот
+// блоков из compression делит текст на непересекающиеся блоки по n рун,
+// поэтому n-граммы, начинающиеся с позиции o mod n, — это блоки текста
+// без первых o рун; короткие хвосты отбрасываются.
+func ngramCounts(text string, n int) map[string]int {
+	counts := make(map[string]int)
+	runes := []rune(text)
+	for o := 0; o < n && o < len(runes); o++ {
+		for g, c := range compression.SymbolFrequencies(string(runes[o:]), n) {
+			if utf8.RuneCountInString(g) == n {
+				counts[g] += c
+			}
+		}
+	}
+	return counts
+}
+
+func (md *ngramModel) index(g []int) int {
+	i := 0
+	for _, x := range g {
+		i = i*md.m + x
+	}
+	return i
+}
+
+// score возвращает средний логарифм вероятности n-грамм текста x.
+func (md *ngramModel) score(x []int) float64 {
+	if len(x) < md.n {
+		return 0
+	}
+	sum, i := 0.0, 0
+	for j, v := range x {
+		i = (i*md.m + v) % len(md.logp)
+		if j >= md.n-1 {
+			sum += md.logp[i]
+		}
+	}
+	return sum / float64(len(x)-md.n+1)
+}
@@ -0,0 +1,90 @@
+package cryptanalysis
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/PritOriginal/cryptolabs-back/internal/services/classical"
+)
+
+// profile — эталонные частоты языка, посчитанные по корпусу. Вероятности
+// сглажены (к каждому счётчику добавляется ½), чтобы ни одна буква или
+// биграмма не имела нулевой вероятности и хи-квадрат оставался конечным.
+type profile struct {
+	letters []float64
+	bigrams []float64 // bigrams[i*m+j] — вероятность пары (i, j)
+	ic      float64
+
+	text   string // корпус из одних букв алфавита в нижнем регистре
+	mu     sync.Mutex
+	models map[int]*ngramModel
+}
+
+func newProfile(a *classical.Alphabet, corpus string) (*profile, error) {
+	// Пробельные символы корпуса сводятся к одному пробелу: если пробел
+	// входит в алфавит, он считается буквой, как и в шифрах.
+	x := a.Indices(strings.Join(strings.Fields(corpus), " "))
+	if len(x) < 2 {
+		return nil, errors.New("corpus has too few letters of the alphabet")
+	}
+
+	m := a.Len()
+	counts := letterCounts(x, m)
+	bigrams := make([]int, m*m)
+	for i := 0; i+1 < len(x); i++ {
+		bigrams[x[i]*m+x[i+1]]++
+	}
+
+	return &profile{
+		letters: smooth(counts),
+		bigrams: smooth(bigrams),
+		ic:      indexOfCoincidence(counts, len(x)),
+		text:    a.Text(x),
+		models:  make(map[int]*ngramModel),
+	}, nil
+}
+
+func smooth(counts []int) []float64 {
+	total := 0
+	for _, c := range counts {
+		total += c
+	}
+	p := make([]float64, len(counts))
+	for i, c := range counts {
+		p[i] = (float64(c) + 0.5) / (float64(total) + 0.5*float64(len(counts)))
+	}
+	return p
+}
+
+func letterCounts(x []int, m int) []int {
+	counts := make([]int, m)
+	for _, i := range x {
+		counts[i]++
+	}
+	return counts
+}
+
+// indexOfCoincidence — вероятность того, что две случайно выбранные буквы
+// текста совпадают: Σ nᵢ(nᵢ − 1) / N(N − 1).
+func indexOfCoincidence(counts []int, n int) float64 {
+	if n < 2 {
+		return 0
+	}
+	sum := 0
+	for _, c := range counts {
+		sum += c * (c - 1)
+	}
+	return float64(sum) / float64(n*(n-1))
+}
+
+// chiSquared сравнивает счётчики букв с ожидаемыми вероятностями expected(i).
+func chiSquared(counts []int, n int, expected func(i int) float64) float64 {
+	chi := 0.0
+	for i, c := range counts {
+		e := float64(n) * expected(i)
+		d := float64(c) - e
+		chi += d * d / e
+	}
+	return chi
+}
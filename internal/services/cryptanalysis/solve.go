@@ -0,0 +1,237 @@
+package cryptanalysis
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+
+	"github.com/PritOriginal/cryptolabs-back/internal/services/classical"
+)
+
+// Candidate — возможный ключ в формате ключа classical и расшифрованный им
+// текст. Confidence — апостериорная вероятность ключа при равновероятных
+// ключах, если правдоподобие оценивать как exp(−χ²/2).
+type Candidate struct {
+	Key        string  `json:"key"`
+	ChiSquared float64 `json:"chi_squared"`
+	Confidence float64 `json:"confidence"`
+	Text       string  `json:"text"`
+}
+
+type SolveResult struct {
+	Cipher     string        `json:"cipher"`
+	Alphabet   string        `json:"alphabet"`
+	Key        string        `json:"key"`
+	Confidence float64       `json:"confidence"`
+	Text       string        `json:"text"`
+	KeyLengths []KeyLengthIC `json:"key_lengths,omitempty"`
+	Candidates []Candidate   `json:"candidates"`
+}
+
+// Caesar перебирает все сдвиги и ранжирует их по хи-квадрату между
+// частотами расшифрованного текста и профилем языка.
+func (s *CryptanalysisService) Caesar(params Params) (SolveResult, error) {
+	an, err := s.prepareWithProfile(params)
+	if err != nil {
+		return SolveResult{}, err
+	}
+	m := an.a.Len()
+	counts := letterCounts(an.x, m)
+	keys := make([]string, m)
+	chi := make([]float64, m)
+	for k := range m {
+		keys[k] = strconv.Itoa(k)
+		chi[k] = chiSquared(counts, len(an.x), func(y int) float64 { return an.profile.letters[mod(y-k, m)] })
+	}
+	return an.solve("caesar", keys, chi)
+}
+
+// Affine перебирает все ключи (a, b) с a, взаимно простым с m.
+func (s *CryptanalysisService) Affine(params Params) (SolveResult, error) {
+	an, err := s.prepareWithProfile(params)
+	if err != nil {
+		return SolveResult{}, err
+	}
+	m := an.a.Len()
+	counts := letterCounts(an.x, m)
+	var keys []string
+	var chi []float64
+	for a := 1; a < m; a++ {
+		aInv, ok := inverse(a, m)
+		if !ok {
+			continue
+		}
+		for b := range m {
+			keys = append(keys, fmt.Sprintf("%d,%d", a, b))
+			chi = append(chi, chiSquared(counts, len(an.x), func(y int) float64 {
+				return an.profile.letters[mod(aInv*(y-b), m)]
+			}))
+		}
+	}
+	return an.solve("affine", keys, chi)
+}
+
+// solve выбирает ключ с наименьшим хи-квадратом и возвращает Top лучших
+// кандидатов.
+func (an *analysis) solve(cipher string, keys []string, chi []float64) (SolveResult, error) {
+	conf := posteriors(chi)
+	order := make([]int, len(keys))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(i, j int) int { return cmp.Compare(chi[i], chi[j]) })
+
+	result := SolveResult{Cipher: cipher, Alphabet: an.a.String()}
+	for _, i := range order[:min(len(order), an.Top)] {
+		text, err := an.decrypt(cipher, keys[i])
+		if err != nil {
+			return SolveResult{}, err
+		}
+		result.Candidates = append(result.Candidates, Candidate{Key: keys[i], ChiSquared: chi[i], Confidence: conf[i], Text: text})
+	}
+	best := result.Candidates[0]
+	result.Key, result.Confidence, result.Text = best.Key, best.Confidence, best.Text
+	return result, nil
+}
+
+// Vigenere определяет длину ключа по индексу совпадений столбцов, затем
+// находит сдвиг каждого столбца как у шифра Цезаря. Confidence — произведение
+// уверенностей по столбцам. Кандидаты — решения для Top длин с наибольшим
+// индексом совпадений.
+func (s *CryptanalysisService) Vigenere(params Params) (SolveResult, error) {
+	an, err := s.prepareWithProfile(params)
+	if err != nil {
+		return SolveResult{}, err
+	}
+	m := an.a.Len()
+	ics := keyLengthICs(an.x, m, an.MaxKeyLength)
+	if len(ics) == 0 {
+		return SolveResult{}, fmt.Errorf("%w: want at least 2 letters of the alphabet", ErrInvalidText)
+	}
+
+	result := SolveResult{Cipher: "vigenere", Alphabet: an.a.String(), KeyLengths: ics}
+	best, err := an.vigenereKey(keyLength(ics, an.profile.ic, 1/float64(m)))
+	if err != nil {
+		return SolveResult{}, err
+	}
+	result.Key, result.Confidence, result.Text = best.Key, best.Confidence, best.Text
+
+	byIC := slices.Clone(ics)
+	slices.SortStableFunc(byIC, func(a, b KeyLengthIC) int { return cmp.Compare(b.IC, a.IC) })
+	seen := make(map[string]bool)
+	for _, ic := range byIC {
+		if len(result.Candidates) == an.Top {
+			break
+		}
+		c, err := an.vigenereKey(ic.Length)
+		if err != nil {
+			return SolveResult{}, err
+		}
+		if !seen[c.Key] {
+			seen[c.Key] = true
+			result.Candidates = append(result.Candidates, c)
+		}
+	}
+	return result, nil
+}
+
+// keyLength выбирает наименьшую длину, индекс совпадений которой близок
+// к наибольшему: у кратных истинной длины индекс такой же, а у длинных ключей
+// на коротком тексте он завышен случайными совпадениями в маленьких
+// столбцах, поэтому ориентиром служит не больше индекса языка.
+func keyLength(ics []KeyLengthIC, languageIC, randomIC float64) int {
+	target := 0.0
+	for _, ic := range ics {
+		target = max(target, ic.IC)
+	}
+	target = min(target, languageIC)
+	for _, ic := range ics {
+		if ic.IC >= randomIC+0.8*(target-randomIC) {
+			return ic.Length
+		}
+	}
+	return slices.MaxFunc(ics, func(a, b KeyLengthIC) int { return cmp.Compare(a.IC, b.IC) }).Length
+}
+
+func (an *analysis) vigenereKey(l int) (Candidate, error) {
+	m := an.a.Len()
+	key := make([]int, l)
+	confidence := 1.0
+	for j, col := range columns(an.x, l) {
+		counts := letterCounts(col, m)
+		chi := make([]float64, m)
+		for k := range m {
+			chi[k] = chiSquared(counts, len(col), func(y int) float64 { return an.profile.letters[mod(y-k, m)] })
+		}
+		key[j] = slices.Index(chi, slices.Min(chi))
+		confidence *= posteriors(chi)[key[j]]
+	}
+	key = key[:period(key)]
+
+	plain := make([]int, len(an.x))
+	for i, y := range an.x {
+		plain[i] = mod(y-key[i%len(key)], m)
+	}
+	c := Candidate{
+		Key:        an.a.Text(key),
+		ChiSquared: chiSquared(letterCounts(plain, m), len(plain), func(i int) float64 { return an.profile.letters[i] }),
+		Confidence: confidence,
+	}
+	var err error
+	c.Text, err = an.decrypt("vigenere", c.Key)
+	return c, err
+}
+
+// period возвращает длину наименьшего повторяющегося фрагмента ключа:
+// найденный по длине 6 ключ «keykey» сводится к «key».
+func period(key []int) int {
+	for p := 1; p < len(key); p++ {
+		if len(key)%p == 0 && slices.Equal(key[p:], key[:len(key)-p]) {
+			return p
+		}
+	}
+	return len(key)
+}
+
+func (an *analysis) decrypt(cipher, key string) (string, error) {
+	c, err := classical.NewCipher(cipher, an.a, key)
+	if err != nil {
+		return "", err
+	}
+	return c.Decrypt(an.Text), nil
+}
+
+// posteriors нормирует правдоподобия exp(−χ²/2) в вероятности.
+func posteriors(chi []float64) []float64 {
+	best := slices.Min(chi)
+	p := make([]float64, len(chi))
+	sum := 0.0
+	for i, c := range chi {
+		p[i] = math.Exp(-(c - best) / 2)
+		sum += p[i]
+	}
+	for i := range p {
+		p[i] /= sum
+	}
+	return p
+}
+
+func mod(a, m int) int {
+	a %= m
+	if a < 0 {
+		a += m
+	}
+	return a
+}
+
+// inverse ищет обратный к a по модулю m перебором: алфавиты малы.
+func inverse(a, m int) (int, bool) {
+	for i := 1; i < m; i++ {
+		if a*i%m == 1 {
+			return i, true
+		}
+	}
+	return 0, false
+}
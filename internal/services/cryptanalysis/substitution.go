@@ -0,0 +1,289 @@
+package cryptanalysis
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"runtime"
+	"slices"
+	"sync"
+)
+
+const (
+	MethodHillClimbing = "hill_climbing"
+	MethodAnnealing    = "annealing"
+
+	DefaultNGram      = 4
+	DefaultIterations = 5000
+	DefaultRestarts   = 8
+	MaxIterations     = 1_000_000
+	MaxRestarts       = 64
+	// MaxSubstitutionText — наибольшая длина шифртекста в буквах алфавита.
+	MaxSubstitutionText = 20_000
+	// MaxSubstitutionWork — предел произведения Iterations·Restarts и длины
+	// текста: худшая перестановка пересчитывает оценку по всему тексту.
+	MaxSubstitutionWork = 2_000_000_000
+
+	// historyPoints — сколько точек истории оценки сохраняется на запуск.
+	historyPoints = 100
+	// annealingTemperature — начальная температура отжига в единицах
+	// логарифма правдоподобия на одну n-грамму; к концу она линейно падает
+	// до нуля.
+	annealingTemperature = 0.02
+)
+
+// ScorePoint — лучшая оценка запуска после Iteration перестановок.
+type ScorePoint struct {
+	Iteration int     `json:"iteration"`
+	Score     float64 `json:"score"`
+}
+
+// Restart — результат одного запуска со случайного ключа (первый запуск
+// начинается с ключа, сопоставляющего буквы по частоте).
+type Restart struct {
+	Key     string       `json:"key"`
+	Score   float64      `json:"score"`
+	History []ScorePoint `json:"history"`
+}
+
+// SubstitutionResult — найденный ключ простой замены в формате classical
+// (алфавит замены) и расшифрованный текст. Score — средний логарифм
+// вероятности n-граммы открытого текста; LanguageScore — он же для корпуса,
+// у верного решения Score близок к нему.
+type SubstitutionResult struct {
+	Alphabet      string    `json:"alphabet"`
+	Method        string    `json:"method"`
+	NGram         int       `json:"ngram"`
+	Seed          uint64    `json:"seed"`
+	Key           string    `json:"key"`
+	Score         float64   `json:"score"`
+	LanguageScore float64   `json:"language_score"`
+	Text          string    `json:"text"`
+	Best          int       `json:"best"`
+	Restarts      []Restart `json:"restarts"`
+}
+
+// Substitution взламывает простую замену: Restarts запусков по Iterations
+// случайных перестановок двух букв ключа выполняются параллельно в Workers
+// горутинах (по умолчанию по числу процессоров). Перестановка принимается,
+// если она повышает правдоподобие n-грамм открытого текста, а при отжиге —
+// иногда и если понижает. Нулевой Seed заменяется случайным и возвращается
+// в результате.
+func (s *CryptanalysisService) Substitution(params Params) (SubstitutionResult, error) {
+	an, err := s.prepareWithProfile(params)
+	if err != nil {
+		return SubstitutionResult{}, err
+	}
+	if err := an.substitutionParams(); err != nil {
+		return SubstitutionResult{}, err
+	}
+	if len(an.x) < an.NGram || len(an.x) > MaxSubstitutionText {
+		return SubstitutionResult{}, fmt.Errorf("%w: want %d to %d letters of the alphabet, got %d", ErrInvalidText, an.NGram, MaxSubstitutionText, len(an.x))
+	}
+
+	md := an.profile.model(an.a, an.NGram)
+	result := SubstitutionResult{
+		Alphabet:      an.a.String(),
+		Method:        an.Method,
+		NGram:         an.NGram,
+		Seed:          an.Seed,
+		LanguageScore: md.average,
+		Restarts:      make([]Restart, an.Restarts),
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(an.Workers, an.Restarts) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range jobs {
+				result.Restarts[r] = an.climb(md, r)
+			}
+		}()
+	}
+	for r := range an.Restarts {
+		jobs <- r
+	}
+	close(jobs)
+	wg.Wait()
+
+	for r, restart := range result.Restarts {
+		if restart.Score > result.Restarts[result.Best].Score {
+			result.Best = r
+		}
+	}
+	best := result.Restarts[result.Best]
+	result.Key, result.Score = best.Key, best.Score
+	if result.Text, err = an.decrypt("substitution", best.Key); err != nil {
+		return SubstitutionResult{}, err
+	}
+	return result, nil
+}
+
+func (an *analysis) substitutionParams() error {
+	if an.NGram == 0 {
+		an.NGram = DefaultNGram
+	}
+	if an.Iterations == 0 {
+		an.Iterations = DefaultIterations
+	}
+	if an.Restarts == 0 {
+		an.Restarts = DefaultRestarts
+	}
+	if an.Workers == 0 {
+		an.Workers = runtime.NumCPU()
+	}
+	if an.Method == "" {
+		an.Method = MethodAnnealing
+	}
+	if an.Seed == 0 {
+		an.Seed = rand.Uint64()
+	}
+	switch {
+	case an.NGram < 1 || an.NGram > MaxNGram:
+		return fmt.Errorf("%w: ngram must be from 1 to %d, got %d", ErrInvalidParams, MaxNGram, an.NGram)
+	case an.Iterations < 1 || an.Iterations > MaxIterations:
+		return fmt.Errorf("%w: iterations must be from 1 to %d, got %d", ErrInvalidParams, MaxIterations, an.Iterations)
+	case an.Restarts < 1 || an.Restarts > MaxRestarts:
+		return fmt.Errorf("%w: restarts must be from 1 to %d, got %d", ErrInvalidParams, MaxRestarts, an.Restarts)
+	case an.Workers < 1:
+		return fmt.Errorf("%w: workers must be positive, got %d", ErrInvalidParams, an.Workers)
+	case an.Method != MethodHillClimbing && an.Method != MethodAnnealing:
+		return fmt.Errorf("%w: unknown method %q, want %s or %s", ErrInvalidParams, an.Method, MethodHillClimbing, MethodAnnealing)
+	case an.Iterations*an.Restarts*len(an.x) > MaxSubstitutionWork:
+		return fmt.Errorf("%w: iterations·restarts·letters must not exceed %d, got %d·%d·%d", ErrInvalidParams, MaxSubstitutionWork, an.Iterations, an.Restarts, len(an.x))
+	}
+	return nil
+}
+
+// climb выполняет запуск r. Ключ dec сопоставляет букве шифртекста букву
+// открытого текста; у каждого запуска свой генератор, поэтому результат
+// зависит только от Seed и номера запуска.
+func (an *analysis) climb(md *ngramModel, r int) Restart {
+	rng := rand.New(rand.NewPCG(an.Seed, uint64(r)))
+	m := an.a.Len()
+	dec := an.frequencyKey()
+	if r > 0 {
+		rng.Shuffle(m, func(i, j int) { dec[i], dec[j] = dec[j], dec[i] })
+	}
+
+	// Перестановка букв i и j ключа меняет только n-граммы, в которые входят
+	// буквы i и j шифртекста, поэтому сумма логарифмов пересчитывается лишь
+	// по ним: starts[c] — начала n-грамм, содержащих букву c.
+	n, count := md.n, float64(len(an.x)-md.n+1)
+	plain := make([]int, len(an.x))
+	positions, starts := make([][]int, m), make([][]int, m)
+	for p, y := range an.x {
+		plain[p] = dec[y]
+		positions[y] = append(positions[y], p)
+		lo := max(0, p-n+1)
+		if st := starts[y]; len(st) > 0 {
+			lo = max(lo, st[len(st)-1]+1)
+		}
+		for s := lo; s <= min(p, len(an.x)-n); s++ {
+			starts[y] = append(starts[y], s)
+		}
+	}
+	sum := func(starts []int) float64 {
+		total := 0.0
+		for _, s := range starts {
+			total += md.logp[md.index(plain[s:s+n])]
+		}
+		return total
+	}
+	swap := func(i, j int) {
+		dec[i], dec[j] = dec[j], dec[i]
+		for _, c := range []int{i, j} {
+			for _, p := range positions[c] {
+				plain[p] = dec[c]
+			}
+		}
+	}
+
+	current := md.score(plain)
+	best, bestScore := slices.Clone(dec), current
+	step := max(1, an.Iterations/historyPoints)
+	history := []ScorePoint{{0, bestScore}}
+
+	var affected []int
+	for it := 1; it <= an.Iterations; it++ {
+		i, j := rng.IntN(m), rng.IntN(m-1)
+		if j >= i {
+			j++
+		}
+		affected = mergeUnique(affected[:0], starts[i], starts[j])
+		delta := -sum(affected)
+		swap(i, j)
+		delta += sum(affected)
+		next := current + delta/count
+		accept := next > current
+		if !accept && an.Method == MethodAnnealing {
+			t := annealingTemperature * (1 - float64(it)/float64(an.Iterations))
+			accept = t > 0 && rng.Float64() < math.Exp((next-current)/t)
+		}
+		if accept {
+			current = next
+			if current > bestScore {
+				copy(best, dec)
+				bestScore = current
+			}
+		} else {
+			swap(i, j)
+		}
+		if it%step == 0 || it == an.Iterations {
+			history = append(history, ScorePoint{it, bestScore})
+		}
+	}
+
+	// Ключ classical — алфавит замены: key[p] = c, где dec[c] = p.
+	key := make([]int, m)
+	for c, p := range best {
+		key[p] = c
+	}
+	return Restart{Key: an.a.Text(key), Score: bestScore, History: history}
+}
+
+// frequencyKey сопоставляет буквы шифртекста и языка в порядке убывания
+// частот.
+func (an *analysis) frequencyKey() []int {
+	m := an.a.Len()
+	counts := letterCounts(an.x, m)
+	cipher, language := seq(m), seq(m)
+	slices.SortStableFunc(cipher, func(i, j int) int { return cmp.Compare(counts[j], counts[i]) })
+	slices.SortStableFunc(language, func(i, j int) int {
+		return cmp.Compare(an.profile.letters[j], an.profile.letters[i])
+	})
+	dec := make([]int, m)
+	for r, c := range cipher {
+		dec[c] = language[r]
+	}
+	return dec
+}
+
+// mergeUnique дописывает в dst объединение возрастающих списков a и b
+// без повторов.
+func mergeUnique(dst, a, b []int) []int {
+	for len(a) > 0 || len(b) > 0 {
+		var v int
+		switch {
+		case len(b) == 0 || len(a) > 0 && a[0] < b[0]:
+			v, a = a[0], a[1:]
+		case len(a) == 0 || b[0] < a[0]:
+			v, b = b[0], b[1:]
+		default:
+			v, a, b = a[0], a[1:], b[1:]
+		}
+		dst = append(dst, v)
+	}
+	return dst
+}
+
+func seq(n int) []int {
+	s := make([]int, n)
+	for i := range s {
+		s[i] = i
+	}
+	return s
+}
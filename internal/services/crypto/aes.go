@@ -0,0 +1,262 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"fmt"
+)
+
+// AESBlockSize — размер блока AES в байтах.
+const AESBlockSize = 16
+
+// AES по FIPS-197. Состояние — матрица 4×4 байт, заполняемая по столбцам:
+// state[r][c] = in[r+4c]. Раунд шифрования — SubBytes, ShiftRows, MixColumns
+// (кроме последнего раунда) и AddRoundKey; перед первым раундом выполняется
+// AddRoundKey с ключом из первых четырёх слов расписания.
+type AESCipher struct {
+	nr        int // число раундов: 10, 12 или 14
+	roundKeys [][AESBlockSize]byte
+}
+
+type aesState [4][4]byte
+
+var sbox, invSbox [256]byte
+
+func init() {
+	// S-блок: обратный элемент в GF(2⁸) по модулю x⁸+x⁴+x³+x+1 (для нуля —
+	// ноль), затем аффинное преобразование b ⊕ rotl(b,1..4) ⊕ 0x63.
+	for i := range 256 {
+		b := gfInverse(byte(i))
+		s := b ^ rotl8(b, 1) ^ rotl8(b, 2) ^ rotl8(b, 3) ^ rotl8(b, 4) ^ 0x63
+		sbox[i] = s
+		invSbox[s] = byte(i)
+	}
+}
+
+func rotl8(b byte, n int) byte {
+	return b<<n | b>>(8-n)
+}
+
+// gfMul умножает многочлены над GF(2) по модулю x⁸+x⁴+x³+x+1.
+func gfMul(a, b byte) byte {
+	var p byte
+	for b != 0 {
+		if b&1 != 0 {
+			p ^= a
+		}
+		a = xtime(a)
+		b >>= 1
+	}
+	return p
+}
+
+// xtime умножает на x.
+func xtime(a byte) byte {
+	if a&0x80 != 0 {
+		return a<<1 ^ 0x1b
+	}
+	return a << 1
+}
+
+// gfInverse возводит в степень 254 = 2⁸ − 2: a²⁵⁴ = a⁻¹ для a ≠ 0.
+func gfInverse(a byte) byte {
+	r := byte(1)
+	for range 254 {
+		r = gfMul(r, a)
+	}
+	return r
+}
+
+// NewAESCipher создаёт AES-128, AES-192 или AES-256 по длине ключа.
+func NewAESCipher(key []byte) (*AESCipher, error) {
+	nk := len(key) / 4
+	if len(key) != 16 && len(key) != 24 && len(key) != 32 {
+		return nil, fmt.Errorf("%w: AES key must be 16, 24 or 32 bytes, got %d", ErrInvalidCipherParams, len(key))
+	}
+	c := &AESCipher{nr: nk + 6}
+
+	// Расширение ключа: w[i] = w[i−Nk] ⊕ temp, где temp = SubWord(RotWord(w[i−1])) ⊕ Rcon
+	// для i кратных Nk, SubWord(w[i−1]) для AES-256 при i mod Nk = 4, иначе w[i−1].
+	w := make([][4]byte, 4*(c.nr+1))
+	for i := range nk {
+		copy(w[i][:], key[4*i:])
+	}
+	rcon := byte(1)
+	for i := nk; i < len(w); i++ {
+		temp := w[i-1]
+		switch {
+		case i%nk == 0:
+			temp = [4]byte{sbox[temp[1]] ^ rcon, sbox[temp[2]], sbox[temp[3]], sbox[temp[0]]}
+			rcon = xtime(rcon)
+		case nk > 6 && i%nk == 4:
+			temp = [4]byte{sbox[temp[0]], sbox[temp[1]], sbox[temp[2]], sbox[temp[3]]}
+		}
+		for j := range 4 {
+			w[i][j] = w[i-nk][j] ^ temp[j]
+		}
+	}
+
+	c.roundKeys = make([][AESBlockSize]byte, c.nr+1)
+	for i, word := range w {
+		copy(c.roundKeys[i/4][4*(i%4):], word[:])
+	}
+	return c, nil
+}
+
+func (c *AESCipher) BlockSize() int {
+	return AESBlockSize
+}
+
+func (c *AESCipher) Encrypt(dst, src []byte) {
+	c.encrypt(dst, src, nil)
+}
+
+func (c *AESCipher) Decrypt(dst, src []byte) {
+	c.decrypt(dst, src, nil)
+}
+
+func (c *AESCipher) EncryptTrace(dst, src []byte) []RoundStep {
+	var trace []RoundStep
+	c.encrypt(dst, src, &trace)
+	return trace
+}
+
+func (c *AESCipher) DecryptTrace(dst, src []byte) []RoundStep {
+	var trace []RoundStep
+	c.decrypt(dst, src, &trace)
+	return trace
+}
+
+// KeySchedule возвращает ключи раундов в hex.
+func (c *AESCipher) KeySchedule() []string {
+	keys := make([]string, len(c.roundKeys))
+	for i, k := range c.roundKeys {
+		keys[i] = hex.EncodeToString(k[:])
+	}
+	return keys
+}
+
+func (c *AESCipher) encrypt(dst, src []byte, trace *[]RoundStep) {
+	s := newAESState(src)
+	s.record(trace, 0, "input")
+	s.addRoundKey(c.roundKeys[0])
+	s.record(trace, 0, "add_round_key")
+	for round := 1; round <= c.nr; round++ {
+		s.subBytes(&sbox)
+		s.record(trace, round, "sub_bytes")
+		s.shiftRows()
+		s.record(trace, round, "shift_rows")
+		if round < c.nr {
+			s.mixColumns()
+			s.record(trace, round, "mix_columns")
+		}
+		s.addRoundKey(c.roundKeys[round])
+		s.record(trace, round, "add_round_key")
+	}
+	s.bytes(dst)
+}
+
+// decrypt — обратный шифр (FIPS-197, раздел 5.3): обратные преобразования
+// в обратном порядке.
+func (c *AESCipher) decrypt(dst, src []byte, trace *[]RoundStep) {
+	s := newAESState(src)
+	s.record(trace, 0, "input")
+	s.addRoundKey(c.roundKeys[c.nr])
+	s.record(trace, 0, "add_round_key")
+	for round := 1; round <= c.nr; round++ {
+		s.invShiftRows()
+		s.record(trace, round, "inv_shift_rows")
+		s.subBytes(&invSbox)
+		s.record(trace, round, "inv_sub_bytes")
+		s.addRoundKey(c.roundKeys[c.nr-round])
+		s.record(trace, round, "add_round_key")
+		if round < c.nr {
+			s.invMixColumns()
+			s.record(trace, round, "inv_mix_columns")
+		}
+	}
+	s.bytes(dst)
+}
+
+func newAESState(in []byte) *aesState {
+	var s aesState
+	for i := range AESBlockSize {
+		s[i%4][i/4] = in[i]
+	}
+	return &s
+}
+
+func (s *aesState) bytes(out []byte) {
+	for i := range AESBlockSize {
+		out[i] = s[i%4][i/4]
+	}
+}
+
+func (s *aesState) record(trace *[]RoundStep, round int, step string) {
+	if trace == nil {
+		return
+	}
+	var b [AESBlockSize]byte
+	s.bytes(b[:])
+	grid := make([][]string, 4)
+	for r := range grid {
+		grid[r] = make([]string, 4)
+		for c := range grid[r] {
+			grid[r][c] = hex.EncodeToString([]byte{s[r][c]})
+		}
+	}
+	*trace = append(*trace, RoundStep{Round: round, Step: step, State: hex.EncodeToString(b[:]), Grid: grid})
+}
+
+func (s *aesState) addRoundKey(k [AESBlockSize]byte) {
+	for i := range AESBlockSize {
+		s[i%4][i/4] ^= k[i]
+	}
+}
+
+func (s *aesState) subBytes(box *[256]byte) {
+	for r := range 4 {
+		for c := range 4 {
+			s[r][c] = box[s[r][c]]
+		}
+	}
+}
+
+// shiftRows циклически сдвигает строку r влево на r позиций.
+func (s *aesState) shiftRows() {
+	for r := 1; r < 4; r++ {
+		row := s[r]
+		for c := range 4 {
+			s[r][c] = row[(c+r)%4]
+		}
+	}
+}
+
+func (s *aesState) invShiftRows() {
+	for r := 1; r < 4; r++ {
+		row := s[r]
+		for c := range 4 {
+			s[r][(c+r)%4] = row[c]
+		}
+	}
+}
+
+// mixColumns умножает каждый столбец на многочлен {03}x³+{01}x²+{01}x+{02}
+// по модулю x⁴+1, то есть на циркулянтную матрицу (2 3 1 1).
+func (s *aesState) mixColumns() {
+	s.mulColumns([4]byte{2, 3, 1, 1})
+}
+
+// invMixColumns умножает на обратный многочлен — матрицу (e b d 9).
+func (s *aesState) invMixColumns() {
+	s.mulColumns([4]byte{0x0e, 0x0b, 0x0d, 0x09})
+}
+
+func (s *aesState) mulColumns(m [4]byte) {
+	for c := range 4 {
+		col := [4]byte{s[0][c], s[1][c], s[2][c], s[3][c]}
+		for r := range 4 {
+			s[r][c] = gfMul(m[0], col[r]) ^ gfMul(m[1], col[(r+1)%4]) ^
+				gfMul(m[2], col[(r+2)%4]) ^ gfMul(m[3], col[(r+3)%4])
+		}
+	}
+}
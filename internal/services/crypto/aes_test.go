@@ -0,0 +1,154 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
+	"errors"
+	"math/rand/v2"
+	"testing"
+)
+
+func unhex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// Примеры из FIPS-197, приложение C.
+func TestAESCipher_FIPS197(t *testing.T) {
+	plaintext := "00112233445566778899aabbccddeeff"
+	tests := []struct {
+		name       string
+		key        string
+		ciphertext string
+	}{
+		{"aes-128", "000102030405060708090a0b0c0d0e0f", "69c4e0d86a7b0430d8cdb78070b4c55a"},
+		{"aes-192", "000102030405060708090a0b0c0d0e0f1011121314151617", "dda97ca4864cdfe06eaf70a0ec0d7191"},
+		{"aes-256", "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f", "8ea2b7ca516745bfeafc49904b496089"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewAESCipher(unhex(t, tt.key))
+			if err != nil {
+				t.Fatalf("NewAESCipher() has err = %v", err)
+			}
+			dst := make([]byte, AESBlockSize)
+			c.Encrypt(dst, unhex(t, plaintext))
+			if got := hex.EncodeToString(dst); got != tt.ciphertext {
+				t.Errorf("AESCipher.Encrypt() = %s, want %s", got, tt.ciphertext)
+			}
+			c.Decrypt(dst, dst)
+			if got := hex.EncodeToString(dst); got != plaintext {
+				t.Errorf("AESCipher.Decrypt() = %s, want %s", got, plaintext)
+			}
+		})
+	}
+}
+
+// Пример шифрования из FIPS-197, приложение B.
+func TestAESCipher_EncryptTrace(t *testing.T) {
+	c, err := NewAESCipher(unhex(t, "2b7e151628aed2a6abf7158809cf4f3c"))
+	if err != nil {
+		t.Fatalf("NewAESCipher() has err = %v", err)
+	}
+	dst := make([]byte, AESBlockSize)
+	trace := c.EncryptTrace(dst, unhex(t, "3243f6a8885a308d313198a2e0370734"))
+	if got, want := hex.EncodeToString(dst), "3925841d02dc09fbdc118597196a0b32"; got != want {
+		t.Errorf("AESCipher.EncryptTrace() output = %s, want %s", got, want)
+	}
+	// input и add_round_key, по четыре шага в девяти раундах и три в последнем.
+	if len(trace) != 2+4*9+3 {
+		t.Fatalf("AESCipher.EncryptTrace() has %d steps", len(trace))
+	}
+
+	for _, want := range []RoundStep{
+		{Round: 0, Step: "add_round_key", State: "193de3bea0f4e22b9ac68d2ae9f84808"},
+		{Round: 1, Step: "sub_bytes", State: "d42711aee0bf98f1b8b45de51e415230"},
+		{Round: 1, Step: "shift_rows", State: "d4bf5d30e0b452aeb84111f11e2798e5"},
+		{Round: 1, Step: "mix_columns", State: "046681e5e0cb199a48f8d37a2806264c"},
+	} {
+		found := false
+		for _, step := range trace {
+			if step.Round == want.Round && step.Step == want.Step {
+				found = true
+				if step.State != want.State {
+					t.Errorf("round %d %s = %s, want %s", want.Round, want.Step, step.State, want.State)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("round %d %s not traced", want.Round, want.Step)
+		}
+	}
+	if last := trace[len(trace)-1]; last.Round != 10 || last.Step != "add_round_key" || last.State != hex.EncodeToString(dst) {
+		t.Errorf("last step = %+v", last)
+	}
+	// Сетка заполняется по столбцам: первый столбец — первые четыре байта.
+	if grid := trace[1].Grid; grid[0][0] != "19" || grid[1][0] != "3d" || grid[0][1] != "a0" {
+		t.Errorf("grid = %v", grid)
+	}
+
+	back := make([]byte, AESBlockSize)
+	if trace := c.DecryptTrace(back, dst); len(trace) != 2+4*9+3 {
+		t.Errorf("AESCipher.DecryptTrace() has %d steps", len(trace))
+	}
+	if got, want := hex.EncodeToString(back), "3243f6a8885a308d313198a2e0370734"; got != want {
+		t.Errorf("AESCipher.DecryptTrace() output = %s, want %s", got, want)
+	}
+}
+
+func TestAESCipher_KeySchedule(t *testing.T) {
+	c, err := NewAESCipher(unhex(t, "2b7e151628aed2a6abf7158809cf4f3c"))
+	if err != nil {
+		t.Fatalf("NewAESCipher() has err = %v", err)
+	}
+	keys := c.KeySchedule()
+	if len(keys) != 11 || keys[0] != "2b7e151628aed2a6abf7158809cf4f3c" || keys[10] != "d014f9a8c9ee2589e13f0cc8b6630ca6" {
+		t.Errorf("AESCipher.KeySchedule() = %v", keys)
+	}
+}
+
+func TestAESCipher_CompareStdlib(t *testing.T) {
+	rnd := rand.New(rand.NewPCG(1, 2))
+	for _, size := range []int{16, 24, 32} {
+		for range 50 {
+			key, src := make([]byte, size), make([]byte, AESBlockSize)
+			fill(rnd, key)
+			fill(rnd, src)
+			c, err := NewAESCipher(key)
+			if err != nil {
+				t.Fatalf("NewAESCipher() has err = %v", err)
+			}
+			std, _ := aes.NewCipher(key)
+			got, want := make([]byte, AESBlockSize), make([]byte, AESBlockSize)
+			c.Encrypt(got, src)
+			std.Encrypt(want, src)
+			if !bytes.Equal(got, want) {
+				t.Fatalf("AES-%d Encrypt(%x, %x) = %x, want %x", 8*size, key, src, got, want)
+			}
+			c.Decrypt(got, src)
+			std.Decrypt(want, src)
+			if !bytes.Equal(got, want) {
+				t.Fatalf("AES-%d Decrypt(%x, %x) = %x, want %x", 8*size, key, src, got, want)
+			}
+		}
+	}
+}
+
+func TestNewAESCipher_Errors(t *testing.T) {
+	for _, size := range []int{0, 8, 15, 17, 33} {
+		if _, err := NewAESCipher(make([]byte, size)); !errors.Is(err, ErrInvalidCipherParams) {
+			t.Errorf("NewAESCipher(%d bytes) has err = %v, want %v", size, err, ErrInvalidCipherParams)
+		}
+	}
+}
+
+func fill(rnd *rand.Rand, b []byte) {
+	for i := range b {
+		b[i] = byte(rnd.Uint32())
+	}
+}
@@ -0,0 +1,313 @@
+package crypto
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"slices"
+)
+
+// Режимы работы блочного шифра по NIST SP 800-38A и ГОСТ Р 34.13-2015.
+// В CBC, CFB и OFB регистр длины IV сдвигается на блок: шифруется его
+// старший блок, а в конец дописывается шифртекст или гамма. При IV в один
+// блок это режимы NIST; ГОСТ допускает регистр в несколько блоков. CFB —
+// с сегментом в целый блок, CTR — с увеличением всего блока счётчика как
+// числа big-endian, IV короче блока дополняется нулями справа. Длина данных
+// ECB и CBC кратна блоку, остальные режимы превращают шифр в поточный
+// и дополнения не требуют.
+
+func encryptBlocks(b cipher.Block, mode string, iv, data []byte) ([]byte, error) {
+	return cryptBlocks(b, mode, iv, data, false)
+}
+
+func decryptBlocks(b cipher.Block, mode string, iv, data []byte) ([]byte, error) {
+	return cryptBlocks(b, mode, iv, data, true)
+}
+
+func cryptBlocks(b cipher.Block, mode string, iv, data []byte, decrypt bool) ([]byte, error) {
+	bs := b.BlockSize()
+	out := make([]byte, len(data))
+	register := shiftRegister(slices.Clone(iv))
+	gamma := make([]byte, bs)
+	switch mode {
+	case ModeECB:
+		for i := 0; i < len(data); i += bs {
+			if decrypt {
+				b.Decrypt(out[i:], data[i:])
+			} else {
+				b.Encrypt(out[i:], data[i:])
+			}
+		}
+	case ModeCBC:
+		for i := 0; i < len(data); i += bs {
+			if decrypt {
+				b.Decrypt(out[i:], data[i:])
+				subtle.XORBytes(out[i:i+bs], out[i:i+bs], register)
+				register.push(data[i : i+bs])
+			} else {
+				subtle.XORBytes(out[i:i+bs], data[i:i+bs], register)
+				b.Encrypt(out[i:], out[i:])
+				register.push(out[i : i+bs])
+			}
+		}
+	case ModeCFB:
+		for i := 0; i < len(data); i += bs {
+			b.Encrypt(gamma, register)
+			n := subtle.XORBytes(out[i:], data[i:], gamma)
+			if decrypt {
+				register.push(data[i : i+n])
+			} else {
+				register.push(out[i : i+n])
+			}
+		}
+	case ModeOFB:
+		for i := 0; i < len(data); i += bs {
+			b.Encrypt(gamma, register)
+			register.push(gamma)
+			subtle.XORBytes(out[i:], data[i:], gamma)
+		}
+	case ModeCTR:
+		counter := make([]byte, bs)
+		copy(counter, iv)
+		for i := 0; i < len(data); i += bs {
+			b.Encrypt(gamma, counter)
+			increment(counter)
+			subtle.XORBytes(out[i:], data[i:], gamma)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown mode %q", ErrInvalidCipherParams, mode)
+	}
+	return out, nil
+}
+
+// shiftRegister — регистр сдвига режимов CBC, CFB и OFB.
+type shiftRegister []byte
+
+// push сдвигает регистр влево на len(b) байт и записывает b в конец.
+func (r shiftRegister) push(b []byte) {
+	copy(r, r[len(b):])
+	copy(r[len(r)-len(b):], b)
+}
+
+// increment увеличивает счётчик big-endian на единицу по модулю 2^(8·len).
+func increment(counter []byte) {
+	for i := len(counter) - 1; i >= 0; i-- {
+		counter[i]++
+		if counter[i] != 0 {
+			return
+		}
+	}
+}
+
+func pad(data []byte, padding string, bs int) []byte {
+	switch padding {
+	case PaddingPKCS7:
+		return pkcs7Pad(data, bs)
+	case PaddingGOST:
+		return gostPad(data, bs)
+	}
+	return data
+}
+
+func unpad(data []byte, padding string, bs int) ([]byte, error) {
+	switch padding {
+	case PaddingPKCS7:
+		return pkcs7Unpad(data, bs)
+	case PaddingGOST:
+		return gostUnpad(data, bs)
+	}
+	return data, nil
+}
+
+// pkcs7Pad дополняет данные n байтами со значением n, 1 ≤ n ≤ размер блока.
+func pkcs7Pad(data []byte, bs int) []byte {
+	n := bs - len(data)%bs
+	out := make([]byte, len(data), len(data)+n)
+	copy(out, data)
+	for range n {
+		out = append(out, byte(n))
+	}
+	return out
+}
+
+func pkcs7Unpad(data []byte, bs int) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, ErrInvalidPadding
+	}
+	n := int(data[len(data)-1])
+	if n == 0 || n > bs || n > len(data) {
+		return nil, ErrInvalidPadding
+	}
+	for _, p := range data[len(data)-n:] {
+		if int(p) != n {
+			return nil, ErrInvalidPadding
+		}
+	}
+	return data[:len(data)-n], nil
+}
+
+// gostPad дополняет данные байтом 0x80 и нулями до целого блока; блок
+// добавляется всегда, даже если длина уже кратна ему.
+func gostPad(data []byte, bs int) []byte {
+	out := make([]byte, bs*(len(data)/bs+1))
+	copy(out, data)
+	out[len(data)] = 0x80
+	return out
+}
+
+func gostUnpad(data []byte, bs int) ([]byte, error) {
+	i := len(data) - 1
+	for i >= 0 && len(data)-i <= bs && data[i] == 0 {
+		i--
+	}
+	if i < 0 || len(data)-i > bs || data[i] != 0x80 {
+		return nil, ErrInvalidPadding
+	}
+	return data[:i], nil
+}
+
+// omac вычисляет имитовставку ГОСТ Р 34.13-2015 (OMAC1, он же CMAC):
+// CBC с нулевым IV, где к последнему блоку перед шифрованием прибавляется
+// K1, если он полный, или K2 после дополнения процедурой 3 (0x80 и нули).
+// K1 = R·x, K2 = K1·x в GF(2ⁿ), R = E(0ⁿ).
+func omac(b cipher.Block, data []byte) (mac, k1, k2 []byte) {
+	bs := b.BlockSize()
+	k1 = make([]byte, bs)
+	b.Encrypt(k1, k1)
+	k1 = gfDouble(k1)
+	k2 = gfDouble(k1)
+
+	last, k := make([]byte, bs), k1
+	n := (len(data) - 1) / bs * bs
+	if len(data) == 0 || len(data)%bs != 0 {
+		n = len(data) / bs * bs
+		copy(last, gostPad(data[n:], bs))
+		k = k2
+	} else {
+		copy(last, data[n:])
+	}
+
+	mac = make([]byte, bs)
+	for i := 0; i < n; i += bs {
+		subtle.XORBytes(mac, mac, data[i:i+bs])
+		b.Encrypt(mac, mac)
+	}
+	subtle.XORBytes(mac, mac, last)
+	subtle.XORBytes(mac, mac, k)
+	b.Encrypt(mac, mac)
+	return mac, k1, k2
+}
+
+// gfDouble умножает на x в GF(2ⁿ) с многочленом x⁶⁴+x⁴+x³+x+1 (n = 64)
+// или x¹²⁸+x⁷+x²+x+1 (n = 128): сдвиг влево и XOR младшего байта с B.
+func gfDouble(a []byte) []byte {
+	out := make([]byte, len(a))
+	for i := range a {
+		out[i] = a[i] << 1
+		if i+1 < len(a) {
+			out[i] |= a[i+1] >> 7
+		}
+	}
+	if a[0]&0x80 != 0 {
+		if len(a) == 8 {
+			out[len(out)-1] ^= 0x1b
+		} else {
+			out[len(out)-1] ^= 0x87
+		}
+	}
+	return out
+}
+
+// GCM по NIST SP 800-38D: CTR с 32-битным счётчиком, начиная с inc32(J0),
+// и тег E(J0) ⊕ GHASH(A, C). J0 = IV ‖ 0³¹ ‖ 1 для 96-битного IV, иначе
+// GHASH от IV, дополненного нулями, и его длины.
+const (
+	gcmNonceSize = 12
+	gcmTagSize   = 16
+)
+
+func gcmSeal(b cipher.Block, iv, plaintext, aad []byte) (ciphertext, tag []byte) {
+	h, j0 := gcmInit(b, iv)
+	ciphertext = gcmCTR(b, j0, plaintext)
+	return ciphertext, gcmTag(b, h, j0, aad, ciphertext)
+}
+
+func gcmOpen(b cipher.Block, iv, data, aad []byte) ([]byte, error) {
+	ciphertext, tag := data[:len(data)-gcmTagSize], data[len(data)-gcmTagSize:]
+	h, j0 := gcmInit(b, iv)
+	if subtle.ConstantTimeCompare(tag, gcmTag(b, h, j0, aad, ciphertext)) != 1 {
+		return nil, ErrAuthentication
+	}
+	return gcmCTR(b, j0, ciphertext), nil
+}
+
+func gcmInit(b cipher.Block, iv []byte) (h, j0 [16]byte) {
+	b.Encrypt(h[:], h[:])
+	if len(iv) == gcmNonceSize {
+		copy(j0[:], iv)
+		j0[15] = 1
+		return h, j0
+	}
+	var lengths [16]byte
+	binary.BigEndian.PutUint64(lengths[8:], uint64(len(iv))*8)
+	return h, ghash(h, iv, lengths[:])
+}
+
+func gcmCTR(b cipher.Block, j0 [16]byte, data []byte) []byte {
+	out := make([]byte, len(data))
+	counter := j0
+	var gamma [16]byte
+	for i := 0; i < len(data); i += 16 {
+		binary.BigEndian.PutUint32(counter[12:], binary.BigEndian.Uint32(counter[12:])+1)
+		b.Encrypt(gamma[:], counter[:])
+		subtle.XORBytes(out[i:], data[i:], gamma[:])
+	}
+	return out
+}
+
+func gcmTag(b cipher.Block, h, j0 [16]byte, aad, ciphertext []byte) []byte {
+	var lengths [16]byte
+	binary.BigEndian.PutUint64(lengths[:8], uint64(len(aad))*8)
+	binary.BigEndian.PutUint64(lengths[8:], uint64(len(ciphertext))*8)
+	s := ghash(h, aad, ciphertext, lengths[:])
+	tag := make([]byte, gcmTagSize)
+	b.Encrypt(tag, j0[:])
+	subtle.XORBytes(tag, tag, s[:])
+	return tag
+}
+
+// ghash: Y = (Y ⊕ Xᵢ)·H для блоков всех частей, каждая из которых
+// дополняется нулями до целого блока.
+func ghash(h [16]byte, parts ...[]byte) [16]byte {
+	var y [16]byte
+	for _, part := range parts {
+		for i := 0; i < len(part); i += 16 {
+			subtle.XORBytes(y[:], y[:], part[i:min(i+16, len(part))])
+			y = gfMul128(y, h)
+		}
+	}
+	return y
+}
+
+// gfMul128 умножает в GF(2¹²⁸) по модулю x¹²⁸+x⁷+x²+x+1. В GCM биты
+// блока идут от младшей степени к старшей, поэтому умножение на x — это
+// сдвиг вправо, а приведение — XOR с R = 11100001 ‖ 0¹²⁰.
+func gfMul128(x, y [16]byte) [16]byte {
+	var z [16]byte
+	v := y
+	for i := range 128 {
+		if x[i/8]>>(7-i%8)&1 == 1 {
+			subtle.XORBytes(z[:], z[:], v[:])
+		}
+		lsb := v[15] & 1
+		for j := 15; j > 0; j-- {
+			v[j] = v[j]>>1 | v[j-1]<<7
+		}
+		v[0] >>= 1
+		if lsb == 1 {
+			v[0] ^= 0xe1
+		}
+	}
+	return z
+}
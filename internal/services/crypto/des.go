@@ -0,0 +1,323 @@
+package crypto
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// DESBlockSize — размер блока DES в байтах.
+const DESBlockSize = 8
+
+// DESCipher — DES по FIPS 46-3: начальная перестановка IP, 16 раундов сети
+// Фейстеля L' = R, R' = L ⊕ f(R, K) и обратная перестановка IP⁻¹. Функция
+// f расширяет R до 48 бит (E), складывает с ключом раунда, заменяет
+// шестёрки бит по S-блокам на четвёрки и переставляет результат (P).
+// Биты во всех таблицах нумеруются с единицы от старшего.
+type DESCipher struct {
+	subkeys  [16]uint64
+	keyTrace []RoundStep
+}
+
+var desIP = []byte{
+	58, 50, 42, 34, 26, 18, 10, 2, 60, 52, 44, 36, 28, 20, 12, 4,
+	62, 54, 46, 38, 30, 22, 14, 6, 64, 56, 48, 40, 32, 24, 16, 8,
+	57, 49, 41, 33, 25, 17, 9, 1, 59, 51, 43, 35, 27, 19, 11, 3,
+	61, 53, 45, 37, 29, 21, 13, 5, 63, 55, 47, 39, 31, 23, 15, 7,
+}
+
+var desE = []byte{
+	32, 1, 2, 3, 4, 5, 4, 5, 6, 7, 8, 9, 8, 9, 10, 11,
+	12, 13, 12, 13, 14, 15, 16, 17, 16, 17, 18, 19, 20, 21, 20, 21,
+	22, 23, 24, 25, 24, 25, 26, 27, 28, 29, 28, 29, 30, 31, 32, 1,
+}
+
+var desP = []byte{
+	16, 7, 20, 21, 29, 12, 28, 17, 1, 15, 23, 26, 5, 18, 31, 10,
+	2, 8, 24, 14, 32, 27, 3, 9, 19, 13, 30, 6, 22, 11, 4, 25,
+}
+
+var desPC1 = []byte{
+	57, 49, 41, 33, 25, 17, 9, 1, 58, 50, 42, 34, 26, 18,
+	10, 2, 59, 51, 43, 35, 27, 19, 11, 3, 60, 52, 44, 36,
+	63, 55, 47, 39, 31, 23, 15, 7, 62, 54, 46, 38, 30, 22,
+	14, 6, 61, 53, 45, 37, 29, 21, 13, 5, 28, 20, 12, 4,
+}
+
+var desPC2 = []byte{
+	14, 17, 11, 24, 1, 5, 3, 28, 15, 6, 21, 10,
+	23, 19, 12, 4, 26, 8, 16, 7, 27, 20, 13, 2,
+	41, 52, 31, 37, 47, 55, 30, 40, 51, 45, 33, 48,
+	44, 49, 39, 56, 34, 53, 46, 42, 50, 36, 29, 32,
+}
+
+var desShifts = [16]int{1, 1, 2, 2, 2, 2, 2, 2, 1, 2, 2, 2, 2, 2, 2, 1}
+
+// desSBoxes[i][row][column]: строка — крайние биты шестёрки, столбец — средние четыре.
+var desSBoxes = [8][4][16]byte{
+	{
+		{14, 4, 13, 1, 2, 15, 11, 8, 3, 10, 6, 12, 5, 9, 0, 7},
+		{0, 15, 7, 4, 14, 2, 13, 1, 10, 6, 12, 11, 9, 5, 3, 8},
+		{4, 1, 14, 8, 13, 6, 2, 11, 15, 12, 9, 7, 3, 10, 5, 0},
+		{15, 12, 8, 2, 4, 9, 1, 7, 5, 11, 3, 14, 10, 0, 6, 13},
+	},
+	{
+		{15, 1, 8, 14, 6, 11, 3, 4, 9, 7, 2, 13, 12, 0, 5, 10},
+		{3, 13, 4, 7, 15, 2, 8, 14, 12, 0, 1, 10, 6, 9, 11, 5},
+		{0, 14, 7, 11, 10, 4, 13, 1, 5, 8, 12, 6, 9, 3, 2, 15},
+		{13, 8, 10, 1, 3, 15, 4, 2, 11, 6, 7, 12, 0, 5, 14, 9},
+	},
+	{
+		{10, 0, 9, 14, 6, 3, 15, 5, 1, 13, 12, 7, 11, 4, 2, 8},
+		{13, 7, 0, 9, 3, 4, 6, 10, 2, 8, 5, 14, 12, 11, 15, 1},
+		{13, 6, 4, 9, 8, 15, 3, 0, 11, 1, 2, 12, 5, 10, 14, 7},
+		{1, 10, 13, 0, 6, 9, 8, 7, 4, 15, 14, 3, 11, 5, 2, 12},
+	},
+	{
+		{7, 13, 14, 3, 0, 6, 9, 10, 1, 2, 8, 5, 11, 12, 4, 15},
+		{13, 8, 11, 5, 6, 15, 0, 3, 4, 7, 2, 12, 1, 10, 14, 9},
+		{10, 6, 9, 0, 12, 11, 7, 13, 15, 1, 3, 14, 5, 2, 8, 4},
+		{3, 15, 0, 6, 10, 1, 13, 8, 9, 4, 5, 11, 12, 7, 2, 14},
+	},
+	{
+		{2, 12, 4, 1, 7, 10, 11, 6, 8, 5, 3, 15, 13, 0, 14, 9},
+		{14, 11, 2, 12, 4, 7, 13, 1, 5, 0, 15, 10, 3, 9, 8, 6},
+		{4, 2, 1, 11, 10, 13, 7, 8, 15, 9, 12, 5, 6, 3, 0, 14},
+		{11, 8, 12, 7, 1, 14, 2, 13, 6, 15, 0, 9, 10, 4, 5, 3},
+	},
+	{
+		{12, 1, 10, 15, 9, 2, 6, 8, 0, 13, 3, 4, 14, 7, 5, 11},
+		{10, 15, 4, 2, 7, 12, 9, 5, 6, 1, 13, 14, 0, 11, 3, 8},
+		{9, 14, 15, 5, 2, 8, 12, 3, 7, 0, 4, 10, 1, 13, 11, 6},
+		{4, 3, 2, 12, 9, 5, 15, 10, 11, 14, 1, 7, 6, 0, 8, 13},
+	},
+	{
+		{4, 11, 2, 14, 15, 0, 8, 13, 3, 12, 9, 7, 5, 10, 6, 1},
+		{13, 0, 11, 7, 4, 9, 1, 10, 14, 3, 5, 12, 2, 15, 8, 6},
+		{1, 4, 11, 13, 12, 3, 7, 14, 10, 15, 6, 8, 0, 5, 9, 2},
+		{6, 11, 13, 8, 1, 4, 10, 7, 9, 5, 0, 15, 14, 2, 3, 12},
+	},
+	{
+		{13, 2, 8, 4, 6, 15, 11, 1, 10, 9, 3, 14, 5, 0, 12, 7},
+		{1, 15, 13, 8, 10, 3, 7, 4, 12, 5, 6, 11, 0, 14, 9, 2},
+		{7, 11, 4, 1, 9, 12, 14, 2, 0, 6, 10, 13, 15, 3, 5, 8},
+		{2, 1, 14, 7, 4, 10, 8, 13, 15, 12, 9, 0, 3, 5, 6, 11},
+	},
+}
+
+// desFP — IP⁻¹.
+var desFP = make([]byte, 64)
+
+func init() {
+	for i, p := range desIP {
+		desFP[p-1] = byte(i + 1)
+	}
+}
+
+// permute строит число из len(table) бит, i-й бит которого — бит table[i]
+// n-битного входа.
+func permute(in uint64, n int, table []byte) uint64 {
+	var out uint64
+	for _, p := range table {
+		out = out<<1 | in>>(n-int(p))&1
+	}
+	return out
+}
+
+// NewDESCipher создаёт DES с 64-битным ключом; биты чётности не проверяются.
+// Ключи раундов: PC-1 делит ключ на половины C и D по 28 бит, в каждом раунде
+// они циклически сдвигаются влево на 1 или 2 бита, и PC-2 выбирает 48 бит.
+func NewDESCipher(key []byte) (*DESCipher, error) {
+	if len(key) != 8 {
+		return nil, fmt.Errorf("%w: DES key must be 8 bytes, got %d", ErrInvalidCipherParams, len(key))
+	}
+	c := &DESCipher{}
+	cd := permute(binary.BigEndian.Uint64(key), 64, desPC1)
+	recordBits(&c.keyTrace, 0, "pc1", cd, 56, "")
+	l, r := uint32(cd>>28), uint32(cd&0xfffffff)
+	for i, shift := range desShifts {
+		l = (l<<shift | l>>(28-shift)) & 0xfffffff
+		r = (r<<shift | r>>(28-shift)) & 0xfffffff
+		c.subkeys[i] = permute(uint64(l)<<28|uint64(r), 56, desPC2)
+		recordBits(&c.keyTrace, i+1, "shift", uint64(l)<<28|uint64(r), 56, fmt.Sprintf("%012x", c.subkeys[i]))
+	}
+	return c, nil
+}
+
+func (c *DESCipher) BlockSize() int {
+	return DESBlockSize
+}
+
+func (c *DESCipher) Encrypt(dst, src []byte) {
+	c.crypt(dst, src, false, nil)
+}
+
+func (c *DESCipher) Decrypt(dst, src []byte) {
+	c.crypt(dst, src, true, nil)
+}
+
+func (c *DESCipher) EncryptTrace(dst, src []byte) []RoundStep {
+	var trace []RoundStep
+	c.crypt(dst, src, false, &trace)
+	return trace
+}
+
+func (c *DESCipher) DecryptTrace(dst, src []byte) []RoundStep {
+	var trace []RoundStep
+	c.crypt(dst, src, true, &trace)
+	return trace
+}
+
+// KeySchedule возвращает 48-битные ключи раундов K1…K16 в hex.
+func (c *DESCipher) KeySchedule() []string {
+	keys := make([]string, len(c.subkeys))
+	for i, k := range c.subkeys {
+		keys[i] = fmt.Sprintf("%012x", k)
+	}
+	return keys
+}
+
+// KeyScheduleTrace возвращает C0 ‖ D0 после PC-1 и Ci ‖ Di после сдвигов
+// вместе с ключом раунда.
+func (c *DESCipher) KeyScheduleTrace() []RoundStep {
+	return c.keyTrace
+}
+
+func (c *DESCipher) crypt(dst, src []byte, decrypt bool, trace *[]RoundStep) {
+	b := binary.BigEndian.Uint64(src)
+	recordBits(trace, 0, "input", b, 64, "")
+	b = permute(b, 64, desIP)
+	recordBits(trace, 0, "initial_permutation", b, 64, "")
+	l, r := b>>32, b&0xffffffff
+	for round := 1; round <= len(c.subkeys); round++ {
+		k := c.subkeys[round-1]
+		if decrypt {
+			k = c.subkeys[len(c.subkeys)-round]
+		}
+		key := ""
+		if trace != nil {
+			key = fmt.Sprintf("%012x", k)
+		}
+		l, r = r, l^desF(r, k, round, key, trace)
+		recordBits(trace, round, "round", l<<32|r, 64, key)
+	}
+	// После последнего раунда половины не меняются местами.
+	b = permute(r<<32|l, 64, desFP)
+	recordBits(trace, len(c.subkeys), "final_permutation", b, 64, "")
+	binary.BigEndian.PutUint64(dst, b)
+}
+
+func desF(r, k uint64, round int, key string, trace *[]RoundStep) uint64 {
+	e := permute(r, 32, desE)
+	recordBits(trace, round, "expansion", e, 48, "")
+	x := e ^ k
+	recordBits(trace, round, "add_round_key", x, 48, key)
+	var s uint64
+	for i := range desSBoxes {
+		six := x >> (42 - 6*i) & 0x3f
+		s = s<<4 | uint64(desSBoxes[i][six>>4&2|six&1][six>>1&0xf])
+	}
+	recordBits(trace, round, "s_boxes", s, 32, "")
+	f := permute(s, 32, desP)
+	recordBits(trace, round, "permutation", f, 32, "")
+	return f
+}
+
+func recordBits(trace *[]RoundStep, round int, step string, v uint64, n int, key string) {
+	if trace != nil {
+		*trace = append(*trace, RoundStep{Round: round, Step: step, State: fmt.Sprintf("%0*x", n/4, v), Key: key})
+	}
+}
+
+// TripleDESCipher — 3DES (TDEA, NIST SP 800-67) по схеме EDE:
+// C = E_K3(D_K2(E_K1(P))). Ключ из 16 байт задаёт вариант с двумя ключами, K3 = K1.
+type TripleDESCipher struct {
+	stages [3]*DESCipher
+}
+
+func NewTripleDESCipher(key []byte) (*TripleDESCipher, error) {
+	if len(key) != 16 && len(key) != 24 {
+		return nil, fmt.Errorf("%w: 3DES key must be 16 or 24 bytes, got %d", ErrInvalidCipherParams, len(key))
+	}
+	if len(key) == 16 {
+		key = append(key[:16:16], key[:8]...)
+	}
+	c := &TripleDESCipher{}
+	for i := range c.stages {
+		c.stages[i], _ = NewDESCipher(key[8*i : 8*i+8])
+	}
+	return c, nil
+}
+
+func (c *TripleDESCipher) BlockSize() int {
+	return DESBlockSize
+}
+
+func (c *TripleDESCipher) Encrypt(dst, src []byte) {
+	c.crypt(dst, src, false, nil)
+}
+
+func (c *TripleDESCipher) Decrypt(dst, src []byte) {
+	c.crypt(dst, src, true, nil)
+}
+
+func (c *TripleDESCipher) EncryptTrace(dst, src []byte) []RoundStep {
+	var trace []RoundStep
+	c.crypt(dst, src, false, &trace)
+	return trace
+}
+
+func (c *TripleDESCipher) DecryptTrace(dst, src []byte) []RoundStep {
+	var trace []RoundStep
+	c.crypt(dst, src, true, &trace)
+	return trace
+}
+
+// KeySchedule возвращает ключи раундов K1…K16 каждого из трёх ключей подряд.
+func (c *TripleDESCipher) KeySchedule() []string {
+	var keys []string
+	for _, stage := range c.stages {
+		keys = append(keys, stage.KeySchedule()...)
+	}
+	return keys
+}
+
+// KeyScheduleTrace объединяет трассы трёх ключей, нумеруя раунды сквозь.
+func (c *TripleDESCipher) KeyScheduleTrace() []RoundStep {
+	var trace []RoundStep
+	for i, stage := range c.stages {
+		trace = appendStage(trace, stage.KeyScheduleTrace(), i)
+	}
+	return trace
+}
+
+// crypt выполняет три прохода DES; в трассе раунды нумеруются сквозь
+// (1–48), а вход второго и третьего прохода не повторяется.
+func (c *TripleDESCipher) crypt(dst, src []byte, decrypt bool, trace *[]RoundStep) {
+	buf := make([]byte, DESBlockSize)
+	copy(buf, src)
+	for i := range c.stages {
+		stage, inverse := c.stages[i], i == 1
+		if decrypt {
+			stage, inverse = c.stages[len(c.stages)-1-i], i != 1
+		}
+		if trace == nil {
+			stage.crypt(buf, buf, inverse, nil)
+			continue
+		}
+		var steps []RoundStep
+		stage.crypt(buf, buf, inverse, &steps)
+		if i > 0 {
+			steps = steps[1:]
+		}
+		*trace = appendStage(*trace, steps, i)
+	}
+	copy(dst, buf)
+}
+
+func appendStage(trace, steps []RoundStep, stage int) []RoundStep {
+	for _, step := range steps {
+		step.Round += 16 * stage
+		trace = append(trace, step)
+	}
+	return trace
+}
@@ -0,0 +1,193 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/des"
+	"encoding/hex"
+	"errors"
+	"math/rand/v2"
+	"testing"
+)
+
+// Известные ответы из NIST SP 800-17, приложение A: переменный открытый
+// текст, переменный ключ, проверка перестановок и S-блоков.
+func TestDESCipher_KAT(t *testing.T) {
+	tests := []struct {
+		key        string
+		plaintext  string
+		ciphertext string
+	}{
+		{"0101010101010101", "8000000000000000", "95f8a5e5dd31d900"},
+		{"0101010101010101", "4000000000000000", "dd7f121ca5015619"},
+		{"0101010101010101", "2000000000000000", "2e8653104f3834ea"},
+		{"8001010101010101", "0000000000000000", "95a8d72813daa94d"},
+		{"4001010101010101", "0000000000000000", "0eec1487dd8c26d5"},
+		{"1046913489980131", "0000000000000000", "88d55e54f54c97b4"},
+		{"7ca110454a1a6e57", "01a1d6d039776742", "690f5b0d9a26939b"},
+		{"133457799bbcdff1", "0123456789abcdef", "85e813540f0ab405"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key+"/"+tt.plaintext, func(t *testing.T) {
+			c, err := NewDESCipher(unhex(t, tt.key))
+			if err != nil {
+				t.Fatalf("NewDESCipher() has err = %v", err)
+			}
+			dst := make([]byte, DESBlockSize)
+			c.Encrypt(dst, unhex(t, tt.plaintext))
+			if got := hex.EncodeToString(dst); got != tt.ciphertext {
+				t.Errorf("DESCipher.Encrypt() = %s, want %s", got, tt.ciphertext)
+			}
+			c.Decrypt(dst, dst)
+			if got := hex.EncodeToString(dst); got != tt.plaintext {
+				t.Errorf("DESCipher.Decrypt() = %s, want %s", got, tt.plaintext)
+			}
+		})
+	}
+}
+
+// Пример 3DES из NIST SP 800-67, приложение B.
+func TestTripleDESCipher_KAT(t *testing.T) {
+	c, err := NewTripleDESCipher(unhex(t, "0123456789abcdef23456789abcdef01456789abcdef0123"))
+	if err != nil {
+		t.Fatalf("NewTripleDESCipher() has err = %v", err)
+	}
+	dst := make([]byte, DESBlockSize)
+	c.Encrypt(dst, []byte("The qufc"))
+	if got, want := hex.EncodeToString(dst), "a826fd8ce53b855f"; got != want {
+		t.Errorf("TripleDESCipher.Encrypt() = %s, want %s", got, want)
+	}
+	c.Decrypt(dst, dst)
+	if string(dst) != "The qufc" {
+		t.Errorf("TripleDESCipher.Decrypt() = %q", dst)
+	}
+}
+
+// Ход первого раунда для ключа 133457799bbcdff1 и текста 0123456789abcdef.
+func TestDESCipher_Trace(t *testing.T) {
+	c, err := NewDESCipher(unhex(t, "133457799bbcdff1"))
+	if err != nil {
+		t.Fatalf("NewDESCipher() has err = %v", err)
+	}
+	keys := c.KeyScheduleTrace()
+	if len(keys) != 17 || keys[0].State != "f0ccaaf556678f" || keys[1].Key != "1b02effc7072" || keys[16].Key != c.KeySchedule()[15] {
+		t.Errorf("DESCipher.KeyScheduleTrace() = %+v", keys)
+	}
+
+	dst := make([]byte, DESBlockSize)
+	trace := c.EncryptTrace(dst, unhex(t, "0123456789abcdef"))
+	if len(trace) != 2+5*16+1 {
+		t.Fatalf("DESCipher.EncryptTrace() has %d steps", len(trace))
+	}
+	for i, want := range []RoundStep{
+		{Round: 0, Step: "initial_permutation", State: "cc00ccfff0aaf0aa"},
+		{Round: 1, Step: "expansion", State: "7a15557a1555"},
+		{Round: 1, Step: "add_round_key", State: "6117ba866527", Key: "1b02effc7072"},
+		{Round: 1, Step: "s_boxes", State: "5c82b597"},
+		{Round: 1, Step: "permutation", State: "234aa9bb"},
+		{Round: 1, Step: "round", State: "f0aaf0aaef4a6544", Key: "1b02effc7072"},
+	} {
+		if step := trace[1+i]; step.Round != want.Round || step.Step != want.Step || step.State != want.State || step.Key != want.Key {
+			t.Errorf("step %d = %+v, want %+v", 1+i, step, want)
+		}
+	}
+	if last := trace[len(trace)-1]; last.Step != "final_permutation" || last.State != "85e813540f0ab405" {
+		t.Errorf("last step = %+v", last)
+	}
+
+	c3, _ := NewTripleDESCipher(unhex(t, "133457799bbcdff10123456789abcdef"))
+	trace = c3.EncryptTrace(dst, dst)
+	if len(trace) != 3*(2+5*16+1)-2 || trace[len(trace)-1].Round != 48 {
+		t.Errorf("TripleDESCipher.EncryptTrace() has %d steps, last %+v", len(trace), trace[len(trace)-1])
+	}
+	if keys := c3.KeyScheduleTrace(); len(keys) != 3*17 || keys[len(keys)-1].Round != 48 {
+		t.Errorf("TripleDESCipher.KeyScheduleTrace() has %d steps", len(keys))
+	}
+}
+
+func TestDESCipher_CompareStdlib(t *testing.T) {
+	rnd := rand.New(rand.NewPCG(7, 8))
+	for range 100 {
+		key, src := make([]byte, 24), make([]byte, DESBlockSize)
+		fill(rnd, key)
+		fill(rnd, src)
+
+		for _, size := range []int{8, 16, 24} {
+			var got, std cipher.Block
+			if size == 8 {
+				got, _ = NewDESCipher(key[:8])
+				std, _ = des.NewCipher(key[:8])
+			} else {
+				got, _ = NewTripleDESCipher(key[:size])
+				std, _ = des.NewTripleDESCipher(append(key[:size:size], key[:24-size]...))
+			}
+			a, b := make([]byte, DESBlockSize), make([]byte, DESBlockSize)
+			got.Encrypt(a, src)
+			std.Encrypt(b, src)
+			if !bytes.Equal(a, b) {
+				t.Fatalf("Encrypt(%x, %x) with %d-byte key = %x, want %x", key[:size], src, size, a, b)
+			}
+			got.Decrypt(a, src)
+			std.Decrypt(b, src)
+			if !bytes.Equal(a, b) {
+				t.Fatalf("Decrypt(%x, %x) with %d-byte key = %x, want %x", key[:size], src, size, a, b)
+			}
+		}
+	}
+}
+
+func TestSymmetricService_DES(t *testing.T) {
+	s := NewSymmetricService()
+	key := unhex(t, "0123456789abcdef23456789abcdef01456789abcdef0123")
+	iv := unhex(t, "fedcba9876543210")
+	data := []byte("Сеть Фейстеля")
+	block, _ := des.NewTripleDESCipher(key)
+
+	for _, mode := range []string{ModeECB, ModeCBC} {
+		padded := pkcs7Pad(data, DESBlockSize)
+		want := make([]byte, len(padded))
+		if mode == ModeECB {
+			for i := 0; i < len(padded); i += DESBlockSize {
+				block.Encrypt(want[i:], padded[i:])
+			}
+		} else {
+			cipher.NewCBCEncrypter(block, iv).CryptBlocks(want, padded)
+		}
+
+		params := SymmetricParams{Mode: mode, Key: key, Data: data, Trace: true}
+		if mode == ModeCBC {
+			params.IV = iv
+		}
+		result, err := s.Encrypt("3des", params)
+		if err != nil {
+			t.Fatalf("SymmetricService.Encrypt(%s) has err = %v", mode, err)
+		}
+		if !bytes.Equal(result.Data, want) {
+			t.Errorf("SymmetricService.Encrypt(%s) = %x, want %x", mode, result.Data, want)
+		}
+		if len(result.KeySchedule) != 48 || len(result.KeyScheduleTrace) != 51 || len(result.Trace) != MaxTraceBlocks {
+			t.Errorf("SymmetricService.Encrypt(%s) trace is incomplete", mode)
+		}
+
+		params.Data, params.Trace = result.Data, false
+		result, err = s.Decrypt("3des", params)
+		if err != nil {
+			t.Fatalf("SymmetricService.Decrypt(%s) has err = %v", mode, err)
+		}
+		if result.Text != string(data) {
+			t.Errorf("SymmetricService.Decrypt(%s) = %q, want %q", mode, result.Text, data)
+		}
+	}
+
+	for _, tt := range []struct {
+		algorithm string
+		key       []byte
+	}{
+		{"des", make([]byte, 16)},
+		{"3des", make([]byte, 8)},
+	} {
+		if _, err := s.Encrypt(tt.algorithm, SymmetricParams{Key: tt.key}); !errors.Is(err, ErrInvalidCipherParams) {
+			t.Errorf("SymmetricService.Encrypt(%s, %d-byte key) has err = %v, want %v", tt.algorithm, len(tt.key), err, ErrInvalidCipherParams)
+		}
+	}
+}
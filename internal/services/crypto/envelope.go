@@ -0,0 +1,192 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Гибридное шифрование: данные шифруются случайным сеансовым ключом
+// AES-256-GCM, а сам ключ — открытым ключом RSA. Формат конверта:
+//
+//	"CLHE" | версия (1) | схема RSA (1) | AEAD (1) | длина ключа (2, LE) |
+//	зашифрованный ключ | nonce (12) | шифртекст с тегом GCM (16)
+//
+// Заголовок целиком входит в дополнительные данные AEAD, поэтому подмена
+// схемы или ключа обнаруживается при расшифровании.
+
+var envelopeMagic = []byte("CLHE")
+
+const (
+	envelopeVersion = 1
+
+	envelopeAES256GCM = 1
+	sessionKeySize    = 32
+)
+
+// envelopeSchemes — идентификаторы схем шифрования сеансового ключа.
+var envelopeSchemes = []string{SchemePKCS1v15, SchemeOAEPSHA1, SchemeOAEPSHA256}
+
+// ErrInvalidEnvelope возвращается, если данные не являются конвертом
+// или его заголовок повреждён.
+var ErrInvalidEnvelope = errors.New("invalid hybrid envelope")
+
+type EnvelopeDetails struct {
+	Scheme         string  `json:"scheme"`
+	Cipher         string  `json:"cipher"`
+	WrappedKeySize int     `json:"wrapped_key_size"`
+	Nonce          string  `json:"nonce"`
+	Size           int     `json:"size"`
+	EnvelopeSize   int     `json:"envelope_size"`
+	Overhead       float32 `json:"overhead"`
+}
+
+// SealEnvelope шифрует data сеансовым ключом AES-256-GCM и шифрует ключ
+// открытым ключом pub по схеме scheme (SchemePKCS1v15 или OAEP).
+func (r *RSAService) SealEnvelope(pub *PublicKey, data []byte, scheme string) ([]byte, EnvelopeDetails, error) {
+	schemeID := envelopeSchemeID(scheme)
+	if schemeID < 0 {
+		return nil, EnvelopeDetails{}, fmt.Errorf("%w: unknown scheme %q, want one of %v", ErrInvalidParams, scheme, envelopeSchemes)
+	}
+	newHash, _ := OAEPHash(scheme)
+
+	sessionKey := make([]byte, sessionKeySize)
+	if _, err := rand.Read(sessionKey); err != nil {
+		return nil, EnvelopeDetails{}, err
+	}
+	var wrappedKey []byte
+	var err error
+	if newHash == nil {
+		wrappedKey, err = r.Encrypt(pub, sessionKey)
+	} else {
+		wrappedKey, err = r.EncryptOAEP(newHash, pub, sessionKey, nil)
+	}
+	if err != nil {
+		return nil, EnvelopeDetails{}, err
+	}
+
+	aead, err := newEnvelopeAEAD(sessionKey)
+	if err != nil {
+		return nil, EnvelopeDetails{}, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, EnvelopeDetails{}, err
+	}
+
+	buf := new(bytes.Buffer)
+	buf.Write(envelopeMagic)
+	buf.WriteByte(envelopeVersion)
+	buf.WriteByte(byte(schemeID))
+	buf.WriteByte(envelopeAES256GCM)
+	binary.Write(buf, binary.LittleEndian, uint16(len(wrappedKey)))
+	buf.Write(wrappedKey)
+	buf.Write(nonce)
+	header := buf.Bytes()
+	envelope := aead.Seal(header, nonce, data, header)
+
+	return envelope, envelopeDetails(scheme, len(wrappedKey), nonce, len(data), len(envelope)), nil
+}
+
+// OpenEnvelope расшифровывает конверт закрытым ключом priv. Неверный ключ,
+// повреждённый шифртекст и неверный тег неразличимы: во всех случаях
+// возвращается ErrDecryption.
+func (r *RSAService) OpenEnvelope(priv *PrivateKey, envelope []byte) ([]byte, EnvelopeDetails, error) {
+	headerSize := len(envelopeMagic) + 5
+	if !bytes.HasPrefix(envelope, envelopeMagic) || len(envelope) < headerSize {
+		return nil, EnvelopeDetails{}, ErrInvalidEnvelope
+	}
+	version, schemeID, cipherID := envelope[4], int(envelope[5]), envelope[6]
+	if version != envelopeVersion || schemeID >= len(envelopeSchemes) || cipherID != envelopeAES256GCM {
+		return nil, EnvelopeDetails{}, fmt.Errorf("%w: unsupported version %d, scheme %d or cipher %d",
+			ErrInvalidEnvelope, version, schemeID, cipherID)
+	}
+	scheme := envelopeSchemes[schemeID]
+	wrappedKeySize := int(binary.LittleEndian.Uint16(envelope[7:]))
+	nonceSize := 12
+	if len(envelope) < headerSize+wrappedKeySize+nonceSize {
+		return nil, EnvelopeDetails{}, fmt.Errorf("%w: truncated header", ErrInvalidEnvelope)
+	}
+	wrappedKey := envelope[headerSize : headerSize+wrappedKeySize]
+	nonce := envelope[headerSize+wrappedKeySize : headerSize+wrappedKeySize+nonceSize]
+	header := envelope[:headerSize+wrappedKeySize+nonceSize]
+
+	sessionKey, err := r.unwrapSessionKey(priv, wrappedKey, scheme)
+	if err != nil {
+		return nil, EnvelopeDetails{}, err
+	}
+	aead, err := newEnvelopeAEAD(sessionKey)
+	if err != nil {
+		return nil, EnvelopeDetails{}, err
+	}
+	data, err := aead.Open(nil, nonce, envelope[len(header):], header)
+	if err != nil {
+		return nil, EnvelopeDetails{}, ErrDecryption
+	}
+
+	return data, envelopeDetails(scheme, wrappedKeySize, nonce, len(data), len(envelope)), nil
+}
+
+// unwrapSessionKey расшифровывает сеансовый ключ. Если ключ PKCS#1 v1.5
+// не расшифровался, вместо ошибки возвращается случайный ключ: тогда
+// расшифрование завершится ошибкой тега так же, как при верном дополнении,
+// и по ответу нельзя отличить неверное дополнение (RFC 5246, 7.4.7.1).
+func (r *RSAService) unwrapSessionKey(priv *PrivateKey, wrappedKey []byte, scheme string) ([]byte, error) {
+	newHash, _ := OAEPHash(scheme)
+	if newHash != nil {
+		sessionKey, err := r.DecryptOAEP(newHash, priv, wrappedKey, nil)
+		if err != nil || len(sessionKey) != sessionKeySize {
+			return nil, ErrDecryption
+		}
+		return sessionKey, nil
+	}
+
+	sessionKey := make([]byte, sessionKeySize)
+	if _, err := rand.Read(sessionKey); err != nil {
+		return nil, err
+	}
+	key, err := r.Decrypt(priv, wrappedKey)
+	if err != nil && !errors.Is(err, ErrDecryption) {
+		return nil, err
+	}
+	if err != nil || len(key) != sessionKeySize {
+		return sessionKey, nil
+	}
+	return key, nil
+}
+
+func newEnvelopeAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func envelopeSchemeID(scheme string) int {
+	for id, s := range envelopeSchemes {
+		if s == scheme {
+			return id
+		}
+	}
+	return -1
+}
+
+func envelopeDetails(scheme string, wrappedKeySize int, nonce []byte, size, envelopeSize int) EnvelopeDetails {
+	details := EnvelopeDetails{
+		Scheme:         scheme,
+		Cipher:         "AES-256-GCM",
+		WrappedKeySize: wrappedKeySize,
+		Nonce:          fmt.Sprintf("%x", nonce),
+		Size:           size,
+		EnvelopeSize:   envelopeSize,
+	}
+	if size > 0 {
+		details.Overhead = float32(envelopeSize-size) / float32(size)
+	}
+	return details
+}
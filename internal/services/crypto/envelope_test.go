@@ -0,0 +1,101 @@
+package crypto
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestRSAService_Envelope(t *testing.T) {
+	r := NewRsaService()
+	pub, priv, err := r.GenerateKeys(1024, DefaultExponent)
+	if err != nil {
+		t.Fatalf("RSAService.GenerateKeys() has err = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		scheme string
+		data   []byte
+	}{
+		{"pkcs1v15", SchemePKCS1v15, bytes.Repeat([]byte("Большой документ. "), 10000)},
+		{"oaep-sha1", SchemeOAEPSHA1, []byte("Короткий документ")},
+		{"oaep-sha256", SchemeOAEPSHA256, bytes.Repeat([]byte{0, 1, 2, 3}, 1<<16)},
+		{"empty", SchemeOAEPSHA256, []byte{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			envelope, details, err := r.SealEnvelope(pub, tt.data, tt.scheme)
+			if err != nil {
+				t.Fatalf("RSAService.SealEnvelope() has err = %v", err)
+			}
+			if details.EnvelopeSize != len(envelope) || details.WrappedKeySize != 128 || details.Scheme != tt.scheme {
+				t.Errorf("RSAService.SealEnvelope() details = %+v", details)
+			}
+			data, openDetails, err := r.OpenEnvelope(priv, envelope)
+			if err != nil {
+				t.Fatalf("RSAService.OpenEnvelope() has err = %v", err)
+			}
+			if !bytes.Equal(data, tt.data) {
+				t.Errorf("RSAService.OpenEnvelope() returned other data")
+			}
+			if openDetails != details {
+				t.Errorf("RSAService.OpenEnvelope() details = %+v, want %+v", openDetails, details)
+			}
+		})
+	}
+}
+
+func TestRSAService_EnvelopeErrors(t *testing.T) {
+	r := NewRsaService()
+	pub, priv, err := r.GenerateKeys(1024, DefaultExponent)
+	if err != nil {
+		t.Fatalf("RSAService.GenerateKeys() has err = %v", err)
+	}
+	_, otherPriv, err := r.GenerateKeys(1024, DefaultExponent)
+	if err != nil {
+		t.Fatalf("RSAService.GenerateKeys() has err = %v", err)
+	}
+
+	for _, scheme := range []string{SchemePKCS1v15, SchemeOAEPSHA256} {
+		envelope, _, err := r.SealEnvelope(pub, []byte("документ"), scheme)
+		if err != nil {
+			t.Fatalf("RSAService.SealEnvelope() has err = %v", err)
+		}
+		modified := func(i int) []byte {
+			e := bytes.Clone(envelope)
+			e[i] ^= 0x01
+			return e
+		}
+		// Подмена схемы на другую допустимую обнаруживается: заголовок входит в AAD.
+		otherScheme := bytes.Clone(envelope)
+		otherScheme[5] = byte(envelopeSchemeID(SchemeOAEPSHA1))
+		tests := []struct {
+			name     string
+			priv     *PrivateKey
+			envelope []byte
+			want     error
+		}{
+			{"other key", otherPriv, envelope, ErrDecryption},
+			{"wrapped key", priv, modified(20), ErrDecryption},
+			{"scheme", priv, otherScheme, ErrDecryption},
+			{"ciphertext", priv, modified(len(envelope) - 20), ErrDecryption},
+			{"tag", priv, modified(len(envelope) - 1), ErrDecryption},
+			{"truncated", priv, envelope[:len(envelope)-17], ErrDecryption},
+			{"magic", priv, modified(0), ErrInvalidEnvelope},
+			{"version", priv, modified(4), ErrInvalidEnvelope},
+			{"header", priv, envelope[:100], ErrInvalidEnvelope},
+		}
+		for _, tt := range tests {
+			t.Run(scheme+" "+tt.name, func(t *testing.T) {
+				if _, _, err := r.OpenEnvelope(tt.priv, tt.envelope); !errors.Is(err, tt.want) {
+					t.Errorf("RSAService.OpenEnvelope() has err = %v, want %v", err, tt.want)
+				}
+			})
+		}
+	}
+
+	if _, _, err := r.SealEnvelope(pub, nil, "rot13"); !errors.Is(err, ErrInvalidParams) {
+		t.Errorf("RSAService.SealEnvelope() has err = %v, want %v", err, ErrInvalidParams)
+	}
+}
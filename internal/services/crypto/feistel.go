@@ -0,0 +1,190 @@
+package crypto
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"unicode/utf8"
+)
+
+const (
+	DefaultFeistelRounds = 16
+	MaxFeistelRounds     = 64
+	// MaxFeistelBlock — наибольший размер блока учебной сети в байтах.
+	MaxFeistelBlock = 64
+
+	DefaultRoundFunction = "sha256"
+)
+
+// RoundFunction — раундовая функция сети Фейстеля: по правой половине блока
+// и ключу раунда той же длины возвращает значение этой длины. Обратимой она
+// быть не обязана — обратимость даёт сама сеть.
+type RoundFunction func(right, key []byte) []byte
+
+var roundFunctions = []struct {
+	name string
+	f    RoundFunction
+}{
+	{"xor", xorRound},
+	{"add", addRound},
+	{"rotate_xor", rotateXorRound},
+	{"sbox", sboxRound},
+	{"sha256", sha256Round},
+}
+
+// RoundFunctions возвращает имена раундовых функций учебной сети.
+func RoundFunctions() []string {
+	names := make([]string, len(roundFunctions))
+	for i, f := range roundFunctions {
+		names[i] = f.name
+	}
+	return names
+}
+
+// xorRound — F = R ⊕ K. Линейная функция: сеть с ней легко взламывается.
+func xorRound(right, key []byte) []byte {
+	out := make([]byte, len(right))
+	for i := range right {
+		out[i] = right[i] ^ key[i]
+	}
+	return out
+}
+
+// addRound — F = R ⊞ K, сложение чисел big-endian по модулю 2^(8·len).
+func addRound(right, key []byte) []byte {
+	out := make([]byte, len(right))
+	var carry int
+	for i := len(right) - 1; i >= 0; i-- {
+		sum := int(right[i]) + int(key[i]) + carry
+		out[i], carry = byte(sum), sum>>8
+	}
+	return out
+}
+
+// rotateXorRound — F = (R ⊕ K) <<< 3 с циклическим сдвигом всей половины.
+func rotateXorRound(right, key []byte) []byte {
+	x := xorRound(right, key)
+	out := make([]byte, len(x))
+	for i := range x {
+		out[i] = x[i]<<3 | x[(i+1)%len(x)]>>5
+	}
+	return out
+}
+
+// sboxRound — S-блок AES для каждого байта R ⊕ K и циклический сдвиг
+// на байт влево, чтобы байты влияли друг на друга.
+func sboxRound(right, key []byte) []byte {
+	x := xorRound(right, key)
+	out := make([]byte, len(x))
+	for i := range x {
+		out[i] = sbox[x[(i+1)%len(x)]]
+	}
+	return out
+}
+
+// sha256Round — первые len(R) байт SHA-256(K ‖ R): псевдослучайная
+// функция, с которой четырёх раундов достаточно по Луби — Ракоффу.
+func sha256Round(right, key []byte) []byte {
+	h := sha256.Sum256(append(append([]byte{}, key...), right...))
+	return h[:len(right)]
+}
+
+// FeistelParams — параметры учебной сети Фейстеля. Блок — все данные Data,
+// чётной длины. Ключ раунда i — len(Data)/2 байт Key, начиная с байта
+// (i−1)·len(Data)/2 по кругу.
+type FeistelParams struct {
+	Rounds   int
+	Function string
+	Key      []byte
+	Data     []byte
+}
+
+// FeistelRound — половины блока после раунда и значение F в этом раунде.
+type FeistelRound struct {
+	Round int    `json:"round"`
+	Key   string `json:"key,omitempty"`
+	F     string `json:"f,omitempty"`
+	Left  string `json:"left"`
+	Right string `json:"right"`
+}
+
+type FeistelResult struct {
+	Function string         `json:"function"`
+	Rounds   int            `json:"rounds"`
+	Output   string         `json:"output"`
+	Text     string         `json:"text,omitempty"`
+	Trace    []FeistelRound `json:"trace"`
+}
+
+// FeistelEncrypt пропускает блок через сеть: Lᵢ = Rᵢ₋₁, Rᵢ = Lᵢ₋₁ ⊕ F(Rᵢ₋₁, Kᵢ).
+// Результат — Rₙ ‖ Lₙ, поэтому расшифрование — та же сеть с ключами
+// в обратном порядке.
+func (s *SymmetricService) FeistelEncrypt(params FeistelParams) (FeistelResult, error) {
+	return feistel(params, false)
+}
+
+func (s *SymmetricService) FeistelDecrypt(params FeistelParams) (FeistelResult, error) {
+	return feistel(params, true)
+}
+
+func feistel(params FeistelParams, decrypt bool) (FeistelResult, error) {
+	if params.Rounds == 0 {
+		params.Rounds = DefaultFeistelRounds
+	}
+	if params.Function == "" {
+		params.Function = DefaultRoundFunction
+	}
+	if params.Rounds < 1 || params.Rounds > MaxFeistelRounds {
+		return FeistelResult{}, fmt.Errorf("%w: rounds must be 1..%d, got %d", ErrInvalidCipherParams, MaxFeistelRounds, params.Rounds)
+	}
+	var f RoundFunction
+	for _, rf := range roundFunctions {
+		if rf.name == params.Function {
+			f = rf.f
+		}
+	}
+	if f == nil {
+		return FeistelResult{}, fmt.Errorf("%w: unknown round function %q, want one of %v", ErrInvalidCipherParams, params.Function, RoundFunctions())
+	}
+	if n := len(params.Data); n < 2 || n > MaxFeistelBlock || n%2 != 0 {
+		return FeistelResult{}, fmt.Errorf("%w: block must be an even number of bytes from 2 to %d, got %d", ErrInvalidCipherParams, MaxFeistelBlock, n)
+	}
+	if len(params.Key) == 0 {
+		return FeistelResult{}, fmt.Errorf("%w: empty key", ErrInvalidCipherParams)
+	}
+
+	h := len(params.Data) / 2
+	keys := make([][]byte, params.Rounds)
+	for i := range keys {
+		keys[i] = make([]byte, h)
+		for j := range h {
+			keys[i][j] = params.Key[(i*h+j)%len(params.Key)]
+		}
+	}
+
+	l, r := params.Data[:h], params.Data[h:]
+	result := FeistelResult{Function: params.Function, Rounds: params.Rounds}
+	result.Trace = append(result.Trace, FeistelRound{Left: hex.EncodeToString(l), Right: hex.EncodeToString(r)})
+	for round := 1; round <= params.Rounds; round++ {
+		k := keys[round-1]
+		if decrypt {
+			k = keys[params.Rounds-round]
+		}
+		fr := f(r, k)
+		l, r = r, xorRound(l, fr)
+		result.Trace = append(result.Trace, FeistelRound{
+			Round: round,
+			Key:   hex.EncodeToString(k),
+			F:     hex.EncodeToString(fr),
+			Left:  hex.EncodeToString(l),
+			Right: hex.EncodeToString(r),
+		})
+	}
+
+	out := append(append([]byte{}, r...), l...)
+	result.Output = hex.EncodeToString(out)
+	if decrypt && utf8.Valid(out) {
+		result.Text = string(out)
+	}
+	return result, nil
+}
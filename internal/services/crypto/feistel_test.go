@@ -0,0 +1,81 @@
+package crypto
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSymmetricService_Feistel(t *testing.T) {
+	s := NewSymmetricService()
+	for _, function := range RoundFunctions() {
+		for _, rounds := range []int{1, 2, 16, 0} {
+			params := FeistelParams{Rounds: rounds, Function: function, Key: []byte("ключ"), Data: []byte("Фейстель")}
+			encrypted, err := s.FeistelEncrypt(params)
+			if err != nil {
+				t.Fatalf("SymmetricService.FeistelEncrypt(%s, %d) has err = %v", function, rounds, err)
+			}
+			wantRounds := rounds
+			if rounds == 0 {
+				wantRounds = DefaultFeistelRounds
+			}
+			if encrypted.Rounds != wantRounds || len(encrypted.Trace) != wantRounds+1 {
+				t.Errorf("SymmetricService.FeistelEncrypt(%s, %d) = %+v", function, rounds, encrypted)
+			}
+
+			params.Data = unhex(t, encrypted.Output)
+			decrypted, err := s.FeistelDecrypt(params)
+			if err != nil {
+				t.Fatalf("SymmetricService.FeistelDecrypt(%s, %d) has err = %v", function, rounds, err)
+			}
+			if decrypted.Text != "Фейстель" {
+				t.Errorf("SymmetricService.FeistelDecrypt(%s, %d) = %q", function, rounds, decrypted.Text)
+			}
+		}
+	}
+}
+
+func TestSymmetricService_FeistelTrace(t *testing.T) {
+	s := NewSymmetricService()
+	result, err := s.FeistelEncrypt(FeistelParams{
+		Rounds:   2,
+		Function: "xor",
+		Key:      unhex(t, "0f0ff0f0"),
+		Data:     unhex(t, "12345678"),
+	})
+	if err != nil {
+		t.Fatalf("SymmetricService.FeistelEncrypt() has err = %v", err)
+	}
+	// L1 = 5678, R1 = 1234 ⊕ (5678 ⊕ 0f0f) = 4b43;
+	// L2 = 4b43, R2 = 5678 ⊕ (4b43 ⊕ f0f0) = edcb; выход R2 ‖ L2.
+	want := []FeistelRound{
+		{Left: "1234", Right: "5678"},
+		{Round: 1, Key: "0f0f", F: "5977", Left: "5678", Right: "4b43"},
+		{Round: 2, Key: "f0f0", F: "bbb3", Left: "4b43", Right: "edcb"},
+	}
+	for i := range want {
+		if result.Trace[i] != want[i] {
+			t.Errorf("round %d = %+v, want %+v", i, result.Trace[i], want[i])
+		}
+	}
+	if result.Output != "edcb4b43" {
+		t.Errorf("SymmetricService.FeistelEncrypt() = %s, want edcb4b43", result.Output)
+	}
+}
+
+func TestSymmetricService_FeistelErrors(t *testing.T) {
+	s := NewSymmetricService()
+	for _, tt := range []struct {
+		name   string
+		params FeistelParams
+	}{
+		{"rounds", FeistelParams{Rounds: MaxFeistelRounds + 1, Key: []byte("k"), Data: []byte("ab")}},
+		{"function", FeistelParams{Function: "md5", Key: []byte("k"), Data: []byte("ab")}},
+		{"odd block", FeistelParams{Key: []byte("k"), Data: []byte("abc")}},
+		{"long block", FeistelParams{Key: []byte("k"), Data: make([]byte, MaxFeistelBlock+2)}},
+		{"empty key", FeistelParams{Data: []byte("ab")}},
+	} {
+		if _, err := s.FeistelEncrypt(tt.params); !errors.Is(err, ErrInvalidCipherParams) {
+			t.Errorf("%s: SymmetricService.FeistelEncrypt() has err = %v, want %v", tt.name, err, ErrInvalidCipherParams)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"math/rand/v2"
+	"strings"
+	"testing"
+)
+
+// Контрольные примеры ГОСТ Р 34.12-2015 (приложение А) и ГОСТ Р 34.13-2015
+// (приложение А) для «Кузнечика» и «Магмы».
+var gostVectors = []struct {
+	algorithm  string
+	key        string
+	plaintext  string
+	ciphertext string
+	// Четыре блока открытого текста из ГОСТ Р 34.13-2015.
+	blocks string
+	modes  []struct {
+		mode       string
+		iv         string
+		ciphertext string
+	}
+	mac string
+}{
+	{
+		algorithm:  "kuznyechik",
+		key:        "8899aabbccddeeff0011223344556677fedcba98765432100123456789abcdef",
+		plaintext:  "1122334455667700ffeeddccbbaa9988",
+		ciphertext: "7f679d90bebc24305a468d42b9d4edcd",
+		blocks: "1122334455667700ffeeddccbbaa9988" + "00112233445566778899aabbcceeff0a" +
+			"112233445566778899aabbcceeff0a00" + "2233445566778899aabbcceeff0a0011",
+		modes: []struct {
+			mode       string
+			iv         string
+			ciphertext string
+		}{
+			{ModeECB, "", "7f679d90bebc24305a468d42b9d4edcd" + "b429912c6e0032f9285452d76718d08b" +
+				"f0ca33549d247ceef3f5a5313bd4b157" + "d0b09ccde830b9eb3a02c4c5aa8ada98"},
+			{ModeCTR, "1234567890abcef0", "f195d8bec10ed1dbd57b5fa240bda1b8" + "85eee733f6a13e5df33ce4b33c45dee4" +
+				"a5eae88be6356ed3d5e877f13564a3a5" + "cb91fab1f20cbab6d1c6d15820bdba73"},
+			{ModeOFB, "1234567890abcef0a1b2c3d4e5f0011223344556677889901213141516171819",
+				"81800a59b1842b24ff1f795e897abd95" + "ed5b47a7048cfab48fb521369d9326bf" +
+					"66a257ac3ca0b8b1c80fe7fc10288a13" + "203ebbc066138660a0292243f6903150"},
+			{ModeCBC, "1234567890abcef0a1b2c3d4e5f0011223344556677889901213141516171819",
+				"689972d4a085fa4d90e52e3d6d7dcc27" + "2826e661b478eca6af1e8e448d5ea5ac" +
+					"fe7babf1e91999e85640e8b0f49d90d0" + "167688065a895c631a2d9a1560b63970"},
+			{ModeCFB, "1234567890abcef0a1b2c3d4e5f0011223344556677889901213141516171819",
+				"81800a59b1842b24ff1f795e897abd95" + "ed5b47a7048cfab48fb521369d9326bf" +
+					"79f2a8eb5cc68d38842d264e97a238b5" + "4ffebecd4e922de6c75bd9dd44fbf4d1"},
+		},
+		mac: "336f4d296059fbe3",
+	},
+	{
+		algorithm:  "magma",
+		key:        "ffeeddccbbaa99887766554433221100f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff",
+		plaintext:  "fedcba9876543210",
+		ciphertext: "4ee901e5c2d8ca3d",
+		blocks:     "92def06b3c130a59" + "db54c704f8189d20" + "4a98fb2e67a8024c" + "8912409b17b57e41",
+		modes: []struct {
+			mode       string
+			iv         string
+			ciphertext string
+		}{
+			{ModeECB, "", "2b073f0494f372a0" + "de70e715d3556e48" + "11d8d9e9eacfbc1e" + "7c68260996c67efb"},
+			{ModeCTR, "12345678", "4e98110c97b7b93c" + "3e250d93d6e85d69" + "136d868807b2dbef" + "568eb680ab52a12d"},
+			{ModeOFB, "1234567890abcdef234567890abcdef1", "db37e0e266903c83" + "0d46644c1f9a089c" + "a0f83062430e327e" + "c824efb8bd4fdb05"},
+			{ModeCBC, "1234567890abcdef234567890abcdef134567890abcdef12", "96d1b05eea683919" + "aff76129abb937b9" + "5058b4a1c4bc0019" + "20b78b1a7cd7e667"},
+			{ModeCFB, "1234567890abcdef234567890abcdef1", "db37e0e266903c83" + "0d46644c1f9a089c" + "24bdd2035315d38b" + "bcc0321421075505"},
+		},
+		mac: "154e7210",
+	},
+}
+
+func TestGOSTCiphers(t *testing.T) {
+	for _, tt := range gostVectors {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			c, err := findBlockCipher(tt.algorithm)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := c.new(unhex(t, tt.key))
+			if err != nil {
+				t.Fatalf("new(%s) has err = %v", tt.algorithm, err)
+			}
+			dst := make([]byte, b.BlockSize())
+			b.Encrypt(dst, unhex(t, tt.plaintext))
+			if got := hex.EncodeToString(dst); got != tt.ciphertext {
+				t.Errorf("Encrypt() = %s, want %s", got, tt.ciphertext)
+			}
+			b.Decrypt(dst, dst)
+			if got := hex.EncodeToString(dst); got != tt.plaintext {
+				t.Errorf("Decrypt() = %s, want %s", got, tt.plaintext)
+			}
+		})
+	}
+}
+
+func TestSymmetricService_GOSTModes(t *testing.T) {
+	s := NewSymmetricService()
+	for _, tt := range gostVectors {
+		for _, m := range tt.modes {
+			t.Run(tt.algorithm+"/"+m.mode, func(t *testing.T) {
+				params := SymmetricParams{
+					Mode:    m.mode,
+					Padding: PaddingNone,
+					Key:     unhex(t, tt.key),
+					IV:      unhex(t, m.iv),
+					Data:    unhex(t, tt.blocks),
+				}
+				result, err := s.Encrypt(tt.algorithm, params)
+				if err != nil {
+					t.Fatalf("SymmetricService.Encrypt() has err = %v", err)
+				}
+				if result.Output != m.ciphertext {
+					t.Errorf("SymmetricService.Encrypt() = %s, want %s", result.Output, m.ciphertext)
+				}
+
+				params.Data = result.Data
+				result, err = s.Decrypt(tt.algorithm, params)
+				if err != nil {
+					t.Fatalf("SymmetricService.Decrypt() has err = %v", err)
+				}
+				if result.Output != tt.blocks {
+					t.Errorf("SymmetricService.Decrypt() = %s, want %s", result.Output, tt.blocks)
+				}
+			})
+		}
+	}
+}
+
+func TestSymmetricService_MAC(t *testing.T) {
+	s := NewSymmetricService()
+	for _, tt := range gostVectors {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			result, err := s.MAC(tt.algorithm, SymmetricParams{
+				Key:     unhex(t, tt.key),
+				Data:    unhex(t, tt.blocks),
+				MACSize: len(tt.mac) / 2,
+			})
+			if err != nil {
+				t.Fatalf("SymmetricService.MAC() has err = %v", err)
+			}
+			if result.MAC != tt.mac {
+				t.Errorf("SymmetricService.MAC() = %s, want %s", result.MAC, tt.mac)
+			}
+		})
+	}
+
+	// RFC 4493: AES-CMAC пустого сообщения и сообщений из одного и 2.5 блоков.
+	key := unhex(t, "2b7e151628aed2a6abf7158809cf4f3c")
+	for _, tt := range []struct {
+		data string
+		mac  string
+	}{
+		{"", "bb1d6929e95937287fa37d129b756746"},
+		{"6bc1bee22e409f96e93d7e117393172a", "070a16b46b4d4144f79bdd9dd04a287c"},
+		{"6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e5130c81c46a35ce411",
+			"dfa66747de9ae63030ca32611497c827"},
+	} {
+		result, err := s.MAC("aes", SymmetricParams{Key: key, Data: unhex(t, tt.data), Trace: true})
+		if err != nil {
+			t.Fatalf("SymmetricService.MAC() has err = %v", err)
+		}
+		if result.MAC != tt.mac {
+			t.Errorf("SymmetricService.MAC(%s) = %s, want %s", tt.data, result.MAC, tt.mac)
+		}
+		if result.Subkeys[0] != "fbeed618357133667c85e08f7236a8de" || result.Subkeys[1] != "f7ddac306ae266ccf90bc11ee46d513b" {
+			t.Errorf("SymmetricService.MAC() subkeys = %v", result.Subkeys)
+		}
+		if len(result.Trace) == 0 || len(result.KeySchedule) != 11 {
+			t.Errorf("SymmetricService.MAC() has no trace")
+		}
+	}
+
+	for _, size := range []int{-1, 17} {
+		if _, err := s.MAC("aes", SymmetricParams{Key: key, MACSize: size}); !errors.Is(err, ErrInvalidCipherParams) {
+			t.Errorf("SymmetricService.MAC(size %d) has err = %v, want %v", size, err, ErrInvalidCipherParams)
+		}
+	}
+}
+
+func TestKuznyechikCipher_Trace(t *testing.T) {
+	v := gostVectors[0]
+	c, err := NewKuznyechikCipher(unhex(t, v.key))
+	if err != nil {
+		t.Fatalf("NewKuznyechikCipher() has err = %v", err)
+	}
+	// Ключи раундов K1, K3 и K10 из ГОСТ Р 34.12-2015, А.1.4.
+	keys := c.KeySchedule()
+	if keys[0] != "8899aabbccddeeff0011223344556677" || keys[2] != "db31485315694343228d6aef8cc78c44" ||
+		keys[9] != "72e9dd7416bcf45b755dbaa88e4a4043" {
+		t.Errorf("KuznyechikCipher.KeySchedule() = %v", keys)
+	}
+
+	dst := make([]byte, KuznyechikBlockSize)
+	trace := c.EncryptTrace(dst, unhex(t, v.plaintext))
+	if len(trace) != 1+3*9+1 {
+		t.Fatalf("KuznyechikCipher.EncryptTrace() has %d steps", len(trace))
+	}
+	// Промежуточные значения первого раунда из А.1.5.
+	for i, want := range []string{
+		"99bb99ff99bb99ffffffffffffffffff",
+		"e87de8b6e87de8b6b6b6b6b6b6b6b6b6",
+		"e297b686e355b0a1cf4a2f9249140830",
+	} {
+		if step := trace[1+i]; step.Round != 1 || step.State != want {
+			t.Errorf("step %d = %+v, want round 1 state %s", 1+i, step, want)
+		}
+	}
+	if last := trace[len(trace)-1]; last.State != v.ciphertext {
+		t.Errorf("last step = %+v", last)
+	}
+
+	trace = c.DecryptTrace(dst, dst)
+	if len(trace) != 2+3*9 || hex.EncodeToString(dst) != v.plaintext {
+		t.Errorf("KuznyechikCipher.DecryptTrace() = %s in %d steps", hex.EncodeToString(dst), len(trace))
+	}
+}
+
+func TestMagmaCipher_Trace(t *testing.T) {
+	v := gostVectors[1]
+	c, err := NewMagmaCipher(unhex(t, v.key))
+	if err != nil {
+		t.Fatalf("NewMagmaCipher() has err = %v", err)
+	}
+	keys := c.KeySchedule()
+	if keys[0] != "ffeeddcc" || keys[7] != "fcfdfeff" || keys[24] != "fcfdfeff" || keys[31] != "ffeeddcc" {
+		t.Errorf("MagmaCipher.KeySchedule() = %v", keys)
+	}
+
+	dst := make([]byte, MagmaBlockSize)
+	trace := c.EncryptTrace(dst, unhex(t, v.plaintext))
+	if len(trace) != 33 {
+		t.Fatalf("MagmaCipher.EncryptTrace() has %d steps", len(trace))
+	}
+	// Состояния после раундов из ГОСТ Р 34.12-2015, А.2.4.
+	for round, want := range map[int]string{
+		1:  "7654321028da3b14",
+		2:  "28da3b14b14337a5",
+		32: v.ciphertext,
+	} {
+		if step := trace[round]; step.Round != round || step.State != want {
+			t.Errorf("round %d = %+v, want state %s", round, step, want)
+		}
+	}
+	if trace[1].Key != "ffeeddcc" {
+		t.Errorf("round 1 key = %s", trace[1].Key)
+	}
+}
+
+func TestSymmetricService_GOSTPadding(t *testing.T) {
+	s := NewSymmetricService()
+	rnd := rand.New(rand.NewPCG(5, 6))
+	for _, algorithm := range []string{"magma", "kuznyechik"} {
+		key := make([]byte, 32)
+		fill(rnd, key)
+		for _, mode := range []string{ModeECB, ModeCBC, ModeCFB, ModeOFB, ModeCTR} {
+			for _, n := range []int{0, 1, 8, 16, 33} {
+				data := bytes.Repeat([]byte{0x80}, n)
+				params := SymmetricParams{Mode: mode, Padding: PaddingGOST, Key: key, Data: data}
+				result, err := s.Encrypt(algorithm, params)
+				if err != nil {
+					t.Fatalf("SymmetricService.Encrypt(%s, %s) has err = %v", algorithm, mode, err)
+				}
+				params.IV, params.Data = unhex(t, result.IV), result.Data
+				result, err = s.Decrypt(algorithm, params)
+				if err != nil {
+					t.Fatalf("SymmetricService.Decrypt(%s, %s) has err = %v", algorithm, mode, err)
+				}
+				if !bytes.Equal(result.Data, data) {
+					t.Errorf("SymmetricService.Decrypt(%s, %s) = %x, want %x", algorithm, mode, result.Data, data)
+				}
+			}
+		}
+	}
+
+	for _, tt := range []struct {
+		name   string
+		params SymmetricParams
+	}{
+		{"gcm", SymmetricParams{Mode: ModeGCM, Key: make([]byte, 32)}},
+		{"ctr iv", SymmetricParams{Mode: ModeCTR, Key: make([]byte, 32), IV: make([]byte, 8)}},
+		{"cbc iv", SymmetricParams{Mode: ModeCBC, Key: make([]byte, 32), IV: make([]byte, 12)}},
+	} {
+		if _, err := s.Encrypt("magma", tt.params); !errors.Is(err, ErrInvalidCipherParams) {
+			t.Errorf("%s: SymmetricService.Encrypt() has err = %v, want %v", tt.name, err, ErrInvalidCipherParams)
+		}
+	}
+}
+
+func TestGOSTPad(t *testing.T) {
+	for _, tt := range []struct {
+		data   string
+		padded string
+	}{
+		{"", "8000000000000000"},
+		{"0102", "0102800000000000"},
+		{"0102030405060708", "01020304050607088000000000000000"},
+	} {
+		padded := gostPad(unhex(t, tt.data), MagmaBlockSize)
+		if got := hex.EncodeToString(padded); got != tt.padded {
+			t.Errorf("gostPad(%s) = %s, want %s", tt.data, got, tt.padded)
+		}
+		data, err := gostUnpad(padded, MagmaBlockSize)
+		if err != nil || hex.EncodeToString(data) != tt.data {
+			t.Errorf("gostUnpad(%s) = %x, %v", tt.padded, data, err)
+		}
+	}
+	for _, padded := range []string{"", "0000000000000000", "0102030405060701", "80" + strings.Repeat("00", 8)} {
+		if _, err := gostUnpad(unhex(t, padded), MagmaBlockSize); !errors.Is(err, ErrInvalidPadding) {
+			t.Errorf("gostUnpad(%s) has err = %v, want %v", padded, err, ErrInvalidPadding)
+		}
+	}
+}
@@ -0,0 +1,406 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// ErrInvalidKey возвращается, если ключ не удаётся разобрать или он не RSA.
+var ErrInvalidKey = errors.New("invalid RSA key")
+
+// Форматы ключей. Открытый ключ сериализуется в PKCS#1 или SPKI
+// (SubjectPublicKeyInfo, X.509), закрытый — в PKCS#1 или PKCS#8.
+// PKCS#1, PKCS#8 и SPKI записываются в PEM или DER, JWK — в JSON (RFC 7517).
+const (
+	FormatPKCS1 = "pkcs1"
+	FormatPKCS8 = "pkcs8"
+	FormatSPKI  = "spki"
+	FormatJWK   = "jwk"
+
+	EncodingPEM = "pem"
+	EncodingDER = "der"
+)
+
+// Типы блоков PEM.
+const (
+	pemPKCS1PrivateKey = "RSA PRIVATE KEY"
+	pemPKCS1PublicKey  = "RSA PUBLIC KEY"
+	pemPKCS8PrivateKey = "PRIVATE KEY"
+	pemSPKIPublicKey   = "PUBLIC KEY"
+)
+
+// oidRSAEncryption — идентификатор алгоритма rsaEncryption (RFC 8017, A.1).
+var oidRSAEncryption = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+
+// pkcs1PrivateKey — RSAPrivateKey из RFC 8017, A.1.2. Ключи с несколькими
+// простыми (version 1) не поддерживаются.
+type pkcs1PrivateKey struct {
+	Version int
+	N       *big.Int
+	E       *big.Int
+	D       *big.Int
+	P       *big.Int
+	Q       *big.Int
+	Dp      *big.Int
+	Dq      *big.Int
+	Qinv    *big.Int
+}
+
+// pkcs1PublicKey — RSAPublicKey из RFC 8017, A.1.1.
+type pkcs1PublicKey struct {
+	N *big.Int
+	E *big.Int
+}
+
+// pkcs8PrivateKey — PrivateKeyInfo из RFC 5208 без необязательных атрибутов.
+type pkcs8PrivateKey struct {
+	Version    int
+	Algorithm  pkix.AlgorithmIdentifier
+	PrivateKey []byte
+}
+
+// spkiPublicKey — SubjectPublicKeyInfo из RFC 5280.
+type spkiPublicKey struct {
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+// jwk — ключ RSA в формате JSON Web Key (RFC 7518, 6.3). Числа записаны
+// в base64url без выравнивания.
+type jwk struct {
+	Kty string `json:"kty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	D   string `json:"d,omitempty"`
+	P   string `json:"p,omitempty"`
+	Q   string `json:"q,omitempty"`
+	Dp  string `json:"dp,omitempty"`
+	Dq  string `json:"dq,omitempty"`
+	Qi  string `json:"qi,omitempty"`
+}
+
+var rsaAlgorithm = pkix.AlgorithmIdentifier{Algorithm: oidRSAEncryption, Parameters: asn1.NullRawValue}
+
+// MarshalPublicKey сериализует открытый ключ в формате FormatPKCS1, FormatSPKI
+// или FormatJWK; encoding (EncodingPEM или EncodingDER) для JWK не используется.
+func MarshalPublicKey(pub *PublicKey, format, encoding string) ([]byte, error) {
+	if err := checkPublicKey(pub); err != nil {
+		return nil, err
+	}
+	switch format {
+	case FormatPKCS1:
+		der, err := MarshalPKCS1PublicKey(pub)
+		if err != nil {
+			return nil, err
+		}
+		return encodeKey(der, pemPKCS1PublicKey, encoding)
+	case FormatSPKI:
+		der, err := MarshalPKIXPublicKey(pub)
+		if err != nil {
+			return nil, err
+		}
+		return encodeKey(der, pemSPKIPublicKey, encoding)
+	case FormatJWK:
+		return json.Marshal(jwk{Kty: "RSA", N: jwkInt(pub.N), E: jwkInt(pub.E)})
+	}
+	return nil, fmt.Errorf("unknown public key format %q, want %s, %s or %s", format, FormatPKCS1, FormatSPKI, FormatJWK)
+}
+
+// MarshalPrivateKey сериализует закрытый ключ в формате FormatPKCS1, FormatPKCS8
+// или FormatJWK. Для PKCS#1 и PKCS#8 у ключа должны быть заданы P, Q и E.
+func MarshalPrivateKey(priv *PrivateKey, format, encoding string) ([]byte, error) {
+	switch format {
+	case FormatPKCS1:
+		der, err := MarshalPKCS1PrivateKey(priv)
+		if err != nil {
+			return nil, err
+		}
+		return encodeKey(der, pemPKCS1PrivateKey, encoding)
+	case FormatPKCS8:
+		der, err := MarshalPKCS8PrivateKey(priv)
+		if err != nil {
+			return nil, err
+		}
+		return encodeKey(der, pemPKCS8PrivateKey, encoding)
+	case FormatJWK:
+		if priv == nil || priv.N == nil || priv.E == nil || priv.D == nil {
+			return nil, fmt.Errorf("%w: N, E and D are required", ErrInvalidKey)
+		}
+		key := jwk{Kty: "RSA", N: jwkInt(priv.N), E: jwkInt(priv.E), D: jwkInt(priv.D)}
+		if priv.P != nil || priv.Q != nil {
+			if err := checkPrivateKey(priv); err != nil {
+				return nil, err
+			}
+			crt := *priv
+			crt.Precompute()
+			key.P, key.Q = jwkInt(crt.P), jwkInt(crt.Q)
+			key.Dp, key.Dq, key.Qi = jwkInt(crt.Dp), jwkInt(crt.Dq), jwkInt(crt.Qinv)
+		}
+		return json.Marshal(key)
+	}
+	return nil, fmt.Errorf("unknown private key format %q, want %s, %s or %s", format, FormatPKCS1, FormatPKCS8, FormatJWK)
+}
+
+func encodeKey(der []byte, pemType, encoding string) ([]byte, error) {
+	switch encoding {
+	case EncodingPEM:
+		return pem.EncodeToMemory(&pem.Block{Type: pemType, Bytes: der}), nil
+	case EncodingDER:
+		return der, nil
+	}
+	return nil, fmt.Errorf("unknown key encoding %q, want %s or %s", encoding, EncodingPEM, EncodingDER)
+}
+
+// ParsePublicKey разбирает открытый ключ в любом из поддерживаемых форматов:
+// PEM (RSA PUBLIC KEY или PUBLIC KEY), DER (PKCS#1 или SPKI) или JWK.
+func ParsePublicKey(data []byte) (*PublicKey, error) {
+	data = bytes.TrimSpace(data)
+	if bytes.HasPrefix(data, []byte("{")) {
+		pub, _, err := parseJWK(data)
+		return pub, err
+	}
+	if block, _ := pem.Decode(data); block != nil {
+		switch block.Type {
+		case pemPKCS1PublicKey:
+			return ParsePKCS1PublicKey(block.Bytes)
+		case pemSPKIPublicKey:
+			return ParsePKIXPublicKey(block.Bytes)
+		}
+		return nil, fmt.Errorf("%w: unexpected PEM block %q", ErrInvalidKey, block.Type)
+	}
+	if pub, err := ParsePKIXPublicKey(data); err == nil {
+		return pub, nil
+	}
+	return ParsePKCS1PublicKey(data)
+}
+
+// ParsePrivateKey разбирает закрытый ключ в любом из поддерживаемых форматов:
+// PEM (RSA PRIVATE KEY или PRIVATE KEY), DER (PKCS#1 или PKCS#8) или JWK.
+func ParsePrivateKey(data []byte) (*PrivateKey, error) {
+	data = bytes.TrimSpace(data)
+	if bytes.HasPrefix(data, []byte("{")) {
+		_, priv, err := parseJWK(data)
+		if err == nil && priv == nil {
+			err = fmt.Errorf("%w: JWK is a public key", ErrInvalidKey)
+		}
+		return priv, err
+	}
+	if block, _ := pem.Decode(data); block != nil {
+		switch block.Type {
+		case pemPKCS1PrivateKey:
+			return ParsePKCS1PrivateKey(block.Bytes)
+		case pemPKCS8PrivateKey:
+			return ParsePKCS8PrivateKey(block.Bytes)
+		}
+		return nil, fmt.Errorf("%w: unexpected PEM block %q", ErrInvalidKey, block.Type)
+	}
+	if priv, err := ParsePKCS8PrivateKey(data); err == nil {
+		return priv, nil
+	}
+	return ParsePKCS1PrivateKey(data)
+}
+
+func MarshalPKCS1PublicKey(pub *PublicKey) ([]byte, error) {
+	if err := checkPublicKey(pub); err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(pkcs1PublicKey{N: pub.N, E: pub.E})
+}
+
+func ParsePKCS1PublicKey(der []byte) (*PublicKey, error) {
+	var key pkcs1PublicKey
+	if err := unmarshalDER(der, &key); err != nil {
+		return nil, err
+	}
+	pub := &PublicKey{N: key.N, E: key.E}
+	if err := checkPublicKey(pub); err != nil {
+		return nil, err
+	}
+	return pub, nil
+}
+
+func MarshalPKIXPublicKey(pub *PublicKey) ([]byte, error) {
+	der, err := MarshalPKCS1PublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(spkiPublicKey{
+		Algorithm: rsaAlgorithm,
+		PublicKey: asn1.BitString{Bytes: der, BitLength: 8 * len(der)},
+	})
+}
+
+func ParsePKIXPublicKey(der []byte) (*PublicKey, error) {
+	var key spkiPublicKey
+	if err := unmarshalDER(der, &key); err != nil {
+		return nil, err
+	}
+	if !key.Algorithm.Algorithm.Equal(oidRSAEncryption) {
+		return nil, fmt.Errorf("%w: algorithm %v is not rsaEncryption", ErrInvalidKey, key.Algorithm.Algorithm)
+	}
+	return ParsePKCS1PublicKey(key.PublicKey.RightAlign())
+}
+
+func MarshalPKCS1PrivateKey(priv *PrivateKey) ([]byte, error) {
+	if err := checkPrivateKey(priv); err != nil {
+		return nil, err
+	}
+	crt := *priv
+	crt.Precompute()
+	return asn1.Marshal(pkcs1PrivateKey{
+		N:    crt.N,
+		E:    crt.E,
+		D:    crt.D,
+		P:    crt.P,
+		Q:    crt.Q,
+		Dp:   crt.Dp,
+		Dq:   crt.Dq,
+		Qinv: crt.Qinv,
+	})
+}
+
+// ParsePKCS1PrivateKey разбирает RSAPrivateKey. Значения CRT из файла
+// не используются, а вычисляются заново: неверные значения исказили бы
+// расшифрование.
+func ParsePKCS1PrivateKey(der []byte) (*PrivateKey, error) {
+	var key pkcs1PrivateKey
+	if err := unmarshalDER(der, &key); err != nil {
+		return nil, err
+	}
+	if key.Version != 0 {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidKey, key.Version)
+	}
+	priv := &PrivateKey{N: key.N, E: key.E, D: key.D, P: key.P, Q: key.Q}
+	if err := checkPrivateKey(priv); err != nil {
+		return nil, err
+	}
+	priv.Precompute()
+	return priv, nil
+}
+
+func MarshalPKCS8PrivateKey(priv *PrivateKey) ([]byte, error) {
+	der, err := MarshalPKCS1PrivateKey(priv)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(pkcs8PrivateKey{Algorithm: rsaAlgorithm, PrivateKey: der})
+}
+
+func ParsePKCS8PrivateKey(der []byte) (*PrivateKey, error) {
+	var key pkcs8PrivateKey
+	if err := unmarshalDER(der, &key); err != nil {
+		return nil, err
+	}
+	if !key.Algorithm.Algorithm.Equal(oidRSAEncryption) {
+		return nil, fmt.Errorf("%w: algorithm %v is not rsaEncryption", ErrInvalidKey, key.Algorithm.Algorithm)
+	}
+	return ParsePKCS1PrivateKey(key.PrivateKey)
+}
+
+func unmarshalDER(der []byte, v any) error {
+	rest, err := asn1.Unmarshal(der, v)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidKey, err)
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("%w: trailing data after ASN.1", ErrInvalidKey)
+	}
+	return nil
+}
+
+// parseJWK разбирает JWK; для открытого ключа priv равен nil.
+func parseJWK(data []byte) (pub *PublicKey, priv *PrivateKey, err error) {
+	var key jwk
+	if err := json.Unmarshal(data, &key); err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrInvalidKey, err)
+	}
+	if key.Kty != "RSA" {
+		return nil, nil, fmt.Errorf("%w: kty = %q, want RSA", ErrInvalidKey, key.Kty)
+	}
+
+	values := make(map[string]*big.Int)
+	for name, v := range map[string]string{"n": key.N, "e": key.E, "d": key.D, "p": key.P, "q": key.Q} {
+		if v == "" {
+			continue
+		}
+		b, err := base64.RawURLEncoding.DecodeString(v)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %s: %w", ErrInvalidKey, name, err)
+		}
+		values[name] = new(big.Int).SetBytes(b)
+	}
+
+	pub = &PublicKey{N: values["n"], E: values["e"]}
+	if err := checkPublicKey(pub); err != nil {
+		return nil, nil, err
+	}
+	if values["d"] == nil {
+		return pub, nil, nil
+	}
+	priv = &PrivateKey{N: pub.N, E: pub.E, D: values["d"], P: values["p"], Q: values["q"]}
+	if (priv.P == nil) != (priv.Q == nil) {
+		return nil, nil, fmt.Errorf("%w: p and q must be given together", ErrInvalidKey)
+	}
+	if priv.P == nil {
+		// Без p и q ключ нельзя проверить целиком, но D хотя бы должен быть
+		// меньше N: иначе размер D ограничен только размером запроса.
+		if priv.D.Sign() <= 0 || priv.D.Cmp(priv.N) >= 0 {
+			return nil, nil, fmt.Errorf("%w: want 0 < D < N", ErrInvalidKey)
+		}
+		return pub, priv, nil
+	}
+	if err := checkPrivateKey(priv); err != nil {
+		return nil, nil, err
+	}
+	priv.Precompute()
+	return pub, priv, nil
+}
+
+func jwkInt(x *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(x.Bytes())
+}
+
+func checkPublicKey(pub *PublicKey) error {
+	if pub == nil || pub.N == nil || pub.E == nil {
+		return fmt.Errorf("%w: N and E are required", ErrInvalidKey)
+	}
+	if pub.N.Sign() <= 0 || pub.E.Cmp(big.NewInt(1)) <= 0 || pub.E.Cmp(pub.N) >= 0 {
+		return fmt.Errorf("%w: want N > 0 and 1 < E < N", ErrInvalidKey)
+	}
+	// Как и crypto/rsa, ограничиваем размеры: операции с ключом в миллионы
+	// бит заняли бы сервер надолго.
+	if pub.N.BitLen() > MaxKeyBits {
+		return fmt.Errorf("%w: N is %d bits, want at most %d", ErrInvalidKey, pub.N.BitLen(), MaxKeyBits)
+	}
+	if pub.E.BitLen() > 32 {
+		return fmt.Errorf("%w: E is %d bits, want at most 32", ErrInvalidKey, pub.E.BitLen())
+	}
+	return nil
+}
+
+func checkPrivateKey(priv *PrivateKey) error {
+	if priv == nil || priv.N == nil || priv.E == nil || priv.D == nil || priv.P == nil || priv.Q == nil {
+		return fmt.Errorf("%w: N, E, D, P and Q are required", ErrInvalidKey)
+	}
+	if err := checkPublicKey(&PublicKey{N: priv.N, E: priv.E}); err != nil {
+		return err
+	}
+	if priv.P.Cmp(big.NewInt(1)) <= 0 || priv.Q.Cmp(big.NewInt(1)) <= 0 ||
+		new(big.Int).Mul(priv.P, priv.Q).Cmp(priv.N) != 0 {
+		return fmt.Errorf("%w: N != P·Q", ErrInvalidKey)
+	}
+	if priv.P.Cmp(priv.Q) == 0 {
+		return fmt.Errorf("%w: P == Q", ErrInvalidKey)
+	}
+	if priv.D.Sign() <= 0 || priv.D.Cmp(priv.N) >= 0 {
+		return fmt.Errorf("%w: want 0 < D < N", ErrInvalidKey)
+	}
+	return nil
+}
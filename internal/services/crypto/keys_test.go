@@ -0,0 +1,227 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Ключи в testdata созданы OpenSSL 3.0:
+//
+//	openssl genpkey -algorithm RSA -pkeyopt rsa_keygen_bits:1024 -out openssl_pkcs8.pem
+//	openssl pkcs8 -topk8 -nocrypt -in openssl_pkcs8.pem -outform DER -out openssl_pkcs8.der
+//	openssl rsa -in openssl_pkcs8.pem -traditional -out openssl_pkcs1.pem
+//	openssl rsa -in openssl_pkcs8.pem -RSAPublicKey_out -out openssl_pkcs1_pub.pem
+//	openssl rsa -in openssl_pkcs8.pem -pubout -out openssl_spki.pem
+//	openssl rsa -in openssl_pkcs8.pem -pubout -outform DER -out openssl_spki.der
+//	openssl pkeyutl -encrypt -pubin -inkey openssl_spki.pem -pkeyopt rsa_padding_mode:pkcs1 \
+//		-in message.txt -out message.openssl.enc
+//	openssl dgst -sha256 -sign openssl_pkcs8.pem -out message.openssl.sig message.txt
+//	openssl dgst -sha256 -sigopt rsa_padding_mode:pss -sigopt rsa_pss_saltlen:20 \
+//		-sign openssl_pkcs8.pem -out message.openssl.pss.sig message.txt
+//	openssl ecparam -name prime256v1 -genkey -noout | openssl ec -pubout -out openssl_ec_spki.pem
+
+func readTestdata(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestParseKey_OpenSSL(t *testing.T) {
+	want, err := ParsePrivateKey(readTestdata(t, "openssl_pkcs8.pem"))
+	if err != nil {
+		t.Fatalf("ParsePrivateKey() has err = %v", err)
+	}
+
+	for _, name := range []string{"openssl_pkcs1.pem", "openssl_pkcs8.der"} {
+		priv, err := ParsePrivateKey(readTestdata(t, name))
+		if err != nil {
+			t.Fatalf("ParsePrivateKey(%s) has err = %v", name, err)
+		}
+		if priv.N.Cmp(want.N) != 0 || priv.D.Cmp(want.D) != 0 || priv.P.Cmp(want.P) != 0 {
+			t.Errorf("ParsePrivateKey(%s) differs from openssl_pkcs8.pem", name)
+		}
+	}
+	for _, name := range []string{"openssl_pkcs1_pub.pem", "openssl_spki.pem", "openssl_spki.der"} {
+		pub, err := ParsePublicKey(readTestdata(t, name))
+		if err != nil {
+			t.Fatalf("ParsePublicKey(%s) has err = %v", name, err)
+		}
+		if pub.N.Cmp(want.N) != 0 || pub.E.Cmp(want.E) != 0 {
+			t.Errorf("ParsePublicKey(%s) differs from openssl_pkcs8.pem", name)
+		}
+	}
+
+	// Повторная сериализация совпадает с файлами OpenSSL байт в байт.
+	pub := &PublicKey{N: want.N, E: want.E}
+	tests := []struct {
+		file    string
+		marshal func() ([]byte, error)
+	}{
+		{"openssl_pkcs8.pem", func() ([]byte, error) { return MarshalPrivateKey(want, FormatPKCS8, EncodingPEM) }},
+		{"openssl_pkcs8.der", func() ([]byte, error) { return MarshalPrivateKey(want, FormatPKCS8, EncodingDER) }},
+		{"openssl_pkcs1.pem", func() ([]byte, error) { return MarshalPrivateKey(want, FormatPKCS1, EncodingPEM) }},
+		{"openssl_pkcs1_pub.pem", func() ([]byte, error) { return MarshalPublicKey(pub, FormatPKCS1, EncodingPEM) }},
+		{"openssl_spki.pem", func() ([]byte, error) { return MarshalPublicKey(pub, FormatSPKI, EncodingPEM) }},
+		{"openssl_spki.der", func() ([]byte, error) { return MarshalPublicKey(pub, FormatSPKI, EncodingDER) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			got, err := tt.marshal()
+			if err != nil {
+				t.Fatalf("marshal has err = %v", err)
+			}
+			if !bytes.Equal(got, readTestdata(t, tt.file)) {
+				t.Errorf("marshal differs from %s:\n%s", tt.file, got)
+			}
+		})
+	}
+}
+
+func TestRSAService_DecryptOpenSSL(t *testing.T) {
+	priv, err := ParsePrivateKey(readTestdata(t, "openssl_pkcs1.pem"))
+	if err != nil {
+		t.Fatalf("ParsePrivateKey() has err = %v", err)
+	}
+	m, err := NewRsaService().Decrypt(priv, readTestdata(t, "message.openssl.enc"))
+	if err != nil {
+		t.Fatalf("RSAService.Decrypt() has err = %v", err)
+	}
+	if want := readTestdata(t, "message.txt"); !bytes.Equal(m, want) {
+		t.Errorf("RSAService.Decrypt() = %q, want %q", m, want)
+	}
+}
+
+func TestMarshalKey_X509(t *testing.T) {
+	pub, priv, err := NewRsaService().GenerateKeys(1024, DefaultExponent)
+	if err != nil {
+		t.Fatalf("RSAService.GenerateKeys() has err = %v", err)
+	}
+	stdPriv := stdPrivateKey(t, priv)
+
+	stdPKCS8, err := x509.MarshalPKCS8PrivateKey(stdPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdSPKI, err := x509.MarshalPKIXPublicKey(&stdPriv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		marshal func() ([]byte, error)
+		want    []byte
+	}{
+		{"pkcs1 private", func() ([]byte, error) { return MarshalPrivateKey(priv, FormatPKCS1, EncodingDER) }, x509.MarshalPKCS1PrivateKey(stdPriv)},
+		{"pkcs8 private", func() ([]byte, error) { return MarshalPrivateKey(priv, FormatPKCS8, EncodingDER) }, stdPKCS8},
+		{"pkcs1 public", func() ([]byte, error) { return MarshalPublicKey(pub, FormatPKCS1, EncodingDER) }, x509.MarshalPKCS1PublicKey(&stdPriv.PublicKey)},
+		{"spki public", func() ([]byte, error) { return MarshalPublicKey(pub, FormatSPKI, EncodingDER) }, stdSPKI},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.marshal()
+			if err != nil {
+				t.Fatalf("marshal has err = %v", err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("marshal differs from crypto/x509")
+			}
+		})
+	}
+}
+
+func TestMarshalKey_RoundTrip(t *testing.T) {
+	pub, priv, err := NewRsaService().GenerateKeys(1024, DefaultExponent)
+	if err != nil {
+		t.Fatalf("RSAService.GenerateKeys() has err = %v", err)
+	}
+	tests := []struct {
+		format   string
+		encoding string
+	}{
+		{FormatPKCS1, EncodingPEM},
+		{FormatPKCS1, EncodingDER},
+		{FormatPKCS8, EncodingPEM},
+		{FormatPKCS8, EncodingDER},
+		{FormatSPKI, EncodingPEM},
+		{FormatSPKI, EncodingDER},
+		{FormatJWK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format+" "+tt.encoding, func(t *testing.T) {
+			if tt.format != FormatSPKI {
+				data, err := MarshalPrivateKey(priv, tt.format, tt.encoding)
+				if err != nil {
+					t.Fatalf("MarshalPrivateKey() has err = %v", err)
+				}
+				got, err := ParsePrivateKey(data)
+				if err != nil {
+					t.Fatalf("ParsePrivateKey() has err = %v", err)
+				}
+				if got.N.Cmp(priv.N) != 0 || got.E.Cmp(priv.E) != 0 || got.D.Cmp(priv.D) != 0 ||
+					got.P.Cmp(priv.P) != 0 || got.Q.Cmp(priv.Q) != 0 {
+					t.Errorf("ParsePrivateKey() = %+v, want %+v", got, priv)
+				}
+			}
+			if tt.format != FormatPKCS8 {
+				data, err := MarshalPublicKey(pub, tt.format, tt.encoding)
+				if err != nil {
+					t.Fatalf("MarshalPublicKey() has err = %v", err)
+				}
+				got, err := ParsePublicKey(data)
+				if err != nil {
+					t.Fatalf("ParsePublicKey() has err = %v", err)
+				}
+				if got.N.Cmp(pub.N) != 0 || got.E.Cmp(pub.E) != 0 {
+					t.Errorf("ParsePublicKey() = %+v, want %+v", got, pub)
+				}
+			}
+		})
+	}
+}
+
+func TestParseKey_Invalid(t *testing.T) {
+	jwk := func(n []byte, e string) []byte {
+		return fmt.Appendf(nil, `{"kty":"RSA","n":"%s","e":"%s"}`, base64.RawURLEncoding.EncodeToString(n), e)
+	}
+	tests := []struct {
+		name    string
+		data    []byte
+		private bool
+	}{
+		{"garbage", []byte("not a key"), false},
+		{"ec public key", readTestdata(t, "openssl_ec_spki.pem"), false},
+		{"private as public", readTestdata(t, "openssl_pkcs1.pem"), false},
+		{"public as private", readTestdata(t, "openssl_spki.pem"), true},
+		{"public jwk as private", []byte(`{"kty":"RSA","n":"3Q","e":"Aw"}`), true},
+		{"jwk kty", []byte(`{"kty":"EC","n":"3Q","e":"Aw"}`), false},
+		{"jwk without e", []byte(`{"kty":"RSA","n":"3Q"}`), false},
+		{"jwk base64", []byte(`{"kty":"RSA","n":"3Q==","e":"Aw"}`), false},
+		{"jwk wrong primes", []byte(`{"kty":"RSA","n":"3Q","e":"Aw","d":"Kw","p":"Bw","q":"Ew"}`), true},
+		{"jwk large n", jwk(bytes.Repeat([]byte{0xff}, MaxKeyBits/8+1), "AQAB"), false},
+		{"jwk large e", jwk(bytes.Repeat([]byte{0xff}, 128), "AQAAAAE"), false},
+		{"jwk zero d", []byte(`{"kty":"RSA","n":"3Q","e":"Aw","d":"AA"}`), true},
+		{"jwk large d", []byte(`{"kty":"RSA","n":"3Q","e":"Aw","d":"AQAB"}`), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			if tt.private {
+				_, err = ParsePrivateKey(tt.data)
+			} else {
+				_, err = ParsePublicKey(tt.data)
+			}
+			if !errors.Is(err, ErrInvalidKey) {
+				t.Errorf("Parse has err = %v, want %v", err, ErrInvalidKey)
+			}
+		})
+	}
+}
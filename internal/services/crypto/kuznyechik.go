@@ -0,0 +1,208 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"fmt"
+)
+
+// KuznyechikBlockSize — размер блока «Кузнечика» в байтах.
+const KuznyechikBlockSize = 16
+
+// KuznyechikCipher — «Кузнечик» по ГОСТ Р 34.12-2015: SP-сеть из девяти
+// раундов LSX (сложение с ключом X, подстановка S, линейное преобразование L)
+// и завершающего X. Блок записывается от старшего байта a15 к младшему a0.
+type KuznyechikCipher struct {
+	roundKeys [10][KuznyechikBlockSize]byte
+}
+
+// kuznyechikPi — подстановка π из ГОСТ Р 34.12-2015.
+var kuznyechikPi = [256]byte{
+	252, 238, 221, 17, 207, 110, 49, 22, 251, 196, 250, 218, 35, 197, 4, 77,
+	233, 119, 240, 219, 147, 46, 153, 186, 23, 54, 241, 187, 20, 205, 95, 193,
+	249, 24, 101, 90, 226, 92, 239, 33, 129, 28, 60, 66, 139, 1, 142, 79,
+	5, 132, 2, 174, 227, 106, 143, 160, 6, 11, 237, 152, 127, 212, 211, 31,
+	235, 52, 44, 81, 234, 200, 72, 171, 242, 42, 104, 162, 253, 58, 206, 204,
+	181, 112, 14, 86, 8, 12, 118, 18, 191, 114, 19, 71, 156, 183, 93, 135,
+	21, 161, 150, 41, 16, 123, 154, 199, 243, 145, 120, 111, 157, 158, 178, 177,
+	50, 117, 25, 61, 255, 53, 138, 126, 109, 84, 198, 128, 195, 189, 13, 87,
+	223, 245, 36, 169, 62, 168, 67, 201, 215, 121, 214, 246, 124, 34, 185, 3,
+	224, 15, 236, 222, 122, 148, 176, 188, 220, 232, 40, 80, 78, 51, 10, 74,
+	167, 151, 96, 115, 30, 0, 98, 68, 26, 184, 56, 130, 100, 159, 38, 65,
+	173, 69, 70, 146, 39, 94, 85, 47, 140, 163, 165, 125, 105, 213, 149, 59,
+	7, 88, 179, 64, 134, 172, 29, 247, 48, 55, 107, 228, 136, 217, 231, 137,
+	225, 27, 131, 73, 76, 63, 248, 254, 141, 83, 170, 144, 202, 216, 133, 97,
+	32, 113, 103, 164, 45, 43, 9, 91, 203, 155, 37, 208, 190, 229, 108, 82,
+	89, 166, 116, 210, 230, 244, 180, 192, 209, 102, 175, 194, 57, 75, 99, 182,
+}
+
+var (
+	kuznyechikInvPi [256]byte
+	// kuznyechikMul[i][x] — произведение x на i-й коэффициент ℓ.
+	kuznyechikMul [KuznyechikBlockSize][256]byte
+)
+
+// kuznyechikL — коэффициенты ℓ(a15, …, a0).
+var kuznyechikL = [KuznyechikBlockSize]byte{148, 32, 133, 16, 194, 192, 1, 251, 1, 192, 194, 16, 133, 32, 148, 1}
+
+func init() {
+	for i, p := range kuznyechikPi {
+		kuznyechikInvPi[p] = byte(i)
+	}
+	for i, k := range kuznyechikL {
+		for x := range 256 {
+			kuznyechikMul[i][x] = kuznyechikGFMul(k, byte(x))
+		}
+	}
+}
+
+// kuznyechikGFMul умножает в GF(2⁸) по модулю x⁸+x⁷+x⁶+x+1.
+func kuznyechikGFMul(a, b byte) byte {
+	var p byte
+	for b != 0 {
+		if b&1 != 0 {
+			p ^= a
+		}
+		if a&0x80 != 0 {
+			a = a<<1 ^ 0xc3
+		} else {
+			a <<= 1
+		}
+		b >>= 1
+	}
+	return p
+}
+
+// NewKuznyechikCipher создаёт шифр с 256-битным ключом.
+func NewKuznyechikCipher(key []byte) (*KuznyechikCipher, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("%w: Kuznyechik key must be 32 bytes, got %d", ErrInvalidCipherParams, len(key))
+	}
+	c := &KuznyechikCipher{}
+
+	// K1 ‖ K2 = K, следующие пары — восемь раундов сети Фейстеля
+	// F[C](a1, a0) = (LSX[C](a1) ⊕ a0, a1) с константами Cᵢ = L(Vec(i)).
+	copy(c.roundKeys[0][:], key[:16])
+	copy(c.roundKeys[1][:], key[16:])
+	a1, a0 := c.roundKeys[0], c.roundKeys[1]
+	for i := 1; i <= 32; i++ {
+		var constant [KuznyechikBlockSize]byte
+		constant[15] = byte(i)
+		kuznyechikLinear(&constant)
+
+		t := a1
+		xorBlock(&t, &constant)
+		kuznyechikSub(&t, &kuznyechikPi)
+		kuznyechikLinear(&t)
+		xorBlock(&t, &a0)
+		a1, a0 = t, a1
+		if i%8 == 0 {
+			c.roundKeys[i/4], c.roundKeys[i/4+1] = a1, a0
+		}
+	}
+	return c, nil
+}
+
+func (c *KuznyechikCipher) BlockSize() int {
+	return KuznyechikBlockSize
+}
+
+func (c *KuznyechikCipher) Encrypt(dst, src []byte) {
+	c.encrypt(dst, src, nil)
+}
+
+func (c *KuznyechikCipher) Decrypt(dst, src []byte) {
+	c.decrypt(dst, src, nil)
+}
+
+func (c *KuznyechikCipher) EncryptTrace(dst, src []byte) []RoundStep {
+	var trace []RoundStep
+	c.encrypt(dst, src, &trace)
+	return trace
+}
+
+func (c *KuznyechikCipher) DecryptTrace(dst, src []byte) []RoundStep {
+	var trace []RoundStep
+	c.decrypt(dst, src, &trace)
+	return trace
+}
+
+// KeySchedule возвращает ключи раундов K1…K10 в hex.
+func (c *KuznyechikCipher) KeySchedule() []string {
+	keys := make([]string, len(c.roundKeys))
+	for i, k := range c.roundKeys {
+		keys[i] = hex.EncodeToString(k[:])
+	}
+	return keys
+}
+
+func (c *KuznyechikCipher) encrypt(dst, src []byte, trace *[]RoundStep) {
+	var s [KuznyechikBlockSize]byte
+	copy(s[:], src)
+	recordState(trace, 0, "input", s[:])
+	for round := 1; round < len(c.roundKeys); round++ {
+		xorBlock(&s, &c.roundKeys[round-1])
+		recordState(trace, round, "add_round_key", s[:])
+		kuznyechikSub(&s, &kuznyechikPi)
+		recordState(trace, round, "sub_bytes", s[:])
+		kuznyechikLinear(&s)
+		recordState(trace, round, "linear", s[:])
+	}
+	xorBlock(&s, &c.roundKeys[9])
+	recordState(trace, len(c.roundKeys), "add_round_key", s[:])
+	copy(dst, s[:])
+}
+
+func (c *KuznyechikCipher) decrypt(dst, src []byte, trace *[]RoundStep) {
+	var s [KuznyechikBlockSize]byte
+	copy(s[:], src)
+	recordState(trace, 0, "input", s[:])
+	xorBlock(&s, &c.roundKeys[9])
+	recordState(trace, 0, "add_round_key", s[:])
+	for round := 1; round < len(c.roundKeys); round++ {
+		kuznyechikInvLinear(&s)
+		recordState(trace, round, "inv_linear", s[:])
+		kuznyechikSub(&s, &kuznyechikInvPi)
+		recordState(trace, round, "inv_sub_bytes", s[:])
+		xorBlock(&s, &c.roundKeys[9-round])
+		recordState(trace, round, "add_round_key", s[:])
+	}
+	copy(dst, s[:])
+}
+
+func xorBlock(s, k *[KuznyechikBlockSize]byte) {
+	for i := range s {
+		s[i] ^= k[i]
+	}
+}
+
+func kuznyechikSub(s *[KuznyechikBlockSize]byte, box *[256]byte) {
+	for i := range s {
+		s[i] = box[s[i]]
+	}
+}
+
+// kuznyechikLinear — L = R¹⁶, где R(a15, …, a0) = ℓ(a15, …, a0) ‖ a15 ‖ … ‖ a1.
+func kuznyechikLinear(s *[KuznyechikBlockSize]byte) {
+	for range KuznyechikBlockSize {
+		var l byte
+		for i, a := range s {
+			l ^= kuznyechikMul[i][a]
+		}
+		copy(s[1:], s[:15])
+		s[0] = l
+	}
+}
+
+// kuznyechikInvLinear — L⁻¹ = (R⁻¹)¹⁶, R⁻¹(a15, …, a0) = a14 ‖ … ‖ a0 ‖ ℓ(a14, …, a0, a15).
+func kuznyechikInvLinear(s *[KuznyechikBlockSize]byte) {
+	for range KuznyechikBlockSize {
+		a15 := s[0]
+		copy(s[:15], s[1:])
+		s[15] = a15
+		var l byte
+		for i, a := range s {
+			l ^= kuznyechikMul[i][a]
+		}
+		s[15] = l
+	}
+}
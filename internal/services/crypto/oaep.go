@@ -0,0 +1,126 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"hash"
+)
+
+// Схемы шифрования: PKCS#1 v1.5 и RSAES-OAEP (RFC 8017, 7.1) с SHA-1
+// или SHA-256 и в хеш-функции, и в MGF1.
+const (
+	SchemePKCS1v15   = "pkcs1v15"
+	SchemeOAEPSHA1   = "oaep-sha1"
+	SchemeOAEPSHA256 = "oaep-sha256"
+)
+
+// OAEPHash возвращает хеш-функцию схемы OAEP; для SchemePKCS1v15 — nil.
+func OAEPHash(scheme string) (func() hash.Hash, error) {
+	switch scheme {
+	case SchemePKCS1v15:
+		return nil, nil
+	case SchemeOAEPSHA1:
+		return sha1.New, nil
+	case SchemeOAEPSHA256:
+		return sha256.New, nil
+	}
+	return nil, fmt.Errorf("%w: unknown scheme %q, want %s, %s or %s",
+		ErrInvalidParams, scheme, SchemePKCS1v15, SchemeOAEPSHA1, SchemeOAEPSHA256)
+}
+
+// EncryptOAEP шифрует m по схеме RSAES-OAEP с хеш-функцией newHash и меткой label.
+// Результат совместим с crypto/rsa.DecryptOAEP.
+func (r *RSAService) EncryptOAEP(newHash func() hash.Hash, pub *PublicKey, m, label []byte) ([]byte, error) {
+	if err := checkPublicKey(pub); err != nil {
+		return nil, err
+	}
+	h := newHash()
+	keyLen := (pub.N.BitLen() + 7) / 8
+	hLen := h.Size()
+	if len(m) > keyLen-2*hLen-2 {
+		return nil, fmt.Errorf("%w: len(m)=%v, want at most %v", ErrMessageTooLong, len(m), keyLen-2*hLen-2)
+	}
+
+	// EM = 00 || maskedSeed || maskedDB, DB = lHash || PS || 01 || M.
+	em := make([]byte, keyLen)
+	seed := em[1 : 1+hLen]
+	db := em[1+hLen:]
+
+	h.Write(label)
+	h.Sum(db[:0])
+	db[len(db)-len(m)-1] = 0x01
+	copy(db[len(db)-len(m):], m)
+
+	if _, err := rand.Read(seed); err != nil {
+		return nil, err
+	}
+	mgf1XOR(db, h, seed)
+	mgf1XOR(seed, h, db)
+
+	return r.encrypt(pub, em), nil
+}
+
+// DecryptOAEP расшифровывает c по схеме RSAES-OAEP. Как и в Decrypt, при любой
+// ошибке возвращается ErrDecryption, а проверки не зависят по времени от данных.
+func (r *RSAService) DecryptOAEP(newHash func() hash.Hash, priv *PrivateKey, c, label []byte) ([]byte, error) {
+	h := newHash()
+	keyLen := (priv.N.BitLen() + 7) / 8
+	hLen := h.Size()
+	if keyLen < 2*hLen+2 {
+		return nil, ErrDecryption
+	}
+
+	em, err := r.decryptBlock(priv, c)
+	if err != nil {
+		return nil, err
+	}
+
+	h.Write(label)
+	lHash := h.Sum(nil)
+
+	firstByteIsZero := subtle.ConstantTimeByteEq(em[0], 0x00)
+	seed := em[1 : 1+hLen]
+	db := em[1+hLen:]
+	mgf1XOR(seed, h, db)
+	mgf1XOR(db, h, seed)
+
+	lHashGood := subtle.ConstantTimeCompare(db[:hLen], lHash)
+
+	// После lHash идут нули, затем 01. Ищем 01, не выходя из цикла раньше
+	// времени; invalid становится 1, если до 01 встретился другой байт.
+	lookingForIndex, index, invalid := 1, 0, 0
+	rest := db[hLen:]
+	for i := range rest {
+		equals0 := subtle.ConstantTimeByteEq(rest[i], 0x00)
+		equals1 := subtle.ConstantTimeByteEq(rest[i], 0x01)
+		index = subtle.ConstantTimeSelect(lookingForIndex&equals1, i, index)
+		lookingForIndex = subtle.ConstantTimeSelect(equals1, 0, lookingForIndex)
+		invalid = subtle.ConstantTimeSelect(lookingForIndex&^equals0, 1, invalid)
+	}
+
+	if firstByteIsZero&lHashGood&^invalid&^lookingForIndex != 1 {
+		return nil, ErrDecryption
+	}
+	return rest[index+1:], nil
+}
+
+// mgf1XOR накладывает на out маску MGF1(seed) (RFC 8017, B.2.1).
+func mgf1XOR(out []byte, h hash.Hash, seed []byte) {
+	var counter [4]byte
+	var digest []byte
+	for done := 0; done < len(out); {
+		h.Reset()
+		h.Write(seed)
+		h.Write(counter[:])
+		digest = h.Sum(digest[:0])
+		for i := 0; i < len(digest) && done < len(out); i++ {
+			out[done] ^= digest[i]
+			done++
+		}
+		binary.BigEndian.PutUint32(counter[:], binary.BigEndian.Uint32(counter[:])+1)
+	}
+}
@@ -0,0 +1,176 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"errors"
+	"hash"
+	"math/big"
+	"testing"
+)
+
+// stdPrivateKey переводит ключ в crypto/rsa для проверки совместимости.
+func stdPrivateKey(t testing.TB, priv *PrivateKey) *rsa.PrivateKey {
+	t.Helper()
+	key := &rsa.PrivateKey{
+		PublicKey: rsa.PublicKey{N: priv.N, E: int(priv.E.Int64())},
+		D:         priv.D,
+		Primes:    []*big.Int{priv.P, priv.Q},
+	}
+	key.Precompute()
+	if err := key.Validate(); err != nil {
+		t.Fatalf("rsa.PrivateKey.Validate() has err = %v", err)
+	}
+	return key
+}
+
+func TestRSAService_OAEPCompatibility(t *testing.T) {
+	r := NewRsaService()
+	pub, priv, err := r.GenerateKeys(1024, DefaultExponent)
+	if err != nil {
+		t.Fatalf("RSAService.GenerateKeys() has err = %v", err)
+	}
+	std := stdPrivateKey(t, priv)
+
+	tests := []struct {
+		name    string
+		newHash func() hash.Hash
+		label   []byte
+		msg     []byte
+	}{
+		{"sha1", sha1.New, nil, []byte("Секретное сообщение")},
+		{"sha1 label", sha1.New, []byte("метка"), []byte("Секретное сообщение")},
+		{"sha256", sha256.New, nil, []byte("Секретное сообщение")},
+		{"sha256 label", sha256.New, []byte("метка"), []byte("Секретное сообщение")},
+		{"sha256 empty", sha256.New, nil, []byte{}},
+		{"sha256 max length", sha256.New, nil, bytes.Repeat([]byte{0xAB}, 128-2*32-2)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := r.EncryptOAEP(tt.newHash, pub, tt.msg, tt.label)
+			if err != nil {
+				t.Fatalf("RSAService.EncryptOAEP() has err = %v", err)
+			}
+			m, err := rsa.DecryptOAEP(tt.newHash(), nil, std, c, tt.label)
+			if err != nil {
+				t.Fatalf("rsa.DecryptOAEP() has err = %v", err)
+			}
+			if !bytes.Equal(m, tt.msg) {
+				t.Errorf("rsa.DecryptOAEP() = %q, want %q", m, tt.msg)
+			}
+
+			c, err = rsa.EncryptOAEP(tt.newHash(), rand.Reader, &std.PublicKey, tt.msg, tt.label)
+			if err != nil {
+				t.Fatalf("rsa.EncryptOAEP() has err = %v", err)
+			}
+			m, err = r.DecryptOAEP(tt.newHash, priv, c, tt.label)
+			if err != nil {
+				t.Fatalf("RSAService.DecryptOAEP() has err = %v", err)
+			}
+			if !bytes.Equal(m, tt.msg) {
+				t.Errorf("RSAService.DecryptOAEP() = %q, want %q", m, tt.msg)
+			}
+
+			if _, err := r.DecryptOAEP(tt.newHash, priv, c, []byte("другая метка")); err != ErrDecryption {
+				t.Errorf("RSAService.DecryptOAEP() with wrong label has err = %v, want %v", err, ErrDecryption)
+			}
+		})
+	}
+
+	long := bytes.Repeat([]byte{1}, 128-2*32-1)
+	if _, err := r.EncryptOAEP(sha256.New, pub, long, nil); !errors.Is(err, ErrMessageTooLong) {
+		t.Errorf("RSAService.EncryptOAEP() has err = %v, want %v", err, ErrMessageTooLong)
+	}
+}
+
+func TestRSAService_PKCS1v15Compatibility(t *testing.T) {
+	r := NewRsaService()
+	pub, priv, err := r.GenerateKeys(1024, DefaultExponent)
+	if err != nil {
+		t.Fatalf("RSAService.GenerateKeys() has err = %v", err)
+	}
+	std := stdPrivateKey(t, priv)
+	msg := []byte("Секретное сообщение")
+
+	c, err := r.Encrypt(pub, msg)
+	if err != nil {
+		t.Fatalf("RSAService.Encrypt() has err = %v", err)
+	}
+	m, err := rsa.DecryptPKCS1v15(nil, std, c)
+	if err != nil || !bytes.Equal(m, msg) {
+		t.Errorf("rsa.DecryptPKCS1v15() = %q, %v, want %q", m, err, msg)
+	}
+
+	c, err = rsa.EncryptPKCS1v15(rand.Reader, &std.PublicKey, msg)
+	if err != nil {
+		t.Fatalf("rsa.EncryptPKCS1v15() has err = %v", err)
+	}
+	m, err = r.Decrypt(priv, c)
+	if err != nil || !bytes.Equal(m, msg) {
+		t.Errorf("RSAService.Decrypt() = %q, %v, want %q", m, err, msg)
+	}
+}
+
+// TestRSAService_DecryptionErrors проверяет, что все ошибки дополнения
+// неразличимы: возвращается одно и то же значение ErrDecryption.
+func TestRSAService_DecryptionErrors(t *testing.T) {
+	r := NewRsaService()
+	pub, priv, err := r.GenerateKeys(1024, DefaultExponent)
+	if err != nil {
+		t.Fatalf("RSAService.GenerateKeys() has err = %v", err)
+	}
+	std := stdPrivateKey(t, priv)
+	keyLen := (pub.N.BitLen() + 7) / 8
+
+	// rawEncrypt шифрует блок без дополнения, чтобы получить неверное дополнение.
+	rawEncrypt := func(em []byte) []byte {
+		return r.encrypt(pub, em)
+	}
+	block := func(prefix ...byte) []byte {
+		em := bytes.Repeat([]byte{0x11}, keyLen)
+		copy(em, prefix)
+		return em
+	}
+	v15ShortPS := block(0x00, 0x02, 1, 2, 3, 4, 5, 6, 7, 0x00)
+	v15NoSeparator := block(0x00, 0x02)
+	v15WrongType := block(0x00, 0x01, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x00)
+
+	c, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, &std.PublicKey, []byte("msg"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := bytes.Clone(c)
+	tampered[len(tampered)/2] ^= 0x01
+
+	tests := []struct {
+		name string
+		oaep bool
+		c    []byte
+	}{
+		{"v1.5 short", false, c[:len(c)-1]},
+		{"v1.5 not less than n", false, bytes.Repeat([]byte{0xFF}, keyLen)},
+		{"v1.5 short padding string", false, rawEncrypt(v15ShortPS)},
+		{"v1.5 no separator", false, rawEncrypt(v15NoSeparator)},
+		{"v1.5 block type", false, rawEncrypt(v15WrongType)},
+		{"oaep short", true, c[:len(c)-1]},
+		{"oaep tampered", true, tampered},
+		{"oaep zero", true, make([]byte, keyLen)},
+		{"oaep v1.5 block", true, rawEncrypt(v15WrongType)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			if tt.oaep {
+				_, err = r.DecryptOAEP(sha256.New, priv, tt.c, nil)
+			} else {
+				_, err = r.Decrypt(priv, tt.c)
+			}
+			if err != ErrDecryption {
+				t.Errorf("decrypt has err = %v, want %v", err, ErrDecryption)
+			}
+		})
+	}
+}
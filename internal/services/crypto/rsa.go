@@ -1,16 +1,57 @@
 package crypto
 
 import (
-	"bytes"
 	"crypto/rand"
+	"crypto/subtle"
+	"errors"
 	"fmt"
+	"hash"
 	"math/big"
+	"slices"
 )
 
+// Допустимые размеры ключа в битах и открытые экспоненты.
+const (
+	MinKeyBits      = 512
+	MaxKeyBits      = 8192
+	DefaultKeyBits  = 2048
+	DefaultExponent = 65537
+)
+
+var publicExponents = []int{3, 17, 65537}
+
+// ErrInvalidParams возвращается, если параметры RSA не подходят: размер ключа
+// или экспонента вне допустимых, p или q не простые, e не взаимно просто
+// с φ(n), сообщение не меньше n и т. п.
+var ErrInvalidParams = errors.New("invalid RSA parameters")
+
+// ErrMessageTooLong возвращается, если сообщение не помещается в блок
+// с дополнением при данном размере ключа.
+var ErrMessageTooLong = errors.New("message too long for RSA key size")
+
+// ErrDecryption возвращается при любой ошибке расшифрования — неверной длине
+// шифртекста или неверном дополнении, — чтобы по ответу нельзя было понять,
+// какая проверка не прошла.
+var ErrDecryption = errors.New("RSA decryption error")
+
+// ErrKeyGeneration возвращается, если не удалось подобрать простые числа
+// за разумное число попыток.
+var ErrKeyGeneration = errors.New("failed to generate RSA key")
+
+// maxKeyRetries — число попыток подобрать p и q, прежде чем вернуть ErrKeyGeneration.
+const maxKeyRetries = 10
+
 type RSA interface {
-	GenerateKeys(bitlen int) (*PublicKey, *PrivateKey, error)
+	GenerateKeys(bits, e int) (*PublicKey, *PrivateKey, error)
 	Encrypt(pub *PublicKey, m []byte) ([]byte, error)
 	Decrypt(priv *PrivateKey, c []byte) ([]byte, error)
+	EncryptOAEP(newHash func() hash.Hash, pub *PublicKey, m, label []byte) ([]byte, error)
+	DecryptOAEP(newHash func() hash.Hash, priv *PrivateKey, c, label []byte) ([]byte, error)
+	Sign(priv *PrivateKey, msg []byte, scheme string) ([]byte, SignatureDetails, error)
+	Verify(pub *PublicKey, msg, sig []byte, scheme string) (SignatureDetails, error)
+	SealEnvelope(pub *PublicKey, data []byte, scheme string) ([]byte, EnvelopeDetails, error)
+	OpenEnvelope(priv *PrivateKey, envelope []byte) ([]byte, EnvelopeDetails, error)
+	Textbook(params TextbookParams) (*TextbookDetails, error)
 }
 
 type RSAService struct {
@@ -21,66 +62,110 @@ type PublicKey struct {
 	E *big.Int
 }
 
+// PrivateKey — закрытый ключ. P, Q и E нужны для сериализации в PKCS#1
+// и PKCS#8; ключ, заданный только N и D, годится лишь для расшифрования.
+// Dp = d mod (p-1), Dq = d mod (q-1) и Qinv = q⁻¹ mod p ускоряют
+// расшифрование по китайской теореме об остатках, их заполняет Precompute.
 type PrivateKey struct {
-	N *big.Int
-	D *big.Int
+	N    *big.Int
+	E    *big.Int
+	D    *big.Int
+	P    *big.Int
+	Q    *big.Int
+	Dp   *big.Int
+	Dq   *big.Int
+	Qinv *big.Int
+}
+
+// Precompute вычисляет Dp, Dq и Qinv, если у ключа есть P и Q.
+func (priv *PrivateKey) Precompute() {
+	if priv.P == nil || priv.Q == nil || priv.D == nil {
+		return
+	}
+	one := big.NewInt(1)
+	priv.Dp = new(big.Int).Mod(priv.D, new(big.Int).Sub(priv.P, one))
+	priv.Dq = new(big.Int).Mod(priv.D, new(big.Int).Sub(priv.Q, one))
+	priv.Qinv = new(big.Int).ModInverse(priv.Q, priv.P)
 }
 
 func NewRsaService() *RSAService {
 	return &RSAService{}
 }
 
-func (r *RSAService) GenerateKeys(bitlen int) (*PublicKey, *PrivateKey, error) {
-	numRetries := 0
-
-	for {
-		numRetries++
-		if numRetries == 10 {
-			panic("retrying too many times, something is wrong")
-		}
+// GenerateKeys создаёт ключ размером bits бит (MinKeyBits..MaxKeyBits)
+// с открытой экспонентой e (3, 17 или 65537).
+func (r *RSAService) GenerateKeys(bits, e int) (*PublicKey, *PrivateKey, error) {
+	if bits < MinKeyBits || bits > MaxKeyBits {
+		return nil, nil, fmt.Errorf("%w: bits = %d, want %d..%d", ErrInvalidParams, bits, MinKeyBits, MaxKeyBits)
+	}
+	if !slices.Contains(publicExponents, e) {
+		return nil, nil, fmt.Errorf("%w: e = %d, want one of %v", ErrInvalidParams, e, publicExponents)
+	}
+	E := big.NewInt(int64(e))
 
-		p, err := rand.Prime(rand.Reader, bitlen/2)
+	for range maxKeyRetries {
+		// rand.Prime выставляет два старших бита, поэтому в произведении
+		// ровно bits бит, в том числе при нечётном bits.
+		p, err := generatePrime(E, (bits+1)/2)
 		if err != nil {
 			return nil, nil, err
 		}
-		q, err := rand.Prime(rand.Reader, bitlen/2)
+		q, err := generatePrime(E, bits/2)
 		if err != nil {
 			return nil, nil, err
 		}
+		if p.Cmp(q) == 0 {
+			continue
+		}
 
 		// n is pq
-		n := new(big.Int).Set(p)
-		n.Mul(n, q)
-
-		if n.BitLen() != bitlen {
+		n := new(big.Int).Mul(p, q)
+		if n.BitLen() != bits {
 			continue
 		}
 
 		// theta(n) = (p-1)(q-1)
-		p.Sub(p, big.NewInt(1))
-		q.Sub(q, big.NewInt(1))
-		totient := new(big.Int).Set(p)
-		totient.Mul(totient, q)
-
-		// e as recommended by PKCS#1 (RFC 2313)
-		e := big.NewInt(65537)
+		totient := new(big.Int).Sub(p, big.NewInt(1))
+		totient.Mul(totient, new(big.Int).Sub(q, big.NewInt(1)))
 
-		d := new(big.Int).ModInverse(e, totient)
+		d := new(big.Int).ModInverse(E, totient)
 		if d == nil {
 			continue
 		}
 
-		pub := &PublicKey{N: n, E: e}
-		priv := &PrivateKey{N: n, D: d}
+		pub := &PublicKey{N: n, E: E}
+		priv := &PrivateKey{N: n, E: E, D: d, P: p, Q: q}
+		priv.Precompute()
 		return pub, priv, nil
 	}
+	return nil, nil, fmt.Errorf("%w: retrying too many times", ErrKeyGeneration)
+}
+
+// generatePrime возвращает простое p размером bits бит, для которого
+// НОД(e, p-1) = 1, иначе e не будет обратимо по модулю φ(n).
+func generatePrime(e *big.Int, bits int) (*big.Int, error) {
+	for range 100 * maxKeyRetries {
+		p, err := rand.Prime(rand.Reader, bits)
+		if err != nil {
+			return nil, err
+		}
+		pMinus1 := new(big.Int).Sub(p, big.NewInt(1))
+		if new(big.Int).GCD(nil, nil, e, pMinus1).Cmp(big.NewInt(1)) == 0 {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: no prime p with gcd(e, p-1) = 1", ErrKeyGeneration)
 }
 
+// Encrypt шифрует m с дополнением PKCS#1 v1.5 (блок типа 02 из RFC 2313).
 func (r *RSAService) Encrypt(pub *PublicKey, m []byte) ([]byte, error) {
+	if err := checkPublicKey(pub); err != nil {
+		return nil, err
+	}
 	// Compute length of key in bytes, rounding up.
 	keyLen := (pub.N.BitLen() + 7) / 8
 	if len(m) > keyLen-11 {
-		return nil, fmt.Errorf("len(m)=%v, too long", len(m))
+		return nil, fmt.Errorf("%w: len(m)=%v, want at most %v", ErrMessageTooLong, len(m), keyLen-11)
 	}
 
 	// Following RFC 2313, using block type 02 as recommended for encryption:
@@ -107,57 +192,117 @@ func (r *RSAService) Encrypt(pub *PublicKey, m []byte) ([]byte, error) {
 
 	// Now the encryption block is complete; we take it as a m-byte big.Int and
 	// RSA-encrypt it with the public key.
-	mnum := new(big.Int).SetBytes(eb)
-	c := r.encrypt(pub, mnum)
-
-	padLen := keyLen - len(c.Bytes())
-	for i := 0; i < padLen; i++ {
-		eb[i] = 0x00
-	}
-	copy(eb[padLen:], c.Bytes())
-	return eb, nil
+	return r.encrypt(pub, eb), nil
 }
 
-func (r *RSAService) encrypt(pub *PublicKey, c *big.Int) *big.Int {
-	m := new(big.Int)
-	m.Exp(c, pub.E, pub.N)
-	return m
+// encrypt возводит блок em в степень e по модулю n и возвращает результат
+// длиной em байт.
+func (r *RSAService) encrypt(pub *PublicKey, em []byte) []byte {
+	m := new(big.Int).SetBytes(em)
+	c := m.Exp(m, pub.E, pub.N)
+	return c.FillBytes(make([]byte, len(em)))
 }
 
+// Decrypt расшифровывает c с дополнением PKCS#1 v1.5. При любой ошибке
+// в дополнении возвращается ErrDecryption, а проверка выполняется за время,
+// не зависящее от того, какой байт неверен: иначе атака Блейхенбахера
+// позволила бы по ответам расшифровать чужой шифртекст.
 func (r *RSAService) Decrypt(priv *PrivateKey, c []byte) ([]byte, error) {
+	em, err := r.decryptBlock(priv, c)
+	if err != nil {
+		return nil, err
+	}
+
+	// EB = 00 || 02 || PS || 00 || D, в PS не меньше 8 ненулевых байт.
+	firstByteIsZero := subtle.ConstantTimeByteEq(em[0], 0x00)
+	secondByteIsTwo := subtle.ConstantTimeByteEq(em[1], 0x02)
+
+	lookingForIndex, index := 1, 0
+	for i := 2; i < len(em); i++ {
+		equals0 := subtle.ConstantTimeByteEq(em[i], 0x00)
+		index = subtle.ConstantTimeSelect(lookingForIndex&equals0, i, index)
+		lookingForIndex = subtle.ConstantTimeSelect(equals0, 0, lookingForIndex)
+	}
+	validPS := subtle.ConstantTimeLessOrEq(2+8, index)
+
+	if firstByteIsZero&secondByteIsTwo&(^lookingForIndex&1)&validPS != 1 {
+		return nil, ErrDecryption
+	}
+	return em[index+1:], nil
+}
+
+// decryptBlock расшифровывает c и возвращает блок длиной в размер ключа.
+func (r *RSAService) decryptBlock(priv *PrivateKey, c []byte) ([]byte, error) {
 	keyLen := (priv.N.BitLen() + 7) / 8
 	if len(c) != keyLen {
-		return nil, fmt.Errorf("len(c)=%v, want keyLen=%v", len(c), keyLen)
+		return nil, ErrDecryption
 	}
 
 	// Convert c into a bit.Int and decrypt it using the private key.
 	cnum := new(big.Int).SetBytes(c)
-	mnum := r.decrypt(priv, cnum)
+	mnum, err := r.decrypt(priv, cnum)
+	if err != nil {
+		return nil, err
+	}
 
 	// Write the bytes of mnum into m, left-padding if needed.
-	m := make([]byte, keyLen)
-	copy(m[keyLen-len(mnum.Bytes()):], mnum.Bytes())
+	return mnum.FillBytes(make([]byte, keyLen)), nil
+}
 
-	// Expect proper block 02 beginning.
-	if m[0] != 0x00 {
-		return nil, fmt.Errorf("m[0]=%v, want 0x00", m[0])
-	}
-	if m[1] != 0x02 {
-		return nil, fmt.Errorf("m[1]=%v, want 0x02", m[1])
+// decrypt вычисляет c^d mod n. Если у ключа есть P, Q и значения CRT,
+// возведение в степень выполняется отдельно по модулям p и q, что примерно
+// в 3–4 раза быстрее. Если известна E, шифртекст маскируется случайным r^e,
+// чтобы время вычисления не зависело от c, а результат проверяется
+// обратным возведением в степень: ошибка в одной из половин CRT иначе
+// раскрыла бы множитель n.
+func (r *RSAService) decrypt(priv *PrivateKey, c *big.Int) (*big.Int, error) {
+	if c.Cmp(priv.N) >= 0 {
+		return nil, ErrDecryption
 	}
 
-	// Skip over random padding until a 0x00 byte is reached. +2 adjusts the index
-	// back to the full slice.
-	endPad := bytes.IndexByte(m[2:], 0x00) + 2
-	if endPad < 2 {
-		return nil, fmt.Errorf("end of padding not found")
+	var rInv *big.Int
+	if priv.E != nil {
+		var blind *big.Int
+		for {
+			var err error
+			blind, err = rand.Int(rand.Reader, priv.N)
+			if err != nil {
+				return nil, err
+			}
+			if blind.Sign() == 0 {
+				continue
+			}
+			if rInv = new(big.Int).ModInverse(blind, priv.N); rInv != nil {
+				break
+			}
+		}
+		// c' = c·r^e mod n, тогда c'^d = m·r mod n.
+		blind.Exp(blind, priv.E, priv.N)
+		c = new(big.Int).Mul(c, blind)
+		c.Mod(c, priv.N)
 	}
 
-	return m[endPad+1:], nil
-}
+	var m *big.Int
+	if priv.Dp == nil || priv.Dq == nil || priv.Qinv == nil {
+		m = new(big.Int).Exp(c, priv.D, priv.N)
+	} else {
+		// m1 = c^dp mod p, m2 = c^dq mod q, h = qinv·(m1 - m2) mod p, m = m2 + h·q.
+		m1 := new(big.Int).Exp(c, priv.Dp, priv.P)
+		m2 := new(big.Int).Exp(c, priv.Dq, priv.Q)
+		h := m1.Sub(m1, m2)
+		h.Mul(h, priv.Qinv)
+		h.Mod(h, priv.P)
+		m = h.Mul(h, priv.Q)
+		m.Add(m, m2)
+	}
 
-func (r *RSAService) decrypt(priv *PrivateKey, m *big.Int) *big.Int {
-	c := new(big.Int)
-	c.Exp(m, priv.D, priv.N)
-	return c
+	if priv.E != nil {
+		check := new(big.Int).Exp(m, priv.E, priv.N)
+		if check.Cmp(c) != 0 {
+			return nil, fmt.Errorf("%w: check m^e mod n failed", ErrDecryption)
+		}
+		m.Mul(m, rInv)
+		m.Mod(m, priv.N)
+	}
+	return m, nil
 }
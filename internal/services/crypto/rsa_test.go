@@ -2,6 +2,10 @@ package crypto
 
 import (
 	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
 	"testing"
 )
 
@@ -9,7 +13,7 @@ func TestRSAService_EncryptAndDecrypt(t *testing.T) {
 	data := []byte("Какой-то очень секретный текст. Прям ну очень секретный. Отвечаю")
 
 	r := &RSAService{}
-	pub, priv, err := r.GenerateKeys(2048)
+	pub, priv, err := r.GenerateKeys(DefaultKeyBits, DefaultExponent)
 	if err != nil {
 		t.Errorf("RSAService.GenerateKeys() has err = %v", err.Error())
 	}
@@ -25,3 +29,120 @@ func TestRSAService_EncryptAndDecrypt(t *testing.T) {
 		t.Errorf("RSAService want data == m, got data = %v, m = %v", data, m)
 	}
 }
+
+func TestRSAService_GenerateKeys(t *testing.T) {
+	r := NewRsaService()
+	tests := []struct {
+		bits int
+		e    int
+	}{
+		{512, 3},
+		{513, 17},
+		{1024, 65537},
+		{1536, 3},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d bits e=%d", tt.bits, tt.e), func(t *testing.T) {
+			pub, priv, err := r.GenerateKeys(tt.bits, tt.e)
+			if err != nil {
+				t.Fatalf("RSAService.GenerateKeys() has err = %v", err)
+			}
+			if pub.N.BitLen() != tt.bits || pub.E.Int64() != int64(tt.e) {
+				t.Errorf("RSAService.GenerateKeys() n has %d bits and e = %v, want %d and %d",
+					pub.N.BitLen(), pub.E, tt.bits, tt.e)
+			}
+			if priv.Dp == nil || priv.Dq == nil || priv.Qinv == nil {
+				t.Fatalf("RSAService.GenerateKeys() private key has no CRT values")
+			}
+
+			data := []byte("Секрет")
+			c, err := r.Encrypt(pub, data)
+			if err != nil {
+				t.Fatalf("RSAService.Encrypt() has err = %v", err)
+			}
+			m, err := r.Decrypt(priv, c)
+			if err != nil {
+				t.Fatalf("RSAService.Decrypt() has err = %v", err)
+			}
+			if !bytes.Equal(data, m) {
+				t.Errorf("RSAService.Decrypt() = %q, want %q", m, data)
+			}
+		})
+	}
+}
+
+func TestRSAService_GenerateKeysInvalidParams(t *testing.T) {
+	r := NewRsaService()
+	tests := []struct {
+		bits int
+		e    int
+	}{
+		{256, 65537},
+		{8200, 65537},
+		{2048, 5},
+		{2048, 65536},
+	}
+	for _, tt := range tests {
+		if _, _, err := r.GenerateKeys(tt.bits, tt.e); !errors.Is(err, ErrInvalidParams) {
+			t.Errorf("RSAService.GenerateKeys(%d, %d) has err = %v, want %v", tt.bits, tt.e, err, ErrInvalidParams)
+		}
+	}
+}
+
+// TestRSAService_DecryptCRT сравнивает расшифрование по CRT с маскированием
+// с прямым возведением в степень c^d mod n.
+func TestRSAService_DecryptCRT(t *testing.T) {
+	r := NewRsaService()
+	pub, priv, err := r.GenerateKeys(1024, DefaultExponent)
+	if err != nil {
+		t.Fatalf("RSAService.GenerateKeys() has err = %v", err)
+	}
+	keys := map[string]*PrivateKey{
+		"crt":       priv,
+		"n and d":   {N: priv.N, D: priv.D},
+		"no primes": {N: priv.N, E: priv.E, D: priv.D},
+	}
+	for i := range 20 {
+		c, err := rand.Int(rand.Reader, pub.N)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := new(big.Int).Exp(c, priv.D, priv.N)
+		for name, key := range keys {
+			got, err := r.decrypt(key, c)
+			if err != nil {
+				t.Fatalf("%s: RSAService.decrypt() has err = %v", name, err)
+			}
+			if got.Cmp(want) != 0 {
+				t.Errorf("%s: RSAService.decrypt() #%d = %v, want %v", name, i, got, want)
+			}
+		}
+	}
+
+	// Неверное значение CRT обнаруживается проверкой m^e mod n.
+	broken := *priv
+	broken.Dp = new(big.Int).Add(priv.Dp, big.NewInt(1))
+	if _, err := r.decrypt(&broken, big.NewInt(2)); !errors.Is(err, ErrDecryption) {
+		t.Errorf("RSAService.decrypt() with broken Dp has err = %v, want %v", err, ErrDecryption)
+	}
+}
+
+func BenchmarkRSAService_Decrypt(b *testing.B) {
+	r := NewRsaService()
+	_, priv, err := r.GenerateKeys(DefaultKeyBits, DefaultExponent)
+	if err != nil {
+		b.Fatal(err)
+	}
+	c := big.NewInt(0xC0FFEE)
+	b.Run("crt", func(b *testing.B) {
+		for b.Loop() {
+			r.decrypt(priv, c)
+		}
+	})
+	b.Run("n and d", func(b *testing.B) {
+		key := &PrivateKey{N: priv.N, D: priv.D}
+		for b.Loop() {
+			r.decrypt(key, c)
+		}
+	})
+}
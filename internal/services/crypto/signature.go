@@ -0,0 +1,210 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Схемы подписи (RFC 8017, 8.1 и 8.2); хеш-функция — SHA-256.
+const (
+	SignaturePKCS1v15 = "pkcs1v15"
+	SignaturePSS      = "pss"
+)
+
+// ErrVerification возвращает Verify, если подпись не соответствует сообщению.
+var ErrVerification = errors.New("RSA verification error")
+
+// sha256DigestInfo — DER-префикс DigestInfo для SHA-256 (RFC 8017, 9.2, примечание 1).
+var sha256DigestInfo = []byte{
+	0x30, 0x31, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01,
+	0x65, 0x03, 0x04, 0x02, 0x01, 0x05, 0x00, 0x04, 0x20,
+}
+
+// SignatureDetails показывает промежуточные значения подписи: хеш сообщения,
+// закодированный блок EM и подпись как число s = EM^d mod n. При проверке
+// EM восстанавливается как s^e mod n. Числа записаны в hex, s — в десятичной
+// записи строкой, так как не помещается в число JSON.
+type SignatureDetails struct {
+	Scheme    string `json:"scheme"`
+	Hash      string `json:"hash"`
+	EM        string `json:"em"`
+	Salt      string `json:"salt,omitempty"`
+	Signature string `json:"signature"`
+	Valid     bool   `json:"valid"`
+}
+
+// Sign подписывает хеш SHA-256 сообщения msg по схеме SignaturePKCS1v15
+// или SignaturePSS (с солью длиной в хеш). Подпись совместима с
+// crypto/rsa.SignPKCS1v15 и crypto/rsa.VerifyPSS.
+func (r *RSAService) Sign(priv *PrivateKey, msg []byte, scheme string) ([]byte, SignatureDetails, error) {
+	if err := checkPublicKey(&PublicKey{N: priv.N, E: priv.E}); err != nil {
+		return nil, SignatureDetails{}, err
+	}
+	hashed := sha256.Sum256(msg)
+	details := SignatureDetails{Scheme: scheme, Hash: hex.EncodeToString(hashed[:])}
+	keyLen := (priv.N.BitLen() + 7) / 8
+
+	var em []byte
+	switch scheme {
+	case SignaturePKCS1v15:
+		var err error
+		if em, err = emsaPKCS1v15Encode(hashed[:], keyLen); err != nil {
+			return nil, SignatureDetails{}, err
+		}
+	case SignaturePSS:
+		emLen := (priv.N.BitLen() + 6) / 8
+		if emLen < 2*sha256.Size+2 {
+			return nil, SignatureDetails{}, fmt.Errorf("%w: key is too short for PSS with SHA-256", ErrMessageTooLong)
+		}
+		salt := make([]byte, sha256.Size)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, SignatureDetails{}, err
+		}
+		details.Salt = hex.EncodeToString(salt)
+		em = emsaPSSEncode(hashed[:], priv.N.BitLen()-1, salt)
+	default:
+		return nil, SignatureDetails{}, signatureSchemeError(scheme)
+	}
+	details.EM = hex.EncodeToString(em)
+
+	s, err := r.decrypt(priv, new(big.Int).SetBytes(em))
+	if err != nil {
+		return nil, SignatureDetails{}, err
+	}
+	details.Signature = s.String()
+	details.Valid = true
+	return s.FillBytes(make([]byte, keyLen)), details, nil
+}
+
+// Verify проверяет подпись sig сообщения msg. Для неверной подписи
+// возвращаются детали с Valid = false и ошибка ErrVerification.
+func (r *RSAService) Verify(pub *PublicKey, msg, sig []byte, scheme string) (SignatureDetails, error) {
+	if err := checkPublicKey(pub); err != nil {
+		return SignatureDetails{}, err
+	}
+	if scheme != SignaturePKCS1v15 && scheme != SignaturePSS {
+		return SignatureDetails{}, signatureSchemeError(scheme)
+	}
+	hashed := sha256.Sum256(msg)
+	details := SignatureDetails{Scheme: scheme, Hash: hex.EncodeToString(hashed[:])}
+
+	keyLen := (pub.N.BitLen() + 7) / 8
+	s := new(big.Int).SetBytes(sig)
+	if len(sig) != keyLen || s.Cmp(pub.N) >= 0 {
+		return details, ErrVerification
+	}
+	details.Signature = s.String()
+	m := new(big.Int).Exp(s, pub.E, pub.N)
+
+	switch scheme {
+	case SignaturePKCS1v15:
+		em := m.FillBytes(make([]byte, keyLen))
+		details.EM = hex.EncodeToString(em)
+		want, err := emsaPKCS1v15Encode(hashed[:], keyLen)
+		details.Valid = err == nil && subtle.ConstantTimeCompare(em, want) == 1
+	case SignaturePSS:
+		emBits := pub.N.BitLen() - 1
+		emLen := (emBits + 7) / 8
+		if m.BitLen() > 8*emLen {
+			return details, ErrVerification
+		}
+		em := m.FillBytes(make([]byte, emLen))
+		details.EM = hex.EncodeToString(em)
+		salt, ok := emsaPSSVerify(hashed[:], em, emBits)
+		details.Salt = hex.EncodeToString(salt)
+		details.Valid = ok
+	}
+
+	if !details.Valid {
+		return details, ErrVerification
+	}
+	return details, nil
+}
+
+func signatureSchemeError(scheme string) error {
+	return fmt.Errorf("%w: unknown signature scheme %q, want %s or %s",
+		ErrInvalidParams, scheme, SignaturePKCS1v15, SignaturePSS)
+}
+
+// emsaPKCS1v15Encode строит EM = 00 || 01 || FF...FF || 00 || DigestInfo || H.
+func emsaPKCS1v15Encode(hashed []byte, emLen int) ([]byte, error) {
+	tLen := len(sha256DigestInfo) + len(hashed)
+	if emLen < tLen+11 {
+		return nil, fmt.Errorf("%w: key is too short for SHA-256 signature", ErrMessageTooLong)
+	}
+	em := make([]byte, emLen)
+	em[1] = 0x01
+	for i := 2; i < emLen-tLen-1; i++ {
+		em[i] = 0xff
+	}
+	copy(em[emLen-tLen:], sha256DigestInfo)
+	copy(em[emLen-len(hashed):], hashed)
+	return em, nil
+}
+
+// emsaPSSEncode строит EM = maskedDB || H || BC, где H = Hash(00×8 || mHash || salt),
+// DB = 00...00 || 01 || salt. Старшие 8·emLen - emBits бит EM обнуляются,
+// чтобы EM было меньше n.
+func emsaPSSEncode(mHash []byte, emBits int, salt []byte) []byte {
+	h := sha256.New()
+	hLen := h.Size()
+	emLen := (emBits + 7) / 8
+
+	em := make([]byte, emLen)
+	db := em[:emLen-hLen-1]
+	hm := em[emLen-hLen-1 : emLen-1]
+
+	h.Write(make([]byte, 8))
+	h.Write(mHash)
+	h.Write(salt)
+	h.Sum(hm[:0])
+
+	db[len(db)-len(salt)-1] = 0x01
+	copy(db[len(db)-len(salt):], salt)
+	mgf1XOR(db, h, hm)
+	db[0] &= 0xff >> (8*emLen - emBits)
+
+	em[emLen-1] = 0xbc
+	return em
+}
+
+// emsaPSSVerify проверяет EM и возвращает соль. Длина соли определяется
+// по положению байта 01 в DB, поэтому принимаются подписи с любой солью.
+func emsaPSSVerify(mHash, em []byte, emBits int) ([]byte, bool) {
+	h := sha256.New()
+	hLen := h.Size()
+	emLen := len(em)
+	if emLen < hLen+2 || em[emLen-1] != 0xbc {
+		return nil, false
+	}
+
+	db := bytes.Clone(em[:emLen-hLen-1])
+	hm := em[emLen-hLen-1 : emLen-1]
+	mask := byte(0xff >> (8*emLen - emBits))
+	if db[0]&^mask != 0 {
+		return nil, false
+	}
+	mgf1XOR(db, h, hm)
+	db[0] &= mask
+
+	sep := 0
+	for sep < len(db) && db[sep] == 0x00 {
+		sep++
+	}
+	if sep == len(db) || db[sep] != 0x01 {
+		return nil, false
+	}
+	salt := db[sep+1:]
+
+	h.Reset()
+	h.Write(make([]byte, 8))
+	h.Write(mHash)
+	h.Write(salt)
+	return salt, subtle.ConstantTimeCompare(h.Sum(nil), hm) == 1
+}
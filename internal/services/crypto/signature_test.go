@@ -0,0 +1,156 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRSAService_SignCompatibility(t *testing.T) {
+	r := NewRsaService()
+	msg := []byte("Подписываемый документ")
+	hashed := sha256.Sum256(msg)
+
+	// 1025 бит: для PSS emBits = 1024, и EM на байт короче ключа.
+	for _, bits := range []int{1024, 1025} {
+		pub, priv, err := r.GenerateKeys(bits, DefaultExponent)
+		if err != nil {
+			t.Fatalf("RSAService.GenerateKeys() has err = %v", err)
+		}
+		std := stdPrivateKey(t, priv)
+
+		t.Run(fmt.Sprintf("%d pkcs1v15", bits), func(t *testing.T) {
+			sig, details, err := r.Sign(priv, msg, SignaturePKCS1v15)
+			if err != nil {
+				t.Fatalf("RSAService.Sign() has err = %v", err)
+			}
+			want, err := rsa.SignPKCS1v15(nil, std, crypto.SHA256, hashed[:])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(sig, want) {
+				t.Errorf("RSAService.Sign() differs from rsa.SignPKCS1v15()")
+			}
+			if !strings.HasPrefix(details.EM, "0001ffff") || !strings.HasSuffix(details.EM, details.Hash) {
+				t.Errorf("RSAService.Sign() EM = %s", details.EM)
+			}
+			if _, err := r.Verify(pub, msg, want, SignaturePKCS1v15); err != nil {
+				t.Errorf("RSAService.Verify() has err = %v", err)
+			}
+		})
+
+		t.Run(fmt.Sprintf("%d pss", bits), func(t *testing.T) {
+			sig, details, err := r.Sign(priv, msg, SignaturePSS)
+			if err != nil {
+				t.Fatalf("RSAService.Sign() has err = %v", err)
+			}
+			if err := rsa.VerifyPSS(&std.PublicKey, crypto.SHA256, hashed[:], sig, nil); err != nil {
+				t.Errorf("rsa.VerifyPSS() has err = %v", err)
+			}
+			if !strings.HasSuffix(details.EM, "bc") || len(details.Salt) != 2*sha256.Size {
+				t.Errorf("RSAService.Sign() EM = %s, salt = %s", details.EM, details.Salt)
+			}
+
+			for _, saltLength := range []int{0, 20, rsa.PSSSaltLengthEqualsHash} {
+				sig, err := rsa.SignPSS(rand.Reader, std, crypto.SHA256, hashed[:], &rsa.PSSOptions{SaltLength: saltLength})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err := r.Verify(pub, msg, sig, SignaturePSS); err != nil {
+					t.Errorf("RSAService.Verify() with salt length %d has err = %v", saltLength, err)
+				}
+			}
+		})
+	}
+}
+
+func TestRSAService_VerifyOpenSSL(t *testing.T) {
+	r := NewRsaService()
+	pub, err := ParsePublicKey(readTestdata(t, "openssl_spki.pem"))
+	if err != nil {
+		t.Fatalf("ParsePublicKey() has err = %v", err)
+	}
+	msg := readTestdata(t, "message.txt")
+	tests := []struct {
+		file   string
+		scheme string
+	}{
+		{"message.openssl.sig", SignaturePKCS1v15},
+		{"message.openssl.pss.sig", SignaturePSS},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			details, err := r.Verify(pub, msg, readTestdata(t, tt.file), tt.scheme)
+			if err != nil || !details.Valid {
+				t.Errorf("RSAService.Verify() = %+v, %v", details, err)
+			}
+		})
+	}
+}
+
+func TestRSAService_VerifyInvalid(t *testing.T) {
+	r := NewRsaService()
+	pub, priv, err := r.GenerateKeys(1024, DefaultExponent)
+	if err != nil {
+		t.Fatalf("RSAService.GenerateKeys() has err = %v", err)
+	}
+	msg := []byte("Подписываемый документ")
+
+	for _, scheme := range []string{SignaturePKCS1v15, SignaturePSS} {
+		sig, _, err := r.Sign(priv, msg, scheme)
+		if err != nil {
+			t.Fatalf("RSAService.Sign() has err = %v", err)
+		}
+		details, err := r.Verify(pub, msg, sig, scheme)
+		if err != nil || !details.Valid {
+			t.Fatalf("%s: RSAService.Verify() = %+v, %v", scheme, details, err)
+		}
+		if _, err := hex.DecodeString(details.EM); err != nil || details.Signature == "" {
+			t.Errorf("%s: RSAService.Verify() details = %+v", scheme, details)
+		}
+
+		tampered := bytes.Clone(sig)
+		tampered[10] ^= 0x01
+		tests := []struct {
+			name string
+			msg  []byte
+			sig  []byte
+		}{
+			{"other message", []byte("Другой документ"), sig},
+			{"tampered signature", msg, tampered},
+			{"short signature", msg, sig[1:]},
+			{"signature not less than n", msg, bytes.Repeat([]byte{0xFF}, len(sig))},
+		}
+		for _, tt := range tests {
+			details, err := r.Verify(pub, tt.msg, tt.sig, scheme)
+			if !errors.Is(err, ErrVerification) || details.Valid {
+				t.Errorf("%s %s: RSAService.Verify() = %+v, %v, want %v", scheme, tt.name, details, err, ErrVerification)
+			}
+		}
+	}
+
+	otherScheme, _, err := r.Sign(priv, msg, SignaturePSS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Verify(pub, msg, otherScheme, SignaturePKCS1v15); !errors.Is(err, ErrVerification) {
+		t.Errorf("RSAService.Verify() of PSS signature as PKCS#1 v1.5 has err = %v", err)
+	}
+	if _, _, err := r.Sign(priv, msg, "md5"); !errors.Is(err, ErrInvalidParams) {
+		t.Errorf("RSAService.Sign() with unknown scheme has err = %v", err)
+	}
+	_, small, err := r.GenerateKeys(512, DefaultExponent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := r.Sign(small, msg, SignaturePSS); !errors.Is(err, ErrMessageTooLong) {
+		t.Errorf("RSAService.Sign() with PSS and 512-bit key has err = %v", err)
+	}
+}
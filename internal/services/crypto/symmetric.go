@@ -0,0 +1,395 @@
+package crypto
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"unicode/utf8"
+)
+
+// ErrInvalidCipherParams возвращается при неизвестном алгоритме или режиме,
+// неверной длине ключа, IV, имитовставки или данных.
+var ErrInvalidCipherParams = errors.New("invalid block cipher parameters")
+
+// ErrInvalidPadding возвращается, если после расшифрования дополнение
+// оказалось неверным.
+var ErrInvalidPadding = errors.New("invalid padding")
+
+// ErrAuthentication возвращается, если не сошёлся тег GCM.
+var ErrAuthentication = errors.New("message authentication failed")
+
+const (
+	ModeECB = "ecb"
+	ModeCBC = "cbc"
+	ModeCFB = "cfb"
+	ModeOFB = "ofb"
+	ModeCTR = "ctr"
+	ModeGCM = "gcm"
+
+	PaddingPKCS7 = "pkcs7"
+	// PaddingGOST — процедура 2 ГОСТ Р 34.13-2015: единичный бит и нули.
+	PaddingGOST = "gost"
+	PaddingNone = "none"
+)
+
+const (
+	// MaxTraceBlocks — сколько первых вызовов блочного шифра попадает в трассу.
+	MaxTraceBlocks = 4
+	// MaxSymmetricData ограничивает размер данных: учебные реализации
+	// работают побайтно и медленны.
+	MaxSymmetricData = 1 << 20
+)
+
+// RoundStep — состояние шифра после шага раунда. Grid — то же состояние
+// в виде матрицы байт, если шифр так его представляет (AES — 4×4 по столбцам).
+// Key — ключ раунда в шифрах Фейстеля.
+type RoundStep struct {
+	Round int        `json:"round"`
+	Step  string     `json:"step"`
+	State string     `json:"state"`
+	Key   string     `json:"key,omitempty"`
+	Grid  [][]string `json:"grid,omitempty"`
+}
+
+func recordState(trace *[]RoundStep, round int, step string, state []byte) {
+	if trace != nil {
+		*trace = append(*trace, RoundStep{Round: round, Step: step, State: hex.EncodeToString(state)})
+	}
+}
+
+// BlockTrace — трасса одного вызова блочного шифра. В режимах CFB, OFB, CTR
+// и GCM шифруется не сам текст, а регистр или счётчик, поэтому Operation
+// может быть encrypt и при расшифровании.
+type BlockTrace struct {
+	Block     int         `json:"block"`
+	Operation string      `json:"operation"`
+	Input     string      `json:"input"`
+	Output    string      `json:"output"`
+	Rounds    []RoundStep `json:"rounds"`
+}
+
+// tracedBlock — блочный шифр, умеющий записывать состояние после каждого шага.
+type tracedBlock interface {
+	cipher.Block
+	EncryptTrace(dst, src []byte) []RoundStep
+	DecryptTrace(dst, src []byte) []RoundStep
+	KeySchedule() []string
+}
+
+// keyScheduleTracer — шифр, умеющий показать ход расширения ключа (DES).
+type keyScheduleTracer interface {
+	KeyScheduleTrace() []RoundStep
+}
+
+func keyScheduleTrace(b tracedBlock) []RoundStep {
+	if t, ok := b.(keyScheduleTracer); ok {
+		return t.KeyScheduleTrace()
+	}
+	return nil
+}
+
+// blockCipher — шифр из реестра. Для шифров ГОСТ режимы и IV следуют
+// ГОСТ Р 34.13-2015: GCM нет, IV режима CTR — половина блока, регистр
+// CBC, CFB и OFB может быть длиной в несколько блоков.
+type blockCipher struct {
+	name string
+	new  func(key []byte) (tracedBlock, error)
+	gost bool
+}
+
+var blockCiphers = []blockCipher{
+	{"aes", func(key []byte) (tracedBlock, error) { return NewAESCipher(key) }, false},
+	{"magma", func(key []byte) (tracedBlock, error) { return NewMagmaCipher(key) }, true},
+	{"kuznyechik", func(key []byte) (tracedBlock, error) { return NewKuznyechikCipher(key) }, true},
+	{"des", func(key []byte) (tracedBlock, error) { return NewDESCipher(key) }, false},
+	{"3des", func(key []byte) (tracedBlock, error) { return NewTripleDESCipher(key) }, false},
+}
+
+// BlockCiphers возвращает имена блочных шифров.
+func BlockCiphers() []string {
+	names := make([]string, len(blockCiphers))
+	for i, c := range blockCiphers {
+		names[i] = c.name
+	}
+	return names
+}
+
+var modes = []string{ModeECB, ModeCBC, ModeCFB, ModeOFB, ModeCTR, ModeGCM}
+
+func (c blockCipher) modes() []string {
+	if c.gost {
+		return modes[:len(modes)-1]
+	}
+	return modes
+}
+
+// SymmetricParams — параметры шифрования. Если IV не задан при шифровании,
+// он генерируется случайно (для GCM — 12 байт). Padding применяется только
+// в режимах ECB и CBC: pkcs7 (по умолчанию), gost или none, тогда длина данных
+// должна быть кратна блоку. AAD — дополнительные аутентифицируемые данные GCM.
+// MACSize — длина имитовставки в байтах, по умолчанию — размер блока.
+type SymmetricParams struct {
+	Mode    string
+	Padding string
+	Key     []byte
+	IV      []byte
+	AAD     []byte
+	Data    []byte
+	Trace   bool
+	MACSize int
+}
+
+type SymmetricResult struct {
+	Algorithm   string   `json:"algorithm"`
+	Mode        string   `json:"mode"`
+	KeyBits     int      `json:"key_bits"`
+	IV          string   `json:"iv,omitempty"`
+	Tag         string   `json:"tag,omitempty"`
+	Output      string   `json:"output"`
+	Text        string   `json:"text,omitempty"`
+	KeySchedule []string `json:"key_schedule,omitempty"`
+	// KeyScheduleTrace — ход расширения ключа, если шифр его показывает.
+	KeyScheduleTrace []RoundStep  `json:"key_schedule_trace,omitempty"`
+	Trace            []BlockTrace `json:"trace,omitempty"`
+	Data             []byte       `json:"-"`
+}
+
+// MACResult — имитовставка и вспомогательные ключи K1, K2.
+type MACResult struct {
+	Algorithm        string       `json:"algorithm"`
+	KeyBits          int          `json:"key_bits"`
+	MAC              string       `json:"mac"`
+	Subkeys          []string     `json:"subkeys"`
+	KeySchedule      []string     `json:"key_schedule,omitempty"`
+	KeyScheduleTrace []RoundStep  `json:"key_schedule_trace,omitempty"`
+	Trace            []BlockTrace `json:"trace,omitempty"`
+}
+
+type Symmetric interface {
+	Encrypt(algorithm string, params SymmetricParams) (SymmetricResult, error)
+	Decrypt(algorithm string, params SymmetricParams) (SymmetricResult, error)
+	MAC(algorithm string, params SymmetricParams) (MACResult, error)
+	FeistelEncrypt(params FeistelParams) (FeistelResult, error)
+	FeistelDecrypt(params FeistelParams) (FeistelResult, error)
+}
+
+type SymmetricService struct {
+}
+
+func NewSymmetricService() *SymmetricService {
+	return &SymmetricService{}
+}
+
+// Encrypt шифрует Data. Для GCM тег возвращается отдельно в Tag.
+func (s *SymmetricService) Encrypt(algorithm string, params SymmetricParams) (SymmetricResult, error) {
+	return s.apply(algorithm, params, false)
+}
+
+// Decrypt расшифровывает Data. Для GCM Data — шифртекст, за которым следует
+// 16-байтовый тег. Text заполняется, если результат — текст в UTF-8.
+func (s *SymmetricService) Decrypt(algorithm string, params SymmetricParams) (SymmetricResult, error) {
+	return s.apply(algorithm, params, true)
+}
+
+// MAC вычисляет имитовставку Data по ГОСТ Р 34.13-2015 (совпадает с CMAC
+// из NIST SP 800-38B).
+func (s *SymmetricService) MAC(algorithm string, params SymmetricParams) (MACResult, error) {
+	c, err := findBlockCipher(algorithm)
+	if err != nil {
+		return MACResult{}, err
+	}
+	b, err := c.new(params.Key)
+	if err != nil {
+		return MACResult{}, err
+	}
+	bs := b.BlockSize()
+	if params.MACSize == 0 {
+		params.MACSize = bs
+	}
+	if params.MACSize < 1 || params.MACSize > bs {
+		return MACResult{}, fmt.Errorf("%w: MAC size must be 1..%d bytes, got %d", ErrInvalidCipherParams, bs, params.MACSize)
+	}
+	if len(params.Data) > MaxSymmetricData {
+		return MACResult{}, fmt.Errorf("%w: data is longer than %d bytes", ErrInvalidCipherParams, MaxSymmetricData)
+	}
+
+	result := MACResult{Algorithm: algorithm, KeyBits: 8 * len(params.Key)}
+	var block cipher.Block = b
+	var t *tracer
+	if params.Trace {
+		t = &tracer{tracedBlock: b}
+		block = t
+		result.KeySchedule = b.KeySchedule()
+		result.KeyScheduleTrace = keyScheduleTrace(b)
+	}
+	mac, k1, k2 := omac(block, params.Data)
+	result.MAC = hex.EncodeToString(mac[:params.MACSize])
+	result.Subkeys = []string{hex.EncodeToString(k1), hex.EncodeToString(k2)}
+	if t != nil {
+		result.Trace = t.blocks
+	}
+	return result, nil
+}
+
+func (s *SymmetricService) apply(algorithm string, params SymmetricParams, decrypt bool) (SymmetricResult, error) {
+	c, err := findBlockCipher(algorithm)
+	if err != nil {
+		return SymmetricResult{}, err
+	}
+	b, err := c.new(params.Key)
+	if err != nil {
+		return SymmetricResult{}, err
+	}
+	if err := checkSymmetricParams(c, b, &params, decrypt); err != nil {
+		return SymmetricResult{}, err
+	}
+
+	result := SymmetricResult{Algorithm: algorithm, Mode: params.Mode, KeyBits: 8 * len(params.Key)}
+	var block cipher.Block = b
+	var t *tracer
+	if params.Trace {
+		t = &tracer{tracedBlock: b}
+		block = t
+		result.KeySchedule = b.KeySchedule()
+		result.KeyScheduleTrace = keyScheduleTrace(b)
+	}
+
+	var tag []byte
+	switch {
+	case params.Mode == ModeGCM && decrypt:
+		result.Data, err = gcmOpen(block, params.IV, params.Data, params.AAD)
+	case params.Mode == ModeGCM:
+		result.Data, tag = gcmSeal(block, params.IV, params.Data, params.AAD)
+	case decrypt:
+		result.Data, err = decryptBlocks(block, params.Mode, params.IV, params.Data)
+		if err == nil && usesPadding(params.Mode) {
+			result.Data, err = unpad(result.Data, params.Padding, b.BlockSize())
+		}
+	default:
+		data := params.Data
+		if usesPadding(params.Mode) {
+			data = pad(data, params.Padding, b.BlockSize())
+		}
+		result.Data, err = encryptBlocks(block, params.Mode, params.IV, data)
+	}
+	if err != nil {
+		return SymmetricResult{}, err
+	}
+
+	result.Output = hex.EncodeToString(result.Data)
+	if params.Mode != ModeECB {
+		result.IV = hex.EncodeToString(params.IV)
+	}
+	if tag != nil {
+		result.Tag = hex.EncodeToString(tag)
+	}
+	if decrypt && utf8.Valid(result.Data) {
+		result.Text = string(result.Data)
+	}
+	if t != nil {
+		result.Trace = t.blocks
+	}
+	return result, nil
+}
+
+func findBlockCipher(algorithm string) (blockCipher, error) {
+	for _, c := range blockCiphers {
+		if c.name == algorithm {
+			return c, nil
+		}
+	}
+	return blockCipher{}, fmt.Errorf("%w: unknown algorithm %q, want one of %v", ErrInvalidCipherParams, algorithm, BlockCiphers())
+}
+
+// checkSymmetricParams проверяет режим, дополнение и длины, подставляя
+// значения по умолчанию.
+func checkSymmetricParams(c blockCipher, b cipher.Block, params *SymmetricParams, decrypt bool) error {
+	bs := b.BlockSize()
+	if params.Mode == "" {
+		params.Mode = ModeCBC
+	}
+	if !slices.Contains(c.modes(), params.Mode) {
+		return fmt.Errorf("%w: unknown mode %q for %s, want one of %v", ErrInvalidCipherParams, params.Mode, c.name, c.modes())
+	}
+	if params.Mode == ModeGCM && bs != AESBlockSize {
+		return fmt.Errorf("%w: GCM requires a 128-bit block", ErrInvalidCipherParams)
+	}
+	if params.Padding == "" {
+		params.Padding = PaddingPKCS7
+	}
+	if params.Padding != PaddingPKCS7 && params.Padding != PaddingGOST && params.Padding != PaddingNone {
+		return fmt.Errorf("%w: unknown padding %q, want %s, %s or %s", ErrInvalidCipherParams, params.Padding, PaddingPKCS7, PaddingGOST, PaddingNone)
+	}
+	if len(params.Data) > MaxSymmetricData {
+		return fmt.Errorf("%w: data is longer than %d bytes", ErrInvalidCipherParams, MaxSymmetricData)
+	}
+	if usesPadding(params.Mode) && (params.Padding == PaddingNone || decrypt) && len(params.Data)%bs != 0 {
+		return fmt.Errorf("%w: data length %d is not a multiple of block size %d", ErrInvalidCipherParams, len(params.Data), bs)
+	}
+
+	ivSize := bs
+	switch {
+	case params.Mode == ModeECB:
+		ivSize = 0
+	case params.Mode == ModeCTR && c.gost:
+		ivSize = bs / 2
+	case c.gost && len(params.IV) > 0 && len(params.IV)%bs == 0:
+		ivSize = len(params.IV)
+	case params.Mode == ModeGCM:
+		ivSize = gcmNonceSize
+		if len(params.IV) > 0 {
+			ivSize = len(params.IV)
+		}
+		if decrypt && len(params.Data) < gcmTagSize {
+			return fmt.Errorf("%w: GCM data is shorter than tag", ErrInvalidCipherParams)
+		}
+	}
+	if len(params.IV) == 0 && !decrypt && ivSize > 0 {
+		params.IV = make([]byte, ivSize)
+		if _, err := rand.Read(params.IV); err != nil {
+			return err
+		}
+	}
+	if len(params.IV) != ivSize {
+		return fmt.Errorf("%w: %s IV must be %d bytes, got %d", ErrInvalidCipherParams, params.Mode, ivSize, len(params.IV))
+	}
+	return nil
+}
+
+func usesPadding(mode string) bool {
+	return mode == ModeECB || mode == ModeCBC
+}
+
+// tracer записывает трассы первых MaxTraceBlocks вызовов шифра.
+type tracer struct {
+	tracedBlock
+	blocks []BlockTrace
+}
+
+func (t *tracer) Encrypt(dst, src []byte) {
+	t.trace(dst, src, "encrypt", t.tracedBlock.Encrypt, t.tracedBlock.EncryptTrace)
+}
+
+func (t *tracer) Decrypt(dst, src []byte) {
+	t.trace(dst, src, "decrypt", t.tracedBlock.Decrypt, t.tracedBlock.DecryptTrace)
+}
+
+func (t *tracer) trace(dst, src []byte, op string, f func(dst, src []byte), traced func(dst, src []byte) []RoundStep) {
+	if len(t.blocks) >= MaxTraceBlocks {
+		f(dst, src)
+		return
+	}
+	bs := t.BlockSize()
+	input := hex.EncodeToString(src[:bs])
+	rounds := traced(dst, src)
+	t.blocks = append(t.blocks, BlockTrace{
+		Block:     len(t.blocks),
+		Operation: op,
+		Input:     input,
+		Output:    hex.EncodeToString(dst[:bs]),
+		Rounds:    rounds,
+	})
+}
@@ -0,0 +1,276 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"errors"
+	"math/rand/v2"
+	"testing"
+)
+
+// Векторы NIST SP 800-38A, приложение F, для AES-128: первые два блока.
+func TestSymmetricService_SP800_38A(t *testing.T) {
+	key := "2b7e151628aed2a6abf7158809cf4f3c"
+	plaintext := "6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e51"
+	tests := []struct {
+		mode       string
+		iv         string
+		ciphertext string
+	}{
+		{ModeECB, "", "3ad77bb40d7a3660a89ecaf32466ef97f5d3d58503b9699de785895a96fdbaaf"},
+		{ModeCBC, "000102030405060708090a0b0c0d0e0f", "7649abac8119b246cee98e9b12e9197d5086cb9b507219ee95db113a917678b2"},
+		{ModeCFB, "000102030405060708090a0b0c0d0e0f", "3b3fd92eb72dad20333449f8e83cfb4ac8a64537a0b3a93fcde3cdad9f1ce58b"},
+		{ModeOFB, "000102030405060708090a0b0c0d0e0f", "3b3fd92eb72dad20333449f8e83cfb4a7789508d16918f03f53c52dac54ed825"},
+		{ModeCTR, "f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff", "874d6191b620e3261bef6864990db6ce9806f66b7970fdff8617187bb9fffdff"},
+	}
+	s := NewSymmetricService()
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			params := SymmetricParams{
+				Mode:    tt.mode,
+				Padding: PaddingNone,
+				Key:     unhex(t, key),
+				IV:      unhex(t, tt.iv),
+				Data:    unhex(t, plaintext),
+			}
+			result, err := s.Encrypt("aes", params)
+			if err != nil {
+				t.Fatalf("SymmetricService.Encrypt() has err = %v", err)
+			}
+			if result.Output != tt.ciphertext {
+				t.Errorf("SymmetricService.Encrypt() = %s, want %s", result.Output, tt.ciphertext)
+			}
+
+			params.Data = result.Data
+			result, err = s.Decrypt("aes", params)
+			if err != nil {
+				t.Fatalf("SymmetricService.Decrypt() has err = %v", err)
+			}
+			if result.Output != plaintext {
+				t.Errorf("SymmetricService.Decrypt() = %s, want %s", result.Output, plaintext)
+			}
+		})
+	}
+}
+
+// Тестовые примеры 1, 2 и 4 из спецификации GCM (McGrew, Viega).
+func TestSymmetricService_GCM(t *testing.T) {
+	tests := []struct {
+		name       string
+		key        string
+		iv         string
+		aad        string
+		plaintext  string
+		ciphertext string
+		tag        string
+	}{
+		{
+			name: "empty",
+			key:  "00000000000000000000000000000000",
+			iv:   "000000000000000000000000",
+			tag:  "58e2fccefa7e3061367f1d57a4e7455a",
+		},
+		{
+			name:       "zero block",
+			key:        "00000000000000000000000000000000",
+			iv:         "000000000000000000000000",
+			plaintext:  "00000000000000000000000000000000",
+			ciphertext: "0388dace60b6a392f328c2b971b2fe78",
+			tag:        "ab6e47d42cec13bdf53a67b21257bddf",
+		},
+		{
+			name: "aad",
+			key:  "feffe9928665731c6d6a8f9467308308",
+			iv:   "cafebabefacedbaddecaf888",
+			aad:  "feedfacedeadbeeffeedfacedeadbeefabaddad2",
+			plaintext: "d9313225f88406e5a55909c5aff5269a86a7a9531534f7da2e4c303d8a318a72" +
+				"1c3c0c95956809532fcf0e2449a6b525b16aedf5aa0de657ba637b39",
+			ciphertext: "42831ec2217774244b7221b784d0d49ce3aa212f2c02a4e035c17e2329aca12e" +
+				"21d514b25466931c7d8f6a5aac84aa051ba30b396a0aac973d58e091",
+			tag: "5bc94fbc3221a5db94fae95ae7121a47",
+		},
+	}
+	s := NewSymmetricService()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := SymmetricParams{
+				Mode: ModeGCM,
+				Key:  unhex(t, tt.key),
+				IV:   unhex(t, tt.iv),
+				AAD:  unhex(t, tt.aad),
+				Data: unhex(t, tt.plaintext),
+			}
+			result, err := s.Encrypt("aes", params)
+			if err != nil {
+				t.Fatalf("SymmetricService.Encrypt() has err = %v", err)
+			}
+			if result.Output != tt.ciphertext || result.Tag != tt.tag {
+				t.Errorf("SymmetricService.Encrypt() = %s, tag %s, want %s, tag %s", result.Output, result.Tag, tt.ciphertext, tt.tag)
+			}
+
+			params.Data = unhex(t, tt.ciphertext+tt.tag)
+			result, err = s.Decrypt("aes", params)
+			if err != nil {
+				t.Fatalf("SymmetricService.Decrypt() has err = %v", err)
+			}
+			if result.Output != tt.plaintext {
+				t.Errorf("SymmetricService.Decrypt() = %s, want %s", result.Output, tt.plaintext)
+			}
+
+			params.Data[0] ^= 1
+			if _, err := s.Decrypt("aes", params); !errors.Is(err, ErrAuthentication) {
+				t.Errorf("SymmetricService.Decrypt() of modified data has err = %v, want %v", err, ErrAuthentication)
+			}
+		})
+	}
+}
+
+// Сравнение с crypto/cipher на случайных ключах, IV и длинах данных.
+func TestSymmetricService_CompareStdlib(t *testing.T) {
+	rnd := rand.New(rand.NewPCG(3, 4))
+	s := NewSymmetricService()
+	for range 20 {
+		key := make([]byte, 16+8*rnd.IntN(3))
+		iv := make([]byte, AESBlockSize)
+		data := make([]byte, rnd.IntN(100))
+		fill(rnd, key)
+		fill(rnd, iv)
+		fill(rnd, data)
+		block, _ := aes.NewCipher(key)
+
+		padded := pkcs7Pad(data, AESBlockSize)
+		want := map[string][]byte{
+			ModeCBC: make([]byte, len(padded)),
+			ModeCFB: make([]byte, len(data)),
+			ModeOFB: make([]byte, len(data)),
+			ModeCTR: make([]byte, len(data)),
+		}
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(want[ModeCBC], padded)
+		cipher.NewCFBEncrypter(block, iv).XORKeyStream(want[ModeCFB], data)
+		cipher.NewOFB(block, iv).XORKeyStream(want[ModeOFB], data)
+		cipher.NewCTR(block, iv).XORKeyStream(want[ModeCTR], data)
+		nonceSizes := []int{12, 8, 16}
+		nonce := iv[:nonceSizes[rnd.IntN(len(nonceSizes))]]
+		aead, _ := cipher.NewGCMWithNonceSize(block, len(nonce))
+		want[ModeGCM] = aead.Seal(nil, nonce, data, key)
+
+		for mode, expected := range want {
+			params := SymmetricParams{Mode: mode, Key: key, IV: iv, Data: data}
+			if mode == ModeGCM {
+				params.IV, params.AAD = nonce, key
+			}
+			result, err := s.Encrypt("aes", params)
+			if err != nil {
+				t.Fatalf("SymmetricService.Encrypt(%s) has err = %v", mode, err)
+			}
+			if got := append(result.Data, unhex(t, result.Tag)...); !bytes.Equal(got, expected) {
+				t.Fatalf("SymmetricService.Encrypt(%s) = %x, want %x", mode, got, expected)
+			}
+
+			params.Data = expected
+			result, err = s.Decrypt("aes", params)
+			if err != nil {
+				t.Fatalf("SymmetricService.Decrypt(%s) has err = %v", mode, err)
+			}
+			if !bytes.Equal(result.Data, data) {
+				t.Fatalf("SymmetricService.Decrypt(%s) = %x, want %x", mode, result.Data, data)
+			}
+		}
+	}
+}
+
+func TestSymmetricService_Defaults(t *testing.T) {
+	s := NewSymmetricService()
+	key := make([]byte, 16)
+	result, err := s.Encrypt("aes", SymmetricParams{Key: key, Data: []byte("Привет, AES!"), Trace: true})
+	if err != nil {
+		t.Fatalf("SymmetricService.Encrypt() has err = %v", err)
+	}
+	if result.Mode != ModeCBC || result.KeyBits != 128 || len(result.IV) != 32 || len(result.Data) != 32 {
+		t.Errorf("SymmetricService.Encrypt() = %+v", result)
+	}
+	if len(result.KeySchedule) != 11 || len(result.Trace) != 2 || result.Trace[1].Block != 1 || result.Trace[0].Operation != "encrypt" {
+		t.Errorf("SymmetricService.Encrypt() trace = %+v", result.Trace)
+	}
+
+	result, err = s.Decrypt("aes", SymmetricParams{Key: key, IV: unhex(t, result.IV), Data: result.Data})
+	if err != nil {
+		t.Fatalf("SymmetricService.Decrypt() has err = %v", err)
+	}
+	if result.Text != "Привет, AES!" || result.Trace != nil {
+		t.Errorf("SymmetricService.Decrypt() = %+v", result)
+	}
+
+	result, err = s.Encrypt("aes", SymmetricParams{Mode: ModeCTR, Key: key, Data: make([]byte, 100), Trace: true})
+	if err != nil {
+		t.Fatalf("SymmetricService.Encrypt() has err = %v", err)
+	}
+	if len(result.Trace) != MaxTraceBlocks {
+		t.Errorf("SymmetricService.Encrypt() traced %d blocks, want %d", len(result.Trace), MaxTraceBlocks)
+	}
+}
+
+func TestSymmetricService_Errors(t *testing.T) {
+	s := NewSymmetricService()
+	key := make([]byte, 16)
+	tests := []struct {
+		name      string
+		algorithm string
+		decrypt   bool
+		params    SymmetricParams
+		err       error
+	}{
+		{"unknown algorithm", "rc5", false, SymmetricParams{Key: key}, ErrInvalidCipherParams},
+		{"key length", "aes", false, SymmetricParams{Key: key[:10]}, ErrInvalidCipherParams},
+		{"unknown mode", "aes", false, SymmetricParams{Mode: "xts", Key: key}, ErrInvalidCipherParams},
+		{"unknown padding", "aes", false, SymmetricParams{Padding: "zero", Key: key}, ErrInvalidCipherParams},
+		{"unpadded length", "aes", false, SymmetricParams{Mode: ModeECB, Padding: PaddingNone, Key: key, Data: make([]byte, 15)}, ErrInvalidCipherParams},
+		{"iv length", "aes", false, SymmetricParams{Key: key, IV: make([]byte, 8)}, ErrInvalidCipherParams},
+		{"decrypt without iv", "aes", true, SymmetricParams{Key: key, Data: make([]byte, 16)}, ErrInvalidCipherParams},
+		{"decrypt length", "aes", true, SymmetricParams{Mode: ModeECB, Key: key, Data: make([]byte, 20)}, ErrInvalidCipherParams},
+		{"bad padding", "aes", true, SymmetricParams{Mode: ModeECB, Key: key, Data: make([]byte, 16)}, ErrInvalidPadding},
+		{"short gcm", "aes", true, SymmetricParams{Mode: ModeGCM, Key: key, IV: make([]byte, 12), Data: make([]byte, 10)}, ErrInvalidCipherParams},
+		{"too long", "aes", false, SymmetricParams{Mode: ModeCTR, Key: key, Data: make([]byte, MaxSymmetricData+1)}, ErrInvalidCipherParams},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			if tt.decrypt {
+				_, err = s.Decrypt(tt.algorithm, tt.params)
+			} else {
+				_, err = s.Encrypt(tt.algorithm, tt.params)
+			}
+			if !errors.Is(err, tt.err) {
+				t.Errorf("SymmetricService has err = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestPKCS7(t *testing.T) {
+	tests := []struct {
+		data   string
+		padded string
+	}{
+		{"", "10101010101010101010101010101010"},
+		{"00", "000f0f0f0f0f0f0f0f0f0f0f0f0f0f0f"},
+		{"000102030405060708090a0b0c0d0e", "000102030405060708090a0b0c0d0e01"},
+	}
+	for _, tt := range tests {
+		padded := pkcs7Pad(unhex(t, tt.data), AESBlockSize)
+		if got := hex.EncodeToString(padded); got != tt.padded {
+			t.Errorf("pkcs7Pad(%s) = %s, want %s", tt.data, got, tt.padded)
+		}
+		data, err := pkcs7Unpad(padded, AESBlockSize)
+		if err != nil || hex.EncodeToString(data) != tt.data {
+			t.Errorf("pkcs7Unpad(%s) = %x, %v", tt.padded, data, err)
+		}
+	}
+	for _, padded := range []string{"", "000102030405060708090a0b0c0d0e00", "000102030405060708090a0b0c0d0e11", "000102030405060708090a0b0c0d0302"} {
+		if _, err := pkcs7Unpad(unhex(t, padded), AESBlockSize); !errors.Is(err, ErrInvalidPadding) {
+			t.Errorf("pkcs7Unpad(%s) has err = %v, want %v", padded, err, ErrInvalidPadding)
+		}
+	}
+}
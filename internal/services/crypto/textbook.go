@@ -0,0 +1,302 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+)
+
+const (
+	// MaxTextbookPrimeBits ограничивает p и q, чтобы вычисления можно было
+	// проверить вручную, а числа в JSON оставались точными.
+	MaxTextbookPrimeBits = 26
+	// MinTextbookPrimeBits — наименьший размер генерируемых p и q, при котором
+	// n > 255 и в блок помещается хотя бы один байт сообщения.
+	MinTextbookPrimeBits = 5
+	// DefaultTextbookPrimeBits — размер генерируемых p и q по умолчанию.
+	DefaultTextbookPrimeBits = 8
+	// MaxTextbookMessage — наибольшая длина текстового сообщения в байтах.
+	MaxTextbookMessage = 64
+)
+
+// textbookExponents — значения e, которые пробуются по очереди, если e не задано.
+var textbookExponents = []int64{3, 5, 17, 257, 65537}
+
+// TextbookParams — параметры учебного RSA. Если P и Q не заданы, они
+// генерируются размером Bits бит. Если не задано E, выбирается наименьшее
+// подходящее из 3, 5, 17, 257, 65537. Шифруется число M, а если оно не задано —
+// текст Message, разбитый на блоки по (len(n)-1)/8 байт.
+type TextbookParams struct {
+	P       *big.Int
+	Q       *big.Int
+	E       *big.Int
+	Bits    int
+	M       *big.Int
+	Message string
+}
+
+type TextbookDetails struct {
+	P         *big.Int        `json:"p"`
+	Q         *big.Int        `json:"q"`
+	Generated bool            `json:"generated"`
+	N         *big.Int        `json:"n"`
+	Phi       *big.Int        `json:"phi"`
+	E         *big.Int        `json:"e"`
+	D         *big.Int        `json:"d"`
+	Euclid    []EuclidStep    `json:"euclid"`
+	BlockSize int             `json:"block_size,omitempty"`
+	Blocks    []TextbookBlock `json:"blocks"`
+	Decrypted string          `json:"decrypted,omitempty"`
+}
+
+// EuclidStep — строка таблицы расширенного алгоритма Евклида для φ(n) и e.
+// В каждой строке R = X·φ(n) + Y·e, Q — частное от деления предыдущего
+// остатка на текущий.
+type EuclidStep struct {
+	R *big.Int `json:"r"`
+	Q *big.Int `json:"q"`
+	X *big.Int `json:"x"`
+	Y *big.Int `json:"y"`
+}
+
+// TextbookBlock — один блок сообщения: m, c = m^e mod n и обратно m = c^d mod n.
+type TextbookBlock struct {
+	M       *big.Int    `json:"m"`
+	C       *big.Int    `json:"c"`
+	Encrypt ModExpTrace `json:"encrypt"`
+	Decrypt ModExpTrace `json:"decrypt"`
+}
+
+// ModExpTrace — возведение в степень по модулю методом «возведения в квадрат
+// и умножения» слева направо по двоичной записи показателя.
+type ModExpTrace struct {
+	Base     *big.Int     `json:"base"`
+	Exponent *big.Int     `json:"exponent"`
+	Modulus  *big.Int     `json:"modulus"`
+	Binary   string       `json:"binary"`
+	Steps    []ModExpStep `json:"steps"`
+	Result   *big.Int     `json:"result"`
+}
+
+// ModExpStep — обработка одного бита показателя: результат возводится
+// в квадрат и, если бит равен 1, умножается на основание.
+type ModExpStep struct {
+	Bit        uint     `json:"bit"`
+	Squared    *big.Int `json:"squared"`
+	Multiplied *big.Int `json:"multiplied,omitempty"`
+	Result     *big.Int `json:"result"`
+}
+
+// Textbook выполняет RSA «на бумаге»: вычисляет n, φ(n), d с таблицей
+// расширенного алгоритма Евклида и шифрует и расшифровывает сообщение
+// с записью шагов возведения в степень.
+func (r *RSAService) Textbook(params TextbookParams) (*TextbookDetails, error) {
+	details := &TextbookDetails{}
+	if params.P == nil && params.Q == nil {
+		p, q, err := textbookPrimes(params.Bits)
+		if err != nil {
+			return nil, err
+		}
+		details.P, details.Q, details.Generated = p, q, true
+	} else {
+		if err := checkTextbookPrimes(params.P, params.Q); err != nil {
+			return nil, err
+		}
+		details.P, details.Q = params.P, params.Q
+	}
+
+	one := big.NewInt(1)
+	details.N = new(big.Int).Mul(details.P, details.Q)
+	details.Phi = new(big.Int).Mul(
+		new(big.Int).Sub(details.P, one),
+		new(big.Int).Sub(details.Q, one),
+	)
+
+	e, err := textbookExponent(params.E, details.Phi)
+	if err != nil {
+		return nil, err
+	}
+	details.E = e
+	details.Euclid, details.D = extendedEuclid(details.Phi, e)
+
+	messages, err := textbookMessages(params, details)
+	if err != nil {
+		return nil, err
+	}
+	var decrypted []byte
+	for _, m := range messages {
+		encrypt := modExp(m, details.E, details.N)
+		decrypt := modExp(encrypt.Result, details.D, details.N)
+		details.Blocks = append(details.Blocks, TextbookBlock{
+			M:       m,
+			C:       encrypt.Result,
+			Encrypt: encrypt,
+			Decrypt: decrypt,
+		})
+		if details.BlockSize > 0 {
+			block := make([]byte, details.BlockSize)
+			decrypt.Result.FillBytes(block)
+			decrypted = append(decrypted, block...)
+		}
+	}
+	if details.BlockSize > 0 {
+		details.Decrypted = string(decrypted[:len(params.Message)])
+	}
+	return details, nil
+}
+
+// textbookPrimes генерирует два различных простых числа размером bits бит.
+func textbookPrimes(bits int) (*big.Int, *big.Int, error) {
+	if bits == 0 {
+		bits = DefaultTextbookPrimeBits
+	}
+	if bits < MinTextbookPrimeBits || bits > MaxTextbookPrimeBits {
+		return nil, nil, fmt.Errorf("%w: bits = %d, want %d..%d",
+			ErrInvalidParams, bits, MinTextbookPrimeBits, MaxTextbookPrimeBits)
+	}
+	p, err := rand.Prime(rand.Reader, bits)
+	if err != nil {
+		return nil, nil, err
+	}
+	for {
+		q, err := rand.Prime(rand.Reader, bits)
+		if err != nil {
+			return nil, nil, err
+		}
+		if q.Cmp(p) != 0 {
+			return p, q, nil
+		}
+	}
+}
+
+func checkTextbookPrimes(p, q *big.Int) error {
+	if p == nil || q == nil {
+		return fmt.Errorf("%w: both p and q are required", ErrInvalidParams)
+	}
+	for _, x := range []struct {
+		name string
+		v    *big.Int
+	}{{"p", p}, {"q", q}} {
+		if x.v.BitLen() > MaxTextbookPrimeBits {
+			return fmt.Errorf("%w: %s is longer than %d bits", ErrInvalidParams, x.name, MaxTextbookPrimeBits)
+		}
+		if x.v.Sign() <= 0 || !x.v.ProbablyPrime(20) {
+			return fmt.Errorf("%w: %s = %v is not prime", ErrInvalidParams, x.name, x.v)
+		}
+	}
+	if p.Cmp(q) == 0 {
+		return fmt.Errorf("%w: p and q must be different", ErrInvalidParams)
+	}
+	return nil
+}
+
+// textbookExponent проверяет e или, если оно не задано, выбирает его:
+// 1 < e < φ(n) и НОД(e, φ(n)) = 1.
+func textbookExponent(e, phi *big.Int) (*big.Int, error) {
+	valid := func(e *big.Int) bool {
+		gcd := new(big.Int).GCD(nil, nil, e, phi)
+		return e.Cmp(big.NewInt(1)) > 0 && e.Cmp(phi) < 0 && gcd.Cmp(big.NewInt(1)) == 0
+	}
+	if e != nil {
+		if !valid(e) {
+			return nil, fmt.Errorf("%w: e = %v, want 1 < e < φ(n) = %v and gcd(e, φ(n)) = 1", ErrInvalidParams, e, phi)
+		}
+		return e, nil
+	}
+	for _, v := range textbookExponents {
+		if e := big.NewInt(v); valid(e) {
+			return e, nil
+		}
+	}
+	for e := big.NewInt(3); e.Cmp(phi) < 0; e.Add(e, big.NewInt(2)) {
+		if valid(e) {
+			return e, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: no e exists for φ(n) = %v", ErrInvalidParams, phi)
+}
+
+// extendedEuclid строит таблицу расширенного алгоритма Евклида для φ(n) и e
+// и возвращает d = e⁻¹ mod φ(n) — коэффициент Y строки с остатком 1.
+func extendedEuclid(phi, e *big.Int) ([]EuclidStep, *big.Int) {
+	steps := []EuclidStep{
+		{R: new(big.Int).Set(phi), X: big.NewInt(1), Y: big.NewInt(0)},
+		{R: new(big.Int).Set(e), X: big.NewInt(0), Y: big.NewInt(1)},
+	}
+	for {
+		prev, cur := steps[len(steps)-2], steps[len(steps)-1]
+		if cur.R.Sign() == 0 {
+			break
+		}
+		q, r := new(big.Int).QuoRem(prev.R, cur.R, new(big.Int))
+		steps[len(steps)-1].Q = q
+		steps = append(steps, EuclidStep{
+			R: r,
+			X: new(big.Int).Sub(prev.X, new(big.Int).Mul(q, cur.X)),
+			Y: new(big.Int).Sub(prev.Y, new(big.Int).Mul(q, cur.Y)),
+		})
+	}
+
+	// Последний ненулевой остаток равен НОД(φ(n), e) = 1.
+	d := new(big.Int).Mod(steps[len(steps)-2].Y, phi)
+	return steps, d
+}
+
+// textbookMessages возвращает блоки сообщения как числа меньше n.
+func textbookMessages(params TextbookParams, details *TextbookDetails) ([]*big.Int, error) {
+	n := details.N
+	if params.M != nil {
+		if params.M.Sign() < 0 || params.M.Cmp(n) >= 0 {
+			return nil, fmt.Errorf("%w: m = %v, want 0 <= m < n = %v", ErrInvalidParams, params.M, n)
+		}
+		return []*big.Int{params.M}, nil
+	}
+	if params.Message == "" {
+		return nil, nil
+	}
+	if len(params.Message) > MaxTextbookMessage {
+		return nil, fmt.Errorf("%w: message is longer than %d bytes", ErrInvalidParams, MaxTextbookMessage)
+	}
+
+	// Блок должен быть меньше n при любых байтах, поэтому в нём (len(n)-1)/8 байт.
+	blockSize := (n.BitLen() - 1) / 8
+	if blockSize == 0 {
+		return nil, fmt.Errorf("%w: n = %v is too small to encrypt text, use m", ErrInvalidParams, n)
+	}
+	details.BlockSize = blockSize
+
+	// Последний блок дополняется нулями справа.
+	var messages []*big.Int
+	for i := 0; i < len(params.Message); i += blockSize {
+		block := make([]byte, blockSize)
+		copy(block, params.Message[i:])
+		messages = append(messages, new(big.Int).SetBytes(block))
+	}
+	return messages, nil
+}
+
+// modExp вычисляет base^exponent mod modulus, записывая шаги.
+func modExp(base, exponent, modulus *big.Int) ModExpTrace {
+	trace := ModExpTrace{
+		Base:     base,
+		Exponent: exponent,
+		Modulus:  modulus,
+		Binary:   exponent.Text(2),
+	}
+	result := big.NewInt(1)
+	for i := exponent.BitLen() - 1; i >= 0; i-- {
+		step := ModExpStep{Bit: exponent.Bit(i)}
+		result = new(big.Int).Mul(result, result)
+		result.Mod(result, modulus)
+		step.Squared = result
+		if step.Bit == 1 {
+			result = new(big.Int).Mul(result, base)
+			result.Mod(result, modulus)
+			step.Multiplied = result
+		}
+		step.Result = result
+		trace.Steps = append(trace.Steps, step)
+	}
+	trace.Result = result
+	return trace
+}
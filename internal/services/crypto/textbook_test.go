@@ -0,0 +1,127 @@
+package crypto
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestRSAService_Textbook(t *testing.T) {
+	r := NewRsaService()
+	details, err := r.Textbook(TextbookParams{
+		P: big.NewInt(61),
+		Q: big.NewInt(53),
+		E: big.NewInt(17),
+		M: big.NewInt(65),
+	})
+	if err != nil {
+		t.Fatalf("RSAService.Textbook() has err = %v", err)
+	}
+
+	want := map[string]struct {
+		got  *big.Int
+		want int64
+	}{
+		"n":   {details.N, 3233},
+		"phi": {details.Phi, 3120},
+		"d":   {details.D, 2753},
+		"c":   {details.Blocks[0].C, 2790},
+		"m":   {details.Blocks[0].Decrypt.Result, 65},
+	}
+	for name, v := range want {
+		if v.got.Int64() != v.want {
+			t.Errorf("RSAService.Textbook() %s = %v, want %d", name, v.got, v.want)
+		}
+	}
+
+	// 3120 = 183·17 + 9, 17 = 1·9 + 8, 9 = 1·8 + 1, 8 = 8·1.
+	wantEuclid := []EuclidStep{
+		{R: big.NewInt(3120), Q: nil, X: big.NewInt(1), Y: big.NewInt(0)},
+		{R: big.NewInt(17), Q: big.NewInt(183), X: big.NewInt(0), Y: big.NewInt(1)},
+		{R: big.NewInt(9), Q: big.NewInt(1), X: big.NewInt(1), Y: big.NewInt(-183)},
+		{R: big.NewInt(8), Q: big.NewInt(1), X: big.NewInt(-1), Y: big.NewInt(184)},
+		{R: big.NewInt(1), Q: big.NewInt(8), X: big.NewInt(2), Y: big.NewInt(-367)},
+		{R: big.NewInt(0), Q: nil, X: big.NewInt(-17), Y: big.NewInt(3120)},
+	}
+	if len(details.Euclid) != len(wantEuclid) {
+		t.Fatalf("RSAService.Textbook() euclid has %d rows, want %d", len(details.Euclid), len(wantEuclid))
+	}
+	for i, step := range details.Euclid {
+		w := wantEuclid[i]
+		if step.R.Cmp(w.R) != 0 || step.X.Cmp(w.X) != 0 || step.Y.Cmp(w.Y) != 0 ||
+			(step.Q == nil) != (w.Q == nil) || (w.Q != nil && step.Q.Cmp(w.Q) != 0) {
+			t.Errorf("RSAService.Textbook() euclid[%d] = %+v, want %+v", i, step, w)
+		}
+	}
+
+	// 17 = 10001₂: 1, 1·1·65, 65², 2790², 1490², 2601²·65.
+	encrypt := details.Blocks[0].Encrypt
+	if encrypt.Binary != "10001" || len(encrypt.Steps) != 5 {
+		t.Fatalf("RSAService.Textbook() encrypt = %s with %d steps, want 10001 with 5", encrypt.Binary, len(encrypt.Steps))
+	}
+	wantResults := []int64{65, 992, 1232, 1547, 2790}
+	for i, step := range encrypt.Steps {
+		if step.Result.Int64() != wantResults[i] {
+			t.Errorf("RSAService.Textbook() encrypt step %d = %v, want %d", i, step.Result, wantResults[i])
+		}
+		if (step.Multiplied != nil) != (step.Bit == 1) {
+			t.Errorf("RSAService.Textbook() encrypt step %d multiplied = %v with bit %d", i, step.Multiplied, step.Bit)
+		}
+	}
+}
+
+func TestRSAService_TextbookMessage(t *testing.T) {
+	r := NewRsaService()
+	tests := []struct {
+		name   string
+		params TextbookParams
+	}{
+		{"given primes", TextbookParams{P: big.NewInt(1009), Q: big.NewInt(1013), Message: "Привет, RSA!"}},
+		{"generated primes", TextbookParams{Message: "Hello"}},
+		{"generated large primes", TextbookParams{Bits: MaxTextbookPrimeBits, Message: "Какой-то секретный текст"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			details, err := r.Textbook(tt.params)
+			if err != nil {
+				t.Fatalf("RSAService.Textbook() has err = %v", err)
+			}
+			if details.Decrypted != tt.params.Message {
+				t.Errorf("RSAService.Textbook() decrypted = %q, want %q", details.Decrypted, tt.params.Message)
+			}
+			ed := new(big.Int).Mul(details.E, details.D)
+			if ed.Mod(ed, details.Phi).Int64() != 1 {
+				t.Errorf("RSAService.Textbook() e·d mod φ(n) = %v, want 1", ed)
+			}
+			if details.Generated != (tt.params.P == nil) {
+				t.Errorf("RSAService.Textbook() generated = %v", details.Generated)
+			}
+		})
+	}
+}
+
+func TestRSAService_TextbookInvalidParams(t *testing.T) {
+	r := NewRsaService()
+	tests := []struct {
+		name   string
+		params TextbookParams
+	}{
+		{"composite p", TextbookParams{P: big.NewInt(15), Q: big.NewInt(17)}},
+		{"equal primes", TextbookParams{P: big.NewInt(17), Q: big.NewInt(17)}},
+		{"only p", TextbookParams{P: big.NewInt(17)}},
+		{"large p", TextbookParams{P: big.NewInt(1<<31 - 1), Q: big.NewInt(17)}},
+		{"e not coprime", TextbookParams{P: big.NewInt(61), Q: big.NewInt(53), E: big.NewInt(15)}},
+		{"e too large", TextbookParams{P: big.NewInt(61), Q: big.NewInt(53), E: big.NewInt(3121)}},
+		{"no e", TextbookParams{P: big.NewInt(2), Q: big.NewInt(3)}},
+		{"m not less than n", TextbookParams{P: big.NewInt(61), Q: big.NewInt(53), M: big.NewInt(3233)}},
+		{"n too small for text", TextbookParams{P: big.NewInt(5), Q: big.NewInt(7), Message: "a"}},
+		{"bits", TextbookParams{Bits: 64}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := r.Textbook(tt.params); !errors.Is(err, ErrInvalidParams) {
+				t.Errorf("RSAService.Textbook() has err = %v, want %v", err, ErrInvalidParams)
+			}
+		})
+	}
+}
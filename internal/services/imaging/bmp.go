@@ -0,0 +1,159 @@
+package imaging
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+)
+
+// Декодер несжатых BMP: 8 бит с палитрой, 24 и 32 бита на пиксель.
+// Регистрируется в пакете image, поэтому BMP открывается через image.Decode.
+
+func init() {
+	image.RegisterFormat("bmp", "BM", DecodeBMP, DecodeBMPConfig)
+}
+
+const (
+	bmpFileHeaderSize = 14
+	bmpInfoHeaderSize = 40
+
+	bmpRGB       = 0
+	bmpBitFields = 3
+)
+
+type bmpHeader struct {
+	offset      uint32
+	width       int
+	height      int
+	topDown     bool
+	bitCount    uint16
+	compression uint32
+	colorsUsed  uint32
+	infoSize    uint32
+}
+
+func readBMPHeader(r io.Reader) (bmpHeader, error) {
+	var buf [bmpFileHeaderSize + bmpInfoHeaderSize]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return bmpHeader{}, err
+	}
+	if string(buf[:2]) != "BM" {
+		return bmpHeader{}, fmt.Errorf("invalid bmp signature")
+	}
+
+	h := bmpHeader{
+		offset:      binary.LittleEndian.Uint32(buf[10:]),
+		infoSize:    binary.LittleEndian.Uint32(buf[14:]),
+		width:       int(int32(binary.LittleEndian.Uint32(buf[18:]))),
+		height:      int(int32(binary.LittleEndian.Uint32(buf[22:]))),
+		bitCount:    binary.LittleEndian.Uint16(buf[28:]),
+		compression: binary.LittleEndian.Uint32(buf[30:]),
+		colorsUsed:  binary.LittleEndian.Uint32(buf[46:]),
+	}
+	if h.infoSize < bmpInfoHeaderSize {
+		return bmpHeader{}, fmt.Errorf("unsupported bmp header size %d", h.infoSize)
+	}
+	if h.height < 0 {
+		h.height = -h.height
+		h.topDown = true
+	}
+	if h.width <= 0 || h.height <= 0 {
+		return bmpHeader{}, fmt.Errorf("invalid bmp size %dx%d", h.width, h.height)
+	}
+
+	switch {
+	case h.bitCount == 8 && h.compression == bmpRGB:
+	case h.bitCount == 24 && h.compression == bmpRGB:
+	case h.bitCount == 32 && (h.compression == bmpRGB || h.compression == bmpBitFields):
+	default:
+		return bmpHeader{}, fmt.Errorf("unsupported bmp: %d bits, compression %d", h.bitCount, h.compression)
+	}
+	return h, nil
+}
+
+func DecodeBMPConfig(r io.Reader) (image.Config, error) {
+	h, err := readBMPHeader(r)
+	if err != nil {
+		return image.Config{}, err
+	}
+	colorModel := color.RGBAModel
+	if h.bitCount == 8 {
+		colorModel = color.Palette{}
+	}
+	return image.Config{ColorModel: colorModel, Width: h.width, Height: h.height}, nil
+}
+
+func DecodeBMP(r io.Reader) (image.Image, error) {
+	br := bufio.NewReader(r)
+	h, err := readBMPHeader(br)
+	if err != nil {
+		return nil, err
+	}
+	read := uint32(bmpFileHeaderSize + bmpInfoHeaderSize)
+
+	var palette color.Palette
+	if h.bitCount == 8 {
+		numColors := h.colorsUsed
+		if numColors == 0 || numColors > 256 {
+			numColors = 256
+		}
+		if _, err := br.Discard(int(h.infoSize - bmpInfoHeaderSize)); err != nil {
+			return nil, err
+		}
+		read += h.infoSize - bmpInfoHeaderSize
+
+		// Палитра хранится как BGR0.
+		entries := make([]byte, 4*numColors)
+		if _, err := io.ReadFull(br, entries); err != nil {
+			return nil, err
+		}
+		read += uint32(len(entries))
+		palette = make(color.Palette, 256)
+		for i := range palette {
+			palette[i] = color.RGBA{A: 0xFF}
+		}
+		for i := range int(numColors) {
+			palette[i] = color.RGBA{R: entries[4*i+2], G: entries[4*i+1], B: entries[4*i], A: 0xFF}
+		}
+	}
+
+	if h.offset < read {
+		return nil, fmt.Errorf("invalid bmp pixel offset %d", h.offset)
+	}
+	if _, err := br.Discard(int(h.offset - read)); err != nil {
+		return nil, err
+	}
+
+	// Строки выравниваются по 4 байта и по умолчанию идут снизу вверх.
+	bytesPerPixel := int(h.bitCount) / 8
+	stride := (h.width*bytesPerPixel + 3) &^ 3
+	row := make([]byte, stride)
+
+	img := image.NewRGBA(image.Rect(0, 0, h.width, h.height))
+	for i := range h.height {
+		if _, err := io.ReadFull(br, row); err != nil {
+			return nil, err
+		}
+		y := h.height - 1 - i
+		if h.topDown {
+			y = i
+		}
+
+		pix := img.Pix[y*img.Stride:]
+		for x := range h.width {
+			var c color.RGBA
+			switch h.bitCount {
+			case 8:
+				c = palette[row[x]].(color.RGBA)
+			default:
+				p := row[x*bytesPerPixel:]
+				c = color.RGBA{R: p[2], G: p[1], B: p[0], A: 0xFF}
+			}
+			pix[4*x], pix[4*x+1], pix[4*x+2], pix[4*x+3] = c.R, c.G, c.B, c.A
+		}
+	}
+	return img, nil
+}
@@ -0,0 +1,84 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// makeBMP собирает 24-битный BMP, строки которого записаны снизу вверх.
+func makeBMP(img image.Image) []byte {
+	bounds := img.Bounds()
+	stride := (bounds.Dx()*3 + 3) &^ 3
+	size := bmpFileHeaderSize + bmpInfoHeaderSize + stride*bounds.Dy()
+
+	buf := new(bytes.Buffer)
+	buf.WriteString("BM")
+	binary.Write(buf, binary.LittleEndian, uint32(size))
+	binary.Write(buf, binary.LittleEndian, uint32(0))
+	binary.Write(buf, binary.LittleEndian, uint32(bmpFileHeaderSize+bmpInfoHeaderSize))
+	binary.Write(buf, binary.LittleEndian, uint32(bmpInfoHeaderSize))
+	binary.Write(buf, binary.LittleEndian, int32(bounds.Dx()))
+	binary.Write(buf, binary.LittleEndian, int32(bounds.Dy()))
+	binary.Write(buf, binary.LittleEndian, uint16(1))
+	binary.Write(buf, binary.LittleEndian, uint16(24))
+	buf.Write(make([]byte, 24))
+
+	row := make([]byte, stride)
+	for y := bounds.Max.Y - 1; y >= bounds.Min.Y; y-- {
+		for x := range bounds.Dx() {
+			c := color.RGBAModel.Convert(img.At(bounds.Min.X+x, y)).(color.RGBA)
+			row[3*x], row[3*x+1], row[3*x+2] = c.B, c.G, c.R
+		}
+		buf.Write(row)
+	}
+	return buf.Bytes()
+}
+
+func TestDecodeBMP(t *testing.T) {
+	want := testImage(5, 3)
+
+	img, format, err := image.Decode(bytes.NewReader(makeBMP(want)))
+	if err != nil {
+		t.Fatalf("image.Decode() has err = %v", err)
+	}
+	if format != "bmp" {
+		t.Errorf("format = %q, want bmp", format)
+	}
+	if img.Bounds() != want.Bounds() {
+		t.Fatalf("bounds = %v, want %v", img.Bounds(), want.Bounds())
+	}
+	for y := range 3 {
+		for x := range 5 {
+			if got := color.RGBAModel.Convert(img.At(x, y)); got != want.At(x, y) {
+				t.Errorf("pixel (%d, %d) = %v, want %v", x, y, got, want.At(x, y))
+			}
+		}
+	}
+}
+
+func TestDecodeBMP_Invalid(t *testing.T) {
+	data := makeBMP(testImage(4, 4))
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "truncated header", data: data[:20]},
+		{name: "truncated pixels", data: data[:len(data)-10]},
+		{name: "compressed", data: func() []byte {
+			d := append([]byte(nil), data...)
+			binary.LittleEndian.PutUint32(d[30:], 1)
+			return d
+		}()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeBMP(bytes.NewReader(tt.data)); err == nil {
+				t.Errorf("DecodeBMP() has no err")
+			}
+		})
+	}
+}
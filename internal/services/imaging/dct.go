@@ -0,0 +1,161 @@
+package imaging
+
+import (
+	"math"
+)
+
+const blockSize = 8
+
+// Block — блок 8×8 в построчном порядке.
+type Block [blockSize * blockSize]float64
+
+var dctCos = func() [blockSize][blockSize]float64 {
+	var c [blockSize][blockSize]float64
+	for x := range blockSize {
+		for u := range blockSize {
+			c[x][u] = math.Cos(float64(2*x+1) * float64(u) * math.Pi / 16)
+		}
+	}
+	return c
+}()
+
+func dctScale(u int) float64 {
+	if u == 0 {
+		return 1 / math.Sqrt2
+	}
+	return 1
+}
+
+// dct выполняет двумерное ДКП-II блока как два одномерных преобразования:
+// по строкам, затем по столбцам.
+//
+//	F(u,v) = 1/4 C(u) C(v) Σx Σy f(x,y) cos((2x+1)uπ/16) cos((2y+1)vπ/16)
+func dct(b *Block) Block {
+	var tmp, out Block
+	for y := range blockSize {
+		for u := range blockSize {
+			sum := 0.0
+			for x := range blockSize {
+				sum += b[y*blockSize+x] * dctCos[x][u]
+			}
+			tmp[y*blockSize+u] = sum * dctScale(u) / 2
+		}
+	}
+	for u := range blockSize {
+		for v := range blockSize {
+			sum := 0.0
+			for y := range blockSize {
+				sum += tmp[y*blockSize+u] * dctCos[y][v]
+			}
+			out[v*blockSize+u] = sum * dctScale(v) / 2
+		}
+	}
+	return out
+}
+
+// idct — обратное преобразование к dct.
+func idct(b *Block) Block {
+	var tmp, out Block
+	for v := range blockSize {
+		for x := range blockSize {
+			sum := 0.0
+			for u := range blockSize {
+				sum += dctScale(u) * b[v*blockSize+u] * dctCos[x][u]
+			}
+			tmp[v*blockSize+x] = sum / 2
+		}
+	}
+	for x := range blockSize {
+		for y := range blockSize {
+			sum := 0.0
+			for v := range blockSize {
+				sum += dctScale(v) * tmp[v*blockSize+x] * dctCos[y][v]
+			}
+			out[y*blockSize+x] = sum / 2
+		}
+	}
+	return out
+}
+
+// Таблицы квантования из приложения K стандарта JPEG (качество 50).
+var (
+	luminanceQuantTable = [blockSize * blockSize]int{
+		16, 11, 10, 16, 24, 40, 51, 61,
+		12, 12, 14, 19, 26, 58, 60, 55,
+		14, 13, 16, 24, 40, 57, 69, 56,
+		14, 17, 22, 29, 51, 87, 80, 62,
+		18, 22, 37, 56, 68, 109, 103, 77,
+		24, 35, 55, 64, 81, 104, 113, 92,
+		49, 64, 78, 87, 103, 121, 120, 101,
+		72, 92, 95, 98, 112, 100, 103, 99,
+	}
+	chrominanceQuantTable = [blockSize * blockSize]int{
+		17, 18, 24, 47, 99, 99, 99, 99,
+		18, 21, 26, 66, 99, 99, 99, 99,
+		24, 26, 56, 99, 99, 99, 99, 99,
+		47, 66, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+	}
+)
+
+// quantTable масштабирует базовую таблицу под качество 1..100 так же, как libjpeg:
+// при качестве 50 таблица не меняется, при 100 все шаги равны 1.
+func quantTable(base [blockSize * blockSize]int, quality int) [blockSize * blockSize]int {
+	scale := 200 - 2*quality
+	if quality < 50 {
+		scale = 5000 / quality
+	}
+
+	var table [blockSize * blockSize]int
+	for i, q := range base {
+		table[i] = min(max((q*scale+50)/100, 1), 255)
+	}
+	return table
+}
+
+func quantize(b *Block, table *[blockSize * blockSize]int) [blockSize * blockSize]int {
+	var out [blockSize * blockSize]int
+	for i := range b {
+		out[i] = int(math.Round(b[i] / float64(table[i])))
+	}
+	return out
+}
+
+func dequantize(coefficients *[blockSize * blockSize]int, table *[blockSize * blockSize]int) Block {
+	var out Block
+	for i := range coefficients {
+		out[i] = float64(coefficients[i] * table[i])
+	}
+	return out
+}
+
+// zigzagOrder[i] — индекс в построчном порядке i-го коэффициента зигзаг-обхода.
+var zigzagOrder = [blockSize * blockSize]int{
+	0, 1, 8, 16, 9, 2, 3, 10,
+	17, 24, 32, 25, 18, 11, 4, 5,
+	12, 19, 26, 33, 40, 48, 41, 34,
+	27, 20, 13, 6, 7, 14, 21, 28,
+	35, 42, 49, 56, 57, 50, 43, 36,
+	29, 22, 15, 23, 30, 37, 44, 51,
+	58, 59, 52, 45, 38, 31, 39, 46,
+	53, 60, 61, 54, 47, 55, 62, 63,
+}
+
+func zigzag(coefficients *[blockSize * blockSize]int) [blockSize * blockSize]int {
+	var out [blockSize * blockSize]int
+	for i, j := range zigzagOrder {
+		out[i] = coefficients[j]
+	}
+	return out
+}
+
+func unzigzag(coefficients *[blockSize * blockSize]int) [blockSize * blockSize]int {
+	var out [blockSize * blockSize]int
+	for i, j := range zigzagOrder {
+		out[j] = coefficients[i]
+	}
+	return out
+}
@@ -0,0 +1,71 @@
+package imaging
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDCT(t *testing.T) {
+	var b Block
+	for i := range b {
+		b[i] = float64((i*37)%256 - 128)
+	}
+
+	coefficients := dct(&b)
+	restored := idct(&coefficients)
+	for i := range b {
+		if math.Abs(restored[i]-b[i]) > 1e-9 {
+			t.Fatalf("idct(dct(b))[%d] = %f, want %f", i, restored[i], b[i])
+		}
+	}
+
+	// Для постоянного блока отличен от нуля только DC-коэффициент, равный 8·f.
+	var flat Block
+	for i := range flat {
+		flat[i] = 10
+	}
+	coefficients = dct(&flat)
+	if math.Abs(coefficients[0]-80) > 1e-9 {
+		t.Errorf("dct(flat)[0] = %f, want 80", coefficients[0])
+	}
+	for i, c := range coefficients[1:] {
+		if math.Abs(c) > 1e-9 {
+			t.Errorf("dct(flat)[%d] = %f, want 0", i+1, c)
+		}
+	}
+}
+
+func TestQuantTable(t *testing.T) {
+	tests := []struct {
+		quality int
+		want    func(i int) int
+	}{
+		{quality: 50, want: func(i int) int { return luminanceQuantTable[i] }},
+		{quality: 100, want: func(i int) int { return 1 }},
+		{quality: 1, want: func(i int) int { return 255 }},
+	}
+
+	for _, tt := range tests {
+		table := quantTable(luminanceQuantTable, tt.quality)
+		for i, q := range table {
+			if q != tt.want(i) {
+				t.Errorf("quantTable(%d)[%d] = %d, want %d", tt.quality, i, q, tt.want(i))
+			}
+		}
+	}
+}
+
+func TestZigzag(t *testing.T) {
+	var coefficients [blockSize * blockSize]int
+	for i := range coefficients {
+		coefficients[i] = i
+	}
+
+	zz := zigzag(&coefficients)
+	if zz[2] != 8 || zz[3] != 16 || zz[63] != 63 {
+		t.Errorf("zigzag() = %v", zz)
+	}
+	if restored := unzigzag(&zz); restored != coefficients {
+		t.Errorf("unzigzag(zigzag()) = %v, want %v", restored, coefficients)
+	}
+}
@@ -0,0 +1,286 @@
+package imaging
+
+import (
+	"container/heap"
+	"fmt"
+	"math/bits"
+	"sort"
+
+	"github.com/PritOriginal/cryptolabs-back/pkg/bitsio"
+)
+
+// Энтропийное кодирование коэффициентов, как в baseline JPEG.
+// DC-коэффициент кодируется разностью с DC предыдущего блока той же компоненты,
+// AC-коэффициенты — парами (длина серии нулей, значение). Значение v
+// представляется категорией size (числом значащих бит |v|) и size битами
+// амплитуды. Символы Хаффмана — size для DC и run<<4|size для AC.
+
+const (
+	symbolEOB = 0x00 // оставшиеся коэффициенты блока равны нулю
+	symbolZRL = 0xF0 // серия из 16 нулей
+
+	maxCodeLength = 16
+	maxCategory   = 15
+)
+
+// RunLevel — элемент кодирования серий одного блока.
+type RunLevel struct {
+	Symbol string `json:"symbol"`
+	Run    int    `json:"run"`
+	Size   int    `json:"size"`
+	Value  int    `json:"value"`
+	Code   string `json:"code,omitempty"`
+	Bits   string `json:"bits,omitempty"`
+
+	symbol byte
+}
+
+func category(v int) int {
+	if v < 0 {
+		v = -v
+	}
+	return bits.Len(uint(v))
+}
+
+// amplitude возвращает младшие size бит представления v:
+// для отрицательных значений используется v-1 в дополнительном коде.
+func amplitude(v, size int) uint {
+	if v < 0 {
+		v--
+	}
+	return uint(v) & (1<<size - 1)
+}
+
+func extend(amp uint, size int) int {
+	if size == 0 {
+		return 0
+	}
+	if amp < 1<<(size-1) {
+		return int(amp) - 1<<size + 1
+	}
+	return int(amp)
+}
+
+// runLevels кодирует сериями блок в порядке зигзага; dc — разность DC.
+func runLevels(coefficients *[blockSize * blockSize]int, dc int) ([]RunLevel, error) {
+	size := category(dc)
+	if size > maxCategory {
+		return nil, fmt.Errorf("dc difference %d is too large", dc)
+	}
+	items := []RunLevel{{Symbol: "DC", Size: size, Value: dc, symbol: byte(size)}}
+
+	run := 0
+	for _, v := range coefficients[1:] {
+		if v == 0 {
+			run++
+			continue
+		}
+		for run > 15 {
+			items = append(items, RunLevel{Symbol: "ZRL", Run: 16, symbol: symbolZRL})
+			run -= 16
+		}
+		size := category(v)
+		if size > maxCategory {
+			return nil, fmt.Errorf("coefficient %d is too large", v)
+		}
+		items = append(items, RunLevel{Symbol: "AC", Run: run, Size: size, Value: v, symbol: byte(run<<4 | size)})
+		run = 0
+	}
+	if run > 0 {
+		items = append(items, RunLevel{Symbol: "EOB", symbol: symbolEOB})
+	}
+	return items, nil
+}
+
+// huffmanTable — канонический код Хаффмана с длинами не больше 16 бит,
+// задаваемый, как в JPEG, числом кодов каждой длины и списком символов.
+type huffmanTable struct {
+	counts  [maxCodeLength]byte
+	symbols []byte
+
+	codes   map[byte]string
+	minCode [maxCodeLength + 1]int
+	maxCode [maxCodeLength + 1]int
+	valPtr  [maxCodeLength + 1]int
+}
+
+type lengthItem struct {
+	weight  int
+	symbols []int
+}
+
+type lengthQueue []lengthItem
+
+func (q lengthQueue) Len() int           { return len(q) }
+func (q lengthQueue) Less(i, j int) bool { return q[i].weight < q[j].weight }
+func (q lengthQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *lengthQueue) Push(x any)        { *q = append(*q, x.(lengthItem)) }
+func (q *lengthQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// newHuffmanTable строит код по частотам символов. Длины кодов, превышающие
+// 16 бит, укорачиваются процедурой из приложения K.2 стандарта JPEG.
+func newHuffmanTable(frequencies map[byte]int) *huffmanTable {
+	lengths := make(map[int]int, len(frequencies))
+	q := make(lengthQueue, 0, len(frequencies))
+	for symbol, frequency := range frequencies {
+		q = append(q, lengthItem{weight: frequency, symbols: []int{int(symbol)}})
+	}
+	heap.Init(&q)
+	for q.Len() > 1 {
+		a := heap.Pop(&q).(lengthItem)
+		b := heap.Pop(&q).(lengthItem)
+		for _, symbol := range a.symbols {
+			lengths[symbol]++
+		}
+		for _, symbol := range b.symbols {
+			lengths[symbol]++
+		}
+		heap.Push(&q, lengthItem{weight: a.weight + b.weight, symbols: append(a.symbols, b.symbols...)})
+	}
+
+	numLengths := make([]int, max(len(frequencies), maxCodeLength)+2)
+	for symbol := range frequencies {
+		// Единственному символу нужен хотя бы один бит.
+		numLengths[max(lengths[int(symbol)], 1)]++
+	}
+	for i := len(numLengths) - 1; i > maxCodeLength; i-- {
+		for numLengths[i] > 0 {
+			j := i - 2
+			for numLengths[j] == 0 {
+				j--
+			}
+			numLengths[i] -= 2
+			numLengths[i-1]++
+			numLengths[j+1] += 2
+			numLengths[j]--
+		}
+	}
+
+	// Символы упорядочиваются по длине кода, а при равной длине — по значению.
+	symbols := make([]byte, 0, len(frequencies))
+	for symbol := range frequencies {
+		symbols = append(symbols, symbol)
+	}
+	sort.Slice(symbols, func(i, j int) bool {
+		li, lj := lengths[int(symbols[i])], lengths[int(symbols[j])]
+		if li != lj {
+			return li < lj
+		}
+		return symbols[i] < symbols[j]
+	})
+
+	t := &huffmanTable{symbols: symbols}
+	for i := range maxCodeLength {
+		t.counts[i] = byte(numLengths[i+1])
+	}
+	t.build()
+	return t
+}
+
+// build вычисляет канонические коды и таблицы для декодирования.
+func (t *huffmanTable) build() {
+	t.codes = make(map[byte]string, len(t.symbols))
+	code, k := 0, 0
+	for length := 1; length <= maxCodeLength; length++ {
+		t.valPtr[length] = k
+		t.minCode[length] = code
+		for range t.counts[length-1] {
+			t.codes[t.symbols[k]] = fmt.Sprintf("%0*b", length, code)
+			code++
+			k++
+		}
+		t.maxCode[length] = code - 1
+		code <<= 1
+	}
+}
+
+func (t *huffmanTable) bytes() []byte {
+	data := make([]byte, 0, maxCodeLength+len(t.symbols))
+	data = append(data, t.counts[:]...)
+	return append(data, t.symbols...)
+}
+
+func readHuffmanTable(data []byte) (*huffmanTable, int, error) {
+	if len(data) < maxCodeLength {
+		return nil, 0, fmt.Errorf("huffman table is truncated")
+	}
+	t := &huffmanTable{}
+	copy(t.counts[:], data)
+
+	numSymbols := 0
+	for _, count := range t.counts {
+		numSymbols += int(count)
+	}
+	if numSymbols > 256 || len(data) < maxCodeLength+numSymbols {
+		return nil, 0, fmt.Errorf("invalid huffman table")
+	}
+	t.symbols = append([]byte(nil), data[maxCodeLength:maxCodeLength+numSymbols]...)
+	t.build()
+	return t, maxCodeLength + numSymbols, nil
+}
+
+type bitWriter struct {
+	*bitsio.BitWriter
+}
+
+func (bw bitWriter) writeString(s string) {
+	for _, ch := range s {
+		bw.WriteBit(ch == '1')
+	}
+}
+
+func (bw bitWriter) writeBits(v uint, n int) string {
+	bw.WriteBits(uint64(v), n)
+	if n == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%0*b", n, v&(1<<n-1))
+}
+
+// encode записывает элемент серии кодом Хаффмана и битами амплитуды,
+// сохраняя их в item для вывода в отчёт.
+func (bw bitWriter) encode(t *huffmanTable, item *RunLevel) {
+	item.Code = t.codes[item.symbol]
+	bw.writeString(item.Code)
+	item.Bits = bw.writeBits(amplitude(item.Value, item.Size), item.Size)
+}
+
+type bitReader struct {
+	*bitsio.BitReader
+}
+
+func (br bitReader) readBit() (int, error) {
+	v, err := br.ReadBits(1)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected end of data")
+	}
+	return int(v), nil
+}
+
+func (br bitReader) readBits(n int) (uint, error) {
+	v, err := br.ReadBits(n)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected end of data")
+	}
+	return uint(v), nil
+}
+
+func (br bitReader) decode(t *huffmanTable) (byte, error) {
+	code := 0
+	for length := 1; length <= maxCodeLength; length++ {
+		bit, err := br.readBit()
+		if err != nil {
+			return 0, err
+		}
+		code = code<<1 | bit
+		if t.counts[length-1] > 0 && code >= t.minCode[length] && code <= t.maxCode[length] {
+			return t.symbols[t.valPtr[length]+code-t.minCode[length]], nil
+		}
+	}
+	return 0, fmt.Errorf("invalid huffman code")
+}
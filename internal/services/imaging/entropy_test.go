@@ -0,0 +1,85 @@
+package imaging
+
+import (
+	"testing"
+
+	"github.com/PritOriginal/cryptolabs-back/pkg/bitsio"
+)
+
+func TestHuffmanTable_LengthLimit(t *testing.T) {
+	// Частоты Фибоначчи дают дерево глубины len-1 без ограничения длин.
+	frequencies := make(map[byte]int)
+	a, b := 1, 1
+	for i := range 24 {
+		frequencies[byte(i)] = a
+		a, b = b, a+b
+	}
+
+	table := newHuffmanTable(frequencies)
+	kraft := 0.0
+	for symbol := range frequencies {
+		code := table.codes[symbol]
+		if len(code) == 0 || len(code) > maxCodeLength {
+			t.Fatalf("code of %d = %q", symbol, code)
+		}
+		kraft += 1 / float64(uint(1)<<len(code))
+	}
+	if kraft > 1 {
+		t.Errorf("Kraft sum = %f, want <= 1", kraft)
+	}
+
+	restored, n, err := readHuffmanTable(table.bytes())
+	if err != nil || n != maxCodeLength+len(frequencies) {
+		t.Fatalf("readHuffmanTable() = %d, %v", n, err)
+	}
+
+	bw := bitWriter{bitsio.NewBitWriter()}
+	for symbol := range byte(24) {
+		bw.writeString(table.codes[symbol])
+	}
+	br := bitReader{bitsio.NewBitReader(bw.Bytes())}
+	for symbol := range byte(24) {
+		got, err := br.decode(restored)
+		if err != nil || got != symbol {
+			t.Fatalf("decode() = %d, %v, want %d", got, err, symbol)
+		}
+	}
+}
+
+func TestRunLevels(t *testing.T) {
+	var zz [blockSize * blockSize]int
+	zz[0], zz[1], zz[20], zz[40] = 5, -3, 1, 2
+
+	items, err := runLevels(&zz, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		symbol byte
+		value  int
+	}{
+		{symbol: 2, value: 2},
+		{symbol: 0x02, value: -3},
+		{symbol: symbolZRL}, // 18 нулей: ZRL и серия из 2
+		{symbol: 0x21, value: 1},
+		{symbol: symbolZRL}, // 19 нулей: ZRL и серия из 3
+		{symbol: 0x32, value: 2},
+		{symbol: symbolEOB},
+	}
+	if len(items) != len(want) {
+		t.Fatalf("runLevels() = %+v", items)
+	}
+	for i, item := range items {
+		if item.symbol != want[i].symbol || item.Value != want[i].value {
+			t.Errorf("runLevels()[%d] = %+v, want %+v", i, item, want[i])
+		}
+	}
+
+	for _, v := range []int{-1023, -2, -1, 1, 7, 1023} {
+		size := category(v)
+		if got := extend(amplitude(v, size), size); got != v {
+			t.Errorf("extend(amplitude(%d)) = %d", v, got)
+		}
+	}
+}
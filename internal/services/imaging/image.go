@@ -0,0 +1,587 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/png"
+	"math"
+
+	"github.com/PritOriginal/cryptolabs-back/pkg/bitsio"
+)
+
+// Сжатие изображений с потерями по схеме baseline JPEG: перевод RGB в YCbCr
+// с прореживанием цветности, ДКП блоков 8×8, квантование таблицей,
+// масштабированной под качество, зигзаг-обход, кодирование серий и Хаффмана.
+// Сжатые данные хранятся в собственном контейнере:
+//
+//	"CLIM" | ширина uint32 | высота uint32 | качество uint8 | прореживание uint8 (hx<<4 | hy) |
+//	таблицы Хаффмана: DC и AC яркости, DC и AC цветности | коэффициенты
+//
+// Компоненты Y, Cb, Cr кодируются друг за другом, блоки — построчно.
+
+const (
+	DefaultQuality = 75
+	MaxImagePixels = 4096 * 4096
+
+	Subsampling444 = "4:4:4"
+	Subsampling422 = "4:2:2"
+	Subsampling420 = "4:2:0"
+
+	ComponentY  = "y"
+	ComponentCb = "cb"
+	ComponentCr = "cr"
+)
+
+var imageMagic = []byte("CLIM")
+
+var components = []string{ComponentY, ComponentCb, ComponentCr}
+
+type ImageService struct {
+}
+
+func NewImageService() *ImageService {
+	return &ImageService{}
+}
+
+type ImageOptions struct {
+	Quality     int
+	Subsampling string
+	// Block — блок, промежуточные результаты которого попадут в отчёт.
+	Block *BlockPosition
+}
+
+type BlockPosition struct {
+	Component string
+	X, Y      int
+}
+
+type ImageCompressionDetails struct {
+	Details ImageDetails
+	Data    []byte
+	// Image — изображение, восстановленное из сжатых данных.
+	Image image.Image
+}
+
+type ImageDetails struct {
+	Width            int        `json:"width"`
+	Height           int        `json:"height"`
+	Quality          int        `json:"quality"`
+	Subsampling      string     `json:"subsampling"`
+	MSE              float64    `json:"mse"`
+	PSNR             *float64   `json:"psnr"`
+	OriginalSize     int        `json:"original_size"`
+	Size             int        `json:"size"`
+	CompressionRatio float32    `json:"compression_ratio"`
+	BitsPerPixel     float64    `json:"bits_per_pixel"`
+	Block            *BlockDump `json:"block,omitempty"`
+}
+
+// BlockDump — блок на каждом этапе сжатия и восстановления.
+type BlockDump struct {
+	Component     string      `json:"component"`
+	X             int         `json:"x"`
+	Y             int         `json:"y"`
+	Pixels        [][]int     `json:"pixels"`
+	DCT           [][]float64 `json:"dct"`
+	QuantTable    [][]int     `json:"quant_table"`
+	Quantized     [][]int     `json:"quantized"`
+	Zigzag        []int       `json:"zigzag"`
+	RunLevels     []RunLevel  `json:"run_levels"`
+	Dequantized   [][]int     `json:"dequantized"`
+	Reconstructed [][]int     `json:"reconstructed"`
+}
+
+type plane struct {
+	width, height int
+	pix           []float64
+}
+
+func newPlane(width, height int) *plane {
+	return &plane{width: width, height: height, pix: make([]float64, width*height)}
+}
+
+func (p *plane) blocks() (int, int) {
+	return (p.width + blockSize - 1) / blockSize, (p.height + blockSize - 1) / blockSize
+}
+
+// block возвращает блок со сдвигом уровня на -128. Блоки на краю
+// дополняются повторением последних строки и столбца.
+func (p *plane) block(bx, by int) Block {
+	var b Block
+	for y := range blockSize {
+		sy := min(by*blockSize+y, p.height-1)
+		for x := range blockSize {
+			sx := min(bx*blockSize+x, p.width-1)
+			b[y*blockSize+x] = p.pix[sy*p.width+sx] - 128
+		}
+	}
+	return b
+}
+
+func (p *plane) setBlock(bx, by int, b *Block) {
+	for y := range blockSize {
+		sy := by*blockSize + y
+		if sy >= p.height {
+			break
+		}
+		for x := range blockSize {
+			sx := bx*blockSize + x
+			if sx >= p.width {
+				break
+			}
+			p.pix[sy*p.width+sx] = b[y*blockSize+x] + 128
+		}
+	}
+}
+
+// DecodeImage декодирует загруженное изображение в формате PNG или BMP.
+func (s *ImageService) DecodeImage(data []byte) (image.Image, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if format != "png" && format != "bmp" {
+		return nil, fmt.Errorf("unsupported image format %q", format)
+	}
+	if config.Width*config.Height > MaxImagePixels {
+		return nil, fmt.Errorf("image is larger than %d pixels", MaxImagePixels)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
+
+func parseSubsampling(subsampling string) (int, int, error) {
+	switch subsampling {
+	case Subsampling444:
+		return 1, 1, nil
+	case Subsampling422:
+		return 2, 1, nil
+	case Subsampling420, "":
+		return 2, 2, nil
+	default:
+		return 0, 0, fmt.Errorf("unknown subsampling %q", subsampling)
+	}
+}
+
+func subsamplingName(hx, hy int) string {
+	switch {
+	case hx == 1 && hy == 1:
+		return Subsampling444
+	case hx == 2 && hy == 1:
+		return Subsampling422
+	default:
+		return Subsampling420
+	}
+}
+
+func (s *ImageService) Compress(img image.Image, opts ImageOptions) (ImageCompressionDetails, error) {
+	quality := opts.Quality
+	if quality == 0 {
+		quality = DefaultQuality
+	}
+	if quality < 1 || quality > 100 {
+		return ImageCompressionDetails{}, fmt.Errorf("quality must be between 1 and 100")
+	}
+	hx, hy, err := parseSubsampling(opts.Subsampling)
+	if err != nil {
+		return ImageCompressionDetails{}, err
+	}
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width == 0 || height == 0 {
+		return ImageCompressionDetails{}, fmt.Errorf("image is empty")
+	}
+	if width*height > MaxImagePixels {
+		return ImageCompressionDetails{}, fmt.Errorf("image is larger than %d pixels", MaxImagePixels)
+	}
+
+	planes := toYCbCr(img, hx, hy)
+	data, dump, err := s.encode(planes, quality, hx, hy, opts.Block)
+	if err != nil {
+		return ImageCompressionDetails{}, err
+	}
+
+	reconstructed, err := s.Decompress(data)
+	if err != nil {
+		return ImageCompressionDetails{}, err
+	}
+
+	originalSize := width * height * 3
+	details := ImageDetails{
+		Width:            width,
+		Height:           height,
+		Quality:          quality,
+		Subsampling:      subsamplingName(hx, hy),
+		OriginalSize:     originalSize,
+		Size:             len(data),
+		CompressionRatio: 1 - float32(len(data))/float32(originalSize),
+		BitsPerPixel:     float64(8*len(data)) / float64(width*height),
+		Block:            dump,
+	}
+	details.MSE, details.PSNR = psnr(img, reconstructed)
+
+	return ImageCompressionDetails{Details: details, Data: data, Image: reconstructed}, nil
+}
+
+// toYCbCr переводит изображение в плоскости Y, Cb, Cr по формулам JFIF.
+// Каждый отсчёт цветности равен среднему по блоку hx×hy пикселей.
+func toYCbCr(img image.Image, hx, hy int) [3]*plane {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	lum := newPlane(width, height)
+	cb := newPlane(width, height)
+	cr := newPlane(width, height)
+	for y := range height {
+		for x := range width {
+			c := color.RGBAModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.RGBA)
+			r, g, b := float64(c.R), float64(c.G), float64(c.B)
+			i := y*width + x
+			lum.pix[i] = 0.299*r + 0.587*g + 0.114*b
+			cb.pix[i] = -0.168736*r - 0.331264*g + 0.5*b + 128
+			cr.pix[i] = 0.5*r - 0.418688*g - 0.081312*b + 128
+		}
+	}
+	return [3]*plane{lum, downsample(cb, hx, hy), downsample(cr, hx, hy)}
+}
+
+func downsample(p *plane, hx, hy int) *plane {
+	if hx == 1 && hy == 1 {
+		return p
+	}
+	out := newPlane((p.width+hx-1)/hx, (p.height+hy-1)/hy)
+	for y := range out.height {
+		for x := range out.width {
+			sum, n := 0.0, 0
+			for sy := y * hy; sy < min((y+1)*hy, p.height); sy++ {
+				for sx := x * hx; sx < min((x+1)*hx, p.width); sx++ {
+					sum += p.pix[sy*p.width+sx]
+					n++
+				}
+			}
+			out.pix[y*out.width+x] = sum / float64(n)
+		}
+	}
+	return out
+}
+
+func fromYCbCr(planes [3]*plane, hx, hy int) *image.RGBA {
+	lum, cb, cr := planes[0], planes[1], planes[2]
+	img := image.NewRGBA(image.Rect(0, 0, lum.width, lum.height))
+	for y := range lum.height {
+		for x := range lum.width {
+			l := lum.pix[y*lum.width+x]
+			// Цветность восстанавливается повторением отсчёта.
+			i := (y/hy)*cb.width + x/hx
+			b, r := cb.pix[i]-128, cr.pix[i]-128
+
+			img.SetRGBA(x, y, color.RGBA{
+				R: clamp(l + 1.402*r),
+				G: clamp(l - 0.344136*b - 0.714136*r),
+				B: clamp(l + 1.772*b),
+				A: 0xFF,
+			})
+		}
+	}
+	return img
+}
+
+func clamp(v float64) uint8 {
+	return uint8(min(max(math.Round(v), 0), 255))
+}
+
+func componentQuantTables(quality int) [3][blockSize * blockSize]int {
+	lum := quantTable(luminanceQuantTable, quality)
+	chrom := quantTable(chrominanceQuantTable, quality)
+	return [3][blockSize * blockSize]int{lum, chrom, chrom}
+}
+
+// tableClass возвращает номер пары таблиц Хаффмана: 0 для яркости, 1 для цветности.
+func tableClass(component int) int {
+	return min(component, 1)
+}
+
+func (s *ImageService) encode(planes [3]*plane, quality, hx, hy int, position *BlockPosition) ([]byte, *BlockDump, error) {
+	quantTables := componentQuantTables(quality)
+
+	// Первый проход: коэффициенты всех блоков и частоты символов,
+	// по которым затем строятся оптимальные таблицы Хаффмана.
+	var frequencies [2][2]map[byte]int
+	for class := range frequencies {
+		for kind := range frequencies[class] {
+			frequencies[class][kind] = make(map[byte]int)
+		}
+	}
+
+	var dump *BlockDump
+	blocks := make([][][]RunLevel, len(planes))
+	for c, p := range planes {
+		class := tableClass(c)
+		numX, numY := p.blocks()
+		blocks[c] = make([][]RunLevel, 0, numX*numY)
+
+		prevDC := 0
+		for by := range numY {
+			for bx := range numX {
+				pixels := p.block(bx, by)
+				coefficients := dct(&pixels)
+				quantized := quantize(&coefficients, &quantTables[c])
+				zz := zigzag(&quantized)
+
+				items, err := runLevels(&zz, zz[0]-prevDC)
+				if err != nil {
+					return nil, nil, err
+				}
+				prevDC = zz[0]
+
+				frequencies[class][0][items[0].symbol]++
+				for _, item := range items[1:] {
+					frequencies[class][1][item.symbol]++
+				}
+				blocks[c] = append(blocks[c], items)
+
+				if position != nil && position.Component == components[c] && position.X == bx && position.Y == by {
+					dump = newBlockDump(position, &pixels, &coefficients, &quantTables[c], &quantized, &zz)
+				}
+			}
+		}
+	}
+	if position != nil && dump == nil {
+		return nil, nil, fmt.Errorf("block %s (%d, %d) is out of range", position.Component, position.X, position.Y)
+	}
+
+	var tables [2][2]*huffmanTable
+	buf := new(bytes.Buffer)
+	buf.Write(imageMagic)
+	binary.Write(buf, binary.LittleEndian, uint32(planes[0].width))
+	binary.Write(buf, binary.LittleEndian, uint32(planes[0].height))
+	buf.WriteByte(byte(quality))
+	buf.WriteByte(byte(hx<<4 | hy))
+	for class := range tables {
+		for kind := range tables[class] {
+			tables[class][kind] = newHuffmanTable(frequencies[class][kind])
+			buf.Write(tables[class][kind].bytes())
+		}
+	}
+
+	bw := bitWriter{bitsio.NewBitWriter()}
+	for c := range blocks {
+		class := tableClass(c)
+		numX, _ := planes[c].blocks()
+		for i, items := range blocks[c] {
+			bw.encode(tables[class][0], &items[0])
+			for j := range items[1:] {
+				bw.encode(tables[class][1], &items[j+1])
+			}
+
+			if dump != nil && dump.Component == components[c] && dump.X == i%numX && dump.Y == i/numX {
+				dump.RunLevels = items
+			}
+		}
+	}
+	buf.Write(bw.Bytes())
+
+	return buf.Bytes(), dump, nil
+}
+
+func newBlockDump(position *BlockPosition, pixels, coefficients *Block, table, quantized, zz *[blockSize * blockSize]int) *BlockDump {
+	dequantized := dequantize(quantized, table)
+	reconstructed := idct(&dequantized)
+
+	dump := &BlockDump{
+		Component:     position.Component,
+		X:             position.X,
+		Y:             position.Y,
+		Pixels:        make([][]int, blockSize),
+		DCT:           make([][]float64, blockSize),
+		QuantTable:    make([][]int, blockSize),
+		Quantized:     make([][]int, blockSize),
+		Zigzag:        append([]int(nil), zz[:]...),
+		Dequantized:   make([][]int, blockSize),
+		Reconstructed: make([][]int, blockSize),
+	}
+	for y := range blockSize {
+		row := y * blockSize
+		dump.Pixels[y] = make([]int, blockSize)
+		dump.DCT[y] = make([]float64, blockSize)
+		dump.Dequantized[y] = make([]int, blockSize)
+		dump.Reconstructed[y] = make([]int, blockSize)
+		for x := range blockSize {
+			dump.Pixels[y][x] = int(math.Round(pixels[row+x]))
+			dump.DCT[y][x] = math.Round(coefficients[row+x]*100) / 100
+			dump.Dequantized[y][x] = int(dequantized[row+x])
+			dump.Reconstructed[y][x] = int(clamp(reconstructed[row+x]+128)) - 128
+		}
+		dump.QuantTable[y] = append([]int(nil), table[row:row+blockSize]...)
+		dump.Quantized[y] = append([]int(nil), quantized[row:row+blockSize]...)
+	}
+	return dump
+}
+
+// Decompress восстанавливает изображение из сжатых данных.
+func (s *ImageService) Decompress(data []byte) (image.Image, error) {
+	buf := bytes.NewBuffer(data)
+	if !bytes.HasPrefix(data, imageMagic) {
+		return nil, fmt.Errorf("invalid image container")
+	}
+	buf.Next(len(imageMagic))
+
+	var header struct {
+		Width       uint32
+		Height      uint32
+		Quality     uint8
+		Subsampling uint8
+	}
+	if err := binary.Read(buf, binary.LittleEndian, &header); err != nil {
+		return nil, err
+	}
+	width, height := int(header.Width), int(header.Height)
+	if width == 0 || height == 0 || uint64(width)*uint64(height) > MaxImagePixels {
+		return nil, fmt.Errorf("invalid image size %dx%d", width, height)
+	}
+	quality := int(header.Quality)
+	if quality < 1 || quality > 100 {
+		return nil, fmt.Errorf("invalid quality %d", quality)
+	}
+	hx, hy := int(header.Subsampling>>4), int(header.Subsampling&0x0F)
+	if hx < 1 || hx > 2 || hy < 1 || hy > hx {
+		return nil, fmt.Errorf("invalid subsampling %dx%d", hx, hy)
+	}
+
+	var tables [2][2]*huffmanTable
+	for class := range tables {
+		for kind := range tables[class] {
+			table, n, err := readHuffmanTable(buf.Bytes())
+			if err != nil {
+				return nil, err
+			}
+			buf.Next(n)
+			tables[class][kind] = table
+		}
+	}
+
+	// Блок занимает не меньше двух бит (категория DC и конец блока), поэтому
+	// размер изображения проверяется по объёму данных до выделения памяти.
+	chromaBlocks := ((width+hx-1)/hx + blockSize - 1) / blockSize * (((height+hy-1)/hy + blockSize - 1) / blockSize)
+	numBlocks := (width+blockSize-1)/blockSize*((height+blockSize-1)/blockSize) + 2*chromaBlocks
+	if numBlocks > 4*buf.Len() {
+		return nil, fmt.Errorf("image data is truncated")
+	}
+
+	planes := [3]*plane{
+		newPlane(width, height),
+		newPlane((width+hx-1)/hx, (height+hy-1)/hy),
+		newPlane((width+hx-1)/hx, (height+hy-1)/hy),
+	}
+	quantTables := componentQuantTables(quality)
+
+	br := bitReader{bitsio.NewBitReader(buf.Bytes())}
+	for c, p := range planes {
+		class := tableClass(c)
+		numX, numY := p.blocks()
+
+		prevDC := 0
+		for by := range numY {
+			for bx := range numX {
+				zz, err := br.decodeBlock(tables[class][0], tables[class][1], prevDC)
+				if err != nil {
+					return nil, fmt.Errorf("component %s, block (%d, %d): %w", components[c], bx, by, err)
+				}
+				prevDC = zz[0]
+
+				quantized := unzigzag(&zz)
+				coefficients := dequantize(&quantized, &quantTables[c])
+				pixels := idct(&coefficients)
+				p.setBlock(bx, by, &pixels)
+			}
+		}
+	}
+
+	return fromYCbCr(planes, hx, hy), nil
+}
+
+func (br bitReader) decodeBlock(dcTable, acTable *huffmanTable, prevDC int) ([blockSize * blockSize]int, error) {
+	var zz [blockSize * blockSize]int
+
+	size, err := br.decode(dcTable)
+	if err != nil {
+		return zz, err
+	}
+	if size > maxCategory {
+		return zz, fmt.Errorf("invalid dc category %d", size)
+	}
+	amp, err := br.readBits(int(size))
+	if err != nil {
+		return zz, err
+	}
+	zz[0] = prevDC + extend(amp, int(size))
+
+	for k := 1; k < len(zz); {
+		symbol, err := br.decode(acTable)
+		if err != nil {
+			return zz, err
+		}
+		if symbol == symbolEOB {
+			break
+		}
+
+		if symbol == symbolZRL {
+			k += 16
+			if k >= len(zz) {
+				return zz, fmt.Errorf("too many coefficients")
+			}
+			continue
+		}
+
+		run, size := int(symbol>>4), int(symbol&0x0F)
+		if size == 0 {
+			return zz, fmt.Errorf("invalid ac symbol 0x%02X", symbol)
+		}
+		k += run
+		if k >= len(zz) {
+			return zz, fmt.Errorf("too many coefficients")
+		}
+
+		amp, err := br.readBits(size)
+		if err != nil {
+			return zz, err
+		}
+		zz[k] = extend(amp, size)
+		k++
+	}
+	return zz, nil
+}
+
+// psnr сравнивает изображения по каналам R, G, B. Для совпадающих
+// изображений PSNR бесконечен, и вместо него возвращается nil.
+func psnr(original, reconstructed image.Image) (float64, *float64) {
+	bounds := original.Bounds()
+	rb := reconstructed.Bounds()
+
+	sum := 0.0
+	for y := range bounds.Dy() {
+		for x := range bounds.Dx() {
+			a := color.RGBAModel.Convert(original.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.RGBA)
+			b := color.RGBAModel.Convert(reconstructed.At(rb.Min.X+x, rb.Min.Y+y)).(color.RGBA)
+			for _, d := range []float64{
+				float64(a.R) - float64(b.R),
+				float64(a.G) - float64(b.G),
+				float64(a.B) - float64(b.B),
+			} {
+				sum += d * d
+			}
+		}
+	}
+
+	mse := sum / float64(3*bounds.Dx()*bounds.Dy())
+	if mse == 0 {
+		return 0, nil
+	}
+	value := 10 * math.Log10(255*255/mse)
+	return mse, &value
+}
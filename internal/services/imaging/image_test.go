@@ -0,0 +1,163 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"testing"
+)
+
+// testImage — плавный градиент с контрастной диагональю.
+func testImage(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			c := color.RGBA{
+				R: uint8(x * 255 / max(width-1, 1)),
+				G: uint8(y * 255 / max(height-1, 1)),
+				B: uint8((x + y) * 4),
+				A: 0xFF,
+			}
+			if x == y {
+				c = color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestImageService_Compress(t *testing.T) {
+	img := testImage(37, 21)
+	s := NewImageService()
+
+	tests := []struct {
+		name        string
+		quality     int
+		subsampling string
+		minPSNR     float64
+	}{
+		{name: "q100 4:4:4", quality: 100, subsampling: Subsampling444, minPSNR: 40},
+		{name: "q75 4:2:2", quality: 75, subsampling: Subsampling422, minPSNR: 25},
+		{name: "q50 4:2:0", quality: 50, subsampling: Subsampling420, minPSNR: 25},
+		{name: "q5 4:2:0", quality: 5, subsampling: Subsampling420, minPSNR: 15},
+	}
+
+	prevSize := math.MaxInt
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compressed, err := s.Compress(img, ImageOptions{Quality: tt.quality, Subsampling: tt.subsampling})
+			if err != nil {
+				t.Fatalf("ImageService.Compress() has err = %v", err)
+			}
+			details := compressed.Details
+			if details.PSNR == nil || *details.PSNR < tt.minPSNR {
+				t.Errorf("PSNR = %v, want >= %f", details.PSNR, tt.minPSNR)
+			}
+			if details.Size != len(compressed.Data) || details.Size >= prevSize {
+				t.Errorf("size = %d, want less than %d", details.Size, prevSize)
+			}
+			prevSize = details.Size
+
+			if compressed.Image.Bounds() != img.Bounds() {
+				t.Errorf("bounds = %v, want %v", compressed.Image.Bounds(), img.Bounds())
+			}
+
+			decompressed, err := s.Decompress(compressed.Data)
+			if err != nil {
+				t.Fatalf("ImageService.Decompress() has err = %v", err)
+			}
+			if !bytes.Equal(decompressed.(*image.RGBA).Pix, compressed.Image.(*image.RGBA).Pix) {
+				t.Errorf("ImageService.Decompress() differs from reconstructed image")
+			}
+		})
+	}
+}
+
+func TestImageService_CompressBlockDump(t *testing.T) {
+	s := NewImageService()
+	compressed, err := s.Compress(testImage(16, 16), ImageOptions{
+		Quality: 50,
+		Block:   &BlockPosition{Component: ComponentY, X: 1, Y: 0},
+	})
+	if err != nil {
+		t.Fatalf("ImageService.Compress() has err = %v", err)
+	}
+
+	dump := compressed.Details.Block
+	if dump == nil {
+		t.Fatalf("block dump is nil")
+	}
+	if len(dump.RunLevels) == 0 || dump.RunLevels[0].Symbol != "DC" || dump.RunLevels[0].Code == "" {
+		t.Errorf("run levels = %+v, want DC with code first", dump.RunLevels)
+	}
+	for i, v := range dump.Zigzag {
+		row, col := zigzagOrder[i]/blockSize, zigzagOrder[i]%blockSize
+		if dump.Quantized[row][col] != v {
+			t.Errorf("zigzag[%d] = %d, want %d", i, v, dump.Quantized[row][col])
+		}
+	}
+
+	_, err = s.Compress(testImage(16, 16), ImageOptions{Block: &BlockPosition{Component: ComponentCb, X: 1, Y: 0}})
+	if err == nil {
+		t.Errorf("ImageService.Compress() with block out of range has no err")
+	}
+}
+
+func TestImageService_DecodeImage(t *testing.T) {
+	want := testImage(9, 7)
+	buf := new(bytes.Buffer)
+	if err := png.Encode(buf, want); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewImageService()
+	for name, data := range map[string][]byte{"png": buf.Bytes(), "bmp": makeBMP(want)} {
+		t.Run(name, func(t *testing.T) {
+			img, err := s.DecodeImage(data)
+			if err != nil {
+				t.Fatalf("ImageService.DecodeImage() has err = %v", err)
+			}
+			if img.Bounds() != want.Bounds() {
+				t.Errorf("bounds = %v, want %v", img.Bounds(), want.Bounds())
+			}
+		})
+	}
+
+	if _, err := s.DecodeImage([]byte("GIF89a")); err == nil {
+		t.Errorf("ImageService.DecodeImage() with unknown format has no err")
+	}
+}
+
+func TestImageService_DecompressInvalid(t *testing.T) {
+	s := NewImageService()
+	compressed, err := s.Compress(testImage(8, 8), ImageOptions{})
+	if err != nil {
+		t.Fatalf("ImageService.Compress() has err = %v", err)
+	}
+
+	for n := range len(compressed.Data) - 1 {
+		if _, err := s.Decompress(compressed.Data[:n]); err == nil {
+			t.Errorf("ImageService.Decompress() of %d bytes has no err", n)
+		}
+	}
+}
+
+func FuzzImageService_Decompress(f *testing.F) {
+	s := NewImageService()
+	for _, opts := range []ImageOptions{{}, {Quality: 10, Subsampling: Subsampling420}} {
+		compressed, err := s.Compress(testImage(20, 12), opts)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(compressed.Data)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		img, err := s.Decompress(data)
+		if err == nil && img.Bounds().Empty() {
+			t.Fatalf("ImageService.Decompress() = empty image")
+		}
+	})
+}
@@ -1,129 +1,363 @@
+// Package bitsio реализует побитовые запись и чтение.
+//
+// Биты накапливаются в 64-битном регистре и передаются целыми байтами.
+// Порядок MSBFirst заполняет байт от старшего бита к младшему (как в JPEG
+// и LZW), LSBFirst — от младшего к старшему (как в DEFLATE). Многобитное
+// значение в порядке MSBFirst записывается начиная со старшего бита,
+// в порядке LSBFirst — с младшего, поэтому ReadBits возвращает то же число.
 package bitsio
 
 import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
 	"unicode/utf8"
 )
 
+// ErrUnexpectedEOF возвращается при чтении за концом данных.
+var ErrUnexpectedEOF = errors.New("bitsio: unexpected end of data")
+
+type BitOrder int
+
+const (
+	MSBFirst BitOrder = iota
+	LSBFirst
+)
+
+// MaxBits — наибольшее число бит, читаемое или записываемое за один вызов.
+const MaxBits = 64
+
+// writerFlushSize — объём накопленных байт, после которого BitWriter,
+// созданный NewWriter, передаёт их в нижележащий io.Writer.
+const writerFlushSize = 4 << 10
+
 type BitWriter struct {
-	buf    []byte
-	ptr    int
-	bitPtr byte
+	w     io.Writer
+	order BitOrder
+	out   []byte
+	acc   uint64
+	nacc  int
+	bits  int64
+	err   error
 }
 
+// NewBitWriter возвращает BitWriter, накапливающий данные в памяти
+// в порядке MSBFirst. Результат возвращает Bytes.
 func NewBitWriter() *BitWriter {
-	return &BitWriter{buf: []byte{0x00}}
+	return &BitWriter{}
+}
+
+// NewWriter возвращает BitWriter, передающий байты в w. Неполный последний
+// байт дописывается нулями при Flush.
+func NewWriter(w io.Writer, order BitOrder) *BitWriter {
+	return &BitWriter{w: w, order: order}
+}
+
+// WriteBits записывает n младших бит v, 0 <= n <= MaxBits.
+func (bw *BitWriter) WriteBits(v uint64, n int) error {
+	if bw.err != nil {
+		return bw.err
+	}
+	if n < 0 || n > MaxBits {
+		panic("bitsio: invalid number of bits")
+	}
+	// В регистре всегда меньше 8 бит, поэтому за раз добавляется не больше 56.
+	if n > 56 {
+		if bw.order == MSBFirst {
+			bw.WriteBits(v>>32, n-32)
+			return bw.WriteBits(v, 32)
+		}
+		bw.WriteBits(v, 32)
+		return bw.WriteBits(v>>32, n-32)
+	}
+
+	v &= 1<<n - 1
+	if bw.order == MSBFirst {
+		bw.acc = bw.acc<<n | v
+		bw.nacc += n
+		for bw.nacc >= 8 {
+			bw.nacc -= 8
+			bw.out = append(bw.out, byte(bw.acc>>bw.nacc))
+		}
+		bw.acc &= 1<<bw.nacc - 1
+	} else {
+		bw.acc |= v << bw.nacc
+		bw.nacc += n
+		for bw.nacc >= 8 {
+			bw.out = append(bw.out, byte(bw.acc))
+			bw.acc >>= 8
+			bw.nacc -= 8
+		}
+	}
+	bw.bits += int64(n)
+
+	if bw.w != nil && len(bw.out) >= writerFlushSize {
+		return bw.flushBytes()
+	}
+	return nil
 }
 
-func (bw *BitWriter) WriteBit(bit bool) {
+func (bw *BitWriter) WriteBit(bit bool) error {
 	if bit {
-		offset := 7 - bw.bitPtr
-		bw.buf[bw.ptr] |= (1 << offset)
+		return bw.WriteBits(1, 1)
 	}
-	bw.moveBitPtr()
+	return bw.WriteBits(0, 1)
 }
 
-func (bw *BitWriter) moveBitPtr() {
-	bw.bitPtr++
-	if bw.bitPtr >= 8 {
-		bw.buf = append(bw.buf, 0x00)
-		bw.ptr++
-		bw.bitPtr = 0
+func (bw *BitWriter) WriteByte(b byte) error {
+	return bw.WriteBits(uint64(b), 8)
+}
+
+// Write записывает байты p начиная с текущей позиции, не обязательно
+// выровненной по границе байта.
+func (bw *BitWriter) Write(p []byte) (int, error) {
+	if bw.nacc == 0 && bw.err == nil {
+		bw.out = append(bw.out, p...)
+		bw.bits += 8 * int64(len(p))
+		if bw.w != nil && len(bw.out) >= writerFlushSize {
+			if err := bw.flushBytes(); err != nil {
+				return 0, err
+			}
+		}
+		return len(p), nil
+	}
+	for i, b := range p {
+		if err := bw.WriteByte(b); err != nil {
+			return i, err
+		}
 	}
+	return len(p), nil
+}
+
+// WriteRune записывает символ в UTF-8 и возвращает число его байт.
+func (bw *BitWriter) WriteRune(r rune) (int, error) {
+	var b [utf8.UTFMax]byte
+	n := utf8.EncodeRune(b[:], r)
+	return bw.Write(b[:n])
 }
 
-func (bw *BitWriter) WriteByte(b byte) {
-	for i := range 8 {
-		offset := 7 - i
-		val := b & (1 << offset)
-		bw.WriteBit(!(val == 0))
+// Align дополняет нулями текущий байт до границы.
+func (bw *BitWriter) Align() error {
+	if bw.nacc == 0 {
+		return bw.err
 	}
+	return bw.WriteBits(0, 8-bw.nacc)
 }
 
-func (bw *BitWriter) WtiteRune(r rune) {
-	if uint32(r) < utf8.RuneSelf {
-		bw.WriteByte(byte(r))
-		return
+// Flush выравнивает запись по границе байта и передаёт накопленные байты
+// в нижележащий io.Writer.
+func (bw *BitWriter) Flush() error {
+	if err := bw.Align(); err != nil {
+		return err
 	}
-	b := make([]byte, 4)
-	n := utf8.EncodeRune(b, r)
-	for i := range b[:n] {
-		bw.WriteByte(b[i])
+	if bw.w == nil {
+		return nil
 	}
+	return bw.flushBytes()
 }
 
+func (bw *BitWriter) flushBytes() error {
+	if len(bw.out) == 0 {
+		return nil
+	}
+	_, bw.err = bw.w.Write(bw.out)
+	bw.out = bw.out[:0]
+	return bw.err
+}
+
+// BitsLeftToByte возвращает, сколько бит осталось записать до границы байта.
 func (bw *BitWriter) BitsLeftToByte() byte {
-	return 8 - bw.bitPtr
+	return byte(8 - bw.nacc)
 }
 
+// Len возвращает число записанных бит.
+func (bw *BitWriter) Len() int64 {
+	return bw.bits
+}
+
+// Bytes возвращает записанные данные; неполный последний байт дополняется нулями.
+// Для BitWriter, созданного NewWriter, это только ещё не переданные байты.
 func (bw *BitWriter) Bytes() []byte {
-	if bw.bitPtr == 0 {
-		return bw.buf[:len(bw.buf)-1]
+	if bw.nacc == 0 {
+		return bw.out
+	}
+	var last byte
+	if bw.order == MSBFirst {
+		last = byte(bw.acc << (8 - bw.nacc))
+	} else {
+		last = byte(bw.acc)
 	}
-	return bw.buf
+	return append(bw.out[:len(bw.out):len(bw.out)], last)
 }
 
+// BitReader читает биты в заданном порядке. Чтение за концом данных
+// возвращает ErrUnexpectedEOF и не продвигает позицию; ошибка сохраняется в Err.
 type BitReader struct {
-	buf    []byte
-	ptr    int
-	bitPtr byte
+	r     io.ByteReader
+	order BitOrder
+	acc   uint64
+	nacc  int
+	bits  int64
+	eof   bool
+	err   error
 }
 
+// NewBitReader возвращает BitReader, читающий b в порядке MSBFirst.
 func NewBitReader(b []byte) *BitReader {
-	return &BitReader{buf: b}
+	return NewReader(bytes.NewReader(b), MSBFirst)
 }
 
-func (br *BitReader) ReadBit() bool {
-	// if br.IsEmpty() {
-	// 	return false, fmt.Errorf("is empty")
-	// }
-	offset := 7 - br.bitPtr
-	val := br.buf[br.ptr] & (1 << offset)
-	br.moveBitPtr()
-	return !(val == 0)
-	// return !(val == 0), nil
+// NewReader возвращает BitReader, читающий r. Если r не реализует
+// io.ByteReader, чтение буферизуется.
+func NewReader(r io.Reader, order BitOrder) *BitReader {
+	br, ok := r.(io.ByteReader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &BitReader{r: br, order: order}
 }
 
-func (br *BitReader) moveBitPtr() {
-	br.bitPtr++
-	if br.bitPtr >= 8 {
-		br.ptr++
-		br.bitPtr = 0
+// fill дочитывает байты, пока в регистре меньше n бит, n <= 56.
+func (br *BitReader) fill(n int) {
+	for br.nacc < n && !br.eof {
+		b, err := br.r.ReadByte()
+		if err != nil {
+			br.eof = true
+			if err != io.EOF {
+				br.err = err
+			}
+			return
+		}
+		if br.order == MSBFirst {
+			br.acc = br.acc<<8 | uint64(b)
+		} else {
+			br.acc |= uint64(b) << br.nacc
+		}
+		br.nacc += 8
 	}
 }
 
-func (br *BitReader) ReadByte() byte {
-	bitWriter := NewBitWriter()
-	for range 8 {
-		bitWriter.WriteBit(br.ReadBit())
+// ReadBits читает n бит, 0 <= n <= MaxBits.
+func (br *BitReader) ReadBits(n int) (uint64, error) {
+	if n < 0 || n > MaxBits {
+		panic("bitsio: invalid number of bits")
+	}
+	if n > 56 {
+		if br.order == MSBFirst {
+			high, err := br.ReadBits(n - 32)
+			if err != nil {
+				return 0, err
+			}
+			low, err := br.ReadBits(32)
+			return high<<32 | low, err
+		}
+		low, err := br.ReadBits(32)
+		if err != nil {
+			return 0, err
+		}
+		high, err := br.ReadBits(n - 32)
+		return high<<32 | low, err
 	}
-	return bitWriter.Bytes()[0]
+
+	br.fill(n)
+	if br.nacc < n {
+		if br.err == nil {
+			br.err = ErrUnexpectedEOF
+		}
+		return 0, br.err
+	}
+
+	var v uint64
+	if br.order == MSBFirst {
+		br.nacc -= n
+		v = br.acc >> br.nacc
+		br.acc &= 1<<br.nacc - 1
+	} else {
+		v = br.acc & (1<<n - 1)
+		br.acc >>= n
+		br.nacc -= n
+	}
+	br.bits += int64(n)
+	return v, nil
+}
+
+func (br *BitReader) ReadBit() (bool, error) {
+	v, err := br.ReadBits(1)
+	return v == 1, err
+}
+
+func (br *BitReader) ReadByte() (byte, error) {
+	v, err := br.ReadBits(8)
+	return byte(v), err
 }
 
-func (br *BitReader) ReadRune() rune {
-	b := make([]byte, 0)
-	b = append(b, br.ReadByte())
-	for !utf8.Valid(b) {
-		b = append(b, br.ReadByte())
+// Read читает байты начиная с текущей позиции, не обязательно выровненной.
+// Если в конце данных остался неполный байт, возвращается ErrUnexpectedEOF.
+func (br *BitReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		br.fill(8)
+		if br.nacc < 8 {
+			break
+		}
+		p[n], _ = br.ReadByte()
+		n++
 	}
-	r, _ := utf8.DecodeRune(b)
-	return r
+	switch {
+	case n > 0 || len(p) == 0:
+		return n, nil
+	case br.err != nil:
+		return 0, br.err
+	case br.nacc > 0:
+		return 0, ErrUnexpectedEOF
+	}
+	return 0, io.EOF
 }
 
-func (br *BitReader) FinishByte() {
-	if br.bitPtr != 0 {
-		br.ptr++
-		br.bitPtr = 0
+// ReadRune читает символ UTF-8. Для неверной последовательности возвращается
+// utf8.RuneError, а размером — число действительно прочитанных байт: от 1 до
+// utf8.UTFMax, включая байт, на котором последовательность оборвалась.
+func (br *BitReader) ReadRune() (rune, int, error) {
+	b := make([]byte, 0, utf8.UTFMax)
+	for len(b) == 0 || !utf8.FullRune(b) {
+		c, err := br.ReadByte()
+		if err != nil {
+			return 0, 0, err
+		}
+		b = append(b, c)
+	}
+	r, size := utf8.DecodeRune(b)
+	if r == utf8.RuneError {
+		size = len(b)
 	}
+	return r, size, nil
 }
 
-func (br *BitReader) NumReadByte() int {
-	return br.ptr
+// Align пропускает оставшиеся биты текущего байта.
+func (br *BitReader) Align() {
+	n := br.nacc % 8
+	if br.order == MSBFirst {
+		br.acc &= 1<<(br.nacc-n) - 1
+	} else {
+		br.acc >>= n
+	}
+	br.nacc -= n
+	br.bits += int64(n)
 }
 
-func (br *BitReader) IsLastByte() bool {
-	return br.ptr+1 == len(br.buf)
+// BitsRead возвращает число прочитанных бит.
+func (br *BitReader) BitsRead() int64 {
+	return br.bits
 }
 
+// IsEmpty сообщает, что непрочитанных бит не осталось.
 func (br *BitReader) IsEmpty() bool {
-	return br.ptr >= len(br.buf)
+	br.fill(1)
+	return br.nacc == 0
+}
+
+// Err возвращает первую ошибку чтения, например ErrUnexpectedEOF.
+func (br *BitReader) Err() error {
+	return br.err
 }
@@ -1,6 +1,8 @@
 package bitsio_test
 
 import (
+	"bytes"
+	"io"
 	"testing"
 	"unicode/utf8"
 
@@ -31,7 +33,7 @@ func TestBitWriter_WriteByte(t *testing.T) {
 func TestBitWriter_WriteRune_OneByte(t *testing.T) {
 	bw := NewBitWriter()
 	want := 'a'
-	bw.WtiteRune(want)
+	bw.WriteRune(want)
 	r, _ := utf8.DecodeRune(bw.Bytes())
 	if r != want {
 		t.Fatalf("BitWriter = %v; want %v", r, want)
@@ -41,7 +43,7 @@ func TestBitWriter_WriteRune_OneByte(t *testing.T) {
 func TestBitWriter_WriteRune_TwoByte(t *testing.T) {
 	bw := NewBitWriter()
 	want := 'а'
-	bw.WtiteRune(want)
+	bw.WriteRune(want)
 	r, _ := utf8.DecodeRune(bw.Bytes())
 	if r != want {
 		t.Fatalf("BitWriter = %v; want %v", r, want)
@@ -71,3 +73,235 @@ func TestBitWriter_BitsLeftToByte(t *testing.T) {
 		t.Fatalf("BitWriter = %v; want %v", bits, 8)
 	}
 }
+
+func TestBitReader_ReadPastEnd(t *testing.T) {
+	br := NewBitReader([]byte{0xFF})
+	if b, err := br.ReadByte(); b != 0xFF || err != nil {
+		t.Fatalf("BitReader.ReadByte() = %x, %v; want ff, nil", b, err)
+	}
+	if bit, err := br.ReadBit(); bit || err != ErrUnexpectedEOF {
+		t.Fatalf("BitReader.ReadBit() past end = %v, %v; want false, %v", bit, err, ErrUnexpectedEOF)
+	}
+	if br.Err() != ErrUnexpectedEOF {
+		t.Fatalf("BitReader.Err() = %v; want %v", br.Err(), ErrUnexpectedEOF)
+	}
+}
+
+func TestBitReader_ReadRune_Invalid(t *testing.T) {
+	br := NewBitReader([]byte{0xFF, 'a', 0xD0})
+	if r, size, err := br.ReadRune(); r != utf8.RuneError || size != 1 || err != nil {
+		t.Fatalf("BitReader.ReadRune() = %q, %d, %v; want %q, 1, nil", r, size, err, utf8.RuneError)
+	}
+	if r, _, _ := br.ReadRune(); r != 'a' {
+		t.Fatalf("BitReader.ReadRune() = %q; want %q", r, 'a')
+	}
+	if _, _, err := br.ReadRune(); err != ErrUnexpectedEOF {
+		t.Fatalf("BitReader.ReadRune() of truncated rune has err = %v; want %v", err, ErrUnexpectedEOF)
+	}
+
+	// Второй байт не продолжает последовательность, но уже прочитан.
+	br = NewBitReader([]byte{0xE2, 'a', 'b'})
+	if r, size, err := br.ReadRune(); r != utf8.RuneError || size != 2 || err != nil {
+		t.Fatalf("BitReader.ReadRune() = %q, %d, %v; want %q, 2, nil", r, size, err, utf8.RuneError)
+	}
+	if r, _, _ := br.ReadRune(); r != 'b' {
+		t.Fatalf("BitReader.ReadRune() = %q; want %q", r, 'b')
+	}
+}
+
+func TestBits(t *testing.T) {
+	values := []struct {
+		v uint64
+		n int
+	}{
+		{1, 1}, {0, 3}, {0x5, 3}, {0x1FF, 9}, {0, 0}, {0xABCDE, 20},
+		{0xFEDCBA9876543210, 64}, {0x123456789ABCDEF, 60}, {0x3, 2},
+	}
+	tests := []struct {
+		order BitOrder
+		// prefix — первые байты записи {1, 1}, {0, 3}, {0x5, 3}, {0x1FF, 9}.
+		prefix []byte
+	}{
+		{MSBFirst, []byte{0b10001011, 0b11111111}},
+		{LSBFirst, []byte{0b11010001, 0b11111111}},
+	}
+	for _, tt := range tests {
+		buf := new(bytes.Buffer)
+		bw := NewWriter(buf, tt.order)
+		bits := 0
+		for _, value := range values {
+			if err := bw.WriteBits(value.v, value.n); err != nil {
+				t.Fatal(err)
+			}
+			bits += value.n
+		}
+		if bw.Len() != int64(bits) {
+			t.Errorf("order %d: BitWriter.Len() = %d; want %d", tt.order, bw.Len(), bits)
+		}
+		if err := bw.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		if buf.Len() != (bits+7)/8 || !bytes.HasPrefix(buf.Bytes(), tt.prefix) {
+			t.Fatalf("order %d: written %08b; want %d bytes with prefix %08b", tt.order, buf.Bytes(), (bits+7)/8, tt.prefix)
+		}
+
+		br := NewReader(bytes.NewReader(buf.Bytes()), tt.order)
+		for i, value := range values {
+			v, err := br.ReadBits(value.n)
+			if err != nil || v != value.v {
+				t.Fatalf("order %d: BitReader.ReadBits(%d) #%d = %x, %v; want %x", tt.order, value.n, i, v, err, value.v)
+			}
+		}
+		if br.BitsRead() != int64(bits) {
+			t.Errorf("order %d: BitReader.BitsRead() = %d; want %d", tt.order, br.BitsRead(), bits)
+		}
+		br.Align()
+		if !br.IsEmpty() {
+			t.Errorf("order %d: BitReader is not empty after padding", tt.order)
+		}
+		if _, err := br.ReadBits(1); err != ErrUnexpectedEOF {
+			t.Errorf("order %d: BitReader.ReadBits() past end has err = %v; want %v", tt.order, err, ErrUnexpectedEOF)
+		}
+	}
+}
+
+func TestBitReader_ReadBitsDoesNotConsumeOnEOF(t *testing.T) {
+	br := NewBitReader([]byte{0xA5})
+	if _, err := br.ReadBits(9); err != ErrUnexpectedEOF {
+		t.Fatalf("BitReader.ReadBits(9) has err = %v; want %v", err, ErrUnexpectedEOF)
+	}
+	if v, err := br.ReadBits(8); v != 0xA5 || err != nil {
+		t.Fatalf("BitReader.ReadBits(8) = %x, %v; want a5, nil", v, err)
+	}
+}
+
+func TestIOAdapters(t *testing.T) {
+	for _, order := range []BitOrder{MSBFirst, LSBFirst} {
+		buf := new(bytes.Buffer)
+		bw := NewWriter(buf, order)
+		bw.WriteBits(0x5, 3)
+		if _, err := io.WriteString(bw, "unaligned"); err != nil {
+			t.Fatal(err)
+		}
+		bw.Align()
+		if bw.Len() != 8*10 {
+			t.Fatalf("order %d: BitWriter.Len() after Align = %d; want 80", order, bw.Len())
+		}
+		bw.Write([]byte("aligned"))
+		bw.Flush()
+
+		br := NewReader(buf, order)
+		if v, _ := br.ReadBits(3); v != 0x5 {
+			t.Fatalf("order %d: BitReader.ReadBits(3) = %x; want 5", order, v)
+		}
+		unaligned := make([]byte, len("unaligned"))
+		if _, err := io.ReadFull(br, unaligned); err != nil || string(unaligned) != "unaligned" {
+			t.Fatalf("order %d: BitReader.Read() = %q, %v; want %q", order, unaligned, err, "unaligned")
+		}
+		br.Align()
+		rest, err := io.ReadAll(br)
+		if err != nil || string(rest) != "aligned" {
+			t.Fatalf("order %d: io.ReadAll(BitReader) = %q, %v; want %q", order, rest, err, "aligned")
+		}
+	}
+
+	var _ io.ByteReader = NewBitReader(nil)
+	var _ io.ByteWriter = NewBitWriter()
+}
+
+func TestBitReader_ReadPartialByte(t *testing.T) {
+	br := NewBitReader([]byte{0xFF, 0x80})
+	br.ReadBits(4)
+	p := make([]byte, 2)
+	if n, err := br.Read(p); n != 1 || err != nil {
+		t.Fatalf("BitReader.Read() = %d, %v; want 1, nil", n, err)
+	}
+	if n, err := br.Read(p); n != 0 || err != ErrUnexpectedEOF {
+		t.Fatalf("BitReader.Read() of partial byte = %d, %v; want 0, %v", n, err, ErrUnexpectedEOF)
+	}
+}
+
+func FuzzBitReader(f *testing.F) {
+	f.Add([]byte{}, []byte{1, 9, 64})
+	f.Add([]byte("abc"), []byte{3, 0, 13})
+	f.Add([]byte("Ёж\xff\xe0"), []byte{57, 7})
+	f.Fuzz(func(t *testing.T, data []byte, widths []byte) {
+		br := NewBitReader(data)
+		for range 8*len(data) + 16 {
+			br.ReadBit()
+		}
+		if br.Err() != ErrUnexpectedEOF {
+			t.Fatalf("BitReader.Err() = %v after overrun; want %v", br.Err(), ErrUnexpectedEOF)
+		}
+
+		br = NewBitReader(data)
+		for !br.IsEmpty() {
+			br.ReadRune()
+		}
+
+		// Копирование кусками произвольной ширины воспроизводит данные
+		// в обоих порядках бит.
+		for _, order := range []BitOrder{MSBFirst, LSBFirst} {
+			buf := new(bytes.Buffer)
+			bw := NewWriter(buf, order)
+			br = NewReader(bytes.NewReader(data), order)
+			for i := 0; br.BitsRead() < int64(8*len(data)); i++ {
+				n := 1
+				if len(widths) > 0 {
+					n = int(widths[i%len(widths)]) % (MaxBits + 1)
+				}
+				n = min(n, 8*len(data)-int(br.BitsRead()))
+				v, err := br.ReadBits(n)
+				if err != nil {
+					t.Fatalf("BitReader.ReadBits(%d) has err = %v", n, err)
+				}
+				if n < MaxBits && v>>n != 0 {
+					t.Fatalf("BitReader.ReadBits(%d) = %x has extra bits", n, v)
+				}
+				bw.WriteBits(v, n)
+			}
+			bw.Flush()
+			if !bytes.Equal(buf.Bytes(), data) {
+				t.Fatalf("order %d: copy = %x; want %x", order, buf.Bytes(), data)
+			}
+		}
+	})
+}
+
+// BenchmarkWriteBit и BenchmarkWriteBits записывают одни и те же 9-битные коды
+// по одному биту и целиком.
+func BenchmarkWriteBit(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		bw := NewBitWriter()
+		for code := range 1 << 12 {
+			for j := 8; j >= 0; j-- {
+				bw.WriteBit(code>>j&1 == 1)
+			}
+		}
+	}
+}
+
+func BenchmarkWriteBits(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		bw := NewBitWriter()
+		for code := range 1 << 12 {
+			bw.WriteBits(uint64(code), 9)
+		}
+	}
+}
+
+func BenchmarkReadBits(b *testing.B) {
+	bw := NewBitWriter()
+	for code := range 1 << 12 {
+		bw.WriteBits(uint64(code), 9)
+	}
+	data := bw.Bytes()
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		br := NewBitReader(data)
+		for range 1 << 12 {
+			br.ReadBits(9)
+		}
+	}
+}